@@ -13,12 +13,51 @@ import (
 
 	dbm "github.com/tendermint/tmlibs/db"
 
+	"github.com/bytom/clock"
 	"github.com/bytom/crypto/sha3pool"
+	"github.com/bytom/database"
 	"github.com/bytom/errors"
 )
 
 const tokenSize = 32
 
+// tokenNS is the bucket holding the tokens, keyed by json-encoded ID.
+const tokenNS database.Namespace = "AT:"
+
+func init() {
+	// tokens used to live under raw json(id) keys at the root of the
+	// database; move them into the token bucket
+	database.RegisterMigration("accesstoken", database.Migration{
+		Version: 1,
+		Name:    "move access tokens into the AT: bucket",
+		Apply: func(db dbm.DB) error {
+			type record struct{ key, value []byte }
+			legacy := []record{}
+
+			iter := db.Iterator()
+			for iter.Next() {
+				// legacy keys are json strings; bucket keys and
+				// bookkeeping keys never start with a quote
+				if key := iter.Key(); len(key) > 0 && key[0] == '"' {
+					legacy = append(legacy, record{
+						key:   append([]byte{}, key...),
+						value: append([]byte{}, iter.Value()...),
+					})
+				}
+			}
+			iter.Release()
+
+			batch := db.NewBatch()
+			for _, rec := range legacy {
+				batch.Set(tokenNS.Key(rec.key), rec.value)
+				batch.Delete(rec.key)
+			}
+			batch.Write()
+			return nil
+		},
+	})
+}
+
 var (
 	// ErrBadID is returned when Create is called on an invalid id string.
 	ErrBadID = errors.New("invalid id")
@@ -44,13 +83,15 @@ type Token struct {
 
 // CredentialStore store user access credential.
 type CredentialStore struct {
-	DB dbm.DB
+	DB     dbm.DB
+	bucket *database.Bucket
 }
 
 // NewStore creates and returns a new Store object.
 func NewStore(db dbm.DB) *CredentialStore {
 	return &CredentialStore{
-		DB: db,
+		DB:     db,
+		bucket: database.NewBucket(db, tokenNS),
 	}
 }
 
@@ -60,7 +101,7 @@ func (cs *CredentialStore) Create(ctx context.Context, id, typ string) (*string,
 		return nil, errors.WithDetailf(ErrBadID, "invalid id %q", id)
 	}
 	k, err := json.Marshal(id)
-	if v := cs.DB.Get(k); v != nil {
+	if v := cs.bucket.Get(k); v != nil {
 		return nil, errors.WithDetailf(ErrDuplicateID, "id %q already in use", id)
 	}
 	var secret [tokenSize]byte
@@ -71,7 +112,7 @@ func (cs *CredentialStore) Create(ctx context.Context, id, typ string) (*string,
 
 	var hashedSecret [tokenSize]byte
 	sha3pool.Sum256(hashedSecret[:], secret[:])
-	created := time.Now()
+	created := clock.Now()
 
 	token := &Token{
 		ID:      id,
@@ -88,7 +129,7 @@ func (cs *CredentialStore) Create(ctx context.Context, id, typ string) (*string,
 	if err != nil {
 		return nil, err
 	}
-	cs.DB.Set(key, value)
+	cs.bucket.Set(key, value)
 	hexsec := fmt.Sprintf("%s:%x", id, secret)
 	return &hexsec, nil
 }
@@ -112,7 +153,7 @@ func (cs *CredentialStore) Check(ctx context.Context, id string, secret []byte)
 		return false, err
 	}
 
-	if value = cs.DB.Get(k); value == nil {
+	if value = cs.bucket.Get(k); value == nil {
 		return false, errors.WithDetailf(ErrNoMatchID, "check id %q nonexisting", id)
 	}
 	if err := json.Unmarshal(value, token); err != nil {
@@ -126,10 +167,30 @@ func (cs *CredentialStore) Check(ctx context.Context, id string, secret []byte)
 	return false, nil
 }
 
+// Get returns the stored token record for the given id.
+func (cs *CredentialStore) Get(ctx context.Context, id string) (*Token, error) {
+	if !validIDRegexp.MatchString(id) {
+		return nil, errors.WithDetailf(ErrBadID, "invalid id %q", id)
+	}
+	k, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	value := cs.bucket.Get(k)
+	if value == nil {
+		return nil, errors.WithDetailf(ErrNoMatchID, "get id %q nonexisting", id)
+	}
+	token := &Token{}
+	if err := json.Unmarshal(value, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
 // List lists all access tokens.
 func (cs *CredentialStore) List(ctx context.Context) ([]*Token, error) {
 	tokens := make([]*Token, 0)
-	iter := cs.DB.Iterator()
+	iter := cs.bucket.Iterator()
 	defer iter.Release()
 
 	for iter.Next() {
@@ -151,7 +212,7 @@ func (cs *CredentialStore) Delete(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
-	cs.DB.Delete(k)
+	cs.bucket.Delete(k)
 
 	return nil
 }
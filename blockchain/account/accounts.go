@@ -17,16 +17,19 @@ import (
 	"github.com/bytom/common"
 	"github.com/bytom/crypto/ed25519/chainkd"
 	"github.com/bytom/crypto/sha3pool"
+	"github.com/bytom/database"
 	"github.com/bytom/errors"
 	"github.com/bytom/protocol"
 	"github.com/bytom/protocol/vm/vmutil"
 )
 
+const maxAccountCache = 1000
+
+// the account buckets of the wallet database
 const (
-	maxAccountCache = 1000
-	aliasPrefix     = "ALI:"
-	accountPrefix   = "ACC:"
-	accountCPPrefix = "ACP:"
+	aliasNS     database.Namespace = "ALI:"
+	accountNS   database.Namespace = "ACC:"
+	accountCPNS database.Namespace = "ACP:"
 )
 
 // pre-define errors for supporting bytom errorFormatter
@@ -36,17 +39,17 @@ var (
 )
 
 func aliasKey(name string) []byte {
-	return []byte(aliasPrefix + name)
+	return aliasNS.StringKey(name)
 }
 
 //Key account store prefix
 func Key(name string) []byte {
-	return []byte(accountPrefix + name)
+	return accountNS.StringKey(name)
 }
 
 //CPKey account control promgram store prefix
 func CPKey(hash common.Hash) []byte {
-	return append([]byte(accountCPPrefix), hash[:]...)
+	return accountCPNS.Key(hash[:])
 }
 
 // NewManager creates a new account manager
@@ -55,6 +58,7 @@ func NewManager(walletDB dbm.DB, chain *protocol.Chain) *Manager {
 		db:          walletDB,
 		chain:       chain,
 		utxoDB:      newReserver(chain, walletDB),
+		cpFilter:    newCPFilter(walletDB),
 		cache:       lru.New(maxAccountCache),
 		aliasCache:  lru.New(maxAccountCache),
 		delayedACPs: make(map[*txbuilder.TemplateBuilder][]*CtrlProgram),
@@ -63,9 +67,10 @@ func NewManager(walletDB dbm.DB, chain *protocol.Chain) *Manager {
 
 // Manager stores accounts and their associated control programs.
 type Manager struct {
-	db     dbm.DB
-	chain  *protocol.Chain
-	utxoDB *reserver
+	db       dbm.DB
+	chain    *protocol.Chain
+	utxoDB   *reserver
+	cpFilter *CPFilter
 
 	cacheMu    sync.Mutex
 	cache      *lru.Cache
@@ -280,13 +285,47 @@ func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*Ctr
 
 		sha3pool.Sum256(hash[:], prog.ControlProgram)
 		m.db.Set(CPKey(hash), accountCP)
+		m.cpFilter.addHash([32]byte(hash))
 	}
 	return nil
 }
 
+// CPFilter returns the filter over this wallet's control programs, for
+// the block indexer to pre-screen transactions with.
+func (m *Manager) CPFilter() *CPFilter {
+	return m.cpFilter
+}
+
+// AccountIDByProgram returns the ID of the account owning the control
+// program, or the empty string when the program is not local.
+func (m *Manager) AccountIDByProgram(prog []byte) string {
+	var hash common.Hash
+	sha3pool.Sum256(hash[:], prog)
+	if !m.cpFilter.MatchHash([32]byte(hash)) {
+		return ""
+	}
+	raw := m.db.Get(CPKey(hash))
+	if raw == nil {
+		return ""
+	}
+	cp := CtrlProgram{}
+	if err := json.Unmarshal(raw, &cp); err != nil {
+		return ""
+	}
+	return cp.AccountID
+}
+
+// IsLocalControlProgram reports whether the control program belongs to
+// one of this wallet's accounts.
+func (m *Manager) IsLocalControlProgram(prog []byte) bool {
+	var hash common.Hash
+	sha3pool.Sum256(hash[:], prog)
+	return m.db.Get(CPKey(hash)) != nil
+}
+
 // GetCoinbaseControlProgram will return a coinbase script
 func (m *Manager) GetCoinbaseControlProgram(height uint64) ([]byte, error) {
-	signerIter := m.db.IteratorPrefix([]byte(accountPrefix))
+	signerIter := m.db.IteratorPrefix([]byte(accountNS))
 	if !signerIter.Next() {
 		log.Warningf("GetCoinbaseControlProgram: can't find any account in db")
 		return vmutil.CoinbaseProgram(nil, 0, height)
@@ -350,7 +389,7 @@ func (m *Manager) nextIndex(ctx context.Context) (uint64, error) {
 // QueryAll will return all the account in the db
 func (m *Manager) QueryAll(ctx context.Context) (interface{}, error) {
 	accounts := make([]interface{}, 0)
-	accountIter := m.db.IteratorPrefix([]byte(accountPrefix))
+	accountIter := m.db.IteratorPrefix([]byte(accountNS))
 	for accountIter.Next() {
 		accounts = append(accounts, string(accountIter.Value()))
 	}
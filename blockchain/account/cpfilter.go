@@ -0,0 +1,92 @@
+package account
+
+import (
+	"encoding/binary"
+	"sync"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/crypto/sha3pool"
+)
+
+const (
+	// cpFilterBits sizes the filter at 1MBit (128KB). With four probes
+	// per program the false-positive rate stays below one percent up to
+	// roughly a hundred thousand control programs, and a false positive
+	// only costs the database lookup the filter would otherwise save.
+	cpFilterBits   = 1 << 20
+	cpFilterProbes = 4
+)
+
+// CPFilter is an in-memory bloom filter over the sha3-256 hashes of the
+// wallet's control programs. Block indexing consults it to skip
+// transactions that definitely don't pay the wallet without touching
+// the database. Control programs are only ever added, never deleted, so
+// the filter needs no rebuilds; a miss is authoritative, a hit falls
+// through to the CPKey lookup.
+type CPFilter struct {
+	mtx  sync.RWMutex
+	bits []uint64
+}
+
+// newCPFilter seeds a filter from every control program hash already in
+// the wallet database.
+func newCPFilter(walletDB dbm.DB) *CPFilter {
+	f := &CPFilter{bits: make([]uint64, cpFilterBits/64)}
+
+	cpIter := walletDB.IteratorPrefix([]byte(accountCPNS))
+	defer cpIter.Release()
+	for cpIter.Next() {
+		var hash [32]byte
+		copy(hash[:], cpIter.Key()[len(accountCPNS):])
+		f.addHash(hash)
+	}
+	return f
+}
+
+// probes derives the four bit positions for a hash. The hash is already
+// uniform sha3 output, so its quadwords serve directly as indexes.
+func probes(hash [32]byte) (p [cpFilterProbes]uint64) {
+	for i := range p {
+		p[i] = binary.LittleEndian.Uint64(hash[i*8:]) % cpFilterBits
+	}
+	return p
+}
+
+func (f *CPFilter) addHash(hash [32]byte) {
+	f.mtx.Lock()
+	for _, p := range probes(hash) {
+		f.bits[p/64] |= 1 << (p % 64)
+	}
+	f.mtx.Unlock()
+}
+
+// MatchHash reports whether the control program with the given sha3-256
+// hash may belong to the wallet. False means definitely not; true means
+// the caller must confirm with a CPKey lookup. A nil filter matches
+// everything, so unwired callers keep their old behavior.
+func (f *CPFilter) MatchHash(hash [32]byte) bool {
+	if f == nil {
+		return true
+	}
+
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	for _, p := range probes(hash) {
+		if f.bits[p/64]&(1<<(p%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchProgram is MatchHash over the raw control program bytes.
+func (f *CPFilter) MatchProgram(prog []byte) bool {
+	if f == nil {
+		return true
+	}
+
+	var hash [32]byte
+	sha3pool.Sum256(hash[:], prog)
+	return f.MatchHash(hash)
+}
@@ -3,24 +3,25 @@ package account
 import (
 	"encoding/json"
 
+	"github.com/golang/protobuf/proto"
+
 	"github.com/bytom/blockchain/query"
 	"github.com/bytom/blockchain/signers"
+	"github.com/bytom/blockchain/wallet/storage"
+	"github.com/bytom/database"
 	chainjson "github.com/bytom/encoding/json"
 	"github.com/bytom/protocol/bc"
 )
 
-const (
-	//UTXOPreFix is AccountUTXOKey prefix
-	UTXOPreFix = "ACU:"
-)
+// UTXONS is the bucket of account unspent outputs, keyed by output ID
+const UTXONS database.Namespace = "ACU:"
 
-//UTXOKey makes a account unspent outputs key to store
+// UTXOKey makes a account unspent outputs key to store
 func UTXOKey(id bc.Hash) []byte {
-	name := string(id.Bytes())
-	return []byte(UTXOPreFix + name)
+	return UTXONS.Key(id.Bytes())
 }
 
-//UTXO is a structure about account unspent outputs
+// UTXO is a structure about account unspent outputs
 type UTXO struct {
 	OutputID     []byte
 	AssetID      []byte
@@ -34,9 +35,46 @@ type UTXO struct {
 	Change       bool
 }
 
+// Encode packs the UTXO into the compact persisted form it is stored
+// under UTXOKey.
+func (u *UTXO) Encode() ([]byte, error) {
+	return proto.Marshal(&storage.UtxoEntry{
+		OutputID:     u.OutputID,
+		AssetID:      u.AssetID,
+		Amount:       u.Amount,
+		AccountID:    u.AccountID,
+		ProgramIndex: u.ProgramIndex,
+		Program:      u.Program,
+		SourceID:     u.SourceID,
+		SourcePos:    u.SourcePos,
+		RefData:      u.RefData,
+		Change:       u.Change,
+	})
+}
+
+// DecodeUTXO unpacks a persisted account unspent output.
+func DecodeUTXO(data []byte) (*UTXO, error) {
+	entry := &storage.UtxoEntry{}
+	if err := proto.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+	return &UTXO{
+		OutputID:     entry.OutputID,
+		AssetID:      entry.AssetID,
+		Amount:       entry.Amount,
+		AccountID:    entry.AccountID,
+		ProgramIndex: entry.ProgramIndex,
+		Program:      entry.Program,
+		SourceID:     entry.SourceID,
+		SourcePos:    entry.SourcePos,
+		RefData:      entry.RefData,
+		Change:       entry.Change,
+	}, nil
+}
+
 var emptyJSONObject = json.RawMessage(`{}`)
 
-//Annotated init an annotated account object
+// Annotated init an annotated account object
 func Annotated(a *Account) (*query.AnnotatedAccount, error) {
 	aa := &query.AnnotatedAccount{
 		ID:     a.ID,
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/clock"
 	"github.com/bytom/errors"
 )
 
@@ -16,7 +17,7 @@ const defaultReceiverExpiry = 30 * 24 * time.Hour // 30 days
 // used.
 func (m *Manager) CreateReceiver(ctx context.Context, accInfo string, expiresAt time.Time) (*txbuilder.Receiver, error) {
 	if expiresAt.IsZero() {
-		expiresAt = time.Now().Add(defaultReceiverExpiry)
+		expiresAt = clock.Now().Add(defaultReceiverExpiry)
 	}
 
 	accID := accInfo
@@ -3,7 +3,6 @@ package account
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -12,6 +11,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	dbm "github.com/tendermint/tmlibs/db"
 
+	"github.com/bytom/clock"
 	"github.com/bytom/config"
 	"github.com/bytom/errors"
 	"github.com/bytom/protocol"
@@ -213,7 +213,7 @@ func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
 // making their UTXOs available for reservation again.
 func (re *reserver) ExpireReservations(ctx context.Context) error {
 	// Remove records of any reservations that have expired.
-	now := time.Now()
+	now := clock.Now()
 	var canceled []*reservation
 	re.reservationsMu.Lock()
 	for rid, res := range re.reservations {
@@ -379,17 +379,17 @@ func findMatchingUTXOs(db dbm.DB, src source) ([]*utxo, error) {
 
 	var (
 		utxos       []*utxo
-		accountUTXO UTXO
 		rawOutputID [32]byte
 		rawSourceID [32]byte
 		rawRefData  [32]byte
 	)
 
-	accountUTXOIter := db.IteratorPrefix([]byte(UTXOPreFix))
+	accountUTXOIter := db.IteratorPrefix([]byte(UTXONS))
 	defer accountUTXOIter.Release()
 	for accountUTXOIter.Next() {
 
-		if err := json.Unmarshal(accountUTXOIter.Value(), &accountUTXO); err != nil {
+		accountUTXO, err := DecodeUTXO(accountUTXOIter.Value())
+		if err != nil {
 			return nil, errors.Wrap(err)
 		}
 
@@ -424,7 +424,6 @@ func findMatchingUTXOs(db dbm.DB, src source) ([]*utxo, error) {
 
 func findSpecificUTXO(db dbm.DB, outHash bc.Hash) (*utxo, error) {
 	u := new(utxo)
-	accountUTXO := new(UTXO)
 
 	//temp fix for coinbase UTXO isn't add to accountUTXO db, will be remove later
 	if outHash == *config.GenerateGenesisTx().ResultIds[0] {
@@ -436,7 +435,8 @@ func findSpecificUTXO(db dbm.DB, outHash bc.Hash) (*utxo, error) {
 	if accountUTXOValue == nil {
 		return nil, fmt.Errorf("can't find utxo: %s", outHash.String())
 	}
-	if err := json.Unmarshal(accountUTXOValue, &accountUTXO); err != nil {
+	accountUTXO, err := DecodeUTXO(accountUTXOValue)
+	if err != nil {
 		return nil, errors.Wrap(err)
 	}
 
@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/bytom/payment"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+// ValidateAddressResp is the structured verdict on one address. When
+// Valid is false, Reason says what failed; the endpoint itself never
+// errors on bad input, since validating user input is its entire job.
+type ValidateAddressResp struct {
+	Valid   bool   `json:"valid"`
+	Reason  string `json:"reason,omitempty"`
+	Program string `json:"program,omitempty"`
+	// Type is "multisig", "htlc", "retire" or "unknown".
+	Type string `json:"type,omitempty"`
+	// multisig detail: how many of the total keys must sign
+	RequiredSignatures int `json:"required_signatures,omitempty"`
+	TotalKeys          int `json:"total_keys,omitempty"`
+	// IsMine reports whether the program belongs to a wallet account on
+	// this node; always false when the wallet is disabled.
+	IsMine bool `json:"is_mine"`
+}
+
+func invalidAddress(reason string) (*ValidateAddressResp, error) {
+	return &ValidateAddressResp{Valid: false, Reason: reason}, nil
+}
+
+// POST /validate-address
+// Accepts either a hex control program or a bytom: payment URI and
+// reports encoding validity, the program type and signing requirements,
+// so integrators can check user input before building transactions.
+func (bcr *BlockchainReactor) validateAddress(in struct {
+	Address string `json:"address"`
+}) (*ValidateAddressResp, error) {
+	address := strings.TrimSpace(in.Address)
+	if address == "" {
+		return invalidAddress("address is empty")
+	}
+
+	if strings.HasPrefix(address, payment.Scheme) {
+		request, err := payment.Parse(address)
+		if err != nil {
+			return invalidAddress("invalid payment URI: " + err.Error())
+		}
+		address = request.Program
+	}
+
+	program, err := hex.DecodeString(address)
+	if err != nil || len(program) == 0 {
+		return invalidAddress("address is not a hex control program")
+	}
+
+	resp := &ValidateAddressResp{
+		Valid:   true,
+		Program: address,
+		Type:    "unknown",
+	}
+	switch {
+	case vmutil.IsUnspendable(program):
+		resp.Type = "retire"
+	case vmutil.IsHTLCProgram(program):
+		resp.Type = "htlc"
+	default:
+		if pubkeys, nrequired, err := vmutil.ParseP2SPMultiSigProgram(program); err == nil {
+			resp.Type = "multisig"
+			resp.RequiredSignatures = nrequired
+			resp.TotalKeys = len(pubkeys)
+		}
+	}
+
+	if bcr.accounts != nil {
+		resp.IsMine = bcr.accounts.IsLocalControlProgram(program)
+	}
+	return resp, nil
+}
@@ -14,21 +14,21 @@ import (
 	"github.com/bytom/blockchain/signers"
 	"github.com/bytom/crypto/ed25519"
 	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/database"
 	"github.com/bytom/errors"
 	"github.com/bytom/protocol"
 	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/vm/vmutil"
 )
 
-const (
-	maxAssetCache = 1000
-	assetPreFix   = "ASS:"
-)
+const maxAssetCache = 1000
+
+// assetNS is the bucket holding asset definitions, keyed by asset ID.
+const assetNS database.Namespace = "ASS:"
 
 //Key asset store prefix
 func Key(id bc.AssetID) []byte {
-	name := id.String()
-	return []byte(assetPreFix + name)
+	return assetNS.StringKey(id.String())
 }
 
 // pre-define errors for supporting bytom errorFormatter
@@ -226,7 +226,7 @@ func (reg *Registry) FindByAlias(ctx context.Context, alias string) (*Asset, err
 func (reg *Registry) QueryAll(ctx context.Context) (interface{}, error) {
 	ret := make([]interface{}, 0)
 
-	assetIter := reg.db.IteratorPrefix([]byte(assetPreFix))
+	assetIter := reg.db.IteratorPrefix([]byte(assetNS))
 	defer assetIter.Release()
 
 	for assetIter.Next() {
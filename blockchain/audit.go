@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// MerkleStep is one level of a transaction merkle path in wire form.
+type MerkleStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// AssetAuditEvent is one issuance or retirement of the audited asset,
+// carried with enough block context to verify it independently: the
+// merkle path ties the transaction to the header's transactions root.
+type AssetAuditEvent struct {
+	Type        string        `json:"type"`
+	Amount      uint64        `json:"amount"`
+	BlockHeight uint64        `json:"block_height"`
+	BlockHash   string        `json:"block_hash"`
+	TxID        string        `json:"tx_id"`
+	TxIndex     int           `json:"tx_index"`
+	MerkleRoot  string        `json:"merkle_root"`
+	MerkleProof []*MerkleStep `json:"merkle_proof"`
+}
+
+// AssetAudit is the full supply history of one asset.
+type AssetAudit struct {
+	AssetID       string             `json:"asset_id"`
+	IssuedAmount  uint64             `json:"issued_amount"`
+	RetiredAmount uint64             `json:"retired_amount"`
+	Circulation   uint64             `json:"circulation"`
+	Events        []*AssetAuditEvent `json:"events"`
+}
+
+// auditAsset walks the main chain and reports every issuance and
+// retirement of the given asset, each with a merkle path to its block
+// header. Auditors replay the events against the headers they already
+// trust instead of taking the node's aggregate numbers on faith. The
+// scan is linear in chain length, so this is an offline-analysis call,
+// not something to poll.
+func (bcr *BlockchainReactor) auditAsset(in struct {
+	AssetID string `json:"asset_id"`
+}) (*AssetAudit, error) {
+	var assetID bc.AssetID
+	if err := assetID.UnmarshalText([]byte(in.AssetID)); err != nil {
+		return nil, errors.Wrap(err, "decoding asset ID")
+	}
+
+	// the audit needs every historic block body
+	if bcr.chain.PrunedHeight() > 0 {
+		return nil, bcr.errPruned()
+	}
+
+	audit := &AssetAudit{
+		AssetID: assetID.String(),
+		Events:  []*AssetAuditEvent{},
+	}
+
+	for height := uint64(1); height <= bcr.chain.Height(); height++ {
+		block, err := bcr.chain.GetBlockByHeight(height)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting block at height %d", height)
+		}
+
+		bcBlock := legacy.MapBlock(block)
+		blockHash := block.Hash()
+		for i, tx := range bcBlock.Transactions {
+			events := []*AssetAuditEvent{}
+			for _, entry := range tx.Entries {
+				switch e := entry.(type) {
+				case *bc.Issuance:
+					if *e.Value.AssetId != assetID {
+						continue
+					}
+					audit.IssuedAmount += e.Value.Amount
+					events = append(events, &AssetAuditEvent{Type: "issuance", Amount: e.Value.Amount})
+
+				case *bc.Retirement:
+					if *e.Source.Value.AssetId != assetID {
+						continue
+					}
+					audit.RetiredAmount += e.Source.Value.Amount
+					events = append(events, &AssetAuditEvent{Type: "retirement", Amount: e.Source.Value.Amount})
+				}
+			}
+			if len(events) == 0 {
+				continue
+			}
+
+			proof, err := bc.MerkleProof(bcBlock.Transactions, i)
+			if err != nil {
+				return nil, errors.Wrapf(err, "building merkle proof in block %d", height)
+			}
+			steps := make([]*MerkleStep, 0, len(proof))
+			for _, step := range proof {
+				steps = append(steps, &MerkleStep{Hash: step.Hash.String(), Left: step.Left})
+			}
+
+			for _, event := range events {
+				event.BlockHeight = height
+				event.BlockHash = blockHash.String()
+				event.TxID = tx.ID.String()
+				event.TxIndex = i
+				event.MerkleRoot = block.BlockHeader.BlockCommitment.TransactionsMerkleRoot.String()
+				event.MerkleProof = steps
+				audit.Events = append(audit.Events, event)
+			}
+		}
+	}
+
+	audit.Circulation = audit.IssuedAmount - audit.RetiredAmount
+	return audit, nil
+}
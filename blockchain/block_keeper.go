@@ -2,20 +2,45 @@ package blockchain
 
 import (
 	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/bytom/consensus"
+	"github.com/bytom/consensus/algorithm"
 	"github.com/bytom/p2p"
 	"github.com/bytom/protocol"
 	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/bc/legacy"
 )
 
+const (
+	// number of block bodies that may be in flight at once.
+	syncWindowSize = 128
+	// number of headers requested ahead of the bodies in one batch.
+	headersBatchSize = 256
+	// how long to wait for a requested block before asking another peer.
+	blockRequestTimeout = 10 * time.Second
+	// how often in-flight requests are checked for expiry.
+	requestExpireInterval = time.Second
+	// assumed delivery time for peers we have no measurements for.
+	defaultPeerLatency = 2 * time.Second
+	// score penalty added for every timeout since the last delivery.
+	peerTimeoutPenalty = blockRequestTimeout
+)
+
 type blockKeeperPeer struct {
 	mtx    sync.RWMutex
 	height uint64
 	hash   *bc.Hash
+
+	// smoothed block delivery time and timeouts since the last delivery
+	latency  time.Duration
+	timeouts int
 }
 
 func newBlockKeeperPeer(height uint64, hash *bc.Hash) *blockKeeperPeer {
@@ -39,12 +64,68 @@ func (p *blockKeeperPeer) SetStatus(height uint64, hash *bc.Hash) {
 	p.hash = hash
 }
 
+// recordDelivery folds an observed block delivery time into the smoothed
+// latency and clears the timeout streak.
+func (p *blockKeeperPeer) recordDelivery(d time.Duration) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.latency == 0 {
+		p.latency = d
+	} else {
+		p.latency = (p.latency*7 + d*3) / 10
+	}
+	p.timeouts = 0
+}
+
+func (p *blockKeeperPeer) recordTimeout() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.timeouts++
+}
+
+// score is the expected cost of asking this peer for a block: the
+// smoothed delivery time plus a penalty for every recent timeout.
+// Lower is better.
+func (p *blockKeeperPeer) score() time.Duration {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	score := p.latency
+	if score == 0 {
+		score = defaultPeerLatency
+	}
+	return score + time.Duration(p.timeouts)*peerTimeoutPenalty
+}
+
 type pendingResponse struct {
 	block  *legacy.Block
 	peerID string
 }
 
-//TODO: add retry mechanism
+type headersResponse struct {
+	headers []*legacy.BlockHeader
+	peerID  string
+}
+
+// blockDelivery reports back to the request worker that a requested body
+// arrived, so the in-flight entry can be cleared and the peer timed.
+type blockDelivery struct {
+	height uint64
+	peerID string
+}
+
+// inflightBlock records an outstanding block body request so it can be
+// retried on another peer when it times out.
+type inflightBlock struct {
+	hash   bc.Hash
+	peerID string
+	queued time.Time
+}
+
+// blockKeeper drives the headers-first sync: it keeps a window of
+// validated headers ahead of the chain and downloads the matching
+// block bodies in parallel from the peers that have them.
 type blockKeeper struct {
 	mtx           sync.RWMutex
 	chainHeight   uint64
@@ -53,27 +134,45 @@ type blockKeeper struct {
 	peerUpdateCh  chan struct{}
 	done          chan bool
 
-	chain         *protocol.Chain
-	sw            *p2p.Switch
-	peers         map[string]*blockKeeperPeer
+	chain            *protocol.Chain
+	sw               *p2p.Switch
+	headerOnly       bool // light mode: never download bodies
+	peers            map[string]*blockKeeperPeer
 	pendingProcessCh chan *pendingResponse
+	headersCh        chan *headersResponse
+	deliveredCh      chan *blockDelivery
+
+	// pipeline hand-off channels; see sync_pipeline.go
+	verifyCh chan *pendingBlock
+	applyCh  chan *pendingBlock
+
+	// sync state, owned by blockRequestWorker
+	headers        []*legacy.BlockHeader
+	inflight       map[uint64]*inflightBlock
+	headersAskedAt time.Time
 }
 
 func newBlockKeeper(chain *protocol.Chain, sw *p2p.Switch) *blockKeeper {
 	chainHeight := chain.Height()
 	bk := &blockKeeper{
-		chainHeight:      chainHeight,
-		maxPeerHeight:    uint64(0),
-		chainUpdateCh:    chain.BlockWaiter(chainHeight + 1),
-		peerUpdateCh:     make(chan struct{}, 1000),
-		done:             make(chan bool, 1),
+		chainHeight:   chainHeight,
+		maxPeerHeight: uint64(0),
+		chainUpdateCh: chain.BlockWaiter(chainHeight + 1),
+		peerUpdateCh:  make(chan struct{}, 1000),
+		done:          make(chan bool, 1),
 
 		chain:            chain,
 		sw:               sw,
+		headerOnly:       chain.HeaderOnly(),
 		peers:            make(map[string]*blockKeeperPeer),
 		pendingProcessCh: make(chan *pendingResponse),
+		headersCh:        make(chan *headersResponse, 10),
+		deliveredCh:      make(chan *blockDelivery, syncWindowSize),
+		verifyCh:         make(chan *pendingBlock, pipelineDepth),
+		applyCh:          make(chan *pendingBlock, pipelineDepth),
+		inflight:         make(map[uint64]*inflightBlock),
 	}
-	go bk.blockProcessWorker()
+	bk.startPipeline()
 	go bk.blockRequestWorker()
 	return bk
 }
@@ -86,12 +185,24 @@ func (bk *blockKeeper) AddBlock(block *legacy.Block, peerID string) {
 	bk.pendingProcessCh <- &pendingResponse{block: block, peerID: peerID}
 }
 
+func (bk *blockKeeper) AddHeaders(headers []*legacy.BlockHeader, peerID string) {
+	bk.headersCh <- &headersResponse{headers: headers, peerID: peerID}
+}
+
 func (bk *blockKeeper) IsCaughtUp() bool {
 	bk.mtx.RLock()
 	defer bk.mtx.RUnlock()
 	return bk.chainHeight >= bk.maxPeerHeight
 }
 
+// BestPeerHeight reports the highest chain height any peer claims;
+// zero when no peer has reported yet.
+func (bk *blockKeeper) BestPeerHeight() uint64 {
+	bk.mtx.RLock()
+	defer bk.mtx.RUnlock()
+	return bk.maxPeerHeight
+}
+
 func (bk *blockKeeper) RemovePeer(peerID string) {
 	bk.mtx.Lock()
 	delete(bk.peers, peerID)
@@ -99,6 +210,21 @@ func (bk *blockKeeper) RemovePeer(peerID string) {
 	log.WithField("ID", peerID).Info("Delete peer from blockKeeper")
 }
 
+// punishPeer disconnects a peer that served a consensus-invalid block.
+// Honest peers never relay such blocks, so keeping the connection only
+// invites more wasted validation work.
+func (bk *blockKeeper) punishPeer(peerID string, reason error) {
+	peer := bk.sw.Peers().Get(peerID)
+	if peer == nil {
+		return
+	}
+	log.WithFields(log.Fields{
+		"peerID": peerID,
+		"error":  reason,
+	}).Warn("blockKeeper dropping peer for serving an invalid block")
+	bk.sw.StopPeerForError(peer, reason)
+}
+
 func (bk *blockKeeper) requestBlockByHash(peerID string, hash *bc.Hash) error {
 	peer := bk.sw.Peers().Get(peerID)
 	if peer == nil {
@@ -119,6 +245,16 @@ func (bk *blockKeeper) requestBlockByHeight(peerID string, height uint64) error
 	return nil
 }
 
+func (bk *blockKeeper) requestHeaders(peerID string, fromHeight, count uint64) error {
+	peer := bk.sw.Peers().Get(peerID)
+	if peer == nil {
+		return errors.New("can't find peer in peer pool")
+	}
+	msg := &HeadersRequestMessage{FromHeight: fromHeight, Count: count}
+	peer.TrySend(BlockchainChannel, struct{ BlockchainMessage }{msg})
+	return nil
+}
+
 func (bk *blockKeeper) SetPeerHeight(peerID string, height uint64, hash *bc.Hash) {
 	bk.mtx.Lock()
 	defer bk.mtx.Unlock()
@@ -137,18 +273,32 @@ func (bk *blockKeeper) SetPeerHeight(peerID string, height uint64, hash *bc.Hash
 	log.WithFields(log.Fields{"ID": peerID, "Height": height}).Info("Add new peer to blockKeeper")
 }
 
-func (bk *blockKeeper) RequestBlockByHeight(height uint64) {
+// bestPeer returns the ID of a peer that claims at least the given height.
+// Peers are ranked by their delivery score and one of the fastest half is
+// picked at random, so slow peers are demoted without starving the rest.
+func (bk *blockKeeper) bestPeer(height uint64) string {
 	bk.mtx.RLock()
 	defer bk.mtx.RUnlock()
 
+	candidates := []string{}
 	for peerID, peer := range bk.peers {
-		if peerHeight, _ := peer.GetStatus(); peerHeight > bk.chainHeight {
-			bk.requestBlockByHeight(peerID, height)
+		if peerHeight, _ := peer.GetStatus(); peerHeight >= height {
+			candidates = append(candidates, peerID)
 		}
 	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return bk.peers[candidates[i]].score() < bk.peers[candidates[j]].score()
+	})
+	return candidates[rand.Intn((len(candidates)+1)/2)]
 }
 
 func (bk *blockKeeper) blockRequestWorker() {
+	expireTicker := time.NewTicker(requestExpireInterval)
+	defer expireTicker.Stop()
+
 	for {
 		select {
 		case <-bk.chainUpdateCh:
@@ -159,41 +309,269 @@ func (bk *blockKeeper) blockRequestWorker() {
 			}
 			bk.chainUpdateCh = bk.chain.BlockWaiter(bk.chainHeight + 1)
 			bk.mtx.Unlock()
+			bk.schedule()
 
 		case <-bk.peerUpdateCh:
-			bk.mtx.RLock()
-			chainHeight := bk.chainHeight
-			maxPeerHeight := bk.maxPeerHeight
-			bk.mtx.RUnlock()
-
-			for i := chainHeight + 1; i <= maxPeerHeight; i++ {
-				bk.RequestBlockByHeight(i)
-				waiter := bk.chain.BlockWaiter(i)
-				<-waiter
+			bk.schedule()
+
+		case resp := <-bk.headersCh:
+			bk.processHeaders(resp)
+			bk.schedule()
+
+		case delivery := <-bk.deliveredCh:
+			bk.processDelivery(delivery)
+
+		case <-expireTicker.C:
+			bk.expireRequests()
+
+		case <-bk.done:
+			return
+		}
+	}
+}
+
+// schedule advances the sync state machine: it trims stale headers,
+// keeps a batch of headers requested ahead, and fills the sliding
+// download window with block body requests.
+func (bk *blockKeeper) schedule() {
+	bk.mtx.Lock()
+	chainHeight := bk.chainHeight
+	maxPeerHeight := bk.maxPeerHeight
+	bk.mtx.Unlock()
+
+	// Drop headers and in-flight markers the chain has caught up with.
+	for len(bk.headers) > 0 && bk.headers[0].Height <= chainHeight {
+		bk.headers = bk.headers[1:]
+	}
+	for height := range bk.inflight {
+		if height <= chainHeight {
+			delete(bk.inflight, height)
+		}
+	}
+
+	if chainHeight >= maxPeerHeight {
+		return
+	}
+
+	// Fetch headers ahead of the bodies.
+	headersTail := chainHeight + uint64(len(bk.headers))
+	if uint64(len(bk.headers)) < syncWindowSize && headersTail < maxPeerHeight &&
+		time.Since(bk.headersAskedAt) > blockRequestTimeout {
+		if peerID := bk.bestPeer(headersTail + 1); peerID != "" {
+			if err := bk.requestHeaders(peerID, headersTail+1, headersBatchSize); err == nil {
+				bk.headersAskedAt = time.Now()
 			}
-		case <- bk.done:
+		}
+	}
+
+	// Light mode: the headers are the blocks; connect them directly
+	// instead of scheduling body downloads.
+	if bk.headerOnly {
+		for len(bk.headers) > 0 {
+			header := bk.headers[0]
+			if _, err := bk.chain.ProcessBlockHeader(header); err != nil {
+				log.WithFields(log.Fields{
+					"height": header.Height,
+					"error":  err,
+				}).Error("blockKeeper failed to connect header")
+				bk.headers = nil
+				return
+			}
+			bk.headers = bk.headers[1:]
+		}
+		return
+	}
+
+	// Fill the window with body requests against the validated headers.
+	for i, header := range bk.headers {
+		if i >= syncWindowSize {
+			break
+		}
+		if _, ok := bk.inflight[header.Height]; ok {
+			continue
+		}
+		peerID := bk.bestPeer(header.Height)
+		if peerID == "" {
+			break
+		}
+		hash := header.Hash()
+		if err := bk.requestBlockByHash(peerID, &hash); err != nil {
+			continue
+		}
+		bk.inflight[header.Height] = &inflightBlock{hash: hash, peerID: peerID, queued: time.Now()}
+	}
+}
+
+// processHeaders checks that the received headers extend our known
+// header chain contiguously and carry valid proof of work before
+// accepting them for download.
+func (bk *blockKeeper) processHeaders(resp *headersResponse) {
+	if len(resp.headers) == 0 {
+		return
+	}
+
+	prev := &bk.chain.BestBlock().BlockHeader
+	if len(bk.headers) > 0 {
+		prev = bk.headers[len(bk.headers)-1]
+	}
+	prevHash := prev.Hash()
+
+	for _, header := range resp.headers {
+		if header.Height <= prev.Height {
+			// overlap with what we already know, skip it.
+			continue
+		}
+		headerHash := header.Hash()
+		if bk.chain.IsBlockRejected(&headerHash) {
+			log.WithFields(log.Fields{
+				"peerID": resp.peerID,
+				"height": header.Height,
+				"hash":   headerHash.String(),
+			}).Error("blockKeeper received headers extending a rejected block")
+			return
+		}
+		if header.Height != prev.Height+1 || header.PreviousBlockHash != prevHash {
+			log.WithFields(log.Fields{
+				"peerID": resp.peerID,
+				"height": header.Height,
+			}).Error("blockKeeper received non-contiguous headers")
+			return
+		}
+		if err := bk.validateHeader(header, prev, &prevHash); err != nil {
+			log.WithFields(log.Fields{
+				"peerID": resp.peerID,
+				"height": header.Height,
+				"error":  err,
+			}).Error("blockKeeper received invalid header")
+			bk.punishPeer(resp.peerID, err)
 			return
 		}
+		bk.headers = append(bk.headers, header)
+		prevHash = headerHash
+		prev = header
 	}
+	bk.headersAskedAt = time.Time{}
 }
 
-func (bk *blockKeeper) blockProcessWorker() {
-	for pendingResponse := range bk.pendingProcessCh {
-		block := pendingResponse.block
-		blockHash := block.Hash()
-		isOrphan, err := bk.chain.ProcessBlock(block)
-		if err != nil {
-			log.WithField("hash", blockHash.String()).Errorf("blockKeeper fail process block %v", err)
+// validateHeader checks the consensus fields a header can prove before
+// its body arrives: seed lineage, expected difficulty and proof of
+// work. Without this a peer could answer a headers request with a
+// fabricated chain whose bodies never materialize, filling the sliding
+// window with phantom in-flight requests and stalling sync.
+func (bk *blockKeeper) validateHeader(header, prev *legacy.BlockHeader, prevHash *bc.Hash) error {
+	if header.Seed != *algorithm.CreateSeed(header.Height, &prev.Seed, []*bc.Hash{prevHash}) {
+		return errors.New("header carries wrong seed")
+	}
+	if bits := bk.requiredBits(prev); header.Bits != bits {
+		return fmt.Errorf("header carries bits %d, expected %d", header.Bits, bits)
+	}
+
+	seedCache, err := bk.chain.SeedCaches().Get(&header.Seed)
+	if err != nil {
+		return err
+	}
+	headerHash := header.Hash()
+	proofHash, err := algorithm.AIHash(header.Height, &headerHash, seedCache)
+	if err != nil {
+		return err
+	}
+	if !consensus.CheckProofOfWork(proofHash, header.Bits) {
+		return errors.New("header fails proof of work")
+	}
+	return nil
+}
+
+// requiredBits computes the difficulty the header following prev must
+// carry, resolving the retarget comparison header from the pending
+// headers or the chain.
+func (bk *blockKeeper) requiredBits(prev *legacy.BlockHeader) uint64 {
+	if prev.Height%consensus.ActiveNetParams.BlocksPerRetarget != 0 {
+		return prev.Bits
+	}
+	compare := bk.headerAt(prev.Height + 1 - consensus.ActiveNetParams.BlocksPerRetarget)
+	if compare == nil {
+		// the comparison block was pruned away; accept the carried
+		// difficulty rather than reject a possibly honest chain
+		return prev.Bits
+	}
+	return consensus.CalcNextRequiredDifficulty(prev, compare)
+}
+
+// headerAt returns the header at the given height from the pending
+// headers or the main chain, nil when neither has it.
+func (bk *blockKeeper) headerAt(height uint64) *legacy.BlockHeader {
+	if len(bk.headers) > 0 && height >= bk.headers[0].Height {
+		if idx := height - bk.headers[0].Height; idx < uint64(len(bk.headers)) {
+			return bk.headers[idx]
+		}
+		return nil
+	}
+	if block, err := bk.chain.GetBlockByHeight(height); err == nil {
+		return &block.BlockHeader
+	}
+	return nil
+}
+
+// processDelivery clears the in-flight entry for an arrived body and
+// credits the delivering peer with the measured delivery time.
+func (bk *blockKeeper) processDelivery(delivery *blockDelivery) {
+	req, ok := bk.inflight[delivery.height]
+	if !ok || req.peerID != delivery.peerID {
+		return
+	}
+	delete(bk.inflight, delivery.height)
+
+	bk.mtx.RLock()
+	peer := bk.peers[delivery.peerID]
+	bk.mtx.RUnlock()
+	if peer != nil {
+		peer.recordDelivery(time.Since(req.queued))
+	}
+}
+
+// expireRequests re-issues body requests that have been outstanding for
+// too long, preferring a different peer than the one that stalled.
+func (bk *blockKeeper) expireRequests() {
+	for height, req := range bk.inflight {
+		if time.Since(req.queued) < blockRequestTimeout {
+			continue
+		}
+		// The body may have arrived out of order and be waiting as an orphan.
+		if bk.chain.BlockExist(&req.hash) {
+			delete(bk.inflight, height)
+			continue
+		}
+		bk.mtx.RLock()
+		stalled := bk.peers[req.peerID]
+		bk.mtx.RUnlock()
+		if stalled != nil {
+			stalled.recordTimeout()
+		}
+		peerID := bk.bestPeer(height)
+		if peerID == "" {
+			delete(bk.inflight, height)
 			continue
 		}
 		log.WithFields(log.Fields{
-			"height":   block.Height,
-			"hash":     blockHash.String(),
-			"isOrphan": isOrphan,
-		}).Info("blockKeeper processed block")
-
-		if isOrphan {
-			bk.requestBlockByHash(pendingResponse.peerID, &block.PreviousBlockHash)
+			"height":  height,
+			"stalled": req.peerID,
+			"peerID":  peerID,
+		}).Info("blockKeeper retrying stalled block request")
+		if err := bk.requestBlockByHash(peerID, &req.hash); err != nil {
+			delete(bk.inflight, height)
+			continue
 		}
+		req.peerID = peerID
+		req.queued = time.Now()
 	}
 }
+
+// isScheduled reports whether the given height is already covered by the
+// headers-first download window, in which case an orphan body is simply
+// an out-of-order arrival and needs no extra parent request.
+func (bk *blockKeeper) isScheduled(height uint64) bool {
+	bk.mtx.RLock()
+	chainHeight := bk.chainHeight
+	bk.mtx.RUnlock()
+	return height > chainHeight && height <= chainHeight+syncWindowSize
+}
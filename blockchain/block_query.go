@@ -0,0 +1,209 @@
+package blockchain
+
+import (
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// Verbosity levels for the uniform block query endpoints.
+const (
+	// the serialized block or header as hex
+	VerbosityRaw = 0
+	// the decoded header plus transaction IDs
+	VerbosityDecoded = 1
+	// the decoded header plus fully annotated transactions
+	VerbosityFull = 2
+)
+
+// BlockHeaderJSON is the decoded form of a block header.
+type BlockHeaderJSON struct {
+	Version                uint64 `json:"version"`
+	Height                 uint64 `json:"height"`
+	PreviousBlockHash      string `json:"previous_block_hash"`
+	Seed                   string `json:"seed"`
+	TimestampMS            uint64 `json:"timestamp_ms"`
+	TransactionsMerkleRoot string `json:"transactions_merkle_root"`
+	AssetsMerkleRoot       string `json:"assets_merkle_root"`
+	Nonce                  uint64 `json:"nonce"`
+	Bits                   uint64 `json:"bits"`
+}
+
+// GetBlockResp is the get-block response. Which fields are filled
+// depends on the requested verbosity.
+type GetBlockResp struct {
+	Hash         string           `json:"hash"`
+	Height       uint64           `json:"height"`
+	Raw          string           `json:"raw,omitempty"`
+	Header       *BlockHeaderJSON `json:"header,omitempty"`
+	TxIDs        []string         `json:"tx_ids,omitempty"`
+	Transactions []*TxJSON        `json:"transactions,omitempty"`
+}
+
+// GetBlockHeaderResp is the get-block-header response.
+type GetBlockHeaderResp struct {
+	Hash   string           `json:"hash"`
+	Height uint64           `json:"height"`
+	Raw    string           `json:"raw,omitempty"`
+	Header *BlockHeaderJSON `json:"header,omitempty"`
+}
+
+// errPruned builds the error returned when a query needs a block body
+// this pruned node has discarded.
+func (bcr *BlockchainReactor) errPruned() error {
+	return errors.WithDetailf(errors.New("block body pruned"),
+		"this node is pruned to depth %d and no longer stores block bodies at or below height %d; use an archival node",
+		bcr.chain.PruneDepth(), bcr.chain.PrunedHeight())
+}
+
+// lookupBlock resolves the block selector the query endpoints share:
+// by hash when one is given, by height otherwise.
+func (bcr *BlockchainReactor) lookupBlock(blockHash string, blockHeight uint64) (*legacy.Block, error) {
+	if blockHash != "" {
+		hash := bc.Hash{}
+		if err := hash.UnmarshalText([]byte(blockHash)); err != nil {
+			return nil, errors.Wrap(err, "decoding block hash")
+		}
+		block, err := bcr.chain.GetBlockByHash(&hash)
+		if err != nil && bcr.chain.IsBlockPruned(&hash) {
+			return nil, bcr.errPruned()
+		}
+		return block, err
+	}
+	if pruned := bcr.chain.PrunedHeight(); blockHeight <= pruned && pruned > 0 {
+		return nil, bcr.errPruned()
+	}
+	return bcr.chain.GetBlockByHeight(blockHeight)
+}
+
+func decodeHeader(header *legacy.BlockHeader) *BlockHeaderJSON {
+	return &BlockHeaderJSON{
+		Version:                header.Version,
+		Height:                 header.Height,
+		PreviousBlockHash:      header.PreviousBlockHash.String(),
+		Seed:                   header.Seed.String(),
+		TimestampMS:            header.TimestampMS,
+		TransactionsMerkleRoot: header.BlockCommitment.TransactionsMerkleRoot.String(),
+		AssetsMerkleRoot:       header.BlockCommitment.AssetsMerkleRoot.String(),
+		Nonce:                  header.Nonce,
+		Bits:                   header.Bits,
+	}
+}
+
+// annotateTxs maps every transaction of a block to its entry-level
+// input/output representation.
+func annotateTxs(bcBlock *bc.Block) []*TxJSON {
+	txs := make([]*TxJSON, 0, len(bcBlock.Transactions))
+	for _, tx := range bcBlock.Transactions {
+		txJSON := &TxJSON{}
+		for _, e := range tx.Entries {
+			switch e := e.(type) {
+			case *bc.Issuance:
+				txJSON.Inputs = append(txJSON.Inputs, e)
+			case *bc.Spend:
+				txJSON.Inputs = append(txJSON.Inputs, e)
+			case *bc.Retirement:
+				txJSON.Outputs = append(txJSON.Outputs, e)
+			case *bc.Output:
+				txJSON.Outputs = append(txJSON.Outputs, e)
+			default:
+				continue
+			}
+		}
+		txs = append(txs, txJSON)
+	}
+	return txs
+}
+
+// getBlock returns one block, selected by hash or height, at the
+// requested verbosity: raw hex, decoded header with transaction IDs, or
+// fully annotated transactions.
+func (bcr *BlockchainReactor) getBlock(in struct {
+	BlockHash   string `json:"block_hash"`
+	BlockHeight uint64 `json:"block_height"`
+	Verbosity   int    `json:"verbosity"`
+}) (*GetBlockResp, error) {
+	block, err := bcr.lookupBlock(in.BlockHash, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash := block.Hash()
+	resp := &GetBlockResp{
+		Hash:   blockHash.String(),
+		Height: block.Height,
+	}
+
+	switch in.Verbosity {
+	case VerbosityRaw:
+		raw, err := block.MarshalText()
+		if err != nil {
+			return nil, errors.Wrap(err, "marshaling block")
+		}
+		resp.Raw = string(raw)
+
+	case VerbosityDecoded:
+		resp.Header = decodeHeader(&block.BlockHeader)
+		resp.TxIDs = make([]string, 0, len(block.Transactions))
+		for _, tx := range block.Transactions {
+			resp.TxIDs = append(resp.TxIDs, tx.Tx.ID.String())
+		}
+
+	case VerbosityFull:
+		resp.Header = decodeHeader(&block.BlockHeader)
+		resp.Transactions = annotateTxs(legacy.MapBlock(block))
+
+	default:
+		return nil, errors.New("unknown verbosity level")
+	}
+	return resp, nil
+}
+
+// lookupHeader resolves the same selector for header-only queries.
+// Unlike lookupBlock it keeps working on a pruned node, which retains
+// every header.
+func (bcr *BlockchainReactor) lookupHeader(blockHash string, blockHeight uint64) (*legacy.BlockHeader, error) {
+	if blockHash != "" {
+		hash := bc.Hash{}
+		if err := hash.UnmarshalText([]byte(blockHash)); err != nil {
+			return nil, errors.Wrap(err, "decoding block hash")
+		}
+		return bcr.chain.GetHeaderByHash(&hash)
+	}
+	return bcr.chain.GetHeaderByHeight(blockHeight)
+}
+
+// getBlockHeader returns one block header, selected by hash or height,
+// either as raw hex or decoded.
+func (bcr *BlockchainReactor) getBlockHeader(in struct {
+	BlockHash   string `json:"block_hash"`
+	BlockHeight uint64 `json:"block_height"`
+	Verbosity   int    `json:"verbosity"`
+}) (*GetBlockHeaderResp, error) {
+	header, err := bcr.lookupHeader(in.BlockHash, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash := header.Hash()
+	resp := &GetBlockHeaderResp{
+		Hash:   blockHash.String(),
+		Height: header.Height,
+	}
+
+	switch in.Verbosity {
+	case VerbosityRaw:
+		raw, err := header.MarshalText()
+		if err != nil {
+			return nil, errors.Wrap(err, "marshaling block header")
+		}
+		resp.Raw = string(raw)
+
+	case VerbosityDecoded, VerbosityFull:
+		resp.Header = decodeHeader(header)
+
+	default:
+		return nil, errors.New("unknown verbosity level")
+	}
+	return resp, nil
+}
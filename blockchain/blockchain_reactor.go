@@ -2,13 +2,18 @@ package blockchain
 
 import (
 	"bytes"
-	"strconv"
 	stdjson "encoding/json"
+	"io/ioutil"
+	"strconv"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/bytom/blockchain/rpc"
 	ctypes "github.com/bytom/blockchain/rpc/types"
+	"github.com/bytom/blockchain/txdb"
+	"github.com/bytom/errors"
+	"github.com/bytom/p2p"
+	"github.com/bytom/protocol"
 	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/bc/legacy"
 	"github.com/bytom/protocol/validation"
@@ -19,6 +24,157 @@ func (bcR *BlockchainReactor) getNetInfo() (*ctypes.ResultNetInfo, error) {
 	return rpc.NetInfo(bcR.sw)
 }
 
+// return p2p traffic counters per channel and per message type
+func (bcr *BlockchainReactor) getNetStats() (*p2p.MessageMetrics, error) {
+	return p2p.GetMessageMetrics(), nil
+}
+
+// return every known address that we managed to connect to, with its
+// quality metrics. This feeds the DNS seed infrastructure.
+func (bcr *BlockchainReactor) dumpReachableNodes() ([]*p2p.AddressInfo, error) {
+	book := bcr.sw.AddrBook()
+	if book == nil {
+		return nil, errors.New("address book is not enabled")
+	}
+
+	reachable := []*p2p.AddressInfo{}
+	for _, info := range book.ListAddresses() {
+		if info.Successes > 0 {
+			reachable = append(reachable, info)
+		}
+	}
+	return reachable, nil
+}
+
+// exportSnapshot dumps the verified state at the current best block to
+// the given file, so another node can bootstrap from it.
+func (bcr *BlockchainReactor) exportSnapshot(in struct {
+	Path string `json:"path"`
+}) error {
+	if bcr.snapshotStore == nil {
+		return errors.New("snapshot store is not available")
+	}
+	snap, err := bcr.snapshotStore.ExportSnapshot()
+	if err != nil {
+		return err
+	}
+	data, err := stdjson.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(in.Path, data, 0644)
+}
+
+// getUtxoCommitment returns the merkle commitment of the UTXO set at
+// the current best block, for auditing state against other nodes and
+// verifying snapshots before importing them.
+func (bcr *BlockchainReactor) getUtxoCommitment() (*txdb.UtxoCommitment, error) {
+	if bcr.snapshotStore == nil {
+		return nil, errors.New("snapshot store is not available")
+	}
+	return bcr.snapshotStore.UtxoCommitment()
+}
+
+// DeploymentStatus is one soft fork deployment and its activation
+// state at the current tip.
+type DeploymentStatus struct {
+	Name          string `json:"name"`
+	Bit           uint8  `json:"bit"`
+	StartHeight   uint64 `json:"start_height"`
+	TimeoutHeight uint64 `json:"timeout_height"`
+	State         string `json:"state"`
+}
+
+// getDeploymentStates reports every registered soft fork deployment
+// and how far along activation it is.
+func (bcr *BlockchainReactor) getDeploymentStates() ([]*DeploymentStatus, error) {
+	statuses := []*DeploymentStatus{}
+	for _, d := range bcr.chain.Deployments() {
+		state, err := bcr.chain.ThresholdState(d.Name)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, &DeploymentStatus{
+			Name:          d.Name,
+			Bit:           d.Bit,
+			StartHeight:   d.StartHeight,
+			TimeoutHeight: d.TimeoutHeight,
+			State:         state.String(),
+		})
+	}
+	return statuses, nil
+}
+
+// TxValidation is the response of validate-transaction: the stateless
+// diagnosis plus which prevouts the node cannot find.
+type TxValidation struct {
+	*validation.TxDiagnosis
+	MissingPrevouts []string `json:"missing_prevouts"`
+}
+
+// validateTx runs a raw transaction through full validation against the
+// current best block without broadcasting it, reporting per-input
+// failure reasons, gas used and any prevouts the node does not know
+// about. Wallets use it to debug rejected transactions.
+func (bcr *BlockchainReactor) validateTx(in struct {
+	RawTransaction string `json:"raw_transaction"`
+}) (*TxValidation, error) {
+	tx := &legacy.Tx{}
+	if err := tx.UnmarshalText([]byte(in.RawTransaction)); err != nil {
+		return nil, errors.Wrap(err, "decoding raw transaction")
+	}
+
+	block := legacy.MapBlock(bcr.chain.BestBlock())
+	result := &TxValidation{
+		TxDiagnosis:     validation.DiagnoseTx(tx.Tx, block),
+		MissingPrevouts: []string{},
+	}
+	for _, outID := range tx.Tx.SpentOutputIDs {
+		if _, err := bcr.chain.GetUtxo(&outID); err == nil {
+			continue
+		}
+		if bcr.txPool.IsOutputInPool(&outID) {
+			continue
+		}
+		result.MissingPrevouts = append(result.MissingPrevouts, outID.String())
+	}
+	return result, nil
+}
+
+// ChainTipJSON is one entry of the get-chain-tips response.
+type ChainTipJSON struct {
+	Hash   string `json:"hash"`
+	Height uint64 `json:"height"`
+	Length uint64 `json:"length"`
+	Status string `json:"status"`
+}
+
+// getChainTips lists the main tip and every known side branch tip, so
+// operators can see fork situations at a glance.
+func (bcr *BlockchainReactor) getChainTips() ([]*ChainTipJSON, error) {
+	tips, err := bcr.chain.ChainTips()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*ChainTipJSON, 0, len(tips))
+	for _, tip := range tips {
+		resp = append(resp, &ChainTipJSON{
+			Hash:   tip.Hash.String(),
+			Height: tip.Height,
+			Length: tip.Length,
+			Status: tip.Status,
+		})
+	}
+	return resp, nil
+}
+
+// getMempoolInfo reports pool occupancy and a fee-rate histogram, so
+// wallets can pick a competitive fee when the pool is congested.
+func (bcr *BlockchainReactor) getMempoolInfo() (*protocol.MempoolInfo, error) {
+	return bcr.txPool.Info(), nil
+}
+
 // return best block hash
 func (bcr *BlockchainReactor) getBestBlockHash() []byte {
 	data := []string{bcr.chain.BestBlockHash().String()}
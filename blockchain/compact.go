@@ -0,0 +1,71 @@
+package blockchain
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/database"
+	"github.com/bytom/errors"
+)
+
+// RegisterDatabase hands the reactor one of the node's databases under
+// a stable name, so maintenance APIs like compact-database can reach
+// the raw engines.
+func (bcR *BlockchainReactor) RegisterDatabase(name string, db dbm.DB) {
+	if bcR.databases == nil {
+		bcR.databases = make(map[string]dbm.DB)
+	}
+	bcR.databases[name] = db
+}
+
+// CompactResult reports the outcome of compacting one database.
+type CompactResult struct {
+	Name      string `json:"name"`
+	Ok        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+}
+
+// compactDatabase compacts the named database, or every registered one
+// when no name is given, while the node keeps serving. Compaction runs
+// sequentially and the call returns per-database timings, so operators
+// see where the time went.
+func (bcr *BlockchainReactor) compactDatabase(in struct {
+	Name string `json:"name"`
+}) ([]*CompactResult, error) {
+	names := []string{}
+	if in.Name != "" {
+		if _, ok := bcr.databases[in.Name]; !ok {
+			return nil, errors.WithDetailf(errors.New("unknown database"), "no database named %q is registered", in.Name)
+		}
+		names = append(names, in.Name)
+	} else {
+		for name := range bcr.databases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	results := []*CompactResult{}
+	for _, name := range names {
+		started := time.Now()
+		log.WithField("database", name).Info("Compacting database")
+		err := database.Compact(bcr.databases[name])
+		result := &CompactResult{
+			Name:      name,
+			Ok:        err == nil,
+			ElapsedMS: time.Since(started).Nanoseconds() / int64(time.Millisecond),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			log.WithFields(log.Fields{"database": name, "error": err}).Error("Database compaction failed")
+		} else {
+			log.WithFields(log.Fields{"database": name, "elapsed": time.Since(started)}).Info("Database compaction finished")
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,158 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	chainjson "github.com/bytom/encoding/json"
+	"github.com/bytom/equity"
+	"github.com/bytom/protocol/vm"
+)
+
+// CompiledContract is the API form of a compiled contract: the program
+// bytes plus the parameter layout and clause entry points a caller
+// needs to instantiate and spend it.
+type CompiledContract struct {
+	Name     string           `json:"name"`
+	Program  string           `json:"program"`
+	Params   []equity.Param   `json:"params"`
+	Clauses  []*equity.Clause `json:"clauses"`
+	Assembly string           `json:"assembly"`
+}
+
+// compileContract compiles an Equity-style contract source so contracts
+// can be authored against a running node without external toolchains.
+func (bcr *BlockchainReactor) compileContract(in struct {
+	Contract string `json:"contract"`
+}) (*CompiledContract, error) {
+	contract, err := equity.Compile(in.Contract)
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledContract{
+		Name:     contract.Name,
+		Program:  hex.EncodeToString(contract.Program),
+		Params:   contract.Params,
+		Clauses:  contract.Clauses,
+		Assembly: contract.Assembly,
+	}, nil
+}
+
+// testProgramGasCap bounds what one /test-program call may burn, so the
+// playground cannot be used to tie up the node.
+const testProgramGasCap = int64(1000000)
+
+// TestProgramRequest describes a program run against a caller-supplied
+// fake transaction context. Every context field is optional; an opcode
+// that needs an absent field fails with a context error, exactly as it
+// would inside real validation.
+type TestProgramRequest struct {
+	// the program to run: raw bytes, or assembly for Assemble when
+	// Program is empty
+	Program  chainjson.HexBytes `json:"program"`
+	Assembly string             `json:"assembly"`
+
+	// initial stack arguments, bottom first
+	Args []chainjson.HexBytes `json:"args"`
+
+	// gas budget for the run; 0 means the cap
+	GasLimit int64 `json:"gas_limit"`
+
+	// fake transaction context
+	TxVersion     *uint64             `json:"tx_version"`
+	BlockHeight   *uint64             `json:"block_height"`
+	NumResults    *uint64             `json:"num_results"`
+	AssetID       *chainjson.HexBytes `json:"asset_id"`
+	Amount        *uint64             `json:"amount"`
+	EntryData     *chainjson.HexBytes `json:"entry_data"`
+	TxData        *chainjson.HexBytes `json:"tx_data"`
+	DestPos       *uint64             `json:"dest_pos"`
+	AnchorID      *chainjson.HexBytes `json:"anchor_id"`
+	SpentOutputID *chainjson.HexBytes `json:"spent_output_id"`
+	TxSigHash     *chainjson.HexBytes `json:"tx_sig_hash"`
+}
+
+// TestProgramResult reports how the run went. Ok is true only when the
+// program ran to completion and left a true value on the stack.
+type TestProgramResult struct {
+	Ok      bool  `json:"ok"`
+	GasUsed int64 `json:"gas_used"`
+	GasLeft int64 `json:"gas_left"`
+	// the VM error for failed runs, including "false VM result" for
+	// programs that complete but evaluate false
+	Error string `json:"error,omitempty"`
+}
+
+// testProgram runs a program in an isolated VM against the fake context
+// from the request and reports the outcome. Nothing it does reads or
+// writes chain state, so it is safe to expose to contract developers.
+//
+// POST /test-program
+func (bcr *BlockchainReactor) testProgram(in TestProgramRequest) (*TestProgramResult, error) {
+	code := []byte(in.Program)
+	if len(code) == 0 && in.Assembly != "" {
+		assembled, err := vm.Assemble(in.Assembly)
+		if err != nil {
+			return nil, err
+		}
+		code = assembled
+	}
+
+	gasLimit := in.GasLimit
+	if gasLimit <= 0 || gasLimit > testProgramGasCap {
+		gasLimit = testProgramGasCap
+	}
+
+	args := make([][]byte, 0, len(in.Args))
+	for _, arg := range in.Args {
+		args = append(args, arg)
+	}
+
+	context := &vm.Context{
+		VMVersion:   1,
+		Code:        code,
+		Arguments:   args,
+		TxVersion:   in.TxVersion,
+		BlockHeight: in.BlockHeight,
+		NumResults:  in.NumResults,
+		Amount:      in.Amount,
+		DestPos:     in.DestPos,
+	}
+	if in.AssetID != nil {
+		assetID := []byte(*in.AssetID)
+		context.AssetID = &assetID
+	}
+	if in.EntryData != nil {
+		entryData := []byte(*in.EntryData)
+		context.EntryData = &entryData
+	}
+	if in.TxData != nil {
+		txData := []byte(*in.TxData)
+		context.TxData = &txData
+	}
+	if in.AnchorID != nil {
+		anchorID := []byte(*in.AnchorID)
+		context.AnchorID = &anchorID
+	}
+	if in.SpentOutputID != nil {
+		spentOutputID := []byte(*in.SpentOutputID)
+		context.SpentOutputID = &spentOutputID
+	}
+	if in.TxSigHash != nil {
+		sigHash := []byte(*in.TxSigHash)
+		context.TxSigHash = func() []byte { return sigHash }
+	}
+	context.CheckOutput = func(uint64, []byte, uint64, []byte, uint64, []byte, bool) (bool, error) {
+		return false, fmt.Errorf("CHECKOUTPUT is not available in the test context")
+	}
+
+	result := &TestProgramResult{}
+	gasLeft, err := vm.Verify(context, gasLimit)
+	result.Ok = err == nil
+	result.GasLeft = gasLeft
+	result.GasUsed = gasLimit - gasLeft
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
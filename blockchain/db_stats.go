@@ -0,0 +1,63 @@
+package blockchain
+
+import (
+	"sort"
+
+	"github.com/bytom/database"
+)
+
+// DBStatJSON reports one database's footprint: approximate size on
+// disk, key counts per namespace, and whatever statistics the engine
+// exposes (for LevelDB that includes the compaction table).
+type DBStatJSON struct {
+	Name      string            `json:"name"`
+	SizeBytes int64             `json:"size_bytes"`
+	KeyCounts map[string]int    `json:"key_counts"`
+	Engine    map[string]string `json:"engine,omitempty"`
+}
+
+// UtxoCacheStatJSON reports the utxo cache hit rate.
+type UtxoCacheStatJSON struct {
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// DBStatsResp is the db-stats response.
+type DBStatsResp struct {
+	Databases []*DBStatJSON      `json:"databases"`
+	UtxoCache *UtxoCacheStatJSON `json:"utxo_cache,omitempty"`
+}
+
+// getDBStats reports size, key distribution and engine statistics for
+// every registered database, plus the utxo cache hit rate. Key counting
+// walks each database in full, so this is an operator query, not
+// something to poll every second.
+func (bcr *BlockchainReactor) getDBStats() (*DBStatsResp, error) {
+	names := []string{}
+	for name := range bcr.databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resp := &DBStatsResp{Databases: []*DBStatJSON{}}
+	for _, name := range names {
+		db := bcr.databases[name]
+		resp.Databases = append(resp.Databases, &DBStatJSON{
+			Name:      name,
+			SizeBytes: database.SizeOnDisk(db),
+			KeyCounts: database.CountKeysByPrefix(db),
+			Engine:    db.Stats(),
+		})
+	}
+
+	if bcr.snapshotStore != nil {
+		hits, misses := bcr.snapshotStore.UtxoCacheStats()
+		stat := &UtxoCacheStatJSON{Hits: hits, Misses: misses}
+		if total := hits + misses; total > 0 {
+			stat.HitRate = float64(hits) / float64(total)
+		}
+		resp.UtxoCache = stat
+	}
+	return resp, nil
+}
@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimedebug "runtime/debug"
+	runtimepprof "runtime/pprof"
+	"time"
+
+	"github.com/bytom/net/http/authn"
+)
+
+// debugTokenType is the access-token type that may call the /debug
+// endpoints. Client and network tokens are refused, as is the implicit
+// loopback grant the regular APIs accept.
+const debugTokenType = "admin"
+
+// EnableDebugEndpoints turns on the /debug profiling and diagnostics
+// routes. They are off by default; profiles expose memory contents, so
+// every request must carry an admin access token even from localhost.
+func (bcR *BlockchainReactor) EnableDebugEndpoints() {
+	bcR.debugEnabled = true
+}
+
+// debugHandler serves pprof and runtime diagnostics under /debug,
+// behind the admin-token check.
+func (bcr *BlockchainReactor) debugHandler() http.Handler {
+	m := http.NewServeMux()
+	m.HandleFunc("/debug/pprof/", pprof.Index)
+	m.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	m.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	m.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	m.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	m.HandleFunc("/debug/goroutines", dumpGoroutines)
+	m.HandleFunc("/debug/gc-stats", dumpGCStats)
+	return bcr.requireAdminToken(m)
+}
+
+// requireAdminToken refuses any request that is not authenticated with
+// an access token of type "admin". The regular auth layer lets loopback
+// callers through without a token, which is fine for the wallet APIs
+// but not for endpoints that can dump heap contents.
+func (bcr *BlockchainReactor) requireAdminToken(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := authn.Token(req.Context())
+		if id == "" {
+			http.Error(w, "debug endpoints require an admin access token", http.StatusUnauthorized)
+			return
+		}
+		token, err := bcr.accessTokens.Get(req.Context(), id)
+		if err != nil || token.Type != debugTokenType {
+			http.Error(w, "access token is not admin-scoped", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+// dumpGoroutines writes a full stack dump of every goroutine, the same
+// text SIGQUIT would print, without killing the process.
+func dumpGoroutines(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// GCStatsResp reports allocator and collector figures for /debug/gc-stats.
+type GCStatsResp struct {
+	NumGoroutine   int     `json:"num_goroutine"`
+	HeapAllocBytes uint64  `json:"heap_alloc_bytes"`
+	HeapSysBytes   uint64  `json:"heap_sys_bytes"`
+	HeapObjects    uint64  `json:"heap_objects"`
+	StackSysBytes  uint64  `json:"stack_sys_bytes"`
+	NextGCBytes    uint64  `json:"next_gc_bytes"`
+	NumGC          int64   `json:"num_gc"`
+	LastGC         string  `json:"last_gc,omitempty"`
+	PauseTotalMS   float64 `json:"pause_total_ms"`
+	GCCPUFraction  float64 `json:"gc_cpu_fraction"`
+}
+
+func dumpGCStats(w http.ResponseWriter, req *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	var gc runtimedebug.GCStats
+	runtimedebug.ReadGCStats(&gc)
+
+	resp := &GCStatsResp{
+		NumGoroutine:   runtime.NumGoroutine(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapSysBytes:   mem.HeapSys,
+		HeapObjects:    mem.HeapObjects,
+		StackSysBytes:  mem.StackSys,
+		NextGCBytes:    mem.NextGC,
+		NumGC:          gc.NumGC,
+		PauseTotalMS:   float64(gc.PauseTotal) / float64(time.Millisecond),
+		GCCPUFraction:  mem.GCCPUFraction,
+	}
+	if !gc.LastGC.IsZero() {
+		resp.LastGC = gc.LastGC.Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,214 @@
+// Package deposit tracks payments to externally registered watch
+// addresses - the deposit-crediting workflow every exchange otherwise
+// re-implements on top of raw block data. A watch pairs a control
+// program with a required confirmation count; the tracker follows the
+// chain, records every output paying a watched program and reverses
+// records on reorgs, and the exchange marks a deposit credited once it
+// has been booked.
+package deposit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/errors"
+)
+
+// WatchNumMax caps the number of registered watch addresses.
+const WatchNumMax = 100000
+
+var (
+	// ErrBadProgram means the watch control program is not valid hex.
+	ErrBadProgram = errors.New("invalid watch control program")
+	// ErrDuplicateWatch means the program is already being watched.
+	ErrDuplicateWatch = errors.New("control program already watched")
+	// ErrNoMatchWatch means no watch exists for the program.
+	ErrNoMatchWatch = errors.New("no watch with that control program")
+	// ErrNoMatchDeposit means no deposit matches the given output.
+	ErrNoMatchDeposit = errors.New("no deposit with that transaction output")
+	// ErrNumExceedlimit means the watch number exceeds the limit.
+	ErrNumExceedlimit = errors.New("watch number exceed limit")
+)
+
+// database buckets: watches by program, deposits by height so reorg
+// rollback can find a block's records with one prefix scan
+const (
+	watchPrefix   = "WA:"
+	depositPrefix = "DP:"
+)
+
+// Watch is one externally registered address to credit deposits for.
+type Watch struct {
+	// Program is the hex control program of the watched address.
+	Program string `json:"program"`
+	// Confirmations is the depth at which a deposit counts as final.
+	Confirmations uint64 `json:"confirmations"`
+	// Alias is a free-form label, e.g. the exchange-side user id.
+	Alias   string    `json:"alias,omitempty"`
+	Created time.Time `json:"created_at"`
+}
+
+// Deposit is one recorded output paying a watched program.
+type Deposit struct {
+	Program     string `json:"program"`
+	TxID        string `json:"tx_id"`
+	OutputIndex int    `json:"output_index"`
+	AssetID     string `json:"asset_id"`
+	Amount      uint64 `json:"amount"`
+	BlockHeight uint64 `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+	// Credited is set by the exchange once the deposit is booked; it
+	// survives restarts so crediting is idempotent.
+	Credited bool `json:"credited"`
+}
+
+// Tracker stores watches and deposits in its own database.
+type Tracker struct {
+	DB dbm.DB
+
+	mtx     sync.Mutex
+	watches map[string]*Watch
+
+	status statusInfo
+}
+
+// NewTracker loads the persisted watches and resumes from the block the
+// deposits cover.
+func NewTracker(db dbm.DB) (*Tracker, error) {
+	t := &Tracker{
+		DB:      db,
+		watches: map[string]*Watch{},
+	}
+
+	iter := db.IteratorPrefix([]byte(watchPrefix))
+	defer iter.Release()
+	for iter.Next() {
+		watch := &Watch{}
+		if err := json.Unmarshal(iter.Value(), watch); err != nil {
+			return nil, err
+		}
+		t.watches[watch.Program] = watch
+	}
+
+	if raw := db.Get(statusKey); raw != nil {
+		if err := json.Unmarshal(raw, &t.status); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// CreateWatch registers a control program to track deposits for.
+func (t *Tracker) CreateWatch(program string, confirmations uint64, alias string) (*Watch, error) {
+	if _, err := hex.DecodeString(program); err != nil || program == "" {
+		return nil, errors.WithDetailf(ErrBadProgram, "program %q is not hex", program)
+	}
+	if confirmations == 0 {
+		confirmations = 1
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if _, ok := t.watches[program]; ok {
+		return nil, errors.WithDetailf(ErrDuplicateWatch, "program %q already watched", program)
+	}
+	if len(t.watches) >= WatchNumMax {
+		return nil, errors.WithDetail(ErrNumExceedlimit, "watch number exceed limit")
+	}
+
+	watch := &Watch{
+		Program:       program,
+		Confirmations: confirmations,
+		Alias:         alias,
+		Created:       time.Now(),
+	}
+	raw, err := json.Marshal(watch)
+	if err != nil {
+		return nil, err
+	}
+	t.DB.Set([]byte(watchPrefix+program), raw)
+	t.watches[program] = watch
+	return watch, nil
+}
+
+// DeleteWatch stops tracking a program. Recorded deposits are kept; the
+// exchange may still be reconciling them.
+func (t *Tracker) DeleteWatch(program string) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if _, ok := t.watches[program]; !ok {
+		return errors.WithDetailf(ErrNoMatchWatch, "program %q not watched", program)
+	}
+	delete(t.watches, program)
+	t.DB.Delete([]byte(watchPrefix + program))
+	return nil
+}
+
+// ListWatches returns every registered watch.
+func (t *Tracker) ListWatches() []*Watch {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	watches := make([]*Watch, 0, len(t.watches))
+	for _, watch := range t.watches {
+		watches = append(watches, watch)
+	}
+	return watches
+}
+
+func (t *Tracker) watchFor(program string) *Watch {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.watches[program]
+}
+
+// ListDeposits returns recorded deposits, optionally restricted to one
+// program or to uncredited deposits only.
+func (t *Tracker) ListDeposits(program string, uncreditedOnly bool) ([]*Deposit, error) {
+	deposits := []*Deposit{}
+
+	iter := t.DB.IteratorPrefix([]byte(depositPrefix))
+	defer iter.Release()
+	for iter.Next() {
+		deposit := &Deposit{}
+		if err := json.Unmarshal(iter.Value(), deposit); err != nil {
+			return nil, err
+		}
+		if program != "" && deposit.Program != program {
+			continue
+		}
+		if uncreditedOnly && deposit.Credited {
+			continue
+		}
+		deposits = append(deposits, deposit)
+	}
+	return deposits, nil
+}
+
+// Credit marks one deposit as booked by the exchange.
+func (t *Tracker) Credit(txID string, outputIndex int) error {
+	iter := t.DB.IteratorPrefix([]byte(depositPrefix))
+	defer iter.Release()
+	for iter.Next() {
+		deposit := &Deposit{}
+		if err := json.Unmarshal(iter.Value(), deposit); err != nil {
+			return err
+		}
+		if deposit.TxID != txID || deposit.OutputIndex != outputIndex {
+			continue
+		}
+		deposit.Credited = true
+		raw, err := json.Marshal(deposit)
+		if err != nil {
+			return err
+		}
+		t.DB.SetSync(append([]byte{}, iter.Key()...), raw)
+		return nil
+	}
+	return errors.WithDetailf(ErrNoMatchDeposit, "no deposit for output %s:%d", txID, outputIndex)
+}
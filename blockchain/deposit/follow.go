@@ -0,0 +1,129 @@
+package deposit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+var statusKey = []byte("depositInfo")
+
+// statusInfo is the last block the deposit records cover, kept to
+// detect and roll back orphaned blocks.
+type statusInfo struct {
+	Height uint64
+	Hash   bc.Hash
+}
+
+// depositKey places a block's deposits under one height prefix, so a
+// reorg rollback removes them with a single prefix scan.
+func depositKey(height uint64, txID string, outputIndex int) []byte {
+	return []byte(fmt.Sprintf("%s%016x:%s:%08x", depositPrefix, height, txID, outputIndex))
+}
+
+func (t *Tracker) commitStatus(batch *dbm.Batch) {
+	raw, err := json.Marshal(t.status)
+	if err != nil {
+		log.WithField("err", err).Error("save deposit info")
+		return
+	}
+	(*batch).Set(statusKey, raw)
+	(*batch).Write()
+}
+
+// Update follows the chain the same way the wallet does: new main chain
+// blocks record deposits to watched programs, blocks falling off the
+// main chain drop their records again. Each block commits as one batch
+// with the status marker last, so a crash cannot leave half-recorded
+// state.
+func (t *Tracker) Update(c *protocol.Chain) {
+	var err error
+	var block *legacy.Block
+
+LOOP:
+
+	for !c.InMainChain(t.status.Height, t.status.Hash) {
+		if block, err = c.GetBlockByHash(&t.status.Hash); err != nil {
+			log.WithField("err", err).Error("deposit tracker get block by hash")
+			return
+		}
+
+		batch := t.DB.NewBatch()
+		t.detachBlock(&batch, block)
+		log.WithField("height", t.status.Height).Info("deposit tracker rolling back block")
+
+		t.status.Height = block.Height - 1
+		t.status.Hash = block.PreviousBlockHash
+		t.commitStatus(&batch)
+	}
+
+	block, _ = c.GetBlockByHeight(t.status.Height + 1)
+	if block == nil {
+		<-c.BlockWaiter(t.status.Height + 1)
+		if block, err = c.GetBlockByHeight(t.status.Height + 1); err != nil {
+			log.WithField("err", err).Error("deposit tracker get block by height")
+			return
+		}
+	}
+
+	if block.PreviousBlockHash == t.status.Hash {
+		t.status.Height = block.Height
+		t.status.Hash = block.Hash()
+
+		batch := t.DB.NewBatch()
+		t.attachBlock(&batch, block)
+		t.commitStatus(&batch)
+	}
+
+	goto LOOP
+}
+
+func (t *Tracker) attachBlock(batch *dbm.Batch, block *legacy.Block) {
+	blockHash := block.Hash()
+	for _, tx := range block.Transactions {
+		for index, output := range tx.Outputs {
+			watch := t.watchFor(hex.EncodeToString(output.ControlProgram))
+			if watch == nil {
+				continue
+			}
+
+			txID := tx.Tx.ID.String()
+			deposit := &Deposit{
+				Program:     watch.Program,
+				TxID:        txID,
+				OutputIndex: index,
+				AssetID:     output.AssetId.String(),
+				Amount:      output.Amount,
+				BlockHeight: block.Height,
+				BlockHash:   blockHash.String(),
+			}
+			raw, err := json.Marshal(deposit)
+			if err != nil {
+				log.WithField("err", err).Error("marshal deposit")
+				continue
+			}
+			(*batch).Set(depositKey(block.Height, txID, index), raw)
+			log.WithFields(log.Fields{"tx_id": txID, "amount": output.Amount}).Info("recorded deposit to watched address")
+		}
+	}
+}
+
+// detachBlock drops the block's deposit records; a transaction that is
+// re-included after the reorg records fresh ones, losing only the
+// credited flag, which errs on the side of crediting twice rather than
+// silently keeping a record the chain no longer backs.
+func (t *Tracker) detachBlock(batch *dbm.Batch, block *legacy.Block) {
+	prefix := []byte(fmt.Sprintf("%s%016x:", depositPrefix, block.Height))
+	iter := t.DB.IteratorPrefix(prefix)
+	defer iter.Release()
+	for iter.Next() {
+		(*batch).Delete(append([]byte{}, iter.Key()...))
+	}
+}
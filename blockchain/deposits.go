@@ -0,0 +1,102 @@
+package blockchain
+
+import (
+	"github.com/bytom/blockchain/deposit"
+	"github.com/bytom/errors"
+)
+
+// POST /create-watch-address
+func (bcr *BlockchainReactor) createWatchAddress(in struct {
+	Program       string `json:"program"`
+	Confirmations uint64 `json:"confirmations"`
+	Alias         string `json:"alias"`
+}) (*deposit.Watch, error) {
+	if in.Program == "" {
+		return nil, errors.WithDetail(errBadAction, "program is required")
+	}
+	return bcr.depositTracker.CreateWatch(in.Program, in.Confirmations, in.Alias)
+}
+
+// POST /list-watch-addresses
+func (bcr *BlockchainReactor) listWatchAddresses() ([]*deposit.Watch, error) {
+	return bcr.depositTracker.ListWatches(), nil
+}
+
+// POST /delete-watch-address
+func (bcr *BlockchainReactor) deleteWatchAddress(in struct {
+	Program string `json:"program"`
+}) (*struct {
+	Msg string `json:"msg"`
+}, error) {
+	if in.Program == "" {
+		return nil, errors.WithDetail(errBadAction, "program is required")
+	}
+	if err := bcr.depositTracker.DeleteWatch(in.Program); err != nil {
+		return nil, err
+	}
+	return &struct {
+		Msg string `json:"msg"`
+	}{Msg: "watch address deleted"}, nil
+}
+
+// DepositItem is one /list-deposits entry: the stored record plus the
+// confirmation state computed against the current tip.
+type DepositItem struct {
+	*deposit.Deposit
+	Confirmations uint64 `json:"confirmations"`
+	// Final reports whether the watch's required confirmation count has
+	// been reached; only final deposits should be credited.
+	Final bool `json:"final"`
+}
+
+// POST /list-deposits
+func (bcr *BlockchainReactor) listDeposits(in struct {
+	Program    string `json:"program"`
+	Uncredited bool   `json:"uncredited"`
+}) ([]*DepositItem, error) {
+	deposits, err := bcr.depositTracker.ListDeposits(in.Program, in.Uncredited)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredByProgram := map[string]uint64{}
+	for _, watch := range bcr.depositTracker.ListWatches() {
+		requiredByProgram[watch.Program] = watch.Confirmations
+	}
+
+	height := bcr.chain.Height()
+	items := make([]*DepositItem, 0, len(deposits))
+	for _, dep := range deposits {
+		item := &DepositItem{Deposit: dep}
+		if height >= dep.BlockHeight {
+			item.Confirmations = height - dep.BlockHeight + 1
+		}
+		// a deleted watch leaves its deposits behind; treat those as
+		// final at one confirmation
+		required := requiredByProgram[dep.Program]
+		if required == 0 {
+			required = 1
+		}
+		item.Final = item.Confirmations >= required
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// POST /credit-deposit
+func (bcr *BlockchainReactor) creditDeposit(in struct {
+	TxID        string `json:"tx_id"`
+	OutputIndex int    `json:"output_index"`
+}) (*struct {
+	Msg string `json:"msg"`
+}, error) {
+	if in.TxID == "" {
+		return nil, errors.WithDetail(errBadAction, "tx_id is required")
+	}
+	if err := bcr.depositTracker.Credit(in.TxID, in.OutputIndex); err != nil {
+		return nil, err
+	}
+	return &struct {
+		Msg string `json:"msg"`
+	}{Msg: "deposit credited"}, nil
+}
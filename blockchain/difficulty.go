@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"math/big"
+
+	"github.com/bytom/consensus"
+)
+
+// maximum historical samples difficulty-info returns.
+const maxDifficultySamples = 16
+
+// DifficultySample is the difficulty of the chain at one retarget
+// boundary.
+type DifficultySample struct {
+	Height    uint64 `json:"height"`
+	Bits      uint64 `json:"bits"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// DifficultyInfo describes the current proof-of-work target and what
+// the next retarget is expected to do, for miners planning capacity.
+type DifficultyInfo struct {
+	Height                uint64             `json:"height"`
+	Bits                  uint64             `json:"bits"`
+	Target                string             `json:"target"`
+	NextBlockBits         uint64             `json:"next_block_bits"`
+	NextRetargetHeight    uint64             `json:"next_retarget_height"`
+	BlocksToRetarget      uint64             `json:"blocks_to_retarget"`
+	AvgBlockTimeMS        float64            `json:"avg_block_time_ms"`
+	EstimatedRetargetBits uint64             `json:"estimated_retarget_bits"`
+	Samples               []DifficultySample `json:"samples"`
+}
+
+// getDifficultyInfo reports the current target, when the next retarget
+// happens, an estimate of the retargeted difficulty from recent block
+// times, and historical difficulty samples at retarget boundaries.
+func (bcr *BlockchainReactor) getDifficultyInfo() (*DifficultyInfo, error) {
+	best := bcr.chain.BestBlock()
+	tip := best.Height
+
+	blocksToRetarget := (consensus.ActiveNetParams.BlocksPerRetarget - tip%consensus.ActiveNetParams.BlocksPerRetarget) % consensus.ActiveNetParams.BlocksPerRetarget
+	info := &DifficultyInfo{
+		Height:             tip,
+		Bits:               best.Bits,
+		Target:             consensus.CompactToBig(best.Bits).String(),
+		NextRetargetHeight: tip + blocksToRetarget + 1,
+		BlocksToRetarget:   blocksToRetarget,
+	}
+
+	// The exact bits required for the next block.
+	nextHeight := tip + 1
+	if compareBlock, err := bcr.chain.GetBlockByHeight(nextHeight - consensus.ActiveNetParams.BlocksPerRetarget); err == nil {
+		info.NextBlockBits = consensus.CalcNextRequiredDifficulty(&best.BlockHeader, &compareBlock.BlockHeader)
+	} else {
+		info.NextBlockBits = consensus.CalcNextRequiredDifficulty(&best.BlockHeader, nil)
+	}
+
+	// Estimate the retargeted difficulty by replaying the retarget
+	// formula over the average block time of the current window.
+	windowStart := tip - tip%consensus.ActiveNetParams.BlocksPerRetarget
+	if windowStart < tip {
+		startBlock, err := bcr.chain.GetBlockByHeight(windowStart)
+		if err != nil {
+			return nil, err
+		}
+		elapsed := best.TimestampMS - startBlock.TimestampMS
+		info.AvgBlockTimeMS = float64(elapsed) / float64(tip-windowStart)
+
+		avgSeconds := int64(info.AvgBlockTimeMS / 1000)
+		if avgSeconds > 0 {
+			target := new(big.Int).Mul(consensus.CompactToBig(best.Bits), big.NewInt(avgSeconds))
+			target.Div(target, big.NewInt(int64(consensus.ActiveNetParams.TargetSecondsPerBlock)))
+			info.EstimatedRetargetBits = consensus.BigToCompact(target)
+		} else {
+			info.EstimatedRetargetBits = best.Bits
+		}
+	} else {
+		info.EstimatedRetargetBits = info.NextBlockBits
+	}
+
+	// Historical samples, one per retarget boundary, newest first.
+	info.Samples = []DifficultySample{}
+	for height := windowStart; len(info.Samples) < maxDifficultySamples; height -= consensus.ActiveNetParams.BlocksPerRetarget {
+		block, err := bcr.chain.GetBlockByHeight(height)
+		if err != nil {
+			break
+		}
+		info.Samples = append(info.Samples, DifficultySample{
+			Height:    block.Height,
+			Bits:      block.Bits,
+			Timestamp: block.TimestampMS,
+		})
+		if height == 0 {
+			break
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,285 @@
+// Package electrum serves a subset of the Electrum server protocol so
+// existing light-wallet infrastructure can target bytom. Clients speak
+// newline-delimited JSON-RPC over a plain tcp:// or unix:// listener and
+// address scripts by their "scripthash": the SHA-256 of the control
+// program with the byte order reversed, as the Electrum protocol
+// specifies. History and subscriptions are answered from the explorer
+// index, so the server requires the explorer to be enabled.
+//
+// Supported methods: server.version, server.ping,
+// blockchain.headers.subscribe, blockchain.block.header,
+// blockchain.scripthash.subscribe, blockchain.scripthash.get_history
+// and blockchain.transaction.broadcast.
+package electrum
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/blockchain/explorer"
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/version"
+)
+
+// protocolVersion is the Electrum protocol version this server answers
+// server.version with.
+const protocolVersion = "1.4"
+
+// ErrBadAddr means the listen address is not tcp:// or unix://.
+var ErrBadAddr = errors.New("invalid electrum listen address")
+
+// Server answers Electrum protocol requests and pushes header and
+// scripthash notifications to subscribed clients.
+type Server struct {
+	listener net.Listener
+	chain    *protocol.Chain
+	explorer *explorer.Explorer
+
+	mtx     sync.Mutex
+	clients map[*client]bool
+}
+
+// client is one connected Electrum session with its subscriptions.
+type client struct {
+	conn net.Conn
+
+	writeMtx sync.Mutex
+
+	// guards the subscription state, shared with the notifier
+	subMtx  sync.Mutex
+	headers bool
+	// subscribed scripthash -> last status pushed
+	scripthashes map[string]string
+}
+
+// NewServer starts listening on addr, which must look like
+// tcp://host:port or unix:///path.
+func NewServer(addr string, chain *protocol.Chain, explorer *explorer.Explorer) (*Server, error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) != 2 || (parts[0] != "tcp" && parts[0] != "unix") {
+		return nil, errors.WithDetailf(ErrBadAddr, "address %q must be tcp://host:port or unix:///path", addr)
+	}
+	listener, err := net.Listen(parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		chain:    chain,
+		explorer: explorer,
+		clients:  map[*client]bool{},
+	}
+	go s.acceptLoop()
+	log.WithField("addr", addr).Info("Electrum server listening")
+	return s, nil
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{conn: conn, scripthashes: map[string]string{}}
+		s.mtx.Lock()
+		s.clients[c] = true
+		s.mtx.Unlock()
+		go s.serveConn(c)
+	}
+}
+
+// Close stops the listener and disconnects every client.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.clients = map[*client]bool{}
+}
+
+type request struct {
+	ID     interface{}     `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// result responses always carry the result member, even when null, as
+// the protocol requires
+type response struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Result  interface{} `json:"result"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Error   *rpcError   `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+func (c *client) send(msg interface{}) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	_, err = c.conn.Write(append(raw, '\n'))
+	return err
+}
+
+func (s *Server) serveConn(c *client) {
+	defer func() {
+		c.conn.Close()
+		s.mtx.Lock()
+		delete(s.clients, c)
+		s.mtx.Unlock()
+	}()
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		req := &request{}
+		if err := json.Unmarshal(line, req); err != nil {
+			c.send(&errorResponse{JSONRPC: "2.0", Error: &rpcError{Code: -32700, Message: "parse error"}})
+			continue
+		}
+
+		result, err := s.dispatch(c, req)
+		if err != nil {
+			c.send(&errorResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: 1, Message: err.Error()}})
+			continue
+		}
+		if err := c.send(&response{JSONRPC: "2.0", ID: req.ID, Result: result}); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) dispatch(c *client, req *request) (interface{}, error) {
+	var params []json.RawMessage
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, errors.New("params must be an array")
+		}
+	}
+	stringParam := func(i int) (string, error) {
+		if i >= len(params) {
+			return "", errors.New("missing parameter")
+		}
+		var s string
+		if err := json.Unmarshal(params[i], &s); err != nil {
+			return "", errors.New("parameter must be a string")
+		}
+		return s, nil
+	}
+
+	switch req.Method {
+	case "server.version":
+		return []string{"bytomd " + version.Version, protocolVersion}, nil
+
+	case "server.ping":
+		return nil, nil
+
+	case "blockchain.headers.subscribe":
+		c.subMtx.Lock()
+		c.headers = true
+		c.subMtx.Unlock()
+		return s.tipHeader()
+
+	case "blockchain.block.header":
+		if len(params) == 0 {
+			return nil, errors.New("missing parameter")
+		}
+		var height uint64
+		if err := json.Unmarshal(params[0], &height); err != nil {
+			return nil, errors.New("parameter must be a block height")
+		}
+		return s.headerHex(height)
+
+	case "blockchain.scripthash.subscribe":
+		scripthash, err := stringParam(0)
+		if err != nil {
+			return nil, err
+		}
+		status, err := s.scripthashStatus(scripthash)
+		if err != nil {
+			return nil, err
+		}
+		c.subMtx.Lock()
+		c.scripthashes[scripthash] = status
+		c.subMtx.Unlock()
+		if status == "" {
+			return nil, nil
+		}
+		return status, nil
+
+	case "blockchain.scripthash.get_history":
+		scripthash, err := stringParam(0)
+		if err != nil {
+			return nil, err
+		}
+		return s.scripthashHistory(scripthash)
+
+	case "blockchain.transaction.broadcast":
+		rawTx, err := stringParam(0)
+		if err != nil {
+			return nil, err
+		}
+		tx := &legacy.Tx{}
+		if err := tx.UnmarshalText([]byte(rawTx)); err != nil {
+			return nil, errors.New("invalid raw transaction")
+		}
+		if err := txbuilder.FinalizeTx(context.Background(), s.chain, tx); err != nil {
+			return nil, err
+		}
+		return tx.Tx.ID.String(), nil
+	}
+	return nil, errors.New("unknown method " + req.Method)
+}
+
+// program resolves an Electrum scripthash - reversed hex SHA-256 of the
+// script - to the control program the explorer indexed it under.
+func (s *Server) program(scripthash string) (string, error) {
+	raw, err := hex.DecodeString(scripthash)
+	if err != nil || len(raw) != 32 {
+		return "", errors.New("scripthash must be a hex 32-byte hash")
+	}
+	for i, j := 0, len(raw)-1; i < j; i, j = i+1, j-1 {
+		raw[i], raw[j] = raw[j], raw[i]
+	}
+	program, ok := s.explorer.ProgramByScriptHash(hex.EncodeToString(raw))
+	if !ok {
+		// a script the chain has never seen simply has no history
+		return "", nil
+	}
+	return program, nil
+}
@@ -0,0 +1,260 @@
+package electrum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/blockchain/explorer"
+	"github.com/bytom/blockchain/txdb"
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// startSession wires a client into the server over an in-memory pipe
+// and returns the test's end of the connection.
+func startSession(s *Server) (net.Conn, *client) {
+	server, test := net.Pipe()
+	c := &client{conn: server, scripthashes: map[string]string{}}
+	s.mtx.Lock()
+	s.clients[c] = true
+	s.mtx.Unlock()
+	go s.serveConn(c)
+	return test, c
+}
+
+// call sends one raw request line and decodes the next message the
+// server writes back.
+func call(t *testing.T, conn net.Conn, line string) map[string]interface{} {
+	if _, err := fmt.Fprintf(conn, "%s\n", line); err != nil {
+		t.Fatal(err)
+	}
+	return readMessage(t, conn)
+}
+
+func readMessage(t *testing.T, conn net.Conn) map[string]interface{} {
+	msg := map[string]interface{}{}
+	if err := json.NewDecoder(conn).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func errorMessage(msg map[string]interface{}) string {
+	rpcErr, ok := msg["error"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	message, _ := rpcErr["message"].(string)
+	return message
+}
+
+func TestNewServerBadAddr(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:0", "http://127.0.0.1:0"} {
+		if _, err := NewServer(addr, nil, nil); errors.Root(err) != ErrBadAddr {
+			t.Errorf("addr %q: got error %v, want ErrBadAddr", addr, err)
+		}
+	}
+}
+
+// TestServeConnFraming exercises the line-delimited JSON-RPC framing:
+// well-formed calls, a parse error, malformed params and an unknown
+// method, none of which need chain state.
+func TestServeConnFraming(t *testing.T) {
+	s := &Server{clients: map[*client]bool{}}
+	conn, _ := startSession(s)
+	defer conn.Close()
+
+	msg := call(t, conn, `{"id":1,"method":"server.version","params":["test","1.4"]}`)
+	result, ok := msg["result"].([]interface{})
+	if !ok || len(result) != 2 || result[1] != protocolVersion {
+		t.Fatalf("server.version reply = %+v", msg)
+	}
+	if msg["id"] != float64(1) {
+		t.Errorf("reply id = %v, want 1", msg["id"])
+	}
+
+	// a ping reply is null but must still carry the result member
+	msg = call(t, conn, `{"id":2,"method":"server.ping"}`)
+	if result, ok := msg["result"]; !ok || result != nil {
+		t.Errorf("server.ping reply = %+v, want explicit null result", msg)
+	}
+
+	msg = call(t, conn, `this is not json`)
+	if errorMessage(msg) != "parse error" {
+		t.Errorf("garbage line reply = %+v, want parse error", msg)
+	}
+
+	msg = call(t, conn, `{"id":3,"method":"server.ping","params":{"a":1}}`)
+	if errorMessage(msg) != "params must be an array" {
+		t.Errorf("object params reply = %+v", msg)
+	}
+
+	msg = call(t, conn, `{"id":4,"method":"blockchain.block.header","params":[]}`)
+	if errorMessage(msg) != "missing parameter" {
+		t.Errorf("missing param reply = %+v", msg)
+	}
+
+	msg = call(t, conn, `{"id":5,"method":"blockchain.scripthash.subscribe","params":["zz"]}`)
+	if errorMessage(msg) != "scripthash must be a hex 32-byte hash" {
+		t.Errorf("bad scripthash reply = %+v", msg)
+	}
+
+	msg = call(t, conn, `{"id":6,"method":"server.frobnicate"}`)
+	if errorMessage(msg) != "unknown method server.frobnicate" {
+		t.Errorf("unknown method reply = %+v", msg)
+	}
+}
+
+// testBlock builds a block whose single transaction issues into the
+// given control program; distinct nonces keep the tx ids distinct.
+func testBlock(height uint64, prev bc.Hash, nonce byte, program []byte) *legacy.Block {
+	return &legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			Version:           1,
+			Height:            height,
+			PreviousBlockHash: prev,
+		},
+		Transactions: []*legacy.Tx{
+			legacy.NewTx(legacy.TxData{
+				Version: 1,
+				Inputs: []*legacy.TxInput{
+					legacy.NewIssuanceInput([]byte{nonce}, 10, nil, bc.Hash{V0: 9}, []byte{0x51}, nil, nil),
+				},
+				Outputs: []*legacy.TxOutput{
+					legacy.NewTxOutput(*consensus.BTMAssetID, 10, program, nil),
+				},
+			}),
+		},
+	}
+}
+
+func scripthashOf(program []byte) string {
+	hash := sha256.Sum256(program)
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	return hex.EncodeToString(hash[:])
+}
+
+func statusOf(items ...string) string {
+	h := sha256.New()
+	for _, item := range items {
+		fmt.Fprintf(h, "%s", item)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TestSubscriptions runs the server against a real chain and explorer:
+// header and scripthash subscriptions answer from the index and
+// notifyTip pushes both notification kinds as the tip moves.
+func TestSubscriptions(t *testing.T) {
+	store := txdb.NewStore(dbm.NewMemDB())
+	chain, err := protocol.NewChain(bc.Hash{}, store, protocol.NewTxPool())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	program := []byte{0x51}
+	programHex := hex.EncodeToString(program)
+	block1 := testBlock(1, bc.Hash{}, 1, program)
+	if err := store.SaveBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := explorer.NewExplorer(dbm.NewMemDB())
+	go exp.Update(chain)
+	waitForHistory := func(want int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if items, _ := exp.ListAddressTransactions(programHex, 0, 10); len(items) >= want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("explorer never indexed %d transactions for %s", want, programHex)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForHistory(1)
+
+	s := &Server{chain: chain, explorer: exp, clients: map[*client]bool{}}
+	conn, _ := startSession(s)
+	defer conn.Close()
+
+	msg := call(t, conn, `{"id":1,"method":"blockchain.headers.subscribe"}`)
+	header, ok := msg["result"].(map[string]interface{})
+	if !ok || header["height"] != float64(1) || header["hex"] == "" {
+		t.Fatalf("headers.subscribe reply = %+v", msg)
+	}
+
+	tx1 := block1.Transactions[0].Tx.ID.String()
+	scripthash := scripthashOf(program)
+	msg = call(t, conn, fmt.Sprintf(`{"id":2,"method":"blockchain.scripthash.get_history","params":[%q]}`, scripthash))
+	history, ok := msg["result"].([]interface{})
+	if !ok || len(history) != 1 {
+		t.Fatalf("get_history reply = %+v", msg)
+	}
+	item := history[0].(map[string]interface{})
+	if item["tx_hash"] != tx1 || item["height"] != float64(1) {
+		t.Errorf("history item = %+v, want %s at height 1", item, tx1)
+	}
+
+	status1 := statusOf(fmt.Sprintf("%s:%d:", tx1, 1))
+	msg = call(t, conn, fmt.Sprintf(`{"id":3,"method":"blockchain.scripthash.subscribe","params":[%q]}`, scripthash))
+	if msg["result"] != status1 {
+		t.Fatalf("scripthash.subscribe reply = %+v, want status %s", msg, status1)
+	}
+
+	// a script the chain has never seen subscribes with a null status
+	unknown := scripthashOf([]byte{0x52})
+	msg = call(t, conn, fmt.Sprintf(`{"id":4,"method":"blockchain.scripthash.subscribe","params":[%q]}`, unknown))
+	if result, ok := msg["result"]; !ok || result != nil {
+		t.Fatalf("unknown scripthash reply = %+v, want null status", msg)
+	}
+
+	// a new block paying the program moves the tip and the status
+	block2 := testBlock(2, block1.Hash(), 2, program)
+	if err := store.SaveBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	waitForHistory(2)
+	go s.notifyTip(2)
+
+	msg = readMessage(t, conn)
+	if msg["method"] != "blockchain.headers.subscribe" {
+		t.Fatalf("first notification = %+v, want headers.subscribe", msg)
+	}
+	params := msg["params"].([]interface{})
+	if header := params[0].(map[string]interface{}); header["height"] != float64(2) {
+		t.Errorf("notified header = %+v, want height 2", header)
+	}
+
+	tx2 := block2.Transactions[0].Tx.ID.String()
+	status2 := statusOf(fmt.Sprintf("%s:%d:", tx1, 1), fmt.Sprintf("%s:%d:", tx2, 2))
+	msg = readMessage(t, conn)
+	if msg["method"] != "blockchain.scripthash.subscribe" {
+		t.Fatalf("second notification = %+v, want scripthash.subscribe", msg)
+	}
+	params = msg["params"].([]interface{})
+	if len(params) != 2 || params[0] != scripthash || params[1] != status2 {
+		t.Errorf("scripthash notification params = %+v, want [%s %s]", params, scripthash, status2)
+	}
+	// the unsubscribed-from-history script stayed silent: its status is
+	// still null, so only the two notifications above were pushed
+}
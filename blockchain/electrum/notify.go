@@ -0,0 +1,153 @@
+package electrum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+)
+
+// historyPage is how many index entries one explorer query returns
+// while assembling a scripthash history.
+const historyPage = 1000
+
+// HistoryItem is one confirmed transaction in a scripthash history.
+type HistoryItem struct {
+	TxHash string `json:"tx_hash"`
+	Height uint64 `json:"height"`
+}
+
+func (s *Server) tipHeader() (interface{}, error) {
+	height := s.chain.Height()
+	hex, err := s.headerHex(height)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"height": height, "hex": hex}, nil
+}
+
+func (s *Server) headerHex(height uint64) (string, error) {
+	block, err := s.chain.GetBlockByHeight(height)
+	if err != nil {
+		return "", errors.New("block not found")
+	}
+	raw, err := block.BlockHeader.MarshalText()
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (s *Server) scripthashHistory(scripthash string) ([]*HistoryItem, error) {
+	program, err := s.program(scripthash)
+	if err != nil {
+		return nil, err
+	}
+	items := []*HistoryItem{}
+	if program == "" {
+		return items, nil
+	}
+
+	for {
+		page, lastPage := s.explorer.ListAddressTransactions(program, len(items), historyPage)
+		for _, item := range page {
+			items = append(items, &HistoryItem{TxHash: item.TxID, Height: item.BlockHeight})
+		}
+		if lastPage {
+			return items, nil
+		}
+	}
+}
+
+// scripthashStatus is the protocol's digest of a history: the SHA-256
+// of the concatenated "tx_hash:height:" entries, or "" when the script
+// has no history (sent to the client as null).
+func (s *Server) scripthashStatus(scripthash string) (string, error) {
+	history, err := s.scripthashHistory(scripthash)
+	if err != nil {
+		return "", err
+	}
+	if len(history) == 0 {
+		return "", nil
+	}
+
+	h := sha256.New()
+	for _, item := range history {
+		fmt.Fprintf(h, "%s:%d:", item.TxHash, item.Height)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FollowChain watches the main chain tip and pushes headers.subscribe
+// and scripthash.subscribe notifications as it moves. Status changes
+// are detected by recomputing each subscribed status against the
+// explorer index, so notifications also cover reorganizations.
+func (s *Server) FollowChain() {
+	height := s.chain.Height()
+	for {
+		<-s.chain.BlockWaiter(height + 1)
+		height++
+		if _, err := s.chain.GetBlockByHeight(height); err != nil {
+			// the tip moved backwards during a reorg; catch up when the
+			// chain passes this height again
+			height--
+			continue
+		}
+		s.notifyTip(height)
+	}
+}
+
+func (s *Server) notifyTip(height uint64) {
+	headerHex, err := s.headerHex(height)
+	if err != nil {
+		log.WithFields(log.Fields{"height": height, "error": err}).Error("electrum serialize tip header")
+		return
+	}
+	header := map[string]interface{}{"height": height, "hex": headerHex}
+
+	s.mtx.Lock()
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mtx.Unlock()
+
+	for _, c := range clients {
+		c.subMtx.Lock()
+		sendHeaders := c.headers
+		scripthashes := make(map[string]string, len(c.scripthashes))
+		for scripthash, last := range c.scripthashes {
+			scripthashes[scripthash] = last
+		}
+		c.subMtx.Unlock()
+
+		if sendHeaders {
+			c.send(&notification{
+				JSONRPC: "2.0",
+				Method:  "blockchain.headers.subscribe",
+				Params:  []interface{}{header},
+			})
+		}
+		for scripthash, last := range scripthashes {
+			status, err := s.scripthashStatus(scripthash)
+			if err != nil || status == last {
+				continue
+			}
+			c.subMtx.Lock()
+			c.scripthashes[scripthash] = status
+			c.subMtx.Unlock()
+			var result interface{}
+			if status != "" {
+				result = status
+			}
+			c.send(&notification{
+				JSONRPC: "2.0",
+				Method:  "blockchain.scripthash.subscribe",
+				Params:  []interface{}{scripthash, result},
+			})
+		}
+	}
+}
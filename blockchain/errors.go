@@ -48,8 +48,9 @@ func isTemporary(info httperror.Info, err error) bool {
 // TODO(jackson): Share one error table across Chain
 // products/services so that errors are consistent.
 var errorFormatter = httperror.Formatter{
-	Default:     httperror.Info{500, "CH000", "Chain API Error"},
-	IsTemporary: isTemporary,
+	Default:      httperror.Info{500, "CH000", "Chain API Error"},
+	IsTemporary:  isTemporary,
+	Translations: errorTranslations,
 	Errors: map[error]httperror.Info{
 		// General error namespace (0xx)
 		context.DeadlineExceeded: {408, "CH001", "Request timed out"},
@@ -0,0 +1,41 @@
+package blockchain
+
+// errorTranslations are the localized message catalogs, keyed by locale
+// and then by chain error code. English is the default wording on the
+// errorFormatter entries themselves; a code missing from a catalog
+// falls back to it. Locales are negotiated from the request's
+// Accept-Language header.
+var errorTranslations = map[string]map[string]string{
+	"zh-CN": {
+		"CH000": "链 API 错误",
+		"CH001": "请求超时",
+		"CH003": "请求体无效",
+		"CH010": "缺少一个或多个字段",
+		"CH050": "别名已存在",
+		"CH051": "必须提供 ID 或别名中的一个，但不能同时提供",
+		"CH104": "对端节点运行在不同的区块链网络上",
+		"CH105": "请求的区块高度过于超前",
+		"CH200": "签名数量必须大于 1 且不超过公钥数量",
+		"CH201": "xpub 格式无效",
+		"CH202": "至少需要一个 xpub",
+		"CH203": "获取到的类型与期望类型不匹配",
+		"CH204": "根 xpub 列表不能包含重复的公钥",
+		"CH600": "分页参数 `after` 格式错误",
+		"CH601": "过滤器参数数量不正确",
+		"CH602": "查询过滤器格式错误",
+		"CH700": "引用数据与上一笔交易的引用数据不匹配",
+		"CH704": "资产数量无效",
+		"CH705": "不安全的交易：资产可以被无偿取走",
+		"CH706": "一个或多个操作出错：详见附带数据",
+		"CH730": "缺少原始交易",
+		"CH731": "交易模板中的签名指令过多",
+		"CH732": "交易输入索引无效",
+		"CH733": "见证组件无效",
+		"CH735": "交易被拒绝",
+		"CH736": "交易未最终确定，仍允许追加操作",
+		"CH737": "缺少交易签名，客户端可能缺少签名密钥",
+		"CH738": "未尝试交易签名",
+		"CH760": "余额不足",
+		"CH761": "部分输出已被占用，请重试",
+	},
+}
@@ -0,0 +1,179 @@
+package eventsink
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/blockchain/query"
+	"github.com/bytom/blockchain/txfeed"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+var checkpointKey = []byte("sinkCheckpoint")
+
+const (
+	retryBase = time.Second
+	retryMax  = time.Minute
+)
+
+// checkpoint is the last block whose events the bus has accepted. It is
+// persisted only after a successful publish, so a crash replays the
+// block instead of skipping it.
+type checkpoint struct {
+	Height uint64
+	Hash   bc.Hash
+}
+
+// BlockEvent is the value published to the blocks topic, once per block
+// connected to or detached from the main chain.
+type BlockEvent struct {
+	Type      string `json:"type"` // "block_connected" or "block_detached"
+	Height    uint64 `json:"height"`
+	Hash      string `json:"hash"`
+	Timestamp uint64 `json:"timestamp"`
+	TxCount   int    `json:"tx_count"`
+}
+
+// TxEvent is the value published to the transactions topic for every
+// transaction in a connected block.
+type TxEvent struct {
+	Type        string             `json:"type"` // "transaction"
+	BlockHeight uint64             `json:"block_height"`
+	BlockHash   string             `json:"block_hash"`
+	Position    int                `json:"position"`
+	Tx          *query.AnnotatedTx `json:"transaction"`
+}
+
+// Exporter follows the chain and publishes annotated events through a
+// Sink, checkpointing its position in its own database.
+type Exporter struct {
+	DB          dbm.DB
+	sink        Sink
+	topicPrefix string
+	status      checkpoint
+}
+
+// NewExporter returns an exporter over the given database and sink,
+// resuming from the last checkpointed block. The prefix namespaces the
+// topics, e.g. "bytom." gives bytom.blocks and bytom.transactions.
+func NewExporter(db dbm.DB, sink Sink, topicPrefix string) *Exporter {
+	e := &Exporter{DB: db, sink: sink, topicPrefix: topicPrefix}
+	if raw := db.Get(checkpointKey); raw != nil {
+		if err := json.Unmarshal(raw, &e.status); err != nil {
+			log.WithField("err", err).Warn("get event sink checkpoint")
+		}
+	}
+	return e
+}
+
+func (e *Exporter) commitCheckpoint() {
+	raw, err := json.Marshal(e.status)
+	if err != nil {
+		log.WithField("err", err).Error("save event sink checkpoint")
+		return
+	}
+	e.DB.SetSync(checkpointKey, raw)
+}
+
+// Run follows the chain the same way the wallet does: blocks falling
+// off the main chain publish a block_detached event, new main chain
+// blocks publish block_connected plus one event per transaction. The
+// checkpoint only moves after the sink accepted every event of the
+// block, so restarts can duplicate a block's events but never drop one.
+func (e *Exporter) Run(c *protocol.Chain) {
+	var err error
+	var block *legacy.Block
+
+LOOP:
+
+	for !c.InMainChain(e.status.Height, e.status.Hash) {
+		if block, err = c.GetBlockByHash(&e.status.Hash); err != nil {
+			log.WithField("err", err).Error("event sink get block by hash")
+			return
+		}
+
+		e.publishBlockEvent("block_detached", block)
+		log.WithField("height", e.status.Height).Info("event sink rolling back block")
+
+		e.status.Height = block.Height - 1
+		e.status.Hash = block.PreviousBlockHash
+		e.commitCheckpoint()
+	}
+
+	block, _ = c.GetBlockByHeight(e.status.Height + 1)
+	if block == nil {
+		<-c.BlockWaiter(e.status.Height + 1)
+		if block, err = c.GetBlockByHeight(e.status.Height + 1); err != nil {
+			log.WithField("err", err).Error("event sink get block by height")
+			return
+		}
+	}
+
+	if block.PreviousBlockHash == e.status.Hash {
+		e.publishBlockEvent("block_connected", block)
+		blockHash := block.Hash()
+		for i, tx := range block.Transactions {
+			e.publishTxEvent(block, &blockHash, i, tx)
+		}
+
+		e.status.Height = block.Height
+		e.status.Hash = blockHash
+		e.commitCheckpoint()
+	}
+
+	goto LOOP
+}
+
+func (e *Exporter) publishBlockEvent(eventType string, block *legacy.Block) {
+	hash := block.Hash()
+	value, err := json.Marshal(&BlockEvent{
+		Type:      eventType,
+		Height:    block.Height,
+		Hash:      hash.String(),
+		Timestamp: block.TimestampMS,
+		TxCount:   len(block.Transactions),
+	})
+	if err != nil {
+		log.WithField("err", err).Error("marshal block event")
+		return
+	}
+	e.publishWithRetry(e.topicPrefix+"blocks", []byte(hash.String()), value)
+}
+
+func (e *Exporter) publishTxEvent(block *legacy.Block, blockHash *bc.Hash, position int, tx *legacy.Tx) {
+	value, err := json.Marshal(&TxEvent{
+		Type:        "transaction",
+		BlockHeight: block.Height,
+		BlockHash:   blockHash.String(),
+		Position:    position,
+		Tx:          txfeed.BuildAnnotatedTransaction(tx),
+	})
+	if err != nil {
+		log.WithField("err", err).Error("marshal tx event")
+		return
+	}
+	e.publishWithRetry(e.topicPrefix+"transactions", []byte(tx.Tx.ID.String()), value)
+}
+
+// publishWithRetry blocks until the sink accepts the event, backing off
+// between attempts. Giving up would break the at-least-once guarantee,
+// so an unreachable bus stalls the exporter rather than losing events.
+func (e *Exporter) publishWithRetry(topic string, key, value []byte) {
+	backoff := retryBase
+	for {
+		err := e.sink.Publish(topic, key, value)
+		if err == nil {
+			return
+		}
+		log.WithFields(log.Fields{"topic": topic, "error": err}).Warn("event sink publish failed, retrying")
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > retryMax {
+			backoff = retryMax
+		}
+	}
+}
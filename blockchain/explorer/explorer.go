@@ -0,0 +1,105 @@
+// Package explorer maintains chain-wide indexes - address to
+// transactions, asset to transactions and per-address balances - so a
+// public block explorer can run directly off bytomd. Unlike the wallet
+// it indexes every control program on the chain, not only the node's
+// own accounts.
+package explorer
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+var statusKey = []byte("explorerInfo")
+
+// StatusInfo is the last block the indexes cover, kept to detect and
+// roll back orphaned blocks.
+type StatusInfo struct {
+	Height uint64
+	Hash   bc.Hash
+}
+
+// Explorer holds the chain-wide indexes in its own database.
+type Explorer struct {
+	DB     dbm.DB
+	status StatusInfo
+}
+
+// NewExplorer returns an explorer indexer over the given database,
+// resuming from the block it last indexed.
+func NewExplorer(db dbm.DB) *Explorer {
+	e := &Explorer{DB: db}
+	if raw := db.Get(statusKey); raw != nil {
+		if err := json.Unmarshal(raw, &e.status); err != nil {
+			log.WithField("err", err).Warn("get explorer info")
+		}
+	}
+	return e
+}
+
+// Status reports the height and hash the indexes currently cover.
+func (e *Explorer) Status() StatusInfo {
+	return e.status
+}
+
+func (e *Explorer) commitStatus(batch *dbm.Batch) {
+	raw, err := json.Marshal(e.status)
+	if err != nil {
+		log.WithField("err", err).Error("save explorer info")
+		return
+	}
+	(*batch).Set(statusKey, raw)
+	(*batch).Write()
+}
+
+// Update follows the chain: it indexes every new main chain block and
+// reverses blocks that fall off the main chain, the same way the
+// wallet tracks its accounts. Each block commits as one batch with the
+// status marker last, so a crash cannot leave half-indexed state.
+func (e *Explorer) Update(c *protocol.Chain) {
+	var err error
+	var block *legacy.Block
+
+LOOP:
+
+	for !c.InMainChain(e.status.Height, e.status.Hash) {
+		if block, err = c.GetBlockByHash(&e.status.Hash); err != nil {
+			log.WithField("err", err).Error("explorer get block by hash")
+			return
+		}
+
+		batch := e.DB.NewBatch()
+		e.detachBlock(&batch, block)
+		log.WithField("height", e.status.Height).Info("explorer rolling back block")
+
+		e.status.Height = block.Height - 1
+		e.status.Hash = block.PreviousBlockHash
+		e.commitStatus(&batch)
+	}
+
+	block, _ = c.GetBlockByHeight(e.status.Height + 1)
+	if block == nil {
+		<-c.BlockWaiter(e.status.Height + 1)
+		if block, err = c.GetBlockByHeight(e.status.Height + 1); err != nil {
+			log.WithField("err", err).Error("explorer get block by height")
+			return
+		}
+	}
+
+	if block.PreviousBlockHash == e.status.Hash {
+		e.status.Height = block.Height
+		e.status.Hash = block.Hash()
+
+		batch := e.DB.NewBatch()
+		e.attachBlock(&batch, block)
+		e.commitStatus(&batch)
+	}
+
+	goto LOOP
+}
@@ -0,0 +1,276 @@
+package explorer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// Index key layout. Address and asset entries are keyed so that a
+// prefix iteration over one address or asset yields its transactions
+// in chain order; balances are one entry per (asset, program) pair.
+const (
+	addrPrefix    = "EA:" // EA:<program-hex>:<height>:<txpos> -> txid hex
+	assetPrefix   = "ES:" // ES:<asset-hex>:<height>:<txpos>   -> txid hex
+	balancePrefix = "EB:" // EB:<asset-hex>:<program-hex>      -> amount
+	scriptPrefix  = "EH:" // EH:<sha256(program)-hex>          -> program hex
+)
+
+func addrKey(program string, height uint64, pos int) []byte {
+	return []byte(fmt.Sprintf("%s%s:%016x:%08x", addrPrefix, program, height, pos))
+}
+
+func assetKey(asset string, height uint64, pos int) []byte {
+	return []byte(fmt.Sprintf("%s%s:%016x:%08x", assetPrefix, asset, height, pos))
+}
+
+func balanceKey(asset, program string) []byte {
+	return []byte(balancePrefix + asset + ":" + program)
+}
+
+func scriptKey(program string) []byte {
+	raw, _ := hex.DecodeString(program)
+	hash := sha256.Sum256(raw)
+	return []byte(scriptPrefix + hex.EncodeToString(hash[:]))
+}
+
+// txTouches collects what one transaction means for the indexes: the
+// control programs and assets it involves, and the balance deltas it
+// causes. Spend inputs carry their previous output's program and
+// amount, so no UTXO lookups are needed.
+type txTouches struct {
+	programs map[string]bool
+	assets   map[string]bool
+	// balance delta per "asset:program", outputs positive
+	deltas map[string]int64
+}
+
+func touch(tx *legacy.Tx) *txTouches {
+	t := &txTouches{
+		programs: make(map[string]bool),
+		assets:   make(map[string]bool),
+		deltas:   make(map[string]int64),
+	}
+	for _, input := range tx.Inputs {
+		asset := input.AssetID()
+		t.assets[asset.String()] = true
+		if program := input.ControlProgram(); program != nil {
+			programHex := hex.EncodeToString(program)
+			t.programs[programHex] = true
+			t.deltas[asset.String()+":"+programHex] -= int64(input.Amount())
+		}
+	}
+	for _, output := range tx.Outputs {
+		t.assets[output.AssetId.String()] = true
+		programHex := hex.EncodeToString(output.ControlProgram)
+		t.programs[programHex] = true
+		t.deltas[output.AssetId.String()+":"+programHex] += int64(output.Amount)
+	}
+	return t
+}
+
+func (e *Explorer) getBalance(asset, program string) uint64 {
+	raw := e.DB.Get(balanceKey(asset, program))
+	if raw == nil {
+		return 0
+	}
+	balance, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return balance
+}
+
+// applyDelta adjusts one (asset, program) balance. Zero balances are
+// deleted so the rich list never reports emptied addresses.
+func (e *Explorer) applyDelta(batch *dbm.Batch, key string, delta int64) {
+	parts := strings.SplitN(key, ":", 2)
+	balance := int64(e.getBalance(parts[0], parts[1])) + delta
+	if balance <= 0 {
+		(*batch).Delete(balanceKey(parts[0], parts[1]))
+		return
+	}
+	(*batch).Set(balanceKey(parts[0], parts[1]), []byte(strconv.FormatInt(balance, 10)))
+}
+
+func (e *Explorer) attachBlock(batch *dbm.Batch, block *legacy.Block) {
+	// balance deltas accumulate over the whole block before being
+	// applied: the underlying reads don't see the open batch, so two
+	// writes to one balance within a block would lose the first
+	blockDeltas := make(map[string]int64)
+	for pos, tx := range block.Transactions {
+		txID := []byte(tx.Tx.ID.String())
+		t := touch(tx)
+		for program := range t.programs {
+			(*batch).Set(addrKey(program, block.Height, pos), txID)
+			// script-hash lookup entries are pure mappings, so they are
+			// never deleted on detach
+			(*batch).Set(scriptKey(program), []byte(program))
+		}
+		for asset := range t.assets {
+			(*batch).Set(assetKey(asset, block.Height, pos), txID)
+		}
+		for key, delta := range t.deltas {
+			blockDeltas[key] += delta
+		}
+	}
+	for key, delta := range blockDeltas {
+		e.applyDelta(batch, key, delta)
+	}
+}
+
+func (e *Explorer) detachBlock(batch *dbm.Batch, block *legacy.Block) {
+	blockDeltas := make(map[string]int64)
+	for pos, tx := range block.Transactions {
+		t := touch(tx)
+		for program := range t.programs {
+			(*batch).Delete(addrKey(program, block.Height, pos))
+		}
+		for asset := range t.assets {
+			(*batch).Delete(assetKey(asset, block.Height, pos))
+		}
+		for key, delta := range t.deltas {
+			blockDeltas[key] -= delta
+		}
+	}
+	for key, delta := range blockDeltas {
+		e.applyDelta(batch, key, delta)
+	}
+}
+
+// TxItem is one indexed transaction reference.
+type TxItem struct {
+	TxID        string `json:"tx_id"`
+	BlockHeight uint64 `json:"block_height"`
+}
+
+// listIndexed pages through one address or asset prefix in chain
+// order. The offset-based cursor matches the repo's other list APIs:
+// callers pass the count of items already seen.
+func (e *Explorer) listIndexed(prefix string, from, count int) ([]*TxItem, bool) {
+	items := []*TxItem{}
+	seen := 0
+	lastPage := true
+
+	iter := e.DB.IteratorPrefix([]byte(prefix))
+	defer iter.Release()
+	for iter.Next() {
+		if seen < from {
+			seen++
+			continue
+		}
+		if len(items) >= count {
+			lastPage = false
+			break
+		}
+		// key tail is <height>:<txpos>
+		key := string(iter.Key())
+		var height uint64
+		if i := strings.LastIndex(key, ":"); i >= 17 {
+			height, _ = strconv.ParseUint(key[i-16:i], 16, 64)
+		}
+		items = append(items, &TxItem{TxID: string(iter.Value()), BlockHeight: height})
+	}
+	return items, lastPage
+}
+
+// ListAddressTransactions pages through the transactions touching the
+// given control program (hex), oldest first.
+func (e *Explorer) ListAddressTransactions(program string, from, count int) ([]*TxItem, bool) {
+	return e.listIndexed(addrPrefix+program+":", from, count)
+}
+
+// ListAssetTransactions pages through the transactions moving the
+// given asset, oldest first.
+func (e *Explorer) ListAssetTransactions(asset string, from, count int) ([]*TxItem, bool) {
+	return e.listIndexed(assetPrefix+asset+":", from, count)
+}
+
+// ProgramByScriptHash resolves the SHA-256 hash of a control program
+// back to the program, for protocols that address scripts by hash. It
+// only knows programs that have appeared on the chain.
+func (e *Explorer) ProgramByScriptHash(hash string) (string, bool) {
+	raw := e.DB.Get([]byte(scriptPrefix + hash))
+	if raw == nil {
+		return "", false
+	}
+	return string(raw), true
+}
+
+// AssetBalance is one asset position of a control program.
+type AssetBalance struct {
+	Asset   string `json:"asset"`
+	Balance uint64 `json:"balance"`
+}
+
+// Balances returns every non-zero asset balance of the given control
+// program (hex). Balance entries are keyed asset-first, so this scans
+// the whole balance bucket; like RichList it is meant for the explorer
+// use case, not a hot path.
+func (e *Explorer) Balances(program string) []*AssetBalance {
+	items := []*AssetBalance{}
+	suffix := ":" + program
+
+	iter := e.DB.IteratorPrefix([]byte(balancePrefix))
+	defer iter.Release()
+	for iter.Next() {
+		key := string(iter.Key())
+		if !strings.HasSuffix(key, suffix) {
+			continue
+		}
+		balance, err := strconv.ParseUint(string(iter.Value()), 10, 64)
+		if err != nil {
+			continue
+		}
+		items = append(items, &AssetBalance{
+			Asset:   key[len(balancePrefix) : len(key)-len(suffix)],
+			Balance: balance,
+		})
+	}
+	return items
+}
+
+// BalanceItem is one rich-list entry.
+type BalanceItem struct {
+	Program string `json:"program"`
+	Balance uint64 `json:"balance"`
+}
+
+// RichList returns the top holders of the given asset by balance. It
+// scans every balance entry of the asset, so it is meant for the
+// explorer use case, not a hot path.
+func (e *Explorer) RichList(asset string, count int) []*BalanceItem {
+	items := []*BalanceItem{}
+	prefix := balancePrefix + asset + ":"
+
+	iter := e.DB.IteratorPrefix([]byte(prefix))
+	defer iter.Release()
+	for iter.Next() {
+		balance, err := strconv.ParseUint(string(iter.Value()), 10, 64)
+		if err != nil {
+			continue
+		}
+		items = append(items, &BalanceItem{
+			Program: string(iter.Key()[len(prefix):]),
+			Balance: balance,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Balance != items[j].Balance {
+			return items[i].Balance > items[j].Balance
+		}
+		return items[i].Program < items[j].Program
+	})
+	if len(items) > count {
+		items = items[:count]
+	}
+	return items
+}
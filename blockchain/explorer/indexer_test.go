@@ -0,0 +1,175 @@
+package explorer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+var (
+	programA = []byte{0x51}
+	programB = []byte{0x52}
+)
+
+// issueTx pays the given amount of BTM into program; distinct nonces
+// keep transaction ids distinct.
+func issueTx(nonce byte, amount uint64, program []byte) *legacy.Tx {
+	return legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewIssuanceInput([]byte{nonce}, amount, nil, bc.Hash{V0: 9}, []byte{0x51}, nil, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*consensus.BTMAssetID, amount, program, nil),
+		},
+	})
+}
+
+func attach(e *Explorer, block *legacy.Block) {
+	batch := e.DB.NewBatch()
+	e.attachBlock(&batch, block)
+	batch.Write()
+}
+
+func detach(e *Explorer, block *legacy.Block) {
+	batch := e.DB.NewBatch()
+	e.detachBlock(&batch, block)
+	batch.Write()
+}
+
+func balanceOf(e *Explorer, program []byte) uint64 {
+	return e.getBalance(consensus.BTMAssetID.String(), hex.EncodeToString(program))
+}
+
+func TestAttachDetachBlock(t *testing.T) {
+	e := NewExplorer(dbm.NewMemDB())
+	hexA := hex.EncodeToString(programA)
+	hexB := hex.EncodeToString(programB)
+
+	// block 1 funds A, block 2 spends A's whole output across B and A
+	block1 := &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 1},
+		Transactions: []*legacy.Tx{issueTx(1, 100, programA)},
+	}
+	block2 := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{Height: 2},
+		Transactions: []*legacy.Tx{
+			legacy.NewTx(legacy.TxData{
+				Version: 1,
+				Inputs: []*legacy.TxInput{
+					legacy.NewSpendInput(nil, bc.Hash{V0: 1}, *consensus.BTMAssetID, 100, 0, programA, bc.Hash{}, nil),
+				},
+				Outputs: []*legacy.TxOutput{
+					legacy.NewTxOutput(*consensus.BTMAssetID, 60, programB, nil),
+					legacy.NewTxOutput(*consensus.BTMAssetID, 40, programA, nil),
+				},
+			}),
+		},
+	}
+	attach(e, block1)
+	attach(e, block2)
+
+	if a, b := balanceOf(e, programA), balanceOf(e, programB); a != 40 || b != 60 {
+		t.Errorf("balances after attach: A=%d B=%d, want 40 and 60", a, b)
+	}
+	items, lastPage := e.ListAddressTransactions(hexA, 0, 10)
+	if !lastPage || len(items) != 2 || items[0].BlockHeight != 1 || items[1].BlockHeight != 2 {
+		t.Errorf("A history = %+v last %v, want both blocks in order", items, lastPage)
+	}
+	if items, _ = e.ListAddressTransactions(hexB, 0, 10); len(items) != 1 || items[0].TxID != block2.Transactions[0].Tx.ID.String() {
+		t.Errorf("B history = %+v, want the spend transaction only", items)
+	}
+	if items, _ = e.ListAssetTransactions(consensus.BTMAssetID.String(), 0, 10); len(items) != 2 {
+		t.Errorf("BTM history = %+v, want 2 transactions", items)
+	}
+
+	rich := e.RichList(consensus.BTMAssetID.String(), 10)
+	if len(rich) != 2 || rich[0].Program != hexB || rich[0].Balance != 60 || rich[1].Program != hexA {
+		t.Errorf("rich list = %+v, want B=60 above A=40", rich)
+	}
+	if rich = e.RichList(consensus.BTMAssetID.String(), 1); len(rich) != 1 || rich[0].Program != hexB {
+		t.Errorf("truncated rich list = %+v, want only B", rich)
+	}
+	if balances := e.Balances(hexA); len(balances) != 1 || balances[0].Asset != consensus.BTMAssetID.String() || balances[0].Balance != 40 {
+		t.Errorf("A balances = %+v", balances)
+	}
+
+	hashB := sha256.Sum256(programB)
+	if program, ok := e.ProgramByScriptHash(hex.EncodeToString(hashB[:])); !ok || program != hexB {
+		t.Errorf("script hash lookup = %q %v", program, ok)
+	}
+
+	// a reorg detaches block 2: A's balance is restored, B's entries go
+	detach(e, block2)
+	if a, b := balanceOf(e, programA), balanceOf(e, programB); a != 100 || b != 0 {
+		t.Errorf("balances after detach: A=%d B=%d, want 100 and 0", a, b)
+	}
+	if items, _ = e.ListAddressTransactions(hexA, 0, 10); len(items) != 1 || items[0].BlockHeight != 1 {
+		t.Errorf("A history after detach = %+v, want block 1 only", items)
+	}
+	if items, _ = e.ListAddressTransactions(hexB, 0, 10); len(items) != 0 {
+		t.Errorf("B history after detach = %+v, want none", items)
+	}
+	if balances := e.Balances(hexB); len(balances) != 0 {
+		t.Errorf("B balances after detach = %+v, want none", balances)
+	}
+	// script-hash mappings deliberately survive the detach
+	if _, ok := e.ProgramByScriptHash(hex.EncodeToString(hashB[:])); !ok {
+		t.Error("script hash mapping lost on detach")
+	}
+}
+
+func TestListPaginationCursor(t *testing.T) {
+	e := NewExplorer(dbm.NewMemDB())
+	txs := []*legacy.Tx{}
+	for nonce := byte(1); nonce <= 5; nonce++ {
+		txs = append(txs, issueTx(nonce, 10, programA))
+	}
+	attach(e, &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 3},
+		Transactions: txs,
+	})
+
+	// the cursor is the count of items already seen, like the other
+	// list APIs; pages keep transaction-position order
+	hexA := hex.EncodeToString(programA)
+	seen := []string{}
+	for from := 0; ; {
+		items, lastPage := e.ListAddressTransactions(hexA, from, 2)
+		for _, item := range items {
+			if item.BlockHeight != 3 {
+				t.Errorf("item height = %d, want 3", item.BlockHeight)
+			}
+			seen = append(seen, item.TxID)
+		}
+		from += len(items)
+		if lastPage {
+			if len(items) != 1 {
+				t.Errorf("last page held %d items, want 1", len(items))
+			}
+			break
+		}
+		if len(items) != 2 {
+			t.Fatalf("full page held %d items, want 2", len(items))
+		}
+	}
+	if len(seen) != 5 {
+		t.Fatalf("paged through %d items, want 5", len(seen))
+	}
+	for i, txID := range seen {
+		if txID != txs[i].Tx.ID.String() {
+			t.Errorf("page item %d = %s, want %s", i, txID, txs[i].Tx.ID.String())
+		}
+	}
+
+	// a cursor past the end is an empty last page
+	if items, lastPage := e.ListAddressTransactions(hexA, 10, 2); len(items) != 0 || !lastPage {
+		t.Errorf("overrun page = %+v last %v, want empty last page", items, lastPage)
+	}
+}
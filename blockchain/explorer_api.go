@@ -0,0 +1,81 @@
+package blockchain
+
+import (
+	"github.com/bytom/blockchain/explorer"
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+)
+
+// defaultExplorerPageSize caps one page of explorer results.
+const defaultExplorerPageSize = 50
+
+func (bcr *BlockchainReactor) requireExplorer() error {
+	if bcr.explorer == nil {
+		return errors.New("explorer is not enabled on this node")
+	}
+	return nil
+}
+
+func explorerPageSize(count int) int {
+	if count <= 0 || count > defaultExplorerPageSize {
+		return defaultExplorerPageSize
+	}
+	return count
+}
+
+// ExplorerTxPage is one page of indexed transaction references.
+type ExplorerTxPage struct {
+	Items    []*explorer.TxItem `json:"items"`
+	From     int                `json:"from"`
+	LastPage bool               `json:"last_page"`
+}
+
+// listAddressTransactions handles /explorer/list-address-transactions:
+// every transaction touching the given control program (hex), chain
+// order, offset-paginated.
+func (bcr *BlockchainReactor) listAddressTransactions(in struct {
+	Program string `json:"program"`
+	From    int    `json:"from"`
+	Count   int    `json:"count"`
+}) (*ExplorerTxPage, error) {
+	if err := bcr.requireExplorer(); err != nil {
+		return nil, err
+	}
+	if in.Program == "" {
+		return nil, errors.WithDetail(errBadAction, "program is required")
+	}
+	items, lastPage := bcr.explorer.ListAddressTransactions(in.Program, in.From, explorerPageSize(in.Count))
+	return &ExplorerTxPage{Items: items, From: in.From + len(items), LastPage: lastPage}, nil
+}
+
+// listAssetTransactions handles /explorer/list-asset-transactions.
+func (bcr *BlockchainReactor) listAssetTransactions(in struct {
+	AssetID string `json:"asset_id"`
+	From    int    `json:"from"`
+	Count   int    `json:"count"`
+}) (*ExplorerTxPage, error) {
+	if err := bcr.requireExplorer(); err != nil {
+		return nil, err
+	}
+	if in.AssetID == "" {
+		return nil, errors.WithDetail(errBadAction, "asset_id is required")
+	}
+	items, lastPage := bcr.explorer.ListAssetTransactions(in.AssetID, in.From, explorerPageSize(in.Count))
+	return &ExplorerTxPage{Items: items, From: in.From + len(items), LastPage: lastPage}, nil
+}
+
+// getRichList handles /explorer/rich-list: the top holders of an asset
+// by indexed balance. The asset defaults to BTM.
+func (bcr *BlockchainReactor) getRichList(in struct {
+	AssetID string `json:"asset_id"`
+	Count   int    `json:"count"`
+}) (interface{}, error) {
+	if err := bcr.requireExplorer(); err != nil {
+		return nil, err
+	}
+	asset := in.AssetID
+	if asset == "" {
+		asset = consensus.BTMAssetID.String()
+	}
+	return bcr.explorer.RichList(asset, explorerPageSize(in.Count)), nil
+}
@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+)
+
+// faucetInterval is how long an address must wait between payouts.
+const faucetInterval = 24 * time.Hour
+
+// faucet pays a fixed amount of BTM from one configured account to
+// whoever asks, at most once per address per day. It only exists on
+// test networks.
+type faucet struct {
+	accountID string
+	password  string
+	amount    uint64
+	fee       uint64
+
+	mu   sync.Mutex
+	last map[string]time.Time // address -> time of its last payout
+}
+
+// RegisterFaucet enables the faucet endpoint, paying amount base units
+// per request from the given account. The node refuses to register it
+// on the main network.
+func (bcR *BlockchainReactor) RegisterFaucet(accountID, password string, amount, fee uint64) {
+	bcR.faucet = &faucet{
+		accountID: accountID,
+		password:  password,
+		amount:    amount,
+		fee:       fee,
+		last:      make(map[string]time.Time),
+	}
+}
+
+// POST /faucet/request
+func (a *BlockchainReactor) faucetRequest(ctx context.Context, in struct {
+	Address string `json:"address"`
+}) (interface{}, error) {
+	f := a.faucet
+	if f == nil {
+		return nil, errors.New("faucet is not enabled on this node")
+	}
+	if in.Address == "" {
+		return nil, errors.WithDetail(errBadAction, "address is required")
+	}
+
+	f.mu.Lock()
+	if paid, ok := f.last[in.Address]; ok && time.Since(paid) < faucetInterval {
+		wait := faucetInterval - time.Since(paid)
+		f.mu.Unlock()
+		return nil, errors.WithDetailf(errors.New("faucet limit reached"), "address %s was already paid; try again in %s", in.Address, wait.Truncate(time.Second))
+	}
+	// record before paying so concurrent requests for one address
+	// cannot race past the cap; undone if the payout fails
+	f.last[in.Address] = time.Now()
+	f.mu.Unlock()
+
+	resp, err := a.send(ctx, sendIn{
+		AccountID: f.accountID,
+		AssetID:   consensus.BTMAssetID.String(),
+		Amount:    f.amount,
+		Address:   in.Address,
+		Fee:       f.fee,
+		Password:  f.password,
+	})
+	if err != nil {
+		f.mu.Lock()
+		delete(f.last, in.Address)
+		f.mu.Unlock()
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{"address": in.Address, "amount": f.amount}).Info("Faucet payout")
+	return resp, nil
+}
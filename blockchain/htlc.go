@@ -0,0 +1,275 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/consensus"
+	"github.com/bytom/crypto/ed25519"
+	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+// swap records live in their own database, keyed by the contract
+// program so both parties derive the same key independently
+const swapPrefix = "SW:"
+
+// Swap is one tracked hashed-timelock contract.
+type Swap struct {
+	Program         string `json:"program"`
+	SenderPubkey    string `json:"sender_pubkey"`
+	RecipientPubkey string `json:"recipient_pubkey"`
+	Hash            string `json:"hash"`
+	TimeoutHeight   uint64 `json:"timeout_height"`
+	Status          string `json:"status"` // "pending", "redeemed" or "refunded"
+	// Preimage is recorded when the swap is redeemed through this node;
+	// the counterparty on the other chain needs it to claim their side.
+	Preimage string    `json:"preimage,omitempty"`
+	SpendTx  string    `json:"spend_tx,omitempty"`
+	Created  time.Time `json:"created_at"`
+}
+
+func (bcr *BlockchainReactor) saveSwap(swap *Swap) {
+	raw, err := json.Marshal(swap)
+	if err != nil {
+		log.WithField("err", err).Error("marshal swap record")
+		return
+	}
+	bcr.swapDB.SetSync([]byte(swapPrefix+swap.Program), raw)
+}
+
+func (bcr *BlockchainReactor) updateSwap(program, status, preimage, spendTx string) {
+	raw := bcr.swapDB.Get([]byte(swapPrefix + program))
+	if raw == nil {
+		return
+	}
+	swap := &Swap{}
+	if err := json.Unmarshal(raw, swap); err != nil {
+		return
+	}
+	swap.Status = status
+	swap.Preimage = preimage
+	swap.SpendTx = spendTx
+	bcr.saveSwap(swap)
+}
+
+// POST /create-htlc-program
+// Builds the contract program, records the swap as pending, and returns
+// the program for use in a control_program or control_htlc action.
+func (bcr *BlockchainReactor) createHTLCProgram(in struct {
+	SenderPubkey    string `json:"sender_pubkey"`
+	RecipientPubkey string `json:"recipient_pubkey"`
+	Hash            string `json:"hash"`
+	TimeoutHeight   uint64 `json:"timeout_height"`
+}) (*Swap, error) {
+	senderPubkey, err := hex.DecodeString(in.SenderPubkey)
+	if err != nil || len(senderPubkey) != ed25519.PublicKeySize {
+		return nil, errors.WithDetail(errBadAction, "sender_pubkey must be a hex 32-byte ed25519 key")
+	}
+	recipientPubkey, err := hex.DecodeString(in.RecipientPubkey)
+	if err != nil || len(recipientPubkey) != ed25519.PublicKeySize {
+		return nil, errors.WithDetail(errBadAction, "recipient_pubkey must be a hex 32-byte ed25519 key")
+	}
+	hash, err := hex.DecodeString(in.Hash)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, errors.WithDetail(errBadAction, "hash must be a hex 32-byte sha256 hash")
+	}
+
+	params := vmutil.HTLCParams{
+		SenderPubkey:    ed25519.PublicKey(senderPubkey),
+		RecipientPubkey: ed25519.PublicKey(recipientPubkey),
+		TimeoutHeight:   in.TimeoutHeight,
+	}
+	copy(params.Hash[:], hash)
+	program, err := vmutil.P2HTLCProgram(params)
+	if err != nil {
+		return nil, err
+	}
+
+	swap := &Swap{
+		Program:         hex.EncodeToString(program),
+		SenderPubkey:    in.SenderPubkey,
+		RecipientPubkey: in.RecipientPubkey,
+		Hash:            in.Hash,
+		TimeoutHeight:   in.TimeoutHeight,
+		Status:          "pending",
+		Created:         time.Now(),
+	}
+	bcr.saveSwap(swap)
+	return swap, nil
+}
+
+// POST /list-htlc-swaps
+func (bcr *BlockchainReactor) listHTLCSwaps() ([]*Swap, error) {
+	swaps := []*Swap{}
+	iter := bcr.swapDB.IteratorPrefix([]byte(swapPrefix))
+	defer iter.Release()
+	for iter.Next() {
+		swap := &Swap{}
+		if err := json.Unmarshal(iter.Value(), swap); err != nil {
+			return nil, err
+		}
+		swaps = append(swaps, swap)
+	}
+	return swaps, nil
+}
+
+// htlcSpendTarget locates the contract output on the main chain and
+// recovers its spend-input fields and contract terms.
+func (bcr *BlockchainReactor) htlcSpendTarget(txID string, outputIndex int) (*legacy.TxInput, *bc.AssetAmount, *vmutil.HTLCParams, error) {
+	var hash bc.Hash
+	if err := hash.UnmarshalText([]byte(txID)); err != nil {
+		return nil, nil, nil, errors.WithDetail(errBadAction, "tx_id is not a valid hash")
+	}
+	block, position, _, err := bcr.findTxInMainchain(hash)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tx := block.Transactions[position]
+	if outputIndex < 0 || outputIndex >= len(tx.Outputs) {
+		return nil, nil, nil, errors.WithDetail(errBadAction, "output_index out of range")
+	}
+	output := tx.Outputs[outputIndex]
+	params, err := vmutil.ParseP2HTLCProgram(output.ControlProgram)
+	if err != nil {
+		return nil, nil, nil, errors.WithDetail(err, "output is not an htlc contract")
+	}
+
+	entry, ok := tx.Tx.Entries[*tx.OutputID(outputIndex)].(*bc.Output)
+	if !ok {
+		return nil, nil, nil, errors.New("output entry not found")
+	}
+	input := legacy.NewSpendInput(nil, *entry.Source.Ref, *output.AssetId, output.Amount, entry.Source.Position, output.ControlProgram, *entry.Data, nil)
+	return input, &output.AssetAmount, params, nil
+}
+
+// buildHTLCSpend assembles, signs and submits the transaction spending
+// the contract output to the destination program with the given witness
+// clause.
+func (bcr *BlockchainReactor) buildHTLCSpend(ctx context.Context, input *legacy.TxInput, value *bc.AssetAmount, destination string, fee uint64, xpub chainkd.XPub, password string, clauseArgs func(sig []byte) [][]byte) (string, error) {
+	destProgram, err := hex.DecodeString(destination)
+	if err != nil || len(destProgram) == 0 {
+		return "", errors.WithDetail(errBadAction, "control_program must be the hex destination program")
+	}
+	if fee >= value.Amount {
+		return "", errors.WithDetail(errBadAction, "fee exceeds the contract amount")
+	}
+	if fee > 0 && *value.AssetId != *consensus.BTMAssetID {
+		return "", errors.WithDetail(errBadAction, "fees are paid in BTM; a non-BTM contract must use fee 0")
+	}
+
+	data := legacy.TxData{
+		Version: legacy.CurrentTransactionVersion,
+		Inputs:  []*legacy.TxInput{input},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*value.AssetId, value.Amount-fee, destProgram, nil),
+		},
+	}
+	tx := legacy.NewTx(data)
+
+	sigHash := tx.SigHash(0)
+	sig, err := bcr.hsm.XSign(xpub, nil, sigHash.Bytes(), password)
+	if err != nil {
+		return "", err
+	}
+	tx.SetInputArguments(0, clauseArgs(sig))
+
+	if err := txbuilder.FinalizeTx(ctx, bcr.chain, tx); err != nil {
+		return "", err
+	}
+	return tx.Tx.ID.String(), nil
+}
+
+// POST /redeem-htlc
+// Spends the contract output with the preimage; the signing key must be
+// the contract's recipient key.
+func (bcr *BlockchainReactor) redeemHTLC(ctx context.Context, in struct {
+	TxID           string       `json:"tx_id"`
+	OutputIndex    int          `json:"output_index"`
+	Preimage       string       `json:"preimage"`
+	ControlProgram string       `json:"control_program"`
+	Fee            uint64       `json:"fee"`
+	XPub           chainkd.XPub `json:"xpub"`
+	Password       string       `json:"password"`
+}) (*struct {
+	TxID string `json:"tx_id"`
+}, error) {
+	preimage, err := hex.DecodeString(in.Preimage)
+	if err != nil {
+		return nil, errors.WithDetail(errBadAction, "preimage must be hex")
+	}
+
+	input, value, params, err := bcr.htlcSpendTarget(in.TxID, in.OutputIndex)
+	if err != nil {
+		return nil, err
+	}
+	if sha256.Sum256(preimage) != params.Hash {
+		return nil, errors.WithDetail(errBadAction, "preimage does not hash to the contract hash")
+	}
+	if !bytes.Equal(in.XPub.PublicKey(), params.RecipientPubkey) {
+		return nil, errors.WithDetail(errBadAction, "xpub is not the contract recipient key")
+	}
+
+	spendTx, err := bcr.buildHTLCSpend(ctx, input, value, in.ControlProgram, in.Fee, in.XPub, in.Password, func(sig []byte) [][]byte {
+		return [][]byte{sig, preimage, {1}}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	program := hex.EncodeToString(input.ControlProgram())
+	bcr.updateSwap(program, "redeemed", in.Preimage, spendTx)
+	return &struct {
+		TxID string `json:"tx_id"`
+	}{TxID: spendTx}, nil
+}
+
+// POST /refund-htlc
+// Spends the contract output back to the sender after the timeout; the
+// signing key must be the contract's sender key.
+func (bcr *BlockchainReactor) refundHTLC(ctx context.Context, in struct {
+	TxID           string       `json:"tx_id"`
+	OutputIndex    int          `json:"output_index"`
+	ControlProgram string       `json:"control_program"`
+	Fee            uint64       `json:"fee"`
+	XPub           chainkd.XPub `json:"xpub"`
+	Password       string       `json:"password"`
+}) (*struct {
+	TxID string `json:"tx_id"`
+}, error) {
+	input, value, params, err := bcr.htlcSpendTarget(in.TxID, in.OutputIndex)
+	if err != nil {
+		return nil, err
+	}
+	if bcr.chain.Height() < params.TimeoutHeight {
+		return nil, errors.WithDetailf(errBadAction, "contract is locked until height %d", params.TimeoutHeight)
+	}
+	if !bytes.Equal(in.XPub.PublicKey(), params.SenderPubkey) {
+		return nil, errors.WithDetail(errBadAction, "xpub is not the contract sender key")
+	}
+
+	spendTx, err := bcr.buildHTLCSpend(ctx, input, value, in.ControlProgram, in.Fee, in.XPub, in.Password, func(sig []byte) [][]byte {
+		// an empty final argument selects the refund clause
+		return [][]byte{sig, {}}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	program := hex.EncodeToString(input.ControlProgram())
+	bcr.updateSwap(program, "refunded", "", spendTx)
+	return &struct {
+		TxID string `json:"tx_id"`
+	}{TxID: spendTx}, nil
+}
@@ -0,0 +1,58 @@
+package blockchain
+
+import (
+	"time"
+
+	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/errors"
+	"github.com/bytom/payment"
+)
+
+// POST /create-invoice
+// The request is signed with the given HSM key, so the payer can later
+// authenticate the recipient with /verify-invoice.
+func (bcr *BlockchainReactor) createInvoice(in struct {
+	XPub      chainkd.XPub `json:"xpub"`
+	Password  string       `json:"password"`
+	Program   string       `json:"program"`
+	AssetID   string       `json:"asset_id"`
+	Amount    uint64       `json:"amount"`
+	Memo      string       `json:"memo"`
+	ExpiresIn uint64       `json:"expires_in"`
+}) (*payment.Invoice, error) {
+	if in.Program == "" {
+		return nil, errors.WithDetail(errBadAction, "program is required")
+	}
+
+	request := &payment.Request{
+		Program: in.Program,
+		AssetID: in.AssetID,
+		Amount:  in.Amount,
+		Memo:    in.Memo,
+	}
+	if in.ExpiresIn > 0 {
+		request.Expires = time.Now().Add(time.Duration(in.ExpiresIn) * time.Second)
+	}
+
+	return payment.NewInvoice(request, in.XPub, func(msg []byte) ([]byte, error) {
+		return bcr.hsm.XSign(in.XPub, nil, msg, in.Password)
+	})
+}
+
+// InvoiceResp is the verified request plus its expiry state.
+type InvoiceResp struct {
+	*payment.Request
+	Expired bool `json:"expired"`
+}
+
+// POST /verify-invoice
+// A bad signature is an error, not a false flag in the response, so a
+// caller that forgets to check cannot mistake a forged invoice for a
+// verified one.
+func (bcr *BlockchainReactor) verifyInvoice(in payment.Invoice) (*InvoiceResp, error) {
+	request, err := in.Verify()
+	if err != nil {
+		return nil, err
+	}
+	return &InvoiceResp{Request: request, Expired: request.Expired()}, nil
+}
@@ -0,0 +1,22 @@
+package blockchain
+
+import (
+	"github.com/bytom/logging"
+)
+
+// setLogLevel changes the log level of one module, or the default level
+// when no module is given. Called with no level at all it only reports
+// the current levels. Changes take effect immediately and are not
+// persisted across restarts.
+func (bcr *BlockchainReactor) setLogLevel(in struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}) (map[string]string, error) {
+	if in.Level == "" {
+		return logging.Levels(), nil
+	}
+	if err := logging.SetLevel(in.Module, in.Level); err != nil {
+		return nil, err
+	}
+	return logging.Levels(), nil
+}
@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// TxMerkleProof carries everything an external verifier needs to check
+// that a transaction is part of a block: the decoded header it should
+// already trust and the merkle path to the header's transactions root.
+type TxMerkleProof struct {
+	TxID        string           `json:"tx_id"`
+	BlockHeight uint64           `json:"block_height"`
+	BlockHash   string           `json:"block_hash"`
+	TxIndex     int              `json:"tx_index"`
+	Header      *BlockHeaderJSON `json:"header"`
+	MerkleProof []*MerkleStep    `json:"merkle_proof"`
+}
+
+// findTxInMainchain scans the main chain from the tip backwards for
+// the block containing the given transaction and builds its merkle
+// path. The node has no transaction index, hence the scan.
+func (bcr *BlockchainReactor) findTxInMainchain(txID bc.Hash) (*legacy.Block, int, []bc.MerkleProofStep, error) {
+	prunedHeight := bcr.chain.PrunedHeight()
+	for height := bcr.chain.Height(); height > prunedHeight && height >= 1; height-- {
+		block, err := bcr.chain.GetBlockByHeight(height)
+		if err != nil {
+			return nil, 0, nil, errors.Wrapf(err, "getting block at height %d", height)
+		}
+
+		for i, tx := range block.Transactions {
+			if tx.Tx.ID != txID {
+				continue
+			}
+			bcBlock := legacy.MapBlock(block)
+			proof, err := bc.MerkleProof(bcBlock.Transactions, i)
+			if err != nil {
+				return nil, 0, nil, errors.Wrap(err, "building merkle proof")
+			}
+			return block, i, proof, nil
+		}
+	}
+	if prunedHeight > 0 {
+		return nil, 0, nil, bcr.errPruned()
+	}
+	return nil, 0, nil, errors.New("transaction not found in main chain")
+}
+
+// getMerkleProof locates the main chain block containing the given
+// transaction and returns its header with the merkle path proving
+// inclusion.
+func (bcr *BlockchainReactor) getMerkleProof(in struct {
+	TxID string `json:"tx_id"`
+}) (*TxMerkleProof, error) {
+	var txID bc.Hash
+	if err := txID.UnmarshalText([]byte(in.TxID)); err != nil {
+		return nil, errors.Wrap(err, "decoding transaction ID")
+	}
+
+	block, index, proof, err := bcr.findTxInMainchain(txID)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]*MerkleStep, 0, len(proof))
+	for _, step := range proof {
+		steps = append(steps, &MerkleStep{Hash: step.Hash.String(), Left: step.Left})
+	}
+
+	blockHash := block.Hash()
+	return &TxMerkleProof{
+		TxID:        txID.String(),
+		BlockHeight: block.Height,
+		BlockHash:   blockHash.String(),
+		TxIndex:     index,
+		Header:      decodeHeader(&block.BlockHeader),
+		MerkleProof: steps,
+	}, nil
+}
+
+// verifyMerkleProof checks a merkle path against the given transactions
+// root. It is pure computation; callers must already trust the root,
+// typically because it comes from a header they verified.
+func (bcr *BlockchainReactor) verifyMerkleProof(in struct {
+	TxID        string        `json:"tx_id"`
+	MerkleRoot  string        `json:"merkle_root"`
+	MerkleProof []*MerkleStep `json:"merkle_proof"`
+}) (bool, error) {
+	var txID, root bc.Hash
+	if err := txID.UnmarshalText([]byte(in.TxID)); err != nil {
+		return false, errors.Wrap(err, "decoding transaction ID")
+	}
+	if err := root.UnmarshalText([]byte(in.MerkleRoot)); err != nil {
+		return false, errors.Wrap(err, "decoding merkle root")
+	}
+
+	proof := make([]bc.MerkleProofStep, 0, len(in.MerkleProof))
+	for i, step := range in.MerkleProof {
+		var hash bc.Hash
+		if err := hash.UnmarshalText([]byte(step.Hash)); err != nil {
+			return false, errors.Wrapf(err, "decoding proof step %d", i)
+		}
+		proof = append(proof, bc.MerkleProofStep{Hash: hash, Left: step.Left})
+	}
+	return bc.VerifyMerkleProof(txID, proof, root), nil
+}
@@ -0,0 +1,354 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"math/big"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+	"github.com/bytom/mining"
+	"github.com/bytom/mining/stratum"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// number of recent blocks the network hashrate estimate averages over.
+const hashrateEstimateBlocks = 72
+
+// how often the mining guard re-checks the chain for staleness.
+const miningGuardInterval = 30 * time.Second
+
+// MiningStatus reports the state of the built-in CPU miner.
+type MiningStatus struct {
+	Mining      bool   `json:"mining"`
+	Workers     int32  `json:"workers"`
+	Throttle    int32  `json:"throttle"`
+	Paused      bool   `json:"paused"`
+	PauseReason string `json:"pause_reason,omitempty"`
+}
+
+// ConfigureMiner applies the configured worker count and CPU throttle
+// to the built-in miner before it starts. A non-zero pauseStale arms
+// the guard that pauses mining when the tip is older than that many
+// minutes or every peer is gone.
+func (bcr *BlockchainReactor) ConfigureMiner(workers, throttle, pauseStale int32) {
+	if workers > 0 {
+		bcr.mining.SetNumWorkers(workers)
+	}
+	if throttle > 0 {
+		bcr.mining.SetThrottle(throttle)
+	}
+	bcr.guardMtx.Lock()
+	bcr.pauseStaleMinute = pauseStale
+	bcr.guardMtx.Unlock()
+}
+
+// staleReason reports why mining should pause right now, or "" when
+// the chain looks healthy.
+func (bcr *BlockchainReactor) staleReason() string {
+	bcr.guardMtx.Lock()
+	staleMinute := bcr.pauseStaleMinute
+	bcr.guardMtx.Unlock()
+	if staleMinute <= 0 {
+		return ""
+	}
+
+	if bcr.sw.Peers().Size() == 0 {
+		return "no peers"
+	}
+	tipAge := time.Duration(bc.Millis(time.Now())-bcr.chain.TimestampMS()) * time.Millisecond
+	if tipAge > time.Duration(staleMinute)*time.Minute {
+		return "stale tip"
+	}
+	return ""
+}
+
+// miningGuardRoutine pauses the miner while the node looks forked or
+// stale - the tip too old or no peers left - and resumes it once sync
+// recovers, so no work is wasted on a dead chain.
+func (bcr *BlockchainReactor) miningGuardRoutine() {
+	ticker := time.NewTicker(miningGuardInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		reason := bcr.staleReason()
+
+		bcr.guardMtx.Lock()
+		paused := bcr.miningPaused
+		bcr.guardMtx.Unlock()
+
+		switch {
+		case reason != "" && !paused && bcr.mining.IsMining():
+			bcr.mining.Stop()
+			bcr.guardMtx.Lock()
+			bcr.miningPaused = true
+			bcr.pauseReason = reason
+			bcr.guardMtx.Unlock()
+			log.WithField("reason", reason).Info("Mining paused")
+
+		case reason == "" && paused:
+			bcr.guardMtx.Lock()
+			bcr.miningPaused = false
+			bcr.pauseReason = ""
+			bcr.guardMtx.Unlock()
+			bcr.mining.Start()
+			log.Info("Mining resumed")
+		}
+	}
+}
+
+// setMining starts or stops the CPU miner, optionally retuning the
+// worker count and throttle in the same call.
+func (bcr *BlockchainReactor) setMining(in struct {
+	Mining   bool  `json:"mining"`
+	Workers  int32 `json:"workers"`
+	Throttle int32 `json:"throttle"`
+}) (*MiningStatus, error) {
+	if in.Workers != 0 {
+		bcr.mining.SetNumWorkers(in.Workers)
+	}
+	if in.Throttle != 0 {
+		bcr.mining.SetThrottle(in.Throttle)
+	}
+	// An explicit request overrides any automatic pause.
+	bcr.guardMtx.Lock()
+	bcr.miningPaused = false
+	bcr.pauseReason = ""
+	bcr.guardMtx.Unlock()
+
+	if in.Mining {
+		bcr.mining.Start()
+	} else {
+		bcr.mining.Stop()
+	}
+	return bcr.miningStatus()
+}
+
+// miningStatus reports whether the miner runs and how it is tuned.
+func (bcr *BlockchainReactor) miningStatus() (*MiningStatus, error) {
+	status := &MiningStatus{
+		Mining:   bcr.mining.IsMining(),
+		Workers:  bcr.mining.NumWorkers(),
+		Throttle: bcr.mining.Throttle(),
+	}
+	bcr.guardMtx.Lock()
+	status.Paused = bcr.miningPaused
+	status.PauseReason = bcr.pauseReason
+	bcr.guardMtx.Unlock()
+	return status, nil
+}
+
+// generateBlocks mines the requested number of blocks synchronously,
+// regtest style. It is only registered on test networks; integration
+// suites use it to confirm transactions without waiting for real work.
+// An optional control program (hex) receives the block rewards.
+func (bcr *BlockchainReactor) generateBlocks(in struct {
+	BlockNumber uint64 `json:"block_number"`
+	Program     string `json:"program"`
+}) ([]string, error) {
+	if !bcr.allowGenerate {
+		return nil, errors.New("generate-blocks is only available on test networks")
+	}
+
+	if in.Program != "" {
+		program, err := hex.DecodeString(in.Program)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding coinbase program")
+		}
+		prev := mining.CoinbaseProgram()
+		mining.SetCoinbaseProgram(program)
+		defer mining.SetCoinbaseProgram(prev)
+	}
+
+	blockHashes, err := bcr.mining.GenerateNBlocks(in.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(blockHashes))
+	for _, hash := range blockHashes {
+		hashes = append(hashes, hash.String())
+	}
+	go bcr.BroadcastStatusResponse()
+	return hashes, nil
+}
+
+// MiningStats aggregates local miner throughput with an estimate of
+// what the whole network is hashing at.
+type MiningStats struct {
+	HashesPerSec        float64                         `json:"hashes_per_sec"`
+	BlocksFound         uint64                          `json:"blocks_found"`
+	SharesAccepted      uint64                          `json:"shares_accepted"`
+	SharesRejected      uint64                          `json:"shares_rejected"`
+	Workers             map[string]*stratum.WorkerStats `json:"workers,omitempty"`
+	NetworkHashesPerSec float64                         `json:"network_hashes_per_sec"`
+}
+
+// getMiningStats reports the local hashrate, share and block counters
+// (both the CPU miner's and the stratum server's), and the network
+// hashrate derived from recent difficulty and block times.
+func (bcr *BlockchainReactor) getMiningStats() (*MiningStats, error) {
+	stats := &MiningStats{
+		HashesPerSec: bcr.mining.HashesPerSecond(),
+		BlocksFound:  bcr.mining.BlocksFound(),
+	}
+
+	if bcr.stratumSrv != nil {
+		stats.BlocksFound += bcr.stratumSrv.BlocksFound()
+		stats.Workers = bcr.stratumSrv.Workers()
+		for _, worker := range stats.Workers {
+			stats.SharesAccepted += worker.Accepted
+			stats.SharesRejected += worker.Rejected
+		}
+	}
+
+	networkHashRate, err := bcr.networkHashesPerSec()
+	if err != nil {
+		return nil, err
+	}
+	stats.NetworkHashesPerSec = networkHashRate
+	return stats, nil
+}
+
+// blockWork returns the expected number of hashes needed to solve a
+// block at the given compact difficulty: 2^256 / (target + 1).
+func blockWork(bits uint64) *big.Int {
+	target := consensus.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return new(big.Int)
+	}
+	oneLsh256 := new(big.Int).Lsh(big.NewInt(1), 256)
+	return oneLsh256.Div(oneLsh256, target.Add(target, big.NewInt(1)))
+}
+
+// networkHashesPerSec estimates the network hashrate from the work and
+// timespan of the most recent blocks.
+func (bcr *BlockchainReactor) networkHashesPerSec() (float64, error) {
+	bestHeight := bcr.chain.Height()
+	if bestHeight == 0 {
+		return 0, nil
+	}
+	startHeight := uint64(0)
+	if bestHeight > hashrateEstimateBlocks {
+		startHeight = bestHeight - hashrateEstimateBlocks
+	}
+
+	startBlock, err := bcr.chain.GetBlockByHeight(startHeight)
+	if err != nil {
+		return 0, err
+	}
+	lastTimestamp := startBlock.TimestampMS
+
+	totalWork := new(big.Int)
+	for height := startHeight + 1; height <= bestHeight; height++ {
+		block, err := bcr.chain.GetBlockByHeight(height)
+		if err != nil {
+			return 0, err
+		}
+		totalWork.Add(totalWork, blockWork(block.Bits))
+		lastTimestamp = block.TimestampMS
+	}
+
+	if lastTimestamp <= startBlock.TimestampMS {
+		return 0, nil
+	}
+	timespan := float64(lastTimestamp-startBlock.TimestampMS) / 1000
+	work, _ := new(big.Float).SetInt(totalWork).Float64()
+	return work / timespan, nil
+}
+
+// CoinbaseConfig describes where the block reward is paid and the
+// extra data (pool tag) embedded in the coinbase output.
+type CoinbaseConfig struct {
+	Program   string `json:"program"`
+	ExtraData string `json:"extra_data"`
+}
+
+// setCoinbase reconfigures the payout program (hex encoded) and extra
+// data used for subsequently mined blocks.
+func (bcr *BlockchainReactor) setCoinbase(in CoinbaseConfig) (*CoinbaseConfig, error) {
+	program, err := hex.DecodeString(in.Program)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding coinbase program")
+	}
+	mining.SetCoinbaseProgram(program)
+	mining.SetCoinbaseExtraData([]byte(in.ExtraData))
+	return bcr.getCoinbase()
+}
+
+// getCoinbase reports the current coinbase configuration.
+func (bcr *BlockchainReactor) getCoinbase() (*CoinbaseConfig, error) {
+	return &CoinbaseConfig{
+		Program:   hex.EncodeToString(mining.CoinbaseProgram()),
+		ExtraData: string(mining.CoinbaseExtraData()),
+	}, nil
+}
+
+// BlockTemplate is the getblocktemplate-style work unit handed to
+// external mining software: the assembled candidate block plus the
+// fields a miner needs without decoding it.
+type BlockTemplate struct {
+	RawBlock          string `json:"raw_block"`
+	Height            uint64 `json:"height"`
+	PreviousBlockHash string `json:"previous_block_hash"`
+	Seed              string `json:"seed"`
+	Bits              uint64 `json:"bits"`
+	Timestamp         uint64 `json:"timestamp"`
+	TxCount           int    `json:"transaction_count"`
+	CoinbaseValue     uint64 `json:"coinbase_value"`
+}
+
+// getBlockTemplate assembles a candidate block from the mempool and
+// returns it ready to be solved.
+func (bcr *BlockchainReactor) getBlockTemplate() (*BlockTemplate, error) {
+	block, err := mining.NewBlockTemplate(bcr.chain, bcr.txPool, bcr.accounts)
+	if err != nil {
+		return nil, err
+	}
+	rawBlock, err := block.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &BlockTemplate{
+		RawBlock:          string(rawBlock),
+		Height:            block.Height,
+		PreviousBlockHash: block.PreviousBlockHash.String(),
+		Seed:              block.Seed.String(),
+		Bits:              block.Bits,
+		Timestamp:         block.TimestampMS,
+		TxCount:           len(block.Transactions),
+	}
+	if len(block.Transactions) > 0 && len(block.Transactions[0].Outputs) > 0 {
+		template.CoinbaseValue = block.Transactions[0].Outputs[0].Amount
+	}
+	return template, nil
+}
+
+// submitBlock validates an externally-mined block, connects it to the
+// chain and announces the new status to our peers.
+func (bcr *BlockchainReactor) submitBlock(in struct {
+	RawBlock string `json:"raw_block"`
+}) error {
+	block := &legacy.Block{}
+	if err := block.UnmarshalText([]byte(in.RawBlock)); err != nil {
+		return err
+	}
+
+	isOrphan, err := bcr.chain.ProcessBlock(block)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"height":   block.Height,
+		"isOrphan": isOrphan,
+	}).Info("Processed externally-mined block")
+
+	if !isOrphan {
+		go bcr.BroadcastStatusResponse()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package blockchain
+
+import (
+	"time"
+
+	"github.com/bytom/clock"
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+)
+
+// errMockTimeForbidden is returned when /set-mock-time is called
+// outside of regtest.
+var errMockTimeForbidden = errors.New("mock time is only available on regtest")
+
+// setMockTime freezes the node's clock at the given unix timestamp, so
+// integration tests can exercise timelock and expiry behavior without
+// waiting for wall time. A zero timestamp returns the clock to wall
+// time. Only available on regtest.
+func (bcr *BlockchainReactor) setMockTime(in struct {
+	Timestamp int64 `json:"timestamp"`
+}) (map[string]interface{}, error) {
+	if consensus.ActiveNetParams.Name != "regtest" {
+		return nil, errMockTimeForbidden
+	}
+	if in.Timestamp == 0 {
+		clock.Reset()
+	} else {
+		clock.SetMock(time.Unix(in.Timestamp, 0))
+	}
+	return map[string]interface{}{
+		"mocked": clock.IsMocked(),
+		"now":    clock.Now(),
+	}, nil
+}
@@ -12,11 +12,15 @@ import (
 )
 
 const (
-	BlockRequestByte   = byte(0x10)
-	BlockResponseByte  = byte(0x11)
-	StatusRequestByte  = byte(0x20)
-	StatusResponseByte = byte(0x21)
-	NewTransactionByte = byte(0x30)
+	BlockRequestByte    = byte(0x10)
+	BlockResponseByte   = byte(0x11)
+	HeadersRequestByte  = byte(0x12)
+	HeadersResponseByte = byte(0x13)
+	StatusRequestByte   = byte(0x20)
+	StatusResponseByte  = byte(0x21)
+	NewTransactionByte  = byte(0x30)
+	TxProofRequestByte  = byte(0x40)
+	TxProofResponseByte = byte(0x41)
 )
 
 // BlockchainMessage is a generic message for this reactor.
@@ -26,9 +30,13 @@ var _ = wire.RegisterInterface(
 	struct{ BlockchainMessage }{},
 	wire.ConcreteType{&BlockRequestMessage{}, BlockRequestByte},
 	wire.ConcreteType{&BlockResponseMessage{}, BlockResponseByte},
+	wire.ConcreteType{&HeadersRequestMessage{}, HeadersRequestByte},
+	wire.ConcreteType{&HeadersResponseMessage{}, HeadersResponseByte},
 	wire.ConcreteType{&StatusRequestMessage{}, StatusRequestByte},
 	wire.ConcreteType{&StatusResponseMessage{}, StatusResponseByte},
 	wire.ConcreteType{&TransactionNotifyMessage{}, NewTransactionByte},
+	wire.ConcreteType{&TxProofRequestMessage{}, TxProofRequestByte},
+	wire.ConcreteType{&TxProofResponseMessage{}, TxProofResponseByte},
 )
 
 func DecodeMessage(bz []byte) (msgType byte, msg BlockchainMessage, err error) {
@@ -85,6 +93,47 @@ func (m *BlockResponseMessage) String() string {
 	return fmt.Sprintf("BlockResponseMessage{Size: %d}", len(m.RawBlock))
 }
 
+type HeadersRequestMessage struct {
+	FromHeight uint64
+	Count      uint64
+}
+
+func (m *HeadersRequestMessage) String() string {
+	return fmt.Sprintf("HeadersRequestMessage{FromHeight: %d, Count: %d}", m.FromHeight, m.Count)
+}
+
+type HeadersResponseMessage struct {
+	RawHeaders [][]byte
+}
+
+func NewHeadersResponseMessage(headers []*legacy.BlockHeader) (*HeadersResponseMessage, error) {
+	rawHeaders := make([][]byte, 0, len(headers))
+	for _, header := range headers {
+		rawHeader, err := header.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		rawHeaders = append(rawHeaders, rawHeader)
+	}
+	return &HeadersResponseMessage{RawHeaders: rawHeaders}, nil
+}
+
+func (m *HeadersResponseMessage) GetHeaders() []*legacy.BlockHeader {
+	headers := make([]*legacy.BlockHeader, 0, len(m.RawHeaders))
+	for _, rawHeader := range m.RawHeaders {
+		header := &legacy.BlockHeader{}
+		if err := header.UnmarshalText(rawHeader); err != nil {
+			return nil
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+func (m *HeadersResponseMessage) String() string {
+	return fmt.Sprintf("HeadersResponseMessage{Count: %d}", len(m.RawHeaders))
+}
+
 type TransactionNotifyMessage struct {
 	RawTx []byte
 }
@@ -134,3 +183,78 @@ func (m *StatusResponseMessage) String() string {
 	hash := m.GetHash()
 	return fmt.Sprintf("StatusResponseMessage{Height: %d, Hash: %s}", m.Height, hash.String())
 }
+
+// TxProofRequestMessage asks a full-node peer for the merkle path
+// proving a transaction's inclusion in a main chain block. Light nodes
+// use it to confirm wallet-relevant transactions against their header
+// chain.
+type TxProofRequestMessage struct {
+	RawTxID [32]byte
+}
+
+func (m *TxProofRequestMessage) GetTxID() *bc.Hash {
+	txID := bc.NewHash(m.RawTxID)
+	return &txID
+}
+
+func (m *TxProofRequestMessage) String() string {
+	txID := m.GetTxID()
+	return fmt.Sprintf("TxProofRequestMessage{TxID: %s}", txID.String())
+}
+
+// TxProofResponseMessage carries an inclusion proof, or Found false
+// when the serving peer does not know the transaction. The proof steps
+// are the sibling hashes on the path to the transactions merkle root,
+// with Lefts marking the steps that hash in from the left.
+type TxProofResponseMessage struct {
+	RawTxID      [32]byte
+	Found        bool
+	BlockHeight  uint64
+	RawBlockHash [32]byte
+	RawSteps     [][32]byte
+	Lefts        []byte
+}
+
+func NewTxProofResponseMessage(txID *bc.Hash, height uint64, blockHash *bc.Hash, proof []bc.MerkleProofStep) *TxProofResponseMessage {
+	msg := &TxProofResponseMessage{
+		RawTxID:      txID.Byte32(),
+		Found:        true,
+		BlockHeight:  height,
+		RawBlockHash: blockHash.Byte32(),
+	}
+	for _, step := range proof {
+		msg.RawSteps = append(msg.RawSteps, step.Hash.Byte32())
+		left := byte(0)
+		if step.Left {
+			left = 1
+		}
+		msg.Lefts = append(msg.Lefts, left)
+	}
+	return msg
+}
+
+func (m *TxProofResponseMessage) GetTxID() *bc.Hash {
+	txID := bc.NewHash(m.RawTxID)
+	return &txID
+}
+
+func (m *TxProofResponseMessage) GetBlockHash() *bc.Hash {
+	hash := bc.NewHash(m.RawBlockHash)
+	return &hash
+}
+
+func (m *TxProofResponseMessage) GetProof() []bc.MerkleProofStep {
+	if len(m.RawSteps) != len(m.Lefts) {
+		return nil
+	}
+	proof := make([]bc.MerkleProofStep, 0, len(m.RawSteps))
+	for i, raw := range m.RawSteps {
+		proof = append(proof, bc.MerkleProofStep{Hash: bc.NewHash(raw), Left: m.Lefts[i] == 1})
+	}
+	return proof
+}
+
+func (m *TxProofResponseMessage) String() string {
+	txID := m.GetTxID()
+	return fmt.Sprintf("TxProofResponseMessage{TxID: %s, Found: %v, Height: %d}", txID.String(), m.Found, m.BlockHeight)
+}
@@ -0,0 +1,60 @@
+package blockchain
+
+import (
+	"time"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/version"
+)
+
+// NodeInfoResp aggregates what a dashboard needs in one call instead
+// of polling half a dozen endpoints.
+type NodeInfoResp struct {
+	Version       string  `json:"version"`
+	Network       string  `json:"network"`
+	Height        uint64  `json:"height"`
+	BestBlockHash string  `json:"best_block_hash"`
+	Light         bool    `json:"light"`
+	Syncing       bool    `json:"syncing"`
+	SyncProgress  float64 `json:"sync_progress"`
+	PeerCount     int     `json:"peer_count"`
+	MempoolCount  int     `json:"mempool_count"`
+	MempoolBytes  uint64  `json:"mempool_bytes"`
+	WalletEnabled bool    `json:"wallet_enabled"`
+	WalletHeight  uint64  `json:"wallet_height,omitempty"`
+	Mining        bool    `json:"mining"`
+	UptimeSeconds int64   `json:"uptime_seconds"`
+}
+
+// getNodeInfo handles /node-info.
+func (bcr *BlockchainReactor) getNodeInfo() (*NodeInfoResp, error) {
+	height := bcr.chain.Height()
+	resp := &NodeInfoResp{
+		Version:       version.Version,
+		Network:       consensus.ActiveNetParams.Name,
+		Height:        height,
+		BestBlockHash: bcr.chain.BestBlockHash().String(),
+		Light:         bcr.chain.HeaderOnly(),
+		Syncing:       !bcr.blockKeeper.IsCaughtUp(),
+		SyncProgress:  100,
+		PeerCount:     len(bcr.sw.Peers().List()),
+		Mining:        bcr.mining.IsMining(),
+		UptimeSeconds: int64(time.Since(bcr.startTime).Seconds()),
+	}
+
+	if best := bcr.blockKeeper.BestPeerHeight(); best > height {
+		resp.SyncProgress = float64(height) / float64(best) * 100
+	}
+
+	info := bcr.txPool.Info()
+	resp.MempoolCount = info.Count
+	resp.MempoolBytes = info.Bytes
+
+	if bcr.wallet != nil {
+		resp.WalletEnabled = true
+		if status, err := bcr.wallet.GetWalletInfo(); err == nil {
+			resp.WalletHeight = status.Height
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"time"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/payment"
+)
+
+// POST /create-payment-uri
+// ExpiresIn is in seconds from now; zero means the request never
+// expires.
+func (bcr *BlockchainReactor) createPaymentURI(in struct {
+	Program   string `json:"program"`
+	AssetID   string `json:"asset_id"`
+	Amount    uint64 `json:"amount"`
+	Memo      string `json:"memo"`
+	ExpiresIn uint64 `json:"expires_in"`
+}) (*struct {
+	URI string `json:"uri"`
+}, error) {
+	if in.Program == "" {
+		return nil, errors.WithDetail(errBadAction, "program is required")
+	}
+
+	request := &payment.Request{
+		Program: in.Program,
+		AssetID: in.AssetID,
+		Amount:  in.Amount,
+		Memo:    in.Memo,
+	}
+	if in.ExpiresIn > 0 {
+		request.Expires = time.Now().Add(time.Duration(in.ExpiresIn) * time.Second)
+	}
+
+	uri, err := request.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return &struct {
+		URI string `json:"uri"`
+	}{URI: uri}, nil
+}
+
+// PaymentURIResp is the decoded request plus whether its expiry has
+// already passed, saved from every caller re-deriving it.
+type PaymentURIResp struct {
+	*payment.Request
+	Expired bool `json:"expired"`
+}
+
+// POST /parse-payment-uri
+func (bcr *BlockchainReactor) parsePaymentURI(in struct {
+	URI string `json:"uri"`
+}) (*PaymentURIResp, error) {
+	if in.URI == "" {
+		return nil, errors.WithDetail(errBadAction, "uri is required")
+	}
+	request, err := payment.Parse(in.URI)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentURIResp{Request: request, Expired: request.Expired()}, nil
+}
@@ -0,0 +1,273 @@
+// Package peg is a foundation for federated two-way pegs to sidechains.
+// A federation of members, each identified by an extended public key,
+// shares a multisig "lock" address; the tracker follows the chain and
+// records every deposit to that address as a peg-in, and withdrawals
+// spending locked value are coordinated as signature rounds until the
+// federation quorum is reached. Minting the pegged asset happens on the
+// sidechain with its own issuance machinery; the tracker records which
+// peg-ins have been minted against so none is used twice.
+package peg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/crypto/ed25519"
+	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+var (
+	// ErrNoFederation means no federation members are configured yet.
+	ErrNoFederation = errors.New("no peg federation configured")
+	// ErrDuplicateMember means the xpub is already a federation member.
+	ErrDuplicateMember = errors.New("xpub is already a federation member")
+	// ErrNoMatchMember means the xpub is not a federation member.
+	ErrNoMatchMember = errors.New("xpub is not a federation member")
+	// ErrNoMatchPegIn means no peg-in matches the given output.
+	ErrNoMatchPegIn = errors.New("no peg-in with that transaction output")
+)
+
+// database layout: the federation is one record, peg-ins are keyed by
+// height so reorg rollback can find a block's records with one prefix
+// scan, withdrawals by their id
+const (
+	peginPrefix      = "PI:"
+	withdrawalPrefix = "PW:"
+)
+
+var federationKey = []byte("pegFederation")
+
+// Member is one federation participant.
+type Member struct {
+	XPub  chainkd.XPub `json:"xpub"`
+	Alias string       `json:"alias,omitempty"`
+	Added time.Time    `json:"added_at"`
+}
+
+// Federation is the member set, its signing quorum and the multisig
+// lock program deposits peg in to.
+type Federation struct {
+	Members []*Member `json:"members"`
+	Quorum  int       `json:"quorum"`
+	// LockProgram is the hex quorum-of-members multisig program; it
+	// changes whenever the membership or quorum changes.
+	LockProgram string `json:"lock_program"`
+}
+
+// PegIn is one recorded deposit to the lock address.
+type PegIn struct {
+	TxID        string `json:"tx_id"`
+	OutputIndex int    `json:"output_index"`
+	AssetID     string `json:"asset_id"`
+	Amount      uint64 `json:"amount"`
+	BlockHeight uint64 `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+	// LockProgram records the program the deposit paid, since the
+	// federation - and with it the lock address - can change over time.
+	LockProgram string `json:"lock_program"`
+	// Minted is set once the pegged asset has been issued against this
+	// deposit on the sidechain, with the sidechain transaction recorded.
+	Minted     bool   `json:"minted"`
+	MintedTxID string `json:"minted_tx_id,omitempty"`
+}
+
+// Tracker stores the federation, peg-ins and pending withdrawals in its
+// own database.
+type Tracker struct {
+	DB dbm.DB
+
+	mtx sync.Mutex
+	fed *Federation
+
+	status statusInfo
+}
+
+// NewTracker loads the persisted federation and resumes from the block
+// the peg-in records cover.
+func NewTracker(db dbm.DB) (*Tracker, error) {
+	t := &Tracker{DB: db}
+
+	if raw := db.Get(federationKey); raw != nil {
+		fed := &Federation{}
+		if err := json.Unmarshal(raw, fed); err != nil {
+			return nil, err
+		}
+		t.fed = fed
+	}
+	if raw := db.Get(statusKey); raw != nil {
+		if err := json.Unmarshal(raw, &t.status); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Federation returns the configured federation, or ErrNoFederation.
+func (t *Tracker) Federation() (*Federation, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.fed == nil {
+		return nil, ErrNoFederation
+	}
+	return t.fed, nil
+}
+
+// lockProgram returns the current lock program hex, or "" when no
+// federation is configured; the watcher matches outputs against it.
+func (t *Tracker) lockProgram() string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.fed == nil {
+		return ""
+	}
+	return t.fed.LockProgram
+}
+
+// recompute derives the lock program from the member set and quorum,
+// and persists the federation. Callers hold t.mtx.
+func (t *Tracker) recompute() error {
+	if len(t.fed.Members) == 0 {
+		t.fed = nil
+		t.DB.Delete(federationKey)
+		return nil
+	}
+	if t.fed.Quorum <= 0 || t.fed.Quorum > len(t.fed.Members) {
+		// default to a simple majority
+		t.fed.Quorum = len(t.fed.Members)/2 + 1
+	}
+
+	pubkeys := make([]ed25519.PublicKey, 0, len(t.fed.Members))
+	for _, member := range t.fed.Members {
+		pubkeys = append(pubkeys, member.XPub.PublicKey())
+	}
+	program, err := vmutil.P2SPMultiSigProgram(pubkeys, t.fed.Quorum)
+	if err != nil {
+		return err
+	}
+	t.fed.LockProgram = hex.EncodeToString(program)
+	t.DB.SetSync(federationKey, mustMarshal(t.fed))
+	return nil
+}
+
+// AddMember adds a federation member and recomputes the lock program.
+// A zero quorum keeps the current one, or defaults to a majority.
+func (t *Tracker) AddMember(xpub chainkd.XPub, alias string, quorum int) (*Federation, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.fed == nil {
+		t.fed = &Federation{}
+	}
+	for _, member := range t.fed.Members {
+		if bytes.Equal(member.XPub[:], xpub[:]) {
+			return nil, errors.WithDetailf(ErrDuplicateMember, "xpub %s already a member", xpub.String())
+		}
+	}
+	t.fed.Members = append(t.fed.Members, &Member{XPub: xpub, Alias: alias, Added: time.Now()})
+	if quorum > 0 {
+		t.fed.Quorum = quorum
+	}
+	if err := t.recompute(); err != nil {
+		return nil, err
+	}
+	return t.fed, nil
+}
+
+// RemoveMember removes a federation member and recomputes the lock
+// program. Deposits to the old lock address stay recorded.
+func (t *Tracker) RemoveMember(xpub chainkd.XPub) (*Federation, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.fed == nil {
+		return nil, ErrNoFederation
+	}
+	for i, member := range t.fed.Members {
+		if bytes.Equal(member.XPub[:], xpub[:]) {
+			t.fed.Members = append(t.fed.Members[:i], t.fed.Members[i+1:]...)
+			if t.fed.Quorum > len(t.fed.Members) {
+				t.fed.Quorum = len(t.fed.Members)
+			}
+			if err := t.recompute(); err != nil {
+				return nil, err
+			}
+			if t.fed == nil {
+				return &Federation{}, nil
+			}
+			return t.fed, nil
+		}
+	}
+	return nil, errors.WithDetailf(ErrNoMatchMember, "xpub %s is not a member", xpub.String())
+}
+
+// IsMember reports whether the xpub belongs to the federation.
+func (t *Tracker) IsMember(xpub chainkd.XPub) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.fed == nil {
+		return false
+	}
+	for _, member := range t.fed.Members {
+		if bytes.Equal(member.XPub[:], xpub[:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPegIns returns recorded peg-ins, optionally only those not yet
+// minted against.
+func (t *Tracker) ListPegIns(unmintedOnly bool) ([]*PegIn, error) {
+	pegins := []*PegIn{}
+
+	iter := t.DB.IteratorPrefix([]byte(peginPrefix))
+	defer iter.Release()
+	for iter.Next() {
+		pegin := &PegIn{}
+		if err := json.Unmarshal(iter.Value(), pegin); err != nil {
+			return nil, err
+		}
+		if unmintedOnly && pegin.Minted {
+			continue
+		}
+		pegins = append(pegins, pegin)
+	}
+	return pegins, nil
+}
+
+// MarkMinted records that the pegged asset has been issued against the
+// given peg-in, so it cannot be used twice.
+func (t *Tracker) MarkMinted(txID string, outputIndex int, mintedTxID string) error {
+	iter := t.DB.IteratorPrefix([]byte(peginPrefix))
+	defer iter.Release()
+	for iter.Next() {
+		pegin := &PegIn{}
+		if err := json.Unmarshal(iter.Value(), pegin); err != nil {
+			return err
+		}
+		if pegin.TxID != txID || pegin.OutputIndex != outputIndex {
+			continue
+		}
+		pegin.Minted = true
+		pegin.MintedTxID = mintedTxID
+		raw, err := json.Marshal(pegin)
+		if err != nil {
+			return err
+		}
+		t.DB.SetSync(append([]byte{}, iter.Key()...), raw)
+		return nil
+	}
+	return errors.WithDetailf(ErrNoMatchPegIn, "no peg-in for output %s:%d", txID, outputIndex)
+}
+
+func mustMarshal(v interface{}) []byte {
+	raw, _ := json.Marshal(v)
+	return raw
+}
@@ -0,0 +1,228 @@
+package peg
+
+import (
+	"encoding/hex"
+	"testing"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/crypto/ed25519"
+	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+func newTestTracker(t *testing.T) *Tracker {
+	tracker, err := NewTracker(dbm.NewMemDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracker
+}
+
+func newXPub(t *testing.T) chainkd.XPub {
+	_, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xpub
+}
+
+func TestFederationQuorum(t *testing.T) {
+	tracker := newTestTracker(t)
+	if _, err := tracker.Federation(); errors.Root(err) != ErrNoFederation {
+		t.Fatalf("got error %v, want ErrNoFederation", err)
+	}
+
+	xpubs := []chainkd.XPub{newXPub(t), newXPub(t), newXPub(t)}
+	var fed *Federation
+	var err error
+	for _, xpub := range xpubs {
+		if fed, err = tracker.AddMember(xpub, "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a zero quorum keeps the current one: the majority default was
+	// applied when the first member joined and survives later adds
+	if fed.Quorum != 1 {
+		t.Errorf("quorum = %d, want 1", fed.Quorum)
+	}
+	pubkeys := []ed25519.PublicKey{}
+	for _, xpub := range xpubs {
+		pubkeys = append(pubkeys, xpub.PublicKey())
+	}
+	program, err := vmutil.P2SPMultiSigProgram(pubkeys, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fed.LockProgram != hex.EncodeToString(program) {
+		t.Errorf("lock program = %s, want %s", fed.LockProgram, hex.EncodeToString(program))
+	}
+
+	if _, err := tracker.AddMember(xpubs[0], "", 0); errors.Root(err) != ErrDuplicateMember {
+		t.Errorf("got error %v, want ErrDuplicateMember", err)
+	}
+	if !tracker.IsMember(xpubs[2]) || tracker.IsMember(newXPub(t)) {
+		t.Error("IsMember misjudged the member set")
+	}
+
+	// an explicit quorum is honored and the lock program changes with it
+	oldProgram := fed.LockProgram
+	if fed, err = tracker.AddMember(newXPub(t), "", 4); err != nil {
+		t.Fatal(err)
+	}
+	if fed.Quorum != 4 || fed.LockProgram == oldProgram {
+		t.Errorf("quorum = %d lock program changed %v, want 4 true", fed.Quorum, fed.LockProgram != oldProgram)
+	}
+
+	// removing a member clamps a now-impossible quorum
+	if fed, err = tracker.RemoveMember(xpubs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if fed.Quorum != 3 || len(fed.Members) != 3 {
+		t.Errorf("after removal: quorum = %d members = %d, want 3 and 3", fed.Quorum, len(fed.Members))
+	}
+	if _, err := tracker.RemoveMember(xpubs[0]); errors.Root(err) != ErrNoMatchMember {
+		t.Errorf("got error %v, want ErrNoMatchMember", err)
+	}
+
+	// the federation persists across a tracker restart
+	reloaded, err := NewTracker(tracker.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refed, err := reloaded.Federation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refed.LockProgram != fed.LockProgram || refed.Quorum != fed.Quorum {
+		t.Errorf("reloaded federation = %+v, want %+v", refed, fed)
+	}
+
+	// removing the last member dissolves the federation
+	for _, member := range append([]*Member{}, refed.Members...) {
+		if _, err := reloaded.RemoveMember(member.XPub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := reloaded.Federation(); errors.Root(err) != ErrNoFederation {
+		t.Errorf("got error %v, want ErrNoFederation", err)
+	}
+}
+
+func TestPegInAttachDetach(t *testing.T) {
+	tracker := newTestTracker(t)
+	if _, err := tracker.AddMember(newXPub(t), "", 1); err != nil {
+		t.Fatal(err)
+	}
+	lockProgram, err := hex.DecodeString(tracker.lockProgram())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{Height: 7},
+		Transactions: []*legacy.Tx{
+			legacy.NewTx(legacy.TxData{
+				Version: 1,
+				Inputs: []*legacy.TxInput{
+					legacy.NewSpendInput(nil, bc.Hash{V0: 1}, *consensus.BTMAssetID, 100, 0, []byte{0x51}, bc.Hash{}, nil),
+				},
+				Outputs: []*legacy.TxOutput{
+					// only the deposit to the lock address is a peg-in
+					legacy.NewTxOutput(*consensus.BTMAssetID, 60, lockProgram, nil),
+					legacy.NewTxOutput(*consensus.BTMAssetID, 40, []byte{0x52}, nil),
+				},
+			}),
+		},
+	}
+
+	batch := tracker.DB.NewBatch()
+	tracker.attachBlock(&batch, block)
+	batch.Write()
+
+	pegins, err := tracker.ListPegIns(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pegins) != 1 {
+		t.Fatalf("got %d peg-ins, want 1", len(pegins))
+	}
+	pegin := pegins[0]
+	txID := block.Transactions[0].Tx.ID.String()
+	if pegin.TxID != txID || pegin.OutputIndex != 0 || pegin.Amount != 60 || pegin.BlockHeight != 7 {
+		t.Errorf("peg-in = %+v", pegin)
+	}
+
+	// minting consumes the peg-in exactly once
+	if err := tracker.MarkMinted(txID, 0, "side-tx"); err != nil {
+		t.Fatal(err)
+	}
+	if unminted, err := tracker.ListPegIns(true); err != nil || len(unminted) != 0 {
+		t.Errorf("unminted peg-ins = %v, err %v, want none", unminted, err)
+	}
+	if pegins, err = tracker.ListPegIns(false); err != nil || len(pegins) != 1 || !pegins[0].Minted || pegins[0].MintedTxID != "side-tx" {
+		t.Errorf("minted peg-in = %+v, err %v", pegins, err)
+	}
+	if err := tracker.MarkMinted(txID, 1, "side-tx"); errors.Root(err) != ErrNoMatchPegIn {
+		t.Errorf("got error %v, want ErrNoMatchPegIn", err)
+	}
+
+	// a reorg detaches the block and its records with it
+	batch = tracker.DB.NewBatch()
+	tracker.detachBlock(&batch, block)
+	batch.Write()
+	if pegins, err = tracker.ListPegIns(false); err != nil || len(pegins) != 0 {
+		t.Errorf("peg-ins after detach = %v, err %v, want none", pegins, err)
+	}
+}
+
+func TestWithdrawalLifecycle(t *testing.T) {
+	tracker := newTestTracker(t)
+	w, err := tracker.CreateWithdrawal(&Withdrawal{
+		RawTx:       "00",
+		Predicate:   "00",
+		Destination: "51",
+		AssetID:     consensus.BTMAssetID.String(),
+		Amount:      10,
+		Quorum:      2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.ID == "" || w.Status != WithdrawalPending || w.Sigs == nil {
+		t.Errorf("created withdrawal = %+v", w)
+	}
+
+	if _, err := tracker.GetWithdrawal("feedfacefeedface"); errors.Root(err) != ErrNoMatchWithdrawal {
+		t.Errorf("got error %v, want ErrNoMatchWithdrawal", err)
+	}
+
+	// collecting the quorum and submitting persists across a reload
+	w.Sigs["m1"] = "aa"
+	w.Sigs["m2"] = "bb"
+	w.Status = WithdrawalSubmitted
+	w.SubmitTxID = "tx"
+	if err := tracker.SaveWithdrawal(w); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := tracker.GetWithdrawal(w.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Status != WithdrawalSubmitted || len(loaded.Sigs) != 2 || loaded.SubmitTxID != "tx" {
+		t.Errorf("loaded withdrawal = %+v", loaded)
+	}
+
+	list, err := tracker.ListWithdrawals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != w.ID {
+		t.Errorf("withdrawals = %+v", list)
+	}
+}
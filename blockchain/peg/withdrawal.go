@@ -0,0 +1,93 @@
+package peg
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/bytom/errors"
+)
+
+// ErrNoMatchWithdrawal means no withdrawal exists with the given id.
+var ErrNoMatchWithdrawal = errors.New("no withdrawal with that id")
+
+// Withdrawal statuses.
+const (
+	WithdrawalPending   = "pending"
+	WithdrawalSubmitted = "submitted"
+)
+
+// Withdrawal is one proposed spend of locked value, collecting member
+// signatures until the federation quorum is reached.
+type Withdrawal struct {
+	ID string `json:"id"`
+	// RawTx is the hex unsigned transaction spending the lock output.
+	RawTx string `json:"raw_tx"`
+	// Predicate is the hex signature program the members sign; its
+	// SHA3-256 is the multisig message.
+	Predicate   string `json:"predicate"`
+	Destination string `json:"destination"`
+	AssetID     string `json:"asset_id"`
+	Amount      uint64 `json:"amount"`
+	Quorum      int    `json:"quorum"`
+	// Sigs maps a member xpub to its signature hex.
+	Sigs       map[string]string `json:"signatures"`
+	Status     string            `json:"status"`
+	SubmitTxID string            `json:"submit_tx_id,omitempty"`
+	Created    time.Time         `json:"created_at"`
+}
+
+// CreateWithdrawal assigns the withdrawal an id and persists it.
+func (t *Tracker) CreateWithdrawal(w *Withdrawal) (*Withdrawal, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	w.ID = hex.EncodeToString(id)
+	w.Status = WithdrawalPending
+	w.Created = time.Now()
+	if w.Sigs == nil {
+		w.Sigs = map[string]string{}
+	}
+	return w, t.SaveWithdrawal(w)
+}
+
+// SaveWithdrawal persists the withdrawal under its id.
+func (t *Tracker) SaveWithdrawal(w *Withdrawal) error {
+	raw, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	t.DB.SetSync([]byte(withdrawalPrefix+w.ID), raw)
+	return nil
+}
+
+// GetWithdrawal loads one withdrawal by id.
+func (t *Tracker) GetWithdrawal(id string) (*Withdrawal, error) {
+	raw := t.DB.Get([]byte(withdrawalPrefix + id))
+	if raw == nil {
+		return nil, errors.WithDetailf(ErrNoMatchWithdrawal, "withdrawal %q not found", id)
+	}
+	w := &Withdrawal{}
+	if err := json.Unmarshal(raw, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ListWithdrawals returns every recorded withdrawal.
+func (t *Tracker) ListWithdrawals() ([]*Withdrawal, error) {
+	withdrawals := []*Withdrawal{}
+
+	iter := t.DB.IteratorPrefix([]byte(withdrawalPrefix))
+	defer iter.Release()
+	for iter.Next() {
+		w := &Withdrawal{}
+		if err := json.Unmarshal(iter.Value(), w); err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, w)
+	}
+	return withdrawals, nil
+}
@@ -0,0 +1,229 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/bytom/blockchain/peg"
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/consensus"
+	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/crypto/sha3pool"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/vm"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+// POST /add-peg-member
+// A zero quorum keeps the current quorum, defaulting to a simple
+// majority of the members.
+func (bcr *BlockchainReactor) addPegMember(in struct {
+	XPub   chainkd.XPub `json:"xpub"`
+	Alias  string       `json:"alias"`
+	Quorum int          `json:"quorum"`
+}) (*peg.Federation, error) {
+	return bcr.pegTracker.AddMember(in.XPub, in.Alias, in.Quorum)
+}
+
+// POST /remove-peg-member
+func (bcr *BlockchainReactor) removePegMember(in struct {
+	XPub chainkd.XPub `json:"xpub"`
+}) (*peg.Federation, error) {
+	return bcr.pegTracker.RemoveMember(in.XPub)
+}
+
+// POST /get-peg-federation
+func (bcr *BlockchainReactor) getPegFederation() (*peg.Federation, error) {
+	return bcr.pegTracker.Federation()
+}
+
+// POST /list-peg-ins
+func (bcr *BlockchainReactor) listPegIns(in struct {
+	UnmintedOnly bool `json:"unminted_only"`
+}) ([]*peg.PegIn, error) {
+	return bcr.pegTracker.ListPegIns(in.UnmintedOnly)
+}
+
+// POST /claim-peg-in
+// Records the sidechain transaction that minted the pegged asset
+// against a deposit, so the deposit cannot back a second mint.
+func (bcr *BlockchainReactor) claimPegIn(in struct {
+	TxID        string `json:"tx_id"`
+	OutputIndex int    `json:"output_index"`
+	MintedTxID  string `json:"minted_tx_id"`
+}) (*struct {
+	Msg string `json:"msg"`
+}, error) {
+	if err := bcr.pegTracker.MarkMinted(in.TxID, in.OutputIndex, in.MintedTxID); err != nil {
+		return nil, err
+	}
+	return &struct {
+		Msg string `json:"msg"`
+	}{Msg: "peg-in marked minted"}, nil
+}
+
+// POST /create-peg-withdrawal
+// Builds the transaction spending a lock output to the destination and
+// opens a signature round; members then sign with /sign-peg-withdrawal
+// until the quorum is reached.
+func (bcr *BlockchainReactor) createPegWithdrawal(in struct {
+	TxID           string `json:"tx_id"`
+	OutputIndex    int    `json:"output_index"`
+	ControlProgram string `json:"control_program"`
+	Fee            uint64 `json:"fee"`
+}) (*peg.Withdrawal, error) {
+	fed, err := bcr.pegTracker.Federation()
+	if err != nil {
+		return nil, err
+	}
+
+	var hash bc.Hash
+	if err := hash.UnmarshalText([]byte(in.TxID)); err != nil {
+		return nil, errors.WithDetail(errBadAction, "tx_id is not a valid hash")
+	}
+	block, position, _, err := bcr.findTxInMainchain(hash)
+	if err != nil {
+		return nil, err
+	}
+	tx := block.Transactions[position]
+	if in.OutputIndex < 0 || in.OutputIndex >= len(tx.Outputs) {
+		return nil, errors.WithDetail(errBadAction, "output_index out of range")
+	}
+	output := tx.Outputs[in.OutputIndex]
+	if hex.EncodeToString(output.ControlProgram) != fed.LockProgram {
+		return nil, errors.WithDetail(errBadAction, "output does not pay the federation lock address")
+	}
+
+	destProgram, err := hex.DecodeString(in.ControlProgram)
+	if err != nil || len(destProgram) == 0 {
+		return nil, errors.WithDetail(errBadAction, "control_program must be the hex destination program")
+	}
+	if in.Fee >= output.Amount {
+		return nil, errors.WithDetail(errBadAction, "fee exceeds the locked amount")
+	}
+	if in.Fee > 0 && *output.AssetId != *consensus.BTMAssetID {
+		return nil, errors.WithDetail(errBadAction, "fees are paid in BTM; a non-BTM withdrawal must use fee 0")
+	}
+
+	entry, ok := tx.Tx.Entries[*tx.OutputID(in.OutputIndex)].(*bc.Output)
+	if !ok {
+		return nil, errors.New("output entry not found")
+	}
+	input := legacy.NewSpendInput(nil, *entry.Source.Ref, *output.AssetId, output.Amount, entry.Source.Position, output.ControlProgram, *entry.Data, nil)
+
+	data := legacy.TxData{
+		Version: legacy.CurrentTransactionVersion,
+		Inputs:  []*legacy.TxInput{input},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*output.AssetId, output.Amount-in.Fee, destProgram, nil),
+		},
+	}
+	spendTx := legacy.NewTx(data)
+
+	// the predicate every member signs: the finished transaction's
+	// sighash, checked by the lock program's CHECKPREDICATE call
+	builder := vmutil.NewBuilder()
+	builder.AddData(spendTx.SigHash(0).Bytes())
+	builder.AddOp(vm.OP_TXSIGHASH).AddOp(vm.OP_EQUAL)
+	predicate, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	rawTx, err := spendTx.TxData.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return bcr.pegTracker.CreateWithdrawal(&peg.Withdrawal{
+		RawTx:       string(rawTx),
+		Predicate:   hex.EncodeToString(predicate),
+		Destination: in.ControlProgram,
+		AssetID:     output.AssetId.String(),
+		Amount:      output.Amount - in.Fee,
+		Quorum:      fed.Quorum,
+	})
+}
+
+// POST /sign-peg-withdrawal
+// Adds one member signature; once the quorum is reached the witness is
+// assembled and the transaction submitted.
+func (bcr *BlockchainReactor) signPegWithdrawal(ctx context.Context, in struct {
+	ID       string       `json:"id"`
+	XPub     chainkd.XPub `json:"xpub"`
+	Password string       `json:"password"`
+}) (*peg.Withdrawal, error) {
+	w, err := bcr.pegTracker.GetWithdrawal(in.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w.Status != peg.WithdrawalPending {
+		return nil, errors.WithDetailf(errBadAction, "withdrawal %s is already %s", w.ID, w.Status)
+	}
+	if !bcr.pegTracker.IsMember(in.XPub) {
+		return nil, errors.WithDetail(errBadAction, "xpub is not a federation member")
+	}
+
+	predicate, err := hex.DecodeString(w.Predicate)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := w.Sigs[in.XPub.String()]; !ok {
+		var msg [32]byte
+		sha3pool.Sum256(msg[:], predicate)
+		sig, err := bcr.hsm.XSign(in.XPub, nil, msg[:], in.Password)
+		if err != nil {
+			return nil, err
+		}
+		w.Sigs[in.XPub.String()] = hex.EncodeToString(sig)
+	}
+
+	// collect signatures in member order, which is the pubkey order the
+	// lock program's CHECKMULTISIG expects
+	fed, err := bcr.pegTracker.Federation()
+	if err != nil {
+		return nil, err
+	}
+	sigs := [][]byte{}
+	for _, member := range fed.Members {
+		sigHex, ok := w.Sigs[member.XPub.String()]
+		if !ok || len(sigs) >= w.Quorum {
+			continue
+		}
+		sig, err := hex.DecodeString(sigHex)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+
+	if len(sigs) >= w.Quorum {
+		data := legacy.TxData{}
+		if err := data.UnmarshalText([]byte(w.RawTx)); err != nil {
+			return nil, err
+		}
+		spendTx := legacy.NewTx(data)
+
+		args := [][]byte{vm.Int64Bytes(0)}
+		args = append(args, sigs...)
+		args = append(args, predicate)
+		spendTx.SetInputArguments(0, args)
+
+		if err := txbuilder.FinalizeTx(ctx, bcr.chain, spendTx); err != nil {
+			return nil, err
+		}
+		w.Status = peg.WithdrawalSubmitted
+		w.SubmitTxID = spendTx.Tx.ID.String()
+	}
+
+	if err := bcr.pegTracker.SaveWithdrawal(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// POST /list-peg-withdrawals
+func (bcr *BlockchainReactor) listPegWithdrawals() ([]*peg.Withdrawal, error) {
+	return bcr.pegTracker.ListWithdrawals()
+}
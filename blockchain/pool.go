@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+)
+
+var errNoStratum = errors.New("stratum server is not enabled")
+
+// PoolPayout is one worker's slice of a reward, computed from its
+// recorded shares. Workers authorize with their payout control program
+// (hex encoded) as the worker name, so the slice already carries the
+// program it should be paid to.
+type PoolPayout struct {
+	Worker string  `json:"worker"`
+	Share  float64 `json:"share"`
+	Amount uint64  `json:"amount"`
+}
+
+// poolPayouts splits the given amount among the stratum workers under
+// the requested scheme ("pplns" or "proportional") without building a
+// transaction.
+func (bcr *BlockchainReactor) poolPayouts(in struct {
+	Scheme string `json:"scheme"`
+	Amount uint64 `json:"amount"`
+}) ([]*PoolPayout, error) {
+	if bcr.stratumSrv == nil {
+		return nil, errNoStratum
+	}
+
+	fractions, err := bcr.stratumSrv.Payouts(in.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	payouts := []*PoolPayout{}
+	for worker, fraction := range fractions {
+		payouts = append(payouts, &PoolPayout{
+			Worker: worker,
+			Share:  fraction,
+			Amount: uint64(float64(in.Amount) * fraction),
+		})
+	}
+	return payouts, nil
+}
+
+// buildPoolPayout builds an unsigned transaction that spends the given
+// amount from the pool account and pays each worker its share. Any
+// rounding remainder stays with the pool account as change. The result
+// is a regular build template for /sign-transactions.
+func (bcr *BlockchainReactor) buildPoolPayout(ctx context.Context, in struct {
+	AccountID string `json:"account_id"`
+	Scheme    string `json:"scheme"`
+	Amount    uint64 `json:"amount"`
+}) (*txbuilder.Template, error) {
+	if bcr.accounts == nil {
+		return nil, errors.New("pool payouts require the wallet")
+	}
+
+	payouts, err := bcr.poolPayouts(struct {
+		Scheme string `json:"scheme"`
+		Amount uint64 `json:"amount"`
+	}{Scheme: in.Scheme, Amount: in.Amount})
+	if err != nil {
+		return nil, err
+	}
+	if len(payouts) == 0 {
+		return nil, errors.New("no shares recorded")
+	}
+
+	spent := uint64(0)
+	actions := []txbuilder.Action{}
+	for _, payout := range payouts {
+		if payout.Amount == 0 {
+			continue
+		}
+		if _, err := hex.DecodeString(payout.Worker); err != nil {
+			return nil, errors.WithDetailf(err, "worker %q is not a hex control program", payout.Worker)
+		}
+
+		raw, err := json.Marshal(map[string]interface{}{
+			"asset_id":        consensus.BTMAssetID.String(),
+			"amount":          payout.Amount,
+			"control_program": payout.Worker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		action, err := txbuilder.DecodeControlProgramAction(raw)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+		spent += payout.Amount
+	}
+
+	raw, err := json.Marshal(map[string]interface{}{
+		"account_id": in.AccountID,
+		"asset_id":   consensus.BTMAssetID.String(),
+		"amount":     spent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	spend, err := bcr.accounts.DecodeSpendAction(raw)
+	if err != nil {
+		return nil, err
+	}
+	actions = append(actions, spend)
+
+	return txbuilder.Build(ctx, nil, actions, time.Now().Add(defaultTxTTL))
+}
@@ -0,0 +1,245 @@
+package blockchain
+
+import (
+	"encoding/hex"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/vm"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+// default run limit for ad-hoc program execution, matching the limit
+// validation grants a transaction out of thin air.
+const defaultProgramGasLimit = int64(80000)
+
+// ProgramTraceStep is one executed opcode with the machine state after
+// it: the stack top is the last element.
+type ProgramTraceStep struct {
+	PC      uint32   `json:"pc"`
+	Op      string   `json:"op"`
+	Data    string   `json:"data,omitempty"`
+	GasCost int64    `json:"gas_cost"`
+	GasLeft int64    `json:"gas_left"`
+	Stack   []string `json:"stack"`
+}
+
+// ProgramTrace is the full step-by-step record of one program run.
+type ProgramTrace struct {
+	Success bool                `json:"success"`
+	Error   string              `json:"error,omitempty"`
+	GasUsed int64               `json:"gas_used"`
+	GasLeft int64               `json:"gas_left"`
+	Steps   []*ProgramTraceStep `json:"steps"`
+}
+
+// programContext builds a bare VM context for running a program outside
+// any transaction. Opcodes that introspect an absent field fail with
+// ErrContext, which the trace makes visible.
+func programContext(program string, args []string, blockHeight uint64) (*vm.Context, error) {
+	code, err := hex.DecodeString(program)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding program")
+	}
+	arguments := make([][]byte, 0, len(args))
+	for i, arg := range args {
+		data, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding argument %d", i)
+		}
+		arguments = append(arguments, data)
+	}
+
+	context := &vm.Context{
+		VMVersion: 1,
+		Code:      code,
+		Arguments: arguments,
+	}
+	if blockHeight > 0 {
+		context.BlockHeight = &blockHeight
+	}
+	return context, nil
+}
+
+// ProgramInstruction is one decoded opcode with its push data.
+type ProgramInstruction struct {
+	PC   uint32 `json:"pc"`
+	Op   string `json:"op"`
+	Data string `json:"data,omitempty"`
+}
+
+// DecodedProgram is the human-readable form of a program: its opcodes,
+// a one-line disassembly and, when the program matches a standard
+// template, what that template is.
+type DecodedProgram struct {
+	Disassembly  string                `json:"disassembly"`
+	Instructions []*ProgramInstruction `json:"instructions"`
+	Template     string                `json:"template,omitempty"`
+	Pubkeys      []string              `json:"pubkeys,omitempty"`
+	Quorum       int                   `json:"quorum,omitempty"`
+}
+
+// recognizeTemplate identifies the standard templates the repo can
+// construct: single-key (P2PKH-style) and multisig spend programs, and
+// provably unspendable programs.
+func recognizeTemplate(code []byte, decoded *DecodedProgram) {
+	if vmutil.IsUnspendable(code) {
+		decoded.Template = "unspendable"
+		return
+	}
+	if pubkeys, quorum, err := vmutil.ParseP2SPMultiSigProgram(code); err == nil {
+		if quorum == 1 && len(pubkeys) == 1 {
+			decoded.Template = "p2pkh"
+		} else {
+			decoded.Template = "multisig"
+		}
+		for _, pubkey := range pubkeys {
+			decoded.Pubkeys = append(decoded.Pubkeys, hex.EncodeToString(pubkey))
+		}
+		decoded.Quorum = quorum
+	}
+}
+
+// decodeProgram disassembles hex program bytes into readable opcodes
+// with push data annotated, identifying recognized standard templates
+// along the way. Explorers use it to label outputs.
+func (bcr *BlockchainReactor) decodeProgram(in struct {
+	Program string `json:"program"`
+}) (*DecodedProgram, error) {
+	code, err := hex.DecodeString(in.Program)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding program")
+	}
+
+	insts, err := vm.ParseProgram(code)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing program")
+	}
+	disassembly, err := vm.Disassemble(code)
+	if err != nil {
+		return nil, errors.Wrap(err, "disassembling program")
+	}
+
+	decoded := &DecodedProgram{
+		Disassembly:  disassembly,
+		Instructions: make([]*ProgramInstruction, 0, len(insts)),
+	}
+	pc := uint32(0)
+	for _, inst := range insts {
+		decoded.Instructions = append(decoded.Instructions, &ProgramInstruction{
+			PC:   pc,
+			Op:   inst.Op.String(),
+			Data: hex.EncodeToString(inst.Data),
+		})
+		pc += inst.Len
+	}
+	recognizeTemplate(code, decoded)
+	return decoded, nil
+}
+
+// upper bound granted to a dynamic estimation run, so a runaway
+// program still terminates.
+const estimateGasCeiling = int64(10000000)
+
+// GasEstimate reports what running a program is expected to cost.
+// The static estimate is a cheap instruction-walk upper bound; the
+// dynamic estimate is the gas an actual run with the given witnesses
+// consumed.
+type GasEstimate struct {
+	Instructions    int    `json:"instructions"`
+	StaticEstimate  int64  `json:"static_estimate"`
+	DynamicEstimate int64  `json:"dynamic_estimate"`
+	Success         bool   `json:"success"`
+	Error           string `json:"error,omitempty"`
+}
+
+// estimateProgramGas estimates the run cost of a program with the given
+// witness arguments, so builders can set an appropriate gas budget
+// before broadcasting instead of discovering failures by trial.
+func (bcr *BlockchainReactor) estimateProgramGas(in struct {
+	Program     string   `json:"program"`
+	Args        []string `json:"args"`
+	BlockHeight uint64   `json:"block_height"`
+}) (*GasEstimate, error) {
+	context, err := programContext(in.Program, in.Args, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	// Static pass: every instruction costs at least 1, pushes cost
+	// 8 plus the pushed length, and the initial arguments are pushed
+	// before the program runs.
+	insts, err := vm.ParseProgram(context.Code)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing program")
+	}
+	static := int64(0)
+	for _, inst := range insts {
+		static++
+		if len(inst.Data) > 0 {
+			static += 8 + int64(len(inst.Data))
+		}
+	}
+	for _, arg := range context.Arguments {
+		static += 8 + int64(len(arg))
+	}
+
+	estimate := &GasEstimate{
+		Instructions:   len(insts),
+		StaticEstimate: static,
+	}
+
+	// Dynamic pass: run the program and report what it really used.
+	gasLeft, runErr := vm.Verify(context, estimateGasCeiling)
+	estimate.DynamicEstimate = estimateGasCeiling - gasLeft
+	estimate.Success = runErr == nil
+	if runErr != nil {
+		estimate.Error = runErr.Error()
+	}
+	return estimate, nil
+}
+
+// traceProgram executes a control program against the supplied
+// arguments and returns every step the VM took: opcode, gas and the
+// stack after it. Contract authors use it to debug failed witnesses.
+func (bcr *BlockchainReactor) traceProgram(in struct {
+	Program     string   `json:"program"`
+	Args        []string `json:"args"`
+	GasLimit    int64    `json:"gas_limit"`
+	BlockHeight uint64   `json:"block_height"`
+}) (*ProgramTrace, error) {
+	context, err := programContext(in.Program, in.Args, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	gasLimit := in.GasLimit
+	if gasLimit <= 0 {
+		gasLimit = defaultProgramGasLimit
+	}
+
+	steps, gasLeft, runErr := vm.Trace(context, gasLimit)
+	trace := &ProgramTrace{
+		Success: runErr == nil,
+		GasUsed: gasLimit - gasLeft,
+		GasLeft: gasLeft,
+		Steps:   make([]*ProgramTraceStep, 0, len(steps)),
+	}
+	if runErr != nil {
+		trace.Error = runErr.Error()
+	}
+
+	for _, step := range steps {
+		stack := make([]string, 0, len(step.Stack))
+		for _, data := range step.Stack {
+			stack = append(stack, hex.EncodeToString(data))
+		}
+		trace.Steps = append(trace.Steps, &ProgramTraceStep{
+			PC:      step.PC,
+			Op:      step.Op.String(),
+			Data:    hex.EncodeToString(step.Data),
+			GasCost: step.GasCost,
+			GasLeft: step.GasLeft,
+			Stack:   stack,
+		})
+	}
+	return trace, nil
+}
@@ -1,13 +1,14 @@
 package blockchain
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/bytom/blockchain/account"
-	"github.com/bytom/blockchain/query"
 	"github.com/bytom/blockchain/wallet"
 	log "github.com/sirupsen/logrus"
 )
@@ -25,6 +26,35 @@ var (
 	}`
 )
 
+// bufferPool recycles the scratch buffers the list endpoints render
+// their responses into; these endpoints dominate CPU profiles on busy
+// nodes and the per-row Sprintf garbage was measurable.
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// begin/append/finishStringArray stream a JSON array of pre-rendered
+// string rows into buf, so only the final copy in finishStringArray
+// allocates. The rows themselves are built in pooled buffers by the
+// callers.
+func beginStringArray(buf *bytes.Buffer) *json.Encoder {
+	buf.WriteByte('[')
+	return json.NewEncoder(buf)
+}
+
+func appendStringRow(buf *bytes.Buffer, enc *json.Encoder, first bool, row string) {
+	if !first {
+		buf.WriteByte(',')
+	}
+	enc.Encode(row)
+	// Encode terminates every value with a newline; the array needs
+	// commas instead.
+	buf.Truncate(buf.Len() - 1)
+}
+
+func finishStringArray(buf *bytes.Buffer) json.RawMessage {
+	buf.WriteByte(']')
+	return json.RawMessage(append([]byte(nil), buf.Bytes()...))
+}
+
 //
 // POST /list-accounts
 func (bcr *BlockchainReactor) listAccounts(ctx context.Context, in requestQuery) interface{} {
@@ -48,22 +78,20 @@ func (bcr *BlockchainReactor) listAssets(ctx context.Context, in requestQuery) i
 //GetAccountUTXOs return all account unspent outputs
 func (bcr *BlockchainReactor) GetAccountUTXOs() []account.UTXO {
 
-	var (
-		accountUTXO  = account.UTXO{}
-		accountUTXOs = make([]account.UTXO, 0)
-	)
+	accountUTXOs := make([]account.UTXO, 0)
 
-	accountUTXOIter := bcr.wallet.DB.IteratorPrefix([]byte(account.UTXOPreFix))
+	accountUTXOIter := bcr.wallet.DB.IteratorPrefix([]byte(account.UTXONS))
 	defer accountUTXOIter.Release()
 	for accountUTXOIter.Next() {
 
-		if err := json.Unmarshal(accountUTXOIter.Value(), &accountUTXO); err != nil {
-			hashKey := accountUTXOIter.Key()[len(account.UTXOPreFix):]
+		accountUTXO, err := account.DecodeUTXO(accountUTXOIter.Value())
+		if err != nil {
+			hashKey := accountUTXOIter.Key()[len(account.UTXONS):]
 			log.WithField("UTXO hash", string(hashKey)).Warn("get account UTXO")
 			continue
 		}
 
-		accountUTXOs = append(accountUTXOs, accountUTXO)
+		accountUTXOs = append(accountUTXOs, *accountUTXO)
 	}
 
 	return accountUTXOs
@@ -77,7 +105,6 @@ func (bcr *BlockchainReactor) listBalances(ctx context.Context, in requestQuery)
 
 	accountUTXOs := bcr.GetAccountUTXOs()
 	accBalance := make(map[string]map[string]uint64)
-	response := make([]string, 0)
 
 	for _, accountUTXO := range accountUTXOs {
 
@@ -99,7 +126,14 @@ func (bcr *BlockchainReactor) listBalances(ctx context.Context, in requestQuery)
 	}
 	sort.Strings(sortedAccount)
 
-	for _, account := range sortedAccount {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	scratch := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	defer bufferPool.Put(scratch)
+
+	enc := beginStringArray(buf)
+	for i, account := range sortedAccount {
 		sortedAsset := []string{}
 		for k := range accBalance[account] {
 			sortedAsset = append(sortedAsset, k)
@@ -111,12 +145,17 @@ func (bcr *BlockchainReactor) listBalances(ctx context.Context, in requestQuery)
 			assetAmounts = append(assetAmounts, assetAmount{AssetID: asset, Amount: accBalance[account][asset]})
 		}
 
-		balanceString, _ := json.Marshal(assetAmounts)
-		accBalancesString := fmt.Sprintf(`{"AccountID":"%s","Balances":"%s"}`, account, balanceString)
-		response = append(response, accBalancesString)
+		scratch.Reset()
+		scratch.WriteString(`{"AccountID":"`)
+		scratch.WriteString(account)
+		scratch.WriteString(`","Balances":"`)
+		json.NewEncoder(scratch).Encode(assetAmounts)
+		scratch.Truncate(scratch.Len() - 1)
+		scratch.WriteString(`"}`)
+		appendStringRow(buf, enc, i == 0, scratch.String())
 	}
 
-	return response
+	return finishStringArray(buf)
 }
 
 // listTransactions is an http handler for listing transactions
@@ -126,19 +165,25 @@ func (bcr *BlockchainReactor) listTransactions(ctx context.Context, in requestQu
 
 	var response = Response{Status: SUCCESS}
 	annotatedTxs := make([]string, 0)
-	annotatedTx := &query.AnnotatedTx{}
 
 	txIter := bcr.wallet.DB.IteratorPrefix([]byte(wallet.TxPreFix))
 	defer txIter.Release()
 
+	// stored records are compact protobuf; they become JSON only here,
+	// at the API boundary
 	for txIter.Next() {
-		if err := json.Unmarshal(txIter.Value(), annotatedTx); err != nil {
-			response.Status = FAIL
-			response.Msg = err.Error()
-			log.WithField("err", err).Error("failed get annotatedTx")
-			break
+		annotatedTx, err := wallet.DecodeAnnotatedTx(txIter.Value())
+		if err == nil {
+			var rawTx []byte
+			if rawTx, err = json.Marshal(annotatedTx); err == nil {
+				annotatedTxs = append(annotatedTxs, string(rawTx))
+				continue
+			}
 		}
-		annotatedTxs = append(annotatedTxs, string(txIter.Value()))
+		response.Status = FAIL
+		response.Msg = err.Error()
+		log.WithField("err", err).Error("failed get annotatedTx")
+		break
 	}
 
 	response.Data = annotatedTxs
@@ -151,25 +196,35 @@ func (bcr *BlockchainReactor) listTransactions(ctx context.Context, in requestQu
 	return rawResponse
 }
 
+// walletInfo reports the indexer position so operators can watch a
+// catch-up after long downtime.
+//
+// POST /wallet-info
+func (bcr *BlockchainReactor) walletInfo(ctx context.Context) interface{} {
+	return bcr.wallet.Progress(bcr.chain)
+}
+
 // POST /list-unspent-outputs
 func (bcr *BlockchainReactor) listUnspentOutputs(ctx context.Context, in requestQuery) interface{} {
 
-	var (
-		response = make([]string, 0)
-		restring = ""
-	)
-
 	accountUTXOs := bcr.GetAccountUTXOs()
 
-	for _, res := range accountUTXOs {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	scratch := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	defer bufferPool.Put(scratch)
 
-		restring = fmt.Sprintf(accountUTXOFmt,
+	enc := beginStringArray(buf)
+	for i, res := range accountUTXOs {
+		scratch.Reset()
+		fmt.Fprintf(scratch, accountUTXOFmt,
 			res.OutputID, res.AssetID, res.Amount,
 			res.AccountID, res.ProgramIndex, res.Program,
 			res.SourceID, res.SourcePos, res.RefData, res.Change)
 
-		response = append(response, restring)
+		appendStringRow(buf, enc, i == 0, scratch.String())
 	}
 
-	return response
+	return finishStringArray(buf)
 }
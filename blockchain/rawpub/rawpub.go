@@ -0,0 +1,168 @@
+// Package rawpub streams raw block and transaction notifications to
+// connected subscribers, mirroring the zmqpub integration pattern other
+// chains expose: the topics rawblock, rawtx, hashblock and hashtx, each
+// with its own monotonically increasing sequence number so subscribers
+// can detect dropped messages. The transport is a plain tcp:// or
+// unix:// listener; every subscriber receives every topic and filters
+// client-side, just like a ZMQ SUB socket with an empty prefix.
+//
+// Wire format, per message:
+//
+//	1 byte   topic length
+//	n bytes  topic
+//	4 bytes  payload length, big-endian
+//	n bytes  payload
+//	4 bytes  topic sequence number, little-endian
+package rawpub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// Topic names, matching the convention exchanges already integrate with.
+const (
+	TopicRawBlock  = "rawblock"
+	TopicRawTx     = "rawtx"
+	TopicHashBlock = "hashblock"
+	TopicHashTx    = "hashtx"
+)
+
+// ErrBadAddr means the listen address is not tcp:// or unix://.
+var ErrBadAddr = errors.New("invalid raw notification address")
+
+// Publisher fans raw notifications out to every connected subscriber.
+// A slow or dead subscriber is disconnected rather than allowed to
+// block the others.
+type Publisher struct {
+	listener net.Listener
+
+	mtx   sync.Mutex
+	conns map[net.Conn]bool
+	seq   map[string]uint32
+}
+
+// NewPublisher starts listening on addr, which must look like
+// tcp://host:port or unix:///path.
+func NewPublisher(addr string) (*Publisher, error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) != 2 || (parts[0] != "tcp" && parts[0] != "unix") {
+		return nil, errors.WithDetailf(ErrBadAddr, "address %q must be tcp://host:port or unix:///path", addr)
+	}
+	listener, err := net.Listen(parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Publisher{
+		listener: listener,
+		conns:    map[net.Conn]bool{},
+		seq:      map[string]uint32{},
+	}
+	go p.acceptLoop()
+	log.WithField("addr", addr).Info("Raw notification publisher listening")
+	return p, nil
+}
+
+func (p *Publisher) acceptLoop() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		p.mtx.Lock()
+		p.conns[conn] = true
+		p.mtx.Unlock()
+	}
+}
+
+// Close stops the listener and disconnects every subscriber.
+func (p *Publisher) Close() {
+	p.listener.Close()
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = map[net.Conn]bool{}
+}
+
+// PublishBlock emits hashblock and rawblock for a block connected to
+// the main chain.
+func (p *Publisher) PublishBlock(block *legacy.Block) {
+	hash := block.Hash()
+	p.publish(TopicHashBlock, hash.Bytes())
+
+	var buf bytes.Buffer
+	if _, err := block.WriteTo(&buf); err != nil {
+		log.WithFields(log.Fields{"height": block.Height, "error": err}).Error("serialize block for raw notification")
+		return
+	}
+	p.publish(TopicRawBlock, buf.Bytes())
+}
+
+// PublishTx emits hashtx and rawtx for a transaction accepted into the
+// mempool.
+func (p *Publisher) PublishTx(tx *legacy.Tx) {
+	p.publish(TopicHashTx, tx.Tx.ID.Bytes())
+
+	var buf bytes.Buffer
+	if _, err := tx.TxData.WriteTo(&buf); err != nil {
+		log.WithFields(log.Fields{"tx_id": tx.Tx.ID.String(), "error": err}).Error("serialize tx for raw notification")
+		return
+	}
+	p.publish(TopicRawTx, buf.Bytes())
+}
+
+// FollowChain walks the main chain from the current tip and publishes
+// every newly connected block. Transactions are published straight from
+// the mempool by the reactor instead.
+func (p *Publisher) FollowChain(c *protocol.Chain) {
+	height := c.Height()
+	for {
+		<-c.BlockWaiter(height + 1)
+		height++
+		block, err := c.GetBlockByHeight(height)
+		if err != nil {
+			// the tip moved backwards during a reorg; catch up when the
+			// chain passes this height again
+			height--
+			continue
+		}
+		p.PublishBlock(block)
+	}
+}
+
+func (p *Publisher) publish(topic string, payload []byte) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if len(p.conns) == 0 {
+		return
+	}
+
+	seq := p.seq[topic]
+	p.seq[topic] = seq + 1
+
+	frame := make([]byte, 0, 1+len(topic)+4+len(payload)+4)
+	frame = append(frame, byte(len(topic)))
+	frame = append(frame, topic...)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = binary.LittleEndian.AppendUint32(frame, seq)
+
+	for conn := range p.conns {
+		if _, err := conn.Write(frame); err != nil {
+			conn.Close()
+			delete(p.conns, conn)
+		}
+	}
+}
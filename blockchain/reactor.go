@@ -2,23 +2,34 @@ package blockchain
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
 	"time"
 
+	"github.com/golang/groupcache/lru"
 	log "github.com/sirupsen/logrus"
 	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
 
 	"github.com/bytom/blockchain/accesstoken"
 	"github.com/bytom/blockchain/account"
 	"github.com/bytom/blockchain/asset"
+	"github.com/bytom/blockchain/deposit"
+	"github.com/bytom/blockchain/explorer"
+	"github.com/bytom/blockchain/peg"
 	"github.com/bytom/blockchain/pseudohsm"
+	"github.com/bytom/blockchain/rawpub"
+	"github.com/bytom/blockchain/txdb"
 	"github.com/bytom/blockchain/txfeed"
 	"github.com/bytom/blockchain/wallet"
+	"github.com/bytom/blockchain/webhook"
 	"github.com/bytom/encoding/json"
 	"github.com/bytom/errors"
 	"github.com/bytom/mining/cpuminer"
+	"github.com/bytom/mining/stratum"
 	"github.com/bytom/p2p"
 	"github.com/bytom/protocol"
 	"github.com/bytom/protocol/bc/legacy"
@@ -34,6 +45,12 @@ const (
 	statusUpdateIntervalSeconds = 10
 	maxBlockchainResponseSize   = 22020096 + 2
 	crosscoreRPCPrefix          = "/rpc/"
+
+	// maximum messages one peer may send to us during `msgCountFlushInterval`
+	maxMsgCountByPeer     = 2000
+	msgCountFlushInterval = 10 * time.Minute
+	// recently announced inventory remembered per peer for de-duplication
+	maxKnownInvPerPeer = 1024
 )
 
 const (
@@ -52,7 +69,7 @@ type Response struct {
 // DefaultRawResponse is used as the default response when fail to get data.
 var DefaultRawResponse = []byte(`{"Status":"error","Msg":"Unable to get data","Data":null}`)
 
-//BlockchainReactor handles long-term catchup syncing.
+// BlockchainReactor handles long-term catchup syncing.
 type BlockchainReactor struct {
 	p2p.BaseReactor
 
@@ -63,6 +80,7 @@ type BlockchainReactor struct {
 	accessTokens  *accesstoken.CredentialStore
 	txFeedTracker *txfeed.Tracker
 	blockKeeper   *blockKeeper
+	proofKeeper   *proofKeeper // routes SPV inclusion proofs; see spv.go
 	txPool        *protocol.TxPool
 	hsm           *pseudohsm.HSM
 	mining        *cpuminer.CPUMiner
@@ -71,6 +89,58 @@ type BlockchainReactor struct {
 	handler       http.Handler
 	evsw          types.EventSwitch
 	miningEnable  bool
+	snapshotStore *txdb.Store
+	stratumSrv    *stratum.Server
+	allowGenerate bool
+
+	// the node's databases by name, for maintenance APIs
+	databases map[string]dbm.DB
+
+	// tears the whole node down; set by the node, fired by the stop API
+	shutdownFn func()
+
+	// re-reads and applies the reloadable config subset; set by the
+	// node, fired by the reload-config API
+	reloadFn func() error
+
+	// test-network coin faucet; nil when disabled. see faucet.go
+	faucet *faucet
+
+	// chain-wide explorer indexes; nil when disabled. see explorer_api.go
+	explorer *explorer.Explorer
+
+	// event callbacks to subscriber HTTP endpoints; see webhooks.go
+	webhooks *webhook.Manager
+
+	// raw block/tx notification stream; nil when disabled
+	rawPub *rawpub.Publisher
+
+	// deposits to externally registered watch addresses; see deposits.go
+	depositTracker *deposit.Tracker
+
+	// tracked hashed-timelock swaps; see htlc.go
+	swapDB dbm.DB
+
+	// federated peg state: lock federation, peg-ins, withdrawals; see
+	// peg_api.go
+	pegTracker *peg.Tracker
+
+	// serve the /debug profiling endpoints; see debug.go
+	debugEnabled bool
+
+	// when the reactor came up; the node-info API reports the uptime
+	startTime time.Time
+
+	// automatic mining pause when the chain looks stale
+	guardMtx         sync.Mutex
+	pauseStaleMinute int32
+	miningPaused     bool
+	pauseReason      string
+
+	// per-peer abuse protection
+	msgCountByPeer *cmn.CMap
+	knownInvMtx    sync.Mutex
+	knownInvByPeer map[string]*lru.Cache
 }
 
 func batchRecover(ctx context.Context, v *interface{}) {
@@ -165,6 +235,7 @@ func NewBlockchainReactor(chain *protocol.Chain, txPool *protocol.TxPool, accoun
 		accounts:      accounts,
 		assets:        assets,
 		blockKeeper:   newBlockKeeper(chain, sw),
+		proofKeeper:   newProofKeeper(),
 		txPool:        txPool,
 		mining:        mining,
 		mux:           http.NewServeMux(),
@@ -173,11 +244,82 @@ func NewBlockchainReactor(chain *protocol.Chain, txPool *protocol.TxPool, accoun
 		txFeedTracker: txfeeds,
 		accessTokens:  accessTokens,
 		miningEnable:  miningEnable,
+
+		msgCountByPeer: cmn.NewCMap(),
+		knownInvByPeer: make(map[string]*lru.Cache),
+		startTime:      time.Now(),
 	}
 	bcR.BaseReactor = *p2p.NewBaseReactor("BlockchainReactor", bcR)
 	return bcR
 }
 
+// RegisterSnapshotStore hands the reactor the raw chain store so the
+// snapshot export API can walk the UTXO set directly.
+func (bcR *BlockchainReactor) RegisterSnapshotStore(store *txdb.Store) {
+	bcR.snapshotStore = store
+}
+
+// RegisterStratumServer hands the reactor the stratum server so the
+// mining stats API can include external miner shares.
+func (bcR *BlockchainReactor) RegisterStratumServer(srv *stratum.Server) {
+	bcR.stratumSrv = srv
+}
+
+// RegisterShutdown hands the reactor a callback that stops the whole
+// node, so the stop API can trigger the same ordered teardown a signal
+// would.
+func (bcR *BlockchainReactor) RegisterShutdown(fn func()) {
+	bcR.shutdownFn = fn
+}
+
+// RegisterReload hands the reactor the node's config-reload routine,
+// so the reload-config API behaves exactly like SIGHUP.
+func (bcR *BlockchainReactor) RegisterReload(fn func() error) {
+	bcR.reloadFn = fn
+}
+
+// RegisterExplorer hands the reactor the explorer indexer, unlocking
+// the /explorer query APIs.
+func (bcR *BlockchainReactor) RegisterExplorer(e *explorer.Explorer) {
+	bcR.explorer = e
+}
+
+// RegisterWebhooks hands the reactor the webhook manager, unlocking the
+// webhook management APIs.
+func (bcR *BlockchainReactor) RegisterWebhooks(m *webhook.Manager) {
+	bcR.webhooks = m
+}
+
+// RegisterRawPublisher hands the reactor the raw notification
+// publisher; accepted mempool transactions are streamed to it.
+func (bcR *BlockchainReactor) RegisterRawPublisher(p *rawpub.Publisher) {
+	bcR.rawPub = p
+}
+
+// RegisterDepositTracker hands the reactor the deposit tracker,
+// unlocking the watch-address and deposit APIs.
+func (bcR *BlockchainReactor) RegisterDepositTracker(t *deposit.Tracker) {
+	bcR.depositTracker = t
+}
+
+// RegisterSwapStore hands the reactor the database holding pending
+// hashed-timelock swaps, unlocking the htlc APIs.
+func (bcR *BlockchainReactor) RegisterSwapStore(db dbm.DB) {
+	bcR.swapDB = db
+}
+
+// RegisterPegTracker hands the reactor the federated peg tracker,
+// unlocking the peg APIs.
+func (bcR *BlockchainReactor) RegisterPegTracker(t *peg.Tracker) {
+	bcR.pegTracker = t
+}
+
+// EnableBlockGeneration unlocks the generate-blocks API. It is only
+// called on test networks where mining blocks on demand is harmless.
+func (bcR *BlockchainReactor) EnableBlockGeneration() {
+	bcR.allowGenerate = true
+}
+
 // OnStart implements BaseService
 func (bcR *BlockchainReactor) OnStart() error {
 	bcR.BaseReactor.OnStart()
@@ -187,15 +329,18 @@ func (bcR *BlockchainReactor) OnStart() error {
 		bcR.mining.Start()
 	}
 	go bcR.syncRoutine()
+	go bcR.flushMsgCountRoutine()
+	go bcR.workNotifyRoutine()
+	go bcR.miningGuardRoutine()
 	return nil
 }
 
 // OnStop implements BaseService
 func (bcR *BlockchainReactor) OnStop() {
 	bcR.BaseReactor.OnStop()
-	if bcR.miningEnable {
-		bcR.mining.Stop()
-	}
+	// the miner may have been started over RPC regardless of the
+	// config flag, so always stop it
+	bcR.mining.Stop()
 	bcR.blockKeeper.Stop()
 }
 
@@ -218,12 +363,67 @@ func (bcR *BlockchainReactor) AddPeer(peer *p2p.Peer) {
 // RemovePeer implements Reactor by removing peer from the pool.
 func (bcR *BlockchainReactor) RemovePeer(peer *p2p.Peer, reason interface{}) {
 	bcR.blockKeeper.RemovePeer(peer.Key)
+	bcR.knownInvMtx.Lock()
+	delete(bcR.knownInvByPeer, peer.Key)
+	bcR.knownInvMtx.Unlock()
+}
+
+// incrementMsgCount counts a message from the peer and reports whether it
+// is still within the allowed budget for this flush interval.
+func (bcR *BlockchainReactor) incrementMsgCount(peerID string) bool {
+	var count uint32
+	if countI := bcR.msgCountByPeer.Get(peerID); countI != nil {
+		count = countI.(uint32)
+	}
+	count++
+	bcR.msgCountByPeer.Set(peerID, count)
+	return count <= maxMsgCountByPeer
+}
+
+// isDuplicateInv remembers announced inventory per peer and reports whether
+// the peer already sent us the identical announcement recently.
+func (bcR *BlockchainReactor) isDuplicateInv(peerID string, msgBytes []byte) bool {
+	invHash := sha256.Sum256(msgBytes)
+
+	bcR.knownInvMtx.Lock()
+	defer bcR.knownInvMtx.Unlock()
+
+	known, ok := bcR.knownInvByPeer[peerID]
+	if !ok {
+		known = lru.New(maxKnownInvPerPeer)
+		bcR.knownInvByPeer[peerID] = known
+	}
+	if _, ok := known.Get(invHash); ok {
+		return true
+	}
+	known.Add(invHash, struct{}{})
+	return false
+}
+
+func (bcR *BlockchainReactor) flushMsgCountRoutine() {
+	ticker := time.NewTicker(msgCountFlushInterval)
+	for {
+		select {
+		case <-ticker.C:
+			bcR.msgCountByPeer.Clear()
+		case <-bcR.Quit:
+			ticker.Stop()
+			return
+		}
+	}
 }
 
 // Receive implements Reactor by handling 4 types of messages (look below).
 func (bcR *BlockchainReactor) Receive(chID byte, src *p2p.Peer, msgBytes []byte) {
+	if !bcR.incrementMsgCount(src.Key) {
+		log.WithField("peer", src.Key).Error("Maximum number of messages reached for peer")
+		bcR.sw.StopPeerForError(src, errors.New("peer exceeded message rate limit"))
+		return
+	}
+
 	_, msg, err := DecodeMessage(msgBytes)
 	if err != nil {
+		p2p.RecordDecodeFailure(chID)
 		log.Errorf("Error decoding messagek %v", err)
 		return
 	}
@@ -253,6 +453,31 @@ func (bcR *BlockchainReactor) Receive(chID byte, src *p2p.Peer, msgBytes []byte)
 	case *BlockResponseMessage:
 		bcR.blockKeeper.AddBlock(msg.GetBlock(), src.Key)
 
+	case *HeadersRequestMessage:
+		count := msg.Count
+		if count > headersBatchSize {
+			count = headersBatchSize
+		}
+		headers := []*legacy.BlockHeader{}
+		for height := msg.FromHeight; height < msg.FromHeight+count; height++ {
+			block, err := bcR.chain.GetBlockByHeight(height)
+			if err != nil {
+				break
+			}
+			headers = append(headers, &block.BlockHeader)
+		}
+		response, err := NewHeadersResponseMessage(headers)
+		if err != nil {
+			log.Errorf("Fail on HeadersRequestMessage create response: %v", err)
+			return
+		}
+		src.TrySend(BlockchainChannel, struct{ BlockchainMessage }{response})
+
+	case *HeadersResponseMessage:
+		if headers := msg.GetHeaders(); headers != nil {
+			bcR.blockKeeper.AddHeaders(headers, src.Key)
+		}
+
 	case *StatusRequestMessage:
 		block := bcR.chain.BestBlock()
 		src.TrySend(BlockchainChannel, struct{ BlockchainMessage }{NewStatusResponseMessage(block)})
@@ -261,11 +486,32 @@ func (bcR *BlockchainReactor) Receive(chID byte, src *p2p.Peer, msgBytes []byte)
 		bcR.blockKeeper.SetPeerHeight(src.Key, msg.Height, msg.GetHash())
 
 	case *TransactionNotifyMessage:
+		if bcR.isDuplicateInv(src.Key, msgBytes) {
+			log.WithField("peer", src.Key).Debug("Ignore duplicate transaction announcement")
+			return
+		}
+		if bcR.chain.HeaderOnly() {
+			// a light node has no UTXO set to validate mempool
+			// transactions against
+			return
+		}
 		tx := msg.GetTransaction()
 		if err := bcR.chain.ValidateTx(tx); err != nil {
 			log.Errorf("TransactionNotifyMessage: %v", err)
 		}
 
+	case *TxProofRequestMessage:
+		txID := msg.GetTxID()
+		response := &TxProofResponseMessage{RawTxID: txID.Byte32()}
+		if block, _, proof, err := bcR.findTxInMainchain(*txID); err == nil {
+			blockHash := block.Hash()
+			response = NewTxProofResponseMessage(txID, block.Height, &blockHash, proof)
+		}
+		src.TrySend(BlockchainChannel, struct{ BlockchainMessage }{response})
+
+	case *TxProofResponseMessage:
+		bcR.proofKeeper.deliver(msg)
+
 	default:
 		log.Error(cmn.Fmt("Unknown message type %v", reflect.TypeOf(msg)))
 	}
@@ -282,6 +528,9 @@ func (bcR *BlockchainReactor) syncRoutine() {
 		select {
 		case newTx := <-newTxCh:
 			bcR.txFeedTracker.TxFilter(newTx)
+			if bcR.rawPub != nil {
+				bcR.rawPub.PublishTx(newTx)
+			}
 			go bcR.BroadcastTransaction(newTx)
 		case _ = <-statusUpdateTicker.C:
 			go bcR.BroadcastStatusResponse()
@@ -0,0 +1,25 @@
+package blockchain
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+)
+
+// reloadConfig re-reads the node's config file and applies the
+// reloadable subset, exactly as sending the process SIGHUP would.
+func (bcr *BlockchainReactor) reloadConfig() (*struct {
+	Msg string `json:"msg"`
+}, error) {
+	if bcr.reloadFn == nil {
+		return nil, errors.New("no reload hook registered")
+	}
+
+	log.Info("Config reload requested over RPC")
+	if err := bcr.reloadFn(); err != nil {
+		return nil, err
+	}
+	return &struct {
+		Msg string `json:"msg"`
+	}{Msg: "config reloaded"}, nil
+}
@@ -0,0 +1,136 @@
+// Package replication streams validated blocks from a primary bytomd to
+// read-replica nodes, so heavy explorer and wallet query traffic can be
+// served off the validating node. A replica connects to the primary's
+// replication listener, announces its current height, and receives every
+// main chain block from there on; it applies them through the regular
+// block processing path, which means the wallet, explorer and the other
+// chain followers on the replica index the replicated blocks exactly as
+// they would index locally synced ones.
+//
+// Wire format: the replica opens with its current height as 8 bytes
+// big-endian; the primary then sends frames of a 4-byte big-endian
+// length followed by the hex-serialized block.
+package replication
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+)
+
+// ErrBadAddr means the address is not tcp:// or unix://.
+var ErrBadAddr = errors.New("invalid replication address")
+
+// maxFrameSize caps a block frame; anything larger means a corrupt or
+// hostile stream.
+const maxFrameSize = 1 << 26 // 64 MB of hex, well above any valid block
+
+func splitAddr(addr string) (network, laddr string, err error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) != 2 || (parts[0] != "tcp" && parts[0] != "unix") {
+		return "", "", errors.WithDetailf(ErrBadAddr, "address %q must be tcp://host:port or unix:///path", addr)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Primary streams blocks to every connected replica.
+type Primary struct {
+	listener net.Listener
+	chain    *protocol.Chain
+}
+
+// NewPrimary starts the replication listener on addr, which must look
+// like tcp://host:port or unix:///path.
+func NewPrimary(addr string, chain *protocol.Chain) (*Primary, error) {
+	network, laddr, err := splitAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Primary{listener: listener, chain: chain}
+	go p.acceptLoop()
+	log.WithField("addr", addr).Info("Replication primary listening")
+	return p, nil
+}
+
+func (p *Primary) acceptLoop() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.serveReplica(conn)
+	}
+}
+
+// Close stops accepting replicas; streams in flight end on their next
+// write.
+func (p *Primary) Close() {
+	p.listener.Close()
+}
+
+// serveReplica walks the main chain from the replica's height and
+// streams every block, following the follower pattern: when a reorg
+// moves a sent height off the main chain, the cursor steps back and the
+// replacement blocks are re-sent, and the replica deduplicates blocks
+// it already knows.
+func (p *Primary) serveReplica(conn net.Conn) {
+	defer conn.Close()
+
+	var handshake [8]byte
+	if _, err := io.ReadFull(conn, handshake[:]); err != nil {
+		return
+	}
+	height := binary.BigEndian.Uint64(handshake[:])
+	log.WithFields(log.Fields{"addr": conn.RemoteAddr(), "height": height}).Info("replica connected")
+
+	var lastHash *bc.Hash
+	for {
+		// when a reorg moved the last sent block off the main chain,
+		// walk the stale branch back to the fork point and resend from
+		// there; the replica deduplicates blocks it already knows
+		if lastHash != nil && !p.chain.InMainChain(height, *lastHash) {
+			stale, err := p.chain.GetBlockByHash(lastHash)
+			if err != nil {
+				log.WithFields(log.Fields{"hash": lastHash.String(), "error": err}).Error("replication get stale block")
+				return
+			}
+			height = stale.Height - 1
+			lastHash = &stale.PreviousBlockHash
+			continue
+		}
+
+		block, err := p.chain.GetBlockByHeight(height + 1)
+		if err != nil {
+			<-p.chain.BlockWaiter(height + 1)
+			continue
+		}
+
+		raw, err := block.MarshalText()
+		if err != nil {
+			log.WithFields(log.Fields{"height": block.Height, "error": err}).Error("serialize block for replication")
+			return
+		}
+		frame := make([]byte, 4, 4+len(raw))
+		binary.BigEndian.PutUint32(frame, uint32(len(raw)))
+		frame = append(frame, raw...)
+		if _, err := conn.Write(frame); err != nil {
+			return
+		}
+
+		hash := block.Hash()
+		lastHash = &hash
+		height = block.Height
+	}
+}
@@ -0,0 +1,94 @@
+package replication
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// errBadFrame means the primary sent a frame outside the size bounds.
+var errBadFrame = errors.New("replication frame size out of range")
+
+// reconnectInterval is how long a replica waits before redialing the
+// primary after a broken stream.
+const reconnectInterval = 5 * time.Second
+
+// Replica follows a primary's replication stream and applies the
+// received blocks through the regular block processing path.
+type Replica struct {
+	addr  string
+	chain *protocol.Chain
+}
+
+// NewReplica prepares a replica of the primary at addr, which must look
+// like tcp://host:port or unix:///path.
+func NewReplica(addr string, chain *protocol.Chain) (*Replica, error) {
+	if _, _, err := splitAddr(addr); err != nil {
+		return nil, err
+	}
+	return &Replica{addr: addr, chain: chain}, nil
+}
+
+// Run connects to the primary and applies its stream, redialing with
+// the current height whenever the connection breaks. It never returns.
+func (r *Replica) Run() {
+	for {
+		if err := r.stream(); err != nil {
+			log.WithFields(log.Fields{"primary": r.addr, "error": err}).Error("replication stream broken; reconnecting")
+		}
+		time.Sleep(reconnectInterval)
+	}
+}
+
+func (r *Replica) stream() error {
+	network, raddr, err := splitAddr(r.addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.Dial(network, raddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var handshake [8]byte
+	binary.BigEndian.PutUint64(handshake[:], r.chain.Height())
+	if _, err := conn.Write(handshake[:]); err != nil {
+		return err
+	}
+	log.WithField("primary", r.addr).Info("replicating from primary")
+
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size == 0 || size > maxFrameSize {
+			return errBadFrame
+		}
+		raw := make([]byte, size)
+		if _, err := io.ReadFull(conn, raw); err != nil {
+			return err
+		}
+
+		block := &legacy.Block{}
+		if err := block.UnmarshalText(raw); err != nil {
+			return err
+		}
+		// ProcessBlock validates, deduplicates and handles reorgs the
+		// same way locally synced blocks are handled, so every chain
+		// follower on this node (wallet, explorer, trackers) indexes
+		// the block as usual
+		if _, err := r.chain.ProcessBlock(block); err != nil {
+			log.WithFields(log.Fields{"height": block.Height, "error": err}).Error("apply replicated block")
+		}
+	}
+}
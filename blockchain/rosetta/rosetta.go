@@ -0,0 +1,178 @@
+// Package rosetta maps bytom blocks, transactions and balances onto the
+// Rosetta API data model, so exchanges and custodians with Rosetta
+// tooling can integrate without bespoke code. Accounts are identified by
+// their control program in hex; BTM amounts are denominated in the
+// chain's base unit with 8 decimals, other assets by their asset id
+// with 0 decimals.
+//
+// The node serves the Data API (network, block, account balance) and
+// the transaction-carrying half of the Construction API (derive, hash,
+// submit) under /rosetta/; building and signing transactions stays with
+// the node's own build/sign-transactions APIs. Errors use the node's
+// standard error envelope.
+package rosetta
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// Blockchain is the Rosetta blockchain identifier bytomd reports.
+const Blockchain = "bytom"
+
+// OpStatusSuccess is the only operation status: bytom blocks carry no
+// failed operations.
+const OpStatusSuccess = "SUCCESS"
+
+// Operation types: value leaving a control program and value arriving.
+const (
+	OpTypeInput  = "INPUT"
+	OpTypeOutput = "OUTPUT"
+)
+
+// NetworkIdentifier names one chain, e.g. {"bytom", "mainnet"}.
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+// Network returns the identifier of the chain this node follows.
+func Network() NetworkIdentifier {
+	return NetworkIdentifier{Blockchain: Blockchain, Network: consensus.ActiveNetParams.Name}
+}
+
+// BlockIdentifier is a (height, hash) pair.
+type BlockIdentifier struct {
+	Index int64  `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// PartialBlockIdentifier is a block request by height or hash; both
+// fields are optional and an empty identifier means the current tip.
+type PartialBlockIdentifier struct {
+	Index *int64  `json:"index,omitempty"`
+	Hash  *string `json:"hash,omitempty"`
+}
+
+// TransactionIdentifier wraps a transaction hash.
+type TransactionIdentifier struct {
+	Hash string `json:"hash"`
+}
+
+// AccountIdentifier wraps an account address: the control program hex.
+type AccountIdentifier struct {
+	Address string `json:"address"`
+}
+
+// Currency names an asset and its decimal scale.
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// Amount is a signed value of one currency, as a base-unit string.
+type Amount struct {
+	Value    string   `json:"value"`
+	Currency Currency `json:"currency"`
+}
+
+// OperationIdentifier is the index of an operation in its transaction.
+type OperationIdentifier struct {
+	Index int64 `json:"index"`
+}
+
+// Operation is one balance change within a transaction.
+type Operation struct {
+	OperationIdentifier OperationIdentifier `json:"operation_identifier"`
+	Type                string              `json:"type"`
+	Status              string              `json:"status"`
+	Account             AccountIdentifier   `json:"account"`
+	Amount              Amount              `json:"amount"`
+}
+
+// Transaction is a transaction with its balance-changing operations.
+type Transaction struct {
+	TransactionIdentifier TransactionIdentifier `json:"transaction_identifier"`
+	Operations            []*Operation          `json:"operations"`
+}
+
+// Block is one block in the Rosetta model. Timestamps are milliseconds,
+// which is the chain's native block timestamp unit already.
+type Block struct {
+	BlockIdentifier       BlockIdentifier `json:"block_identifier"`
+	ParentBlockIdentifier BlockIdentifier `json:"parent_block_identifier"`
+	Timestamp             uint64          `json:"timestamp"`
+	Transactions          []*Transaction  `json:"transactions"`
+}
+
+// CurrencyForAsset maps an asset id to its Rosetta currency.
+func CurrencyForAsset(assetID string) Currency {
+	if assetID == consensus.BTMAssetID.String() {
+		return Currency{Symbol: "BTM", Decimals: 8}
+	}
+	return Currency{Symbol: assetID, Decimals: 0}
+}
+
+func amount(assetID string, value int64) Amount {
+	return Amount{Value: strconv.FormatInt(value, 10), Currency: CurrencyForAsset(assetID)}
+}
+
+// ConvertTx maps a transaction to Rosetta operations: one negative
+// INPUT per spend and one positive OUTPUT per output. Issuance and
+// coinbase inputs debit no account and carry no operation, matching how
+// the explorer accounts balances.
+func ConvertTx(tx *legacy.Tx) *Transaction {
+	converted := &Transaction{
+		TransactionIdentifier: TransactionIdentifier{Hash: tx.Tx.ID.String()},
+		Operations:            []*Operation{},
+	}
+	index := int64(0)
+	for _, input := range tx.Inputs {
+		program := input.ControlProgram()
+		if program == nil {
+			continue
+		}
+		assetID := input.AssetID()
+		converted.Operations = append(converted.Operations, &Operation{
+			OperationIdentifier: OperationIdentifier{Index: index},
+			Type:                OpTypeInput,
+			Status:              OpStatusSuccess,
+			Account:             AccountIdentifier{Address: hex.EncodeToString(program)},
+			Amount:              amount(assetID.String(), -int64(input.Amount())),
+		})
+		index++
+	}
+	for _, output := range tx.Outputs {
+		converted.Operations = append(converted.Operations, &Operation{
+			OperationIdentifier: OperationIdentifier{Index: index},
+			Type:                OpTypeOutput,
+			Status:              OpStatusSuccess,
+			Account:             AccountIdentifier{Address: hex.EncodeToString(output.ControlProgram)},
+			Amount:              amount(output.AssetId.String(), int64(output.Amount)),
+		})
+		index++
+	}
+	return converted
+}
+
+// ConvertBlock maps a block and its transactions. The genesis block is
+// its own parent, as the Rosetta model requires.
+func ConvertBlock(block *legacy.Block) *Block {
+	hash := block.Hash()
+	converted := &Block{
+		BlockIdentifier:       BlockIdentifier{Index: int64(block.Height), Hash: hash.String()},
+		ParentBlockIdentifier: BlockIdentifier{Index: int64(block.Height) - 1, Hash: block.PreviousBlockHash.String()},
+		Timestamp:             block.TimestampMS,
+		Transactions:          []*Transaction{},
+	}
+	if block.Height == 1 {
+		converted.ParentBlockIdentifier = converted.BlockIdentifier
+	}
+	for _, tx := range block.Transactions {
+		converted.Transactions = append(converted.Transactions, ConvertTx(tx))
+	}
+	return converted
+}
@@ -0,0 +1,143 @@
+package rosetta
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+func TestCurrencyForAsset(t *testing.T) {
+	if c := CurrencyForAsset(consensus.BTMAssetID.String()); c.Symbol != "BTM" || c.Decimals != 8 {
+		t.Errorf("BTM currency = %+v", c)
+	}
+	otherAsset := bc.AssetID{V0: 1}
+	other := otherAsset.String()
+	if c := CurrencyForAsset(other); c.Symbol != other || c.Decimals != 0 {
+		t.Errorf("asset currency = %+v", c)
+	}
+}
+
+func TestConvertTx(t *testing.T) {
+	spendProgram := []byte{0x51}
+	outProgram := []byte{0x52}
+	assetID := bc.AssetID{V0: 7}
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, bc.Hash{V0: 1}, *consensus.BTMAssetID, 20, 0, spendProgram, bc.Hash{}, nil),
+			// issuances debit no account and must carry no operation
+			legacy.NewIssuanceInput([]byte{1}, 5, nil, bc.Hash{V0: 2}, []byte{0x53}, nil, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*consensus.BTMAssetID, 15, outProgram, nil),
+			legacy.NewTxOutput(assetID, 5, outProgram, nil),
+		},
+	})
+
+	converted := ConvertTx(tx)
+	if converted.TransactionIdentifier.Hash != tx.Tx.ID.String() {
+		t.Errorf("transaction hash = %s, want %s", converted.TransactionIdentifier.Hash, tx.Tx.ID.String())
+	}
+	if len(converted.Operations) != 3 {
+		t.Fatalf("got %d operations, want 3", len(converted.Operations))
+	}
+
+	spend := converted.Operations[0]
+	if spend.Type != OpTypeInput || spend.Status != OpStatusSuccess {
+		t.Errorf("spend operation = %+v", spend)
+	}
+	if spend.Account.Address != hex.EncodeToString(spendProgram) {
+		t.Errorf("spend address = %s", spend.Account.Address)
+	}
+	if spend.Amount.Value != "-20" || spend.Amount.Currency.Symbol != "BTM" {
+		t.Errorf("spend amount = %+v", spend.Amount)
+	}
+
+	for i, op := range converted.Operations {
+		if op.OperationIdentifier.Index != int64(i) {
+			t.Errorf("operation %d carries index %d", i, op.OperationIdentifier.Index)
+		}
+	}
+
+	out := converted.Operations[2]
+	if out.Type != OpTypeOutput || out.Amount.Value != "5" {
+		t.Errorf("output operation = %+v", out)
+	}
+	if out.Amount.Currency.Symbol != assetID.String() || out.Amount.Currency.Decimals != 0 {
+		t.Errorf("output currency = %+v", out.Amount.Currency)
+	}
+}
+
+func TestConvertBlock(t *testing.T) {
+	block := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			Version:           1,
+			Height:            5,
+			PreviousBlockHash: bc.Hash{V0: 9},
+			TimestampMS:       1234,
+		},
+	}
+
+	converted := ConvertBlock(block)
+	hash := block.Hash()
+	if converted.BlockIdentifier.Index != 5 || converted.BlockIdentifier.Hash != hash.String() {
+		t.Errorf("block identifier = %+v", converted.BlockIdentifier)
+	}
+	if converted.ParentBlockIdentifier.Index != 4 || converted.ParentBlockIdentifier.Hash != block.PreviousBlockHash.String() {
+		t.Errorf("parent identifier = %+v", converted.ParentBlockIdentifier)
+	}
+	if converted.Timestamp != 1234 {
+		t.Errorf("timestamp = %d", converted.Timestamp)
+	}
+
+	// the genesis block is its own parent in the Rosetta model
+	block.Height = 1
+	converted = ConvertBlock(block)
+	if converted.ParentBlockIdentifier != converted.BlockIdentifier {
+		t.Errorf("genesis parent = %+v, want %+v", converted.ParentBlockIdentifier, converted.BlockIdentifier)
+	}
+}
+
+// TestBlockSerialization pins the wire field names the Rosetta spec
+// mandates.
+func TestBlockSerialization(t *testing.T) {
+	block := ConvertBlock(&legacy.Block{
+		BlockHeader: legacy.BlockHeader{Height: 2},
+		Transactions: []*legacy.Tx{
+			legacy.NewTx(legacy.TxData{Version: 1}),
+		},
+	})
+
+	raw, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"block_identifier", "parent_block_identifier", "timestamp", "transactions"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("serialized block is missing %q", key)
+		}
+	}
+
+	op := Operation{Type: OpTypeOutput, Status: OpStatusSuccess}
+	raw, err = json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded = map[string]interface{}{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"operation_identifier", "type", "status", "account", "amount"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("serialized operation is missing %q", key)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/bytom/blockchain/rosetta"
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/crypto/ed25519"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/vm/vmutil"
+	"github.com/bytom/version"
+)
+
+// checkRosettaNetwork rejects requests addressed to another chain, as
+// the Rosetta spec requires every endpoint to do.
+func checkRosettaNetwork(in rosetta.NetworkIdentifier) error {
+	want := rosetta.Network()
+	if in.Blockchain != want.Blockchain || in.Network != want.Network {
+		return errors.WithDetailf(errBadAction, "this node serves %s/%s", want.Blockchain, want.Network)
+	}
+	return nil
+}
+
+// rosettaBlockByIdentifier resolves a partial block identifier - by
+// height, by hash or empty for the tip - to a block.
+func (bcr *BlockchainReactor) rosettaBlockByIdentifier(in rosetta.PartialBlockIdentifier) (*legacy.Block, error) {
+	if in.Hash != nil {
+		var hash bc.Hash
+		if err := hash.UnmarshalText([]byte(*in.Hash)); err != nil {
+			return nil, errors.WithDetail(errBadAction, "block_identifier.hash is not a valid hash")
+		}
+		return bcr.chain.GetBlockByHash(&hash)
+	}
+	height := bcr.chain.Height()
+	if in.Index != nil {
+		height = uint64(*in.Index)
+	}
+	return bcr.chain.GetBlockByHeight(height)
+}
+
+// POST /rosetta/network/list
+func (bcr *BlockchainReactor) rosettaNetworkList() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"network_identifiers": []rosetta.NetworkIdentifier{rosetta.Network()},
+	}, nil
+}
+
+// POST /rosetta/network/status
+func (bcr *BlockchainReactor) rosettaNetworkStatus(in struct {
+	NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	tip, err := bcr.chain.GetBlockByHeight(bcr.chain.Height())
+	if err != nil {
+		return nil, err
+	}
+	genesis, err := bcr.chain.GetBlockByHeight(1)
+	if err != nil {
+		return nil, err
+	}
+	tipHash, genesisHash := tip.Hash(), genesis.Hash()
+
+	return map[string]interface{}{
+		"current_block_identifier": rosetta.BlockIdentifier{Index: int64(tip.Height), Hash: tipHash.String()},
+		"current_block_timestamp":  tip.TimestampMS,
+		"genesis_block_identifier": rosetta.BlockIdentifier{Index: int64(genesis.Height), Hash: genesisHash.String()},
+		"sync_status": map[string]interface{}{
+			"synced": bcr.blockKeeper.IsCaughtUp(),
+		},
+	}, nil
+}
+
+// POST /rosetta/network/options
+func (bcr *BlockchainReactor) rosettaNetworkOptions(in struct {
+	NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"version": map[string]string{
+			"rosetta_version": "1.4.13",
+			"node_version":    version.Version,
+		},
+		"allow": map[string]interface{}{
+			"operation_statuses": []map[string]interface{}{
+				{"status": rosetta.OpStatusSuccess, "successful": true},
+			},
+			"operation_types": []string{rosetta.OpTypeInput, rosetta.OpTypeOutput},
+			"errors":          []interface{}{},
+		},
+	}, nil
+}
+
+// POST /rosetta/block
+func (bcr *BlockchainReactor) rosettaBlock(in struct {
+	NetworkIdentifier rosetta.NetworkIdentifier      `json:"network_identifier"`
+	BlockIdentifier   rosetta.PartialBlockIdentifier `json:"block_identifier"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	block, err := bcr.rosettaBlockByIdentifier(in.BlockIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"block": rosetta.ConvertBlock(block)}, nil
+}
+
+// POST /rosetta/block/transaction
+func (bcr *BlockchainReactor) rosettaBlockTransaction(in struct {
+	NetworkIdentifier     rosetta.NetworkIdentifier      `json:"network_identifier"`
+	BlockIdentifier       rosetta.PartialBlockIdentifier `json:"block_identifier"`
+	TransactionIdentifier rosetta.TransactionIdentifier  `json:"transaction_identifier"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	block, err := bcr.rosettaBlockByIdentifier(in.BlockIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range block.Transactions {
+		if tx.Tx.ID.String() == in.TransactionIdentifier.Hash {
+			return map[string]interface{}{"transaction": rosetta.ConvertTx(tx)}, nil
+		}
+	}
+	return nil, errors.WithDetail(errBadAction, "transaction not in the given block")
+}
+
+// POST /rosetta/account/balance
+// Balances come from the explorer index, so the endpoint requires the
+// explorer to be enabled.
+func (bcr *BlockchainReactor) rosettaAccountBalance(in struct {
+	NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+	AccountIdentifier rosetta.AccountIdentifier `json:"account_identifier"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	if err := bcr.requireExplorer(); err != nil {
+		return nil, err
+	}
+	if in.AccountIdentifier.Address == "" {
+		return nil, errors.WithDetail(errBadAction, "account_identifier.address is required")
+	}
+
+	status := bcr.explorer.Status()
+	balances := []rosetta.Amount{}
+	for _, item := range bcr.explorer.Balances(in.AccountIdentifier.Address) {
+		balances = append(balances, rosetta.Amount{
+			Value:    strconv.FormatUint(item.Balance, 10),
+			Currency: rosetta.CurrencyForAsset(item.Asset),
+		})
+	}
+	return map[string]interface{}{
+		"block_identifier": rosetta.BlockIdentifier{Index: int64(status.Height), Hash: status.Hash.String()},
+		"balances":         balances,
+	}, nil
+}
+
+// POST /rosetta/construction/derive
+// Derives the single-signature control program of an ed25519 public
+// key, which is the account address in this Rosetta mapping.
+func (bcr *BlockchainReactor) rosettaConstructionDerive(in struct {
+	NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+	PublicKey         struct {
+		HexBytes  string `json:"hex_bytes"`
+		CurveType string `json:"curve_type"`
+	} `json:"public_key"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	if in.PublicKey.CurveType != "edwards25519" {
+		return nil, errors.WithDetail(errBadAction, "bytom keys use curve_type edwards25519")
+	}
+	pubkey, err := hex.DecodeString(in.PublicKey.HexBytes)
+	if err != nil || len(pubkey) != ed25519.PublicKeySize {
+		return nil, errors.WithDetail(errBadAction, "public_key.hex_bytes must be a hex 32-byte ed25519 key")
+	}
+	program, err := vmutil.P2SPMultiSigProgram([]ed25519.PublicKey{ed25519.PublicKey(pubkey)}, 1)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"account_identifier": rosetta.AccountIdentifier{Address: hex.EncodeToString(program)},
+	}, nil
+}
+
+// POST /rosetta/construction/hash
+func (bcr *BlockchainReactor) rosettaConstructionHash(in struct {
+	NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+	SignedTransaction string                    `json:"signed_transaction"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	tx := &legacy.Tx{}
+	if err := tx.UnmarshalText([]byte(in.SignedTransaction)); err != nil {
+		return nil, errors.WithDetail(errBadAction, "signed_transaction is not a valid raw transaction")
+	}
+	return map[string]interface{}{
+		"transaction_identifier": rosetta.TransactionIdentifier{Hash: tx.Tx.ID.String()},
+	}, nil
+}
+
+// POST /rosetta/construction/submit
+func (bcr *BlockchainReactor) rosettaConstructionSubmit(ctx context.Context, in struct {
+	NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+	SignedTransaction string                    `json:"signed_transaction"`
+}) (map[string]interface{}, error) {
+	if err := checkRosettaNetwork(in.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	tx := &legacy.Tx{}
+	if err := tx.UnmarshalText([]byte(in.SignedTransaction)); err != nil {
+		return nil, errors.WithDetail(errBadAction, "signed_transaction is not a valid raw transaction")
+	}
+	if err := txbuilder.FinalizeTx(ctx, bcr.chain, tx); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"transaction_identifier": rosetta.TransactionIdentifier{Hash: tx.Tx.ID.String()},
+	}, nil
+}
@@ -0,0 +1,96 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/bytom/blockchain/rosetta"
+	"github.com/bytom/consensus"
+	"github.com/bytom/crypto/ed25519"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+func TestRosettaConstructionDerive(t *testing.T) {
+	bcr := &BlockchainReactor{}
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := struct {
+		NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+		PublicKey         struct {
+			HexBytes  string `json:"hex_bytes"`
+			CurveType string `json:"curve_type"`
+		} `json:"public_key"`
+	}{NetworkIdentifier: rosetta.Network()}
+	in.PublicKey.HexBytes = hex.EncodeToString(pub)
+	in.PublicKey.CurveType = "edwards25519"
+	result, err := bcr.rosettaConstructionDerive(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := vmutil.P2SPMultiSigProgram([]ed25519.PublicKey{pub}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := result["account_identifier"].(rosetta.AccountIdentifier)
+	if account.Address != hex.EncodeToString(program) {
+		t.Errorf("derived address %s, want %s", account.Address, hex.EncodeToString(program))
+	}
+
+	in.PublicKey.CurveType = "secp256k1"
+	if _, err := bcr.rosettaConstructionDerive(in); err == nil {
+		t.Error("derive accepted a non-ed25519 curve type")
+	}
+	in.PublicKey.CurveType = "edwards25519"
+	in.PublicKey.HexBytes = "zz"
+	if _, err := bcr.rosettaConstructionDerive(in); err == nil {
+		t.Error("derive accepted a malformed public key")
+	}
+	in.PublicKey.HexBytes = hex.EncodeToString(pub)
+	in.NetworkIdentifier.Network = "elsewhere"
+	if _, err := bcr.rosettaConstructionDerive(in); err == nil {
+		t.Error("derive accepted a foreign network identifier")
+	}
+}
+
+func TestRosettaConstructionHash(t *testing.T) {
+	bcr := &BlockchainReactor{}
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, bc.Hash{V0: 1}, *consensus.BTMAssetID, 20, 0, []byte{0x51}, bc.Hash{}, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*consensus.BTMAssetID, 20, []byte{0x52}, nil),
+		},
+	})
+	raw, err := tx.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := struct {
+		NetworkIdentifier rosetta.NetworkIdentifier `json:"network_identifier"`
+		SignedTransaction string                    `json:"signed_transaction"`
+	}{
+		NetworkIdentifier: rosetta.Network(),
+		SignedTransaction: string(raw),
+	}
+	result, err := bcr.rosettaConstructionHash(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := result["transaction_identifier"].(rosetta.TransactionIdentifier)
+	if id.Hash != tx.Tx.ID.String() {
+		t.Errorf("hash %s, want %s", id.Hash, tx.Tx.ID.String())
+	}
+
+	in.SignedTransaction = "not a transaction"
+	if _, err := bcr.rosettaConstructionHash(in); err == nil {
+		t.Error("hash accepted a malformed raw transaction")
+	}
+}
@@ -1,12 +1,14 @@
 package blockchain
 
 import (
-	"time"
 	"net/http"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
 
 	"github.com/bytom/errors"
+	"github.com/bytom/net/http/httperror"
 	"github.com/bytom/net/http/httpjson"
 )
 
@@ -42,21 +44,53 @@ func (bcr *BlockchainReactor) BuildHander() {
 		m.Handle("/list-assets", jsonHandler(bcr.listAssets))
 		m.Handle("/list-transactions", jsonHandler(bcr.listTransactions))
 		m.Handle("/list-balances", jsonHandler(bcr.listBalances))
+		m.Handle("/wallet-info", jsonHandler(bcr.walletInfo))
 	} else {
 		log.Warn("Please enable wallet")
 	}
 
 	m.Handle("/build-transaction", jsonHandler(bcr.build))
+	m.Handle("/send", jsonHandler(bcr.send))
+	m.Handle("/faucet/request", jsonHandler(bcr.faucetRequest))
 	m.Handle("/create-control-program", jsonHandler(bcr.createControlProgram))
 	m.Handle("/create-transaction-feed", jsonHandler(bcr.createTxFeed))
 	m.Handle("/get-transaction-feed", jsonHandler(bcr.getTxFeed))
 	m.Handle("/update-transaction-feed", jsonHandler(bcr.updateTxFeed))
 	m.Handle("/delete-transaction-feed", jsonHandler(bcr.deleteTxFeed))
 	m.Handle("/list-transaction-feeds", jsonHandler(bcr.listTxFeeds))
+	m.Handle("/get-transaction-feed-items", jsonHandler(bcr.getTxFeedItems))
+	m.Handle("/ack-transaction-feed", jsonHandler(bcr.ackTxFeed))
+	m.Handle("/transaction-feed-ws", websocket.Handler(bcr.txFeedSocket))
+	m.Handle("/create-webhook", jsonHandler(bcr.createWebhook))
+	m.Handle("/list-webhooks", jsonHandler(bcr.listWebhooks))
+	m.Handle("/delete-webhook", jsonHandler(bcr.deleteWebhook))
+	m.Handle("/create-watch-address", jsonHandler(bcr.createWatchAddress))
+	m.Handle("/list-watch-addresses", jsonHandler(bcr.listWatchAddresses))
+	m.Handle("/delete-watch-address", jsonHandler(bcr.deleteWatchAddress))
+	m.Handle("/list-deposits", jsonHandler(bcr.listDeposits))
+	m.Handle("/credit-deposit", jsonHandler(bcr.creditDeposit))
+	m.Handle("/create-payment-uri", jsonHandler(bcr.createPaymentURI))
+	m.Handle("/parse-payment-uri", jsonHandler(bcr.parsePaymentURI))
+	m.Handle("/create-invoice", jsonHandler(bcr.createInvoice))
+	m.Handle("/verify-invoice", jsonHandler(bcr.verifyInvoice))
+	m.Handle("/create-htlc-program", jsonHandler(bcr.createHTLCProgram))
+	m.Handle("/list-htlc-swaps", jsonHandler(bcr.listHTLCSwaps))
+	m.Handle("/redeem-htlc", jsonHandler(bcr.redeemHTLC))
+	m.Handle("/refund-htlc", jsonHandler(bcr.refundHTLC))
+	m.Handle("/add-peg-member", jsonHandler(bcr.addPegMember))
+	m.Handle("/remove-peg-member", jsonHandler(bcr.removePegMember))
+	m.Handle("/get-peg-federation", jsonHandler(bcr.getPegFederation))
+	m.Handle("/list-peg-ins", jsonHandler(bcr.listPegIns))
+	m.Handle("/claim-peg-in", jsonHandler(bcr.claimPegIn))
+	m.Handle("/create-peg-withdrawal", jsonHandler(bcr.createPegWithdrawal))
+	m.Handle("/sign-peg-withdrawal", jsonHandler(bcr.signPegWithdrawal))
+	m.Handle("/list-peg-withdrawals", jsonHandler(bcr.listPegWithdrawals))
+	m.Handle("/validate-address", jsonHandler(bcr.validateAddress))
 	m.Handle("/list-unspent-outputs", jsonHandler(bcr.listUnspentOutputs))
 	m.Handle("/", alwaysError(errors.New("not Found")))
 	m.Handle("/info", jsonHandler(bcr.info))
 	m.Handle("/submit-transaction", jsonHandler(bcr.submit))
+	m.Handle("/validate-transaction", jsonHandler(bcr.validateTx))
 	m.Handle("/create-access-token", jsonHandler(bcr.createAccessToken))
 	m.Handle("/list-access-token", jsonHandler(bcr.listAccessTokens))
 	m.Handle("/delete-access-token", jsonHandler(bcr.deleteAccessToken))
@@ -69,6 +103,39 @@ func (bcr *BlockchainReactor) BuildHander() {
 	m.Handle("/sign-transactions", jsonHandler(bcr.pseudohsmSignTemplates))
 	m.Handle("/reset-password", jsonHandler(bcr.pseudohsmResetPassword))
 	m.Handle("/net-info", jsonHandler(bcr.getNetInfo))
+	m.Handle("/node-info", jsonHandler(bcr.getNodeInfo))
+	m.Handle("/net-stats", jsonHandler(bcr.getNetStats))
+	m.Handle("/dump-reachable-nodes", jsonHandler(bcr.dumpReachableNodes))
+	m.Handle("/export-snapshot", jsonHandler(bcr.exportSnapshot))
+	m.Handle("/get-utxo-commitment", jsonHandler(bcr.getUtxoCommitment))
+	m.Handle("/mempool-info", jsonHandler(bcr.getMempoolInfo))
+	m.Handle("/deployment-states", jsonHandler(bcr.getDeploymentStates))
+	m.Handle("/asset-audit", jsonHandler(bcr.auditAsset))
+	m.Handle("/get-chain-tips", jsonHandler(bcr.getChainTips))
+	m.Handle("/compact-database", jsonHandler(bcr.compactDatabase))
+	m.Handle("/create-snapshot", jsonHandler(bcr.createSnapshot))
+	m.Handle("/db-stats", jsonHandler(bcr.getDBStats))
+	m.Handle("/stop", jsonHandler(bcr.stopNode))
+	m.Handle("/set-log-level", jsonHandler(bcr.setLogLevel))
+	m.Handle("/set-mock-time", jsonHandler(bcr.setMockTime))
+	m.Handle("/reload-config", jsonHandler(bcr.reloadConfig))
+	m.Handle("/get-merkle-proof", jsonHandler(bcr.getMerkleProof))
+	m.Handle("/verify-merkle-proof", jsonHandler(bcr.verifyMerkleProof))
+	m.Handle("/get-transaction-inclusion", jsonHandler(bcr.getTxInclusion))
+	m.Handle("/rosetta/network/list", jsonHandler(bcr.rosettaNetworkList))
+	m.Handle("/rosetta/network/status", jsonHandler(bcr.rosettaNetworkStatus))
+	m.Handle("/rosetta/network/options", jsonHandler(bcr.rosettaNetworkOptions))
+	m.Handle("/rosetta/block", jsonHandler(bcr.rosettaBlock))
+	m.Handle("/rosetta/block/transaction", jsonHandler(bcr.rosettaBlockTransaction))
+	m.Handle("/rosetta/account/balance", jsonHandler(bcr.rosettaAccountBalance))
+	m.Handle("/rosetta/construction/derive", jsonHandler(bcr.rosettaConstructionDerive))
+	m.Handle("/rosetta/construction/hash", jsonHandler(bcr.rosettaConstructionHash))
+	m.Handle("/rosetta/construction/submit", jsonHandler(bcr.rosettaConstructionSubmit))
+	m.Handle("/explorer/list-address-transactions", jsonHandler(bcr.listAddressTransactions))
+	m.Handle("/explorer/list-asset-transactions", jsonHandler(bcr.listAssetTransactions))
+	m.Handle("/explorer/rich-list", jsonHandler(bcr.getRichList))
+	m.Handle("/get-block", jsonHandler(bcr.getBlock))
+	m.Handle("/get-block-header", jsonHandler(bcr.getBlockHeader))
 	m.Handle("/get-best-block-hash", jsonHandler(bcr.getBestBlockHash))
 	m.Handle("/get-block-header-by-hash", jsonHandler(bcr.getBlockHeaderByHash))
 	m.Handle("/get-block-transactions-count-by-hash", jsonHandler(bcr.getBlockTransactionsCountByHash))
@@ -80,12 +147,36 @@ func (bcr *BlockchainReactor) BuildHander() {
 	m.Handle("/get-block-transactions-count-by-height", jsonHandler(bcr.getBlockTransactionsCountByHeight))
 	m.Handle("/block-height", jsonHandler(bcr.blockHeight))
 	m.Handle("/is-mining", jsonHandler(bcr.isMining))
+	m.Handle("/set-mining", jsonHandler(bcr.setMining))
+	m.Handle("/mining-status", jsonHandler(bcr.miningStatus))
+	m.Handle("/mining-stats", jsonHandler(bcr.getMiningStats))
+	m.Handle("/generate-blocks", jsonHandler(bcr.generateBlocks))
+	m.Handle("/pool-payouts", jsonHandler(bcr.poolPayouts))
+	m.Handle("/build-pool-payout", jsonHandler(bcr.buildPoolPayout))
+	m.Handle("/set-coinbase", jsonHandler(bcr.setCoinbase))
+	m.Handle("/get-coinbase", jsonHandler(bcr.getCoinbase))
+	m.Handle("/get-block-template", jsonHandler(bcr.getBlockTemplate))
+	m.Handle("/mining-work-ws", websocket.Handler(bcr.miningWorkSocket))
+	m.Handle("/submit-block", jsonHandler(bcr.submitBlock))
+	m.Handle("/difficulty-info", jsonHandler(bcr.getDifficultyInfo))
+	m.Handle("/trace-program", jsonHandler(bcr.traceProgram))
+	m.Handle("/estimate-program-gas", jsonHandler(bcr.estimateProgramGas))
+	m.Handle("/decode-program", jsonHandler(bcr.decodeProgram))
+	m.Handle("/compile-contract", jsonHandler(bcr.compileContract))
+	m.Handle("/test-program", jsonHandler(bcr.testProgram))
 	m.Handle("/gas-rate", jsonHandler(bcr.gasRate))
 
+	if bcr.debugEnabled {
+		m.Handle("/debug/", bcr.debugHandler())
+	}
+
 	latencyHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		if l := latency(m, req); l != nil {
 			defer l.RecordSince(time.Now())
 		}
+		// make the client's language preference available to the error
+		// formatter, which localizes error messages
+		req = req.WithContext(httperror.WithAcceptLanguage(req.Context(), req.Header.Get("Accept-Language")))
 		m.ServeHTTP(w, req)
 	})
 	handler := maxBytes(latencyHandler) // TODO(tessr): consider moving this to non-core specific mux
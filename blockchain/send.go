@@ -0,0 +1,59 @@
+package blockchain
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+)
+
+// sendIn names the parts of the common transfer: X of asset Y from
+// account A to address B with fee F.
+type sendIn struct {
+	AccountID string `json:"account_id"`
+	AssetID   string `json:"asset_id"`
+	Amount    uint64 `json:"amount"`
+	// the destination control program (hex)
+	Address  string `json:"address"`
+	Fee      uint64 `json:"fee"`
+	Password string `json:"password"`
+}
+
+// POST /send
+func (a *BlockchainReactor) send(ctx context.Context, in sendIn) (interface{}, error) {
+	if in.AccountID == "" || in.AssetID == "" || in.Address == "" || in.Amount == 0 {
+		return nil, errors.WithDetail(errBadAction, "account_id, asset_id, amount and address are required")
+	}
+
+	actions := []map[string]interface{}{
+		{"type": "spend_account", "asset_id": in.AssetID, "amount": in.Amount, "account_id": in.AccountID},
+		{"type": "control_program", "asset_id": in.AssetID, "amount": in.Amount, "control_program": in.Address},
+	}
+	if in.Fee > 0 {
+		actions = append(actions, map[string]interface{}{
+			"type":       "spend_account",
+			"asset_id":   consensus.BTMAssetID.String(),
+			"amount":     in.Fee,
+			"account_id": in.AccountID,
+		})
+	}
+
+	tpl, err := a.buildSingle(ctx, &BuildRequest{Actions: actions})
+	if err != nil {
+		return nil, errors.Wrap(err, "build")
+	}
+
+	if err := txbuilder.Sign(ctx, tpl, nil, in.Password, a.pseudohsmSignTemplate); err != nil {
+		return nil, errors.Wrap(err, "sign")
+	}
+
+	resp, err := a.submitSingle(ctx, tpl, "none")
+	if err != nil {
+		return nil, errors.Wrap(err, "submit")
+	}
+	log.WithField("resp", resp).Info("Sent transaction")
+	return resp, nil
+}
@@ -0,0 +1,156 @@
+package blockchain
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+)
+
+// how long a light node waits for a peer to serve an inclusion proof.
+const txProofTimeout = 15 * time.Second
+
+// proofKeeper routes peer-served inclusion proofs back to the API
+// callers waiting for them. Requests are keyed by transaction ID, so
+// concurrent calls for the same transaction share one answer.
+type proofKeeper struct {
+	mtx     sync.Mutex
+	pending map[bc.Hash][]chan *TxProofResponseMessage
+}
+
+func newProofKeeper() *proofKeeper {
+	return &proofKeeper{pending: make(map[bc.Hash][]chan *TxProofResponseMessage)}
+}
+
+// wait registers interest in a proof for the given transaction and
+// returns the channel the response will arrive on.
+func (pk *proofKeeper) wait(txID bc.Hash) chan *TxProofResponseMessage {
+	ch := make(chan *TxProofResponseMessage, 1)
+	pk.mtx.Lock()
+	pk.pending[txID] = append(pk.pending[txID], ch)
+	pk.mtx.Unlock()
+	return ch
+}
+
+// cancel drops a waiter that timed out or was abandoned.
+func (pk *proofKeeper) cancel(txID bc.Hash, ch chan *TxProofResponseMessage) {
+	pk.mtx.Lock()
+	defer pk.mtx.Unlock()
+	waiters := pk.pending[txID]
+	for i, waiter := range waiters {
+		if waiter == ch {
+			pk.pending[txID] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(pk.pending[txID]) == 0 {
+		delete(pk.pending, txID)
+	}
+}
+
+// deliver hands a received proof response to every waiter for its
+// transaction. Responses nobody asked for are dropped.
+func (pk *proofKeeper) deliver(msg *TxProofResponseMessage) {
+	txID := *msg.GetTxID()
+	pk.mtx.Lock()
+	waiters := pk.pending[txID]
+	delete(pk.pending, txID)
+	pk.mtx.Unlock()
+	for _, ch := range waiters {
+		ch <- msg
+	}
+}
+
+// TxInclusionResp reports whether a transaction is part of the main
+// chain, verified either against this node's own blocks or, on a light
+// node, against the header chain with a peer-served merkle proof.
+type TxInclusionResp struct {
+	TxID          string `json:"tx_id"`
+	Confirmed     bool   `json:"confirmed"`
+	BlockHeight   uint64 `json:"block_height,omitempty"`
+	BlockHash     string `json:"block_hash,omitempty"`
+	Confirmations uint64 `json:"confirmations,omitempty"`
+}
+
+// getTxInclusion handles /get-transaction-inclusion. A full node
+// answers from its own chain; a light node asks a full-node peer for
+// the merkle path and checks it against the locally validated header.
+func (bcr *BlockchainReactor) getTxInclusion(in struct {
+	TxID string `json:"tx_id"`
+}) (*TxInclusionResp, error) {
+	var txID bc.Hash
+	if err := txID.UnmarshalText([]byte(in.TxID)); err != nil {
+		return nil, errors.Wrap(err, "decoding transaction ID")
+	}
+
+	if !bcr.chain.HeaderOnly() {
+		block, _, _, err := bcr.findTxInMainchain(txID)
+		if err != nil {
+			return nil, err
+		}
+		blockHash := block.Hash()
+		return &TxInclusionResp{
+			TxID:          txID.String(),
+			Confirmed:     true,
+			BlockHeight:   block.Height,
+			BlockHash:     blockHash.String(),
+			Confirmations: bcr.chain.Height() - block.Height + 1,
+		}, nil
+	}
+
+	peerID := bcr.blockKeeper.bestPeer(1)
+	if peerID == "" {
+		return nil, errors.New("no peers available to serve the proof")
+	}
+	peer := bcr.sw.Peers().Get(peerID)
+	if peer == nil {
+		return nil, errors.New("no peers available to serve the proof")
+	}
+
+	ch := bcr.proofKeeper.wait(txID)
+	msg := &TxProofRequestMessage{RawTxID: txID.Byte32()}
+	peer.TrySend(BlockchainChannel, struct{ BlockchainMessage }{msg})
+
+	select {
+	case resp := <-ch:
+		return bcr.verifyTxProof(txID, resp)
+	case <-time.After(txProofTimeout):
+		bcr.proofKeeper.cancel(txID, ch)
+		return nil, errors.New("timed out waiting for the proof from peer")
+	}
+}
+
+// verifyTxProof checks a peer-served proof against the light node's own
+// header chain: the named block must be a main chain header we already
+// validated, and the merkle path must hash up to its transactions root.
+func (bcr *BlockchainReactor) verifyTxProof(txID bc.Hash, msg *TxProofResponseMessage) (*TxInclusionResp, error) {
+	if !msg.Found {
+		return &TxInclusionResp{TxID: txID.String(), Confirmed: false}, nil
+	}
+
+	blockHash := msg.GetBlockHash()
+	header, err := bcr.chain.GetHeaderByHash(blockHash)
+	if err != nil {
+		return nil, errors.New("peer proof names a block this node does not know")
+	}
+	if !bcr.chain.InMainChain(header.Height, *blockHash) {
+		return nil, errors.New("peer proof names a block off the main chain")
+	}
+
+	proof := msg.GetProof()
+	if proof == nil {
+		return nil, errors.New("peer served a malformed proof")
+	}
+	if !bc.VerifyMerkleProof(txID, proof, header.BlockCommitment.TransactionsMerkleRoot) {
+		return nil, errors.New("peer proof does not match the transactions merkle root")
+	}
+
+	return &TxInclusionResp{
+		TxID:          txID.String(),
+		Confirmed:     true,
+		BlockHeight:   header.Height,
+		BlockHash:     blockHash.String(),
+		Confirmations: bcr.chain.Height() - header.Height + 1,
+	}, nil
+}
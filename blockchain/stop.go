@@ -0,0 +1,26 @@
+package blockchain
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+)
+
+// stopNode asks the node to shut down cleanly, running the same ordered
+// teardown a SIGINT or SIGTERM would. The shutdown is deferred briefly
+// so this response reaches the caller before the RPC server closes.
+func (bcr *BlockchainReactor) stopNode() (*struct {
+	Msg string `json:"msg"`
+}, error) {
+	if bcr.shutdownFn == nil {
+		return nil, errors.New("no shutdown hook registered")
+	}
+
+	log.Info("Stop requested over RPC")
+	time.AfterFunc(200*time.Millisecond, bcr.shutdownFn)
+	return &struct {
+		Msg string `json:"msg"`
+	}{Msg: "shutting down"}, nil
+}
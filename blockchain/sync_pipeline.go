@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"runtime"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/validation"
+)
+
+// The sync pipeline splits block processing into independent stages so
+// initial sync overlaps network, CPU and disk work instead of running
+// everything on one goroutine: the intake stage does the cheap
+// duplicate check, a pool of workers runs the witness programs, and a
+// single apply stage connects blocks to the chain in arrival order.
+// The stages hand off through buffered channels, so a slow stage
+// backpressures the ones before it instead of growing an unbounded
+// queue.
+
+// pipelineDepth is how many blocks may sit between two pipeline stages
+// before the earlier stage blocks.
+const pipelineDepth = syncWindowSize
+
+// pendingBlock is a block moving through the sync pipeline. verified is
+// closed when the script verification stage is done with the block,
+// releasing the apply stage, which waits on blocks in arrival order.
+type pendingBlock struct {
+	block    *legacy.Block
+	peerID   string
+	verified chan struct{}
+}
+
+// startPipeline wires the stages together. The channels stay open for
+// the life of the process, like the other blockKeeper workers.
+func (bk *blockKeeper) startPipeline() {
+	go bk.blockIntakeWorker()
+	for i := 0; i < runtime.NumCPU(); i++ {
+		go bk.scriptVerifyWorker()
+	}
+	go bk.blockApplyWorker()
+}
+
+// blockIntakeWorker is the first pipeline stage: it drops blocks the
+// chain already has and queues the rest to the verification workers and
+// to the apply stage in the same order.
+func (bk *blockKeeper) blockIntakeWorker() {
+	for resp := range bk.pendingProcessCh {
+		blockHash := resp.block.Hash()
+		if bk.chain.BlockExist(&blockHash) {
+			log.WithField("hash", blockHash.String()).Info("Skip process due to block already been handled")
+			bk.reportDelivery(resp.block.Height, resp.peerID)
+			continue
+		}
+		pb := &pendingBlock{block: resp.block, peerID: resp.peerID, verified: make(chan struct{})}
+		bk.applyCh <- pb
+		bk.verifyCh <- pb
+	}
+}
+
+// scriptVerifyWorker runs the witness programs of queued blocks ahead
+// of the apply stage. Successful runs land in the validation cache, so
+// the authoritative validation during ProcessBlock skips the VM work;
+// errors found here are deliberately dropped and rediscovered there,
+// where rejection and peer punishment are handled.
+func (bk *blockKeeper) scriptVerifyWorker() {
+	for pb := range bk.verifyCh {
+		if !bk.headerOnly && bk.chain.RunsPrograms(pb.block.Height) {
+			bcBlock := legacy.MapBlock(pb.block)
+			for _, tx := range bcBlock.Transactions[1:] {
+				validation.ValidateTx(tx, bcBlock)
+			}
+		}
+		close(pb.verified)
+	}
+}
+
+// blockApplyWorker is the final pipeline stage: it connects blocks to
+// the chain in arrival order, waiting for the verification stage to
+// finish with each block first.
+func (bk *blockKeeper) blockApplyWorker() {
+	for pb := range bk.applyCh {
+		<-pb.verified
+
+		block := pb.block
+		blockHash := block.Hash()
+		isOrphan, err := bk.chain.ProcessBlock(block)
+		if err != nil {
+			log.WithField("hash", blockHash.String()).Errorf("blockKeeper fail process block %v", err)
+			if bk.chain.IsBlockRejected(&blockHash) {
+				bk.punishPeer(pb.peerID, err)
+			}
+			continue
+		}
+		log.WithFields(log.Fields{
+			"height":   block.Height,
+			"hash":     blockHash.String(),
+			"isOrphan": isOrphan,
+		}).Info("blockKeeper processed block")
+
+		bk.reportDelivery(block.Height, pb.peerID)
+
+		if isOrphan && !bk.isScheduled(block.Height) {
+			bk.requestBlockByHash(pb.peerID, &block.PreviousBlockHash)
+		}
+	}
+}
+
+// reportDelivery tells the request worker a requested body arrived, so
+// the in-flight entry is cleared and the peer gets credit.
+func (bk *blockKeeper) reportDelivery(height uint64, peerID string) {
+	select {
+	case bk.deliveredCh <- &blockDelivery{height: height, peerID: peerID}:
+	default:
+	}
+}
@@ -9,6 +9,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/clock"
 	chainjson "github.com/bytom/encoding/json"
 	"github.com/bytom/errors"
 	"github.com/bytom/net/http/httperror"
@@ -23,6 +24,8 @@ func (a *BlockchainReactor) actionDecoder(action string) (func([]byte) (txbuilde
 	switch action {
 	case "control_account":
 		decoder = a.accounts.DecodeControlAction
+	case "control_htlc":
+		decoder = txbuilder.DecodeControlHTLCAction
 	case "control_program":
 		decoder = txbuilder.DecodeControlProgramAction
 	case "control_receiver":
@@ -76,7 +79,7 @@ func (a *BlockchainReactor) buildSingle(ctx context.Context, req *BuildRequest)
 	if ttl == 0 {
 		ttl = defaultTxTTL
 	}
-	maxTime := time.Now().Add(ttl)
+	maxTime := clock.Now().Add(ttl)
 
 	tpl, err := txbuilder.Build(ctx, req.Tx, actions, maxTime)
 	if errors.Root(err) == txbuilder.ErrAction {
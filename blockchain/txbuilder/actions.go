@@ -4,10 +4,13 @@ import (
 	"context"
 	stdjson "encoding/json"
 
+	"github.com/bytom/crypto/ed25519"
 	"github.com/bytom/encoding/json"
+	"github.com/bytom/errors"
 	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/bc/legacy"
 	"github.com/bytom/protocol/vm"
+	"github.com/bytom/protocol/vm/vmutil"
 )
 
 var retirementProgram = []byte{byte(vm.OP_FAIL)}
@@ -76,6 +79,63 @@ func (a *controlProgramAction) Build(ctx context.Context, b *TemplateBuilder) er
 	return b.AddOutput(out)
 }
 
+func DecodeControlHTLCAction(data []byte) (Action, error) {
+	a := new(controlHTLCAction)
+	err := stdjson.Unmarshal(data, a)
+	return a, err
+}
+
+// controlHTLCAction locks value under a hashed-timelock contract:
+// the recipient redeems with the hash preimage before TimeoutHeight,
+// after which the sender can refund.
+type controlHTLCAction struct {
+	bc.AssetAmount
+	SenderPubkey    json.HexBytes `json:"sender_pubkey"`
+	RecipientPubkey json.HexBytes `json:"recipient_pubkey"`
+	Hash            json.HexBytes `json:"hash"`
+	TimeoutHeight   uint64        `json:"timeout_height"`
+	ReferenceData   json.Map      `json:"reference_data"`
+}
+
+func (a *controlHTLCAction) Build(ctx context.Context, b *TemplateBuilder) error {
+	var missing []string
+	if len(a.SenderPubkey) == 0 {
+		missing = append(missing, "sender_pubkey")
+	}
+	if len(a.RecipientPubkey) == 0 {
+		missing = append(missing, "recipient_pubkey")
+	}
+	if len(a.Hash) == 0 {
+		missing = append(missing, "hash")
+	}
+	if a.TimeoutHeight == 0 {
+		missing = append(missing, "timeout_height")
+	}
+	if a.AssetId.IsZero() {
+		missing = append(missing, "asset_id")
+	}
+	if len(missing) > 0 {
+		return MissingFieldsError(missing...)
+	}
+	if len(a.Hash) != 32 {
+		return errors.WithDetail(vmutil.ErrBadValue, "htlc hash must be a 32-byte sha256 hash")
+	}
+
+	params := vmutil.HTLCParams{
+		SenderPubkey:    ed25519.PublicKey(a.SenderPubkey),
+		RecipientPubkey: ed25519.PublicKey(a.RecipientPubkey),
+		TimeoutHeight:   a.TimeoutHeight,
+	}
+	copy(params.Hash[:], a.Hash)
+	program, err := vmutil.P2HTLCProgram(params)
+	if err != nil {
+		return err
+	}
+
+	out := legacy.NewTxOutput(*a.AssetId, a.Amount, program, a.ReferenceData)
+	return b.AddOutput(out)
+}
+
 func DecodeSetTxRefDataAction(data []byte) (Action, error) {
 	a := new(setTxRefDataAction)
 	err := stdjson.Unmarshal(data, a)
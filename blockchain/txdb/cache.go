@@ -62,3 +62,9 @@ func (c *blockCache) add(block *legacy.Block) {
 	c.lru.Add(block.Hash(), block)
 	c.mu.Unlock()
 }
+
+func (c *blockCache) remove(hash *bc.Hash) {
+	c.mu.Lock()
+	c.lru.Remove(*hash)
+	c.mu.Unlock()
+}
@@ -0,0 +1,127 @@
+package txdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/bytom/blockchain/txdb/storage"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+)
+
+// UtxoCommitment is a merkle commitment over the whole UTXO set at a
+// given block. Two nodes at the same tip must produce the same root, so
+// auditors can compare state without downloading it, and a snapshot can
+// be checked against a trusted node before bootstrapping from it.
+type UtxoCommitment struct {
+	Height uint64  `json:"height"`
+	Hash   bc.Hash `json:"hash"`
+	Root   bc.Hash `json:"root"`
+	UTXOs  uint64  `json:"utxos"`
+}
+
+// utxoLeaf hashes one unspent entry into a merkle leaf.
+func utxoLeaf(hash *bc.Hash, entry *storage.UtxoEntry) (bc.Hash, error) {
+	b, err := proto.Marshal(entry)
+	if err != nil {
+		return bc.Hash{}, errors.Wrap(err, "marshaling utxo entry")
+	}
+
+	hasher := sha256.New()
+	hasher.Write(hash.Bytes())
+	hasher.Write(b)
+	var h [32]byte
+	copy(h[:], hasher.Sum(nil))
+	return bc.NewHash(h), nil
+}
+
+// utxoMerkleRoot folds leaves pairwise into a single root. An odd node
+// at any level is promoted unchanged; an empty set commits to the zero
+// hash. Leaves must already be in a deterministic order.
+func utxoMerkleRoot(leaves []bc.Hash) bc.Hash {
+	if len(leaves) == 0 {
+		return bc.Hash{}
+	}
+
+	for len(leaves) > 1 {
+		next := make([]bc.Hash, 0, (len(leaves)+1)/2)
+		for i := 0; i < len(leaves); i += 2 {
+			if i+1 == len(leaves) {
+				next = append(next, leaves[i])
+				continue
+			}
+			hasher := sha256.New()
+			hasher.Write(leaves[i].Bytes())
+			hasher.Write(leaves[i+1].Bytes())
+			var h [32]byte
+			copy(h[:], hasher.Sum(nil))
+			next = append(next, bc.NewHash(h))
+		}
+		leaves = next
+	}
+	return leaves[0]
+}
+
+// UtxoCommitment computes the merkle commitment of the current UTXO
+// set by walking the store. The result is cached until the tip moves,
+// so repeated queries at the same height don't rescan the state.
+func (s *Store) UtxoCommitment() (*UtxoCommitment, error) {
+	status := s.GetStoreStatus()
+	if status.Height == 0 {
+		return nil, errors.New("blockchain is empty")
+	}
+
+	s.commitMtx.Lock()
+	defer s.commitMtx.Unlock()
+	if s.commitCache != nil && s.commitCache.Hash == *status.Hash {
+		return s.commitCache, nil
+	}
+
+	type keyedLeaf struct {
+		hash bc.Hash
+		leaf bc.Hash
+	}
+	keyed := []keyedLeaf{}
+
+	iter := s.db.IteratorPrefix([]byte(utxoPreFix))
+	defer iter.Release()
+	for iter.Next() {
+		var utxo storage.UtxoEntry
+		if err := proto.Unmarshal(iter.Value(), &utxo); err != nil {
+			return nil, errors.Wrap(err, "unmarshaling utxo entry")
+		}
+		if utxo.Spend {
+			continue
+		}
+
+		hash := bc.Hash{}
+		if err := hash.UnmarshalText(bytes.TrimPrefix(iter.Key(), []byte(utxoPreFix))); err != nil {
+			return nil, errors.Wrap(err, "decoding utxo key")
+		}
+		leaf, err := utxoLeaf(&hash, &utxo)
+		if err != nil {
+			return nil, err
+		}
+		keyed = append(keyed, keyedLeaf{hash: hash, leaf: leaf})
+	}
+
+	// the memdb backend iterates in map order; sort for determinism
+	sort.Slice(keyed, func(i, j int) bool {
+		return keyed[i].hash.String() < keyed[j].hash.String()
+	})
+	leaves := make([]bc.Hash, len(keyed))
+	for i, kl := range keyed {
+		leaves[i] = kl.leaf
+	}
+
+	s.commitCache = &UtxoCommitment{
+		Height: status.Height,
+		Hash:   *status.Hash,
+		Root:   utxoMerkleRoot(leaves),
+		UTXOs:  uint64(len(leaves)),
+	}
+	return s.commitCache, nil
+}
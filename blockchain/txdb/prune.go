@@ -0,0 +1,72 @@
+package txdb
+
+import (
+	"fmt"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// Pruned blocks keep their header under this prefix after the full
+// block under blockPreFix is deleted, so chain validation and header
+// queries keep working without the transaction bodies.
+const prunedHeaderPreFix = "PH:"
+
+// ErrBlockPruned is returned when a caller asks for the body of a block
+// this node no longer stores.
+var ErrBlockPruned = errors.New("block body has been pruned")
+
+func calcPrunedHeaderKey(hash *bc.Hash) []byte {
+	return []byte(fmt.Sprintf("%s%v", prunedHeaderPreFix, hash.String()))
+}
+
+// PruneBlock discards the stored body of the given block, retaining
+// only its header. Pruning an already-pruned block is a no-op.
+func (s *Store) PruneBlock(hash *bc.Hash) error {
+	block := GetBlock(s.db, hash)
+	if block == nil {
+		if s.IsBlockPruned(hash) {
+			return nil
+		}
+		return errors.Wrapf(ErrBlockPruned, "no block with hash %s to prune", hash.String())
+	}
+
+	binaryHeader, err := block.BlockHeader.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	batch := s.db.NewBatch()
+	batch.Set(calcPrunedHeaderKey(hash), binaryHeader)
+	batch.Delete(calcBlockKey(hash))
+	batch.Write()
+
+	s.cache.remove(hash)
+	return nil
+}
+
+// IsBlockPruned reports whether only the header of the given block
+// remains on disk.
+func (s *Store) IsBlockPruned(hash *bc.Hash) bool {
+	return s.db.Get(calcPrunedHeaderKey(hash)) != nil
+}
+
+// GetBlockHeader returns the header of the given block, whether or not
+// its body has been pruned.
+func (s *Store) GetBlockHeader(hash *bc.Hash) (*legacy.BlockHeader, error) {
+	if block, err := s.cache.lookup(hash); err == nil {
+		return &block.BlockHeader, nil
+	}
+
+	bytez := s.db.Get(calcPrunedHeaderKey(hash))
+	if bytez == nil {
+		return nil, errors.Wrapf(ErrBlockPruned, "no header with hash %s", hash.String())
+	}
+
+	header := &legacy.BlockHeader{}
+	if err := header.UnmarshalText(bytez); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
@@ -0,0 +1,53 @@
+package txdb
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/bytom/protocol/bc"
+)
+
+// Hashes of blocks that failed validation live under this prefix, so a
+// restart does not forget which blocks are known-bad.
+const rejectedPreFix = "RJ:"
+
+func calcRejectedKey(hash *bc.Hash) []byte {
+	return []byte(rejectedPreFix + hash.String())
+}
+
+// RejectedBlock is one entry of the persisted rejected set.
+type RejectedBlock struct {
+	Hash   bc.Hash
+	Height uint64
+}
+
+// SaveRejectedBlock persists the hash and height of a block that failed
+// validation, so it is never downloaded or validated again.
+func (s *Store) SaveRejectedBlock(hash *bc.Hash, height uint64) error {
+	s.db.Set(calcRejectedKey(hash), []byte(strconv.FormatUint(height, 10)))
+	return nil
+}
+
+// IsBlockRejected reports whether the block with the given hash has
+// failed validation before.
+func (s *Store) IsBlockRejected(hash *bc.Hash) bool {
+	return s.db.Get(calcRejectedKey(hash)) != nil
+}
+
+// ListRejectedBlocks returns every entry of the rejected set.
+func (s *Store) ListRejectedBlocks() ([]*RejectedBlock, error) {
+	rejected := []*RejectedBlock{}
+	iter := s.db.IteratorPrefix([]byte(rejectedPreFix))
+	defer iter.Release()
+
+	for iter.Next() {
+		entry := &RejectedBlock{}
+		if err := entry.Hash.UnmarshalText(bytes.TrimPrefix(iter.Key(), []byte(rejectedPreFix))); err != nil {
+			return nil, err
+		}
+		// entries written before heights were recorded parse as 0
+		entry.Height, _ = strconv.ParseUint(string(iter.Value()), 10, 64)
+		rejected = append(rejected, entry)
+	}
+	return rejected, nil
+}
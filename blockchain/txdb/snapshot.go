@@ -0,0 +1,143 @@
+package txdb
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/bytom/blockchain/txdb/storage"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// SnapshotUTXO is one unspent entry inside a state snapshot.
+type SnapshotUTXO struct {
+	Hash        bc.Hash `json:"hash"`
+	IsCoinBase  bool    `json:"is_coinbase"`
+	BlockHeight uint64  `json:"block_height"`
+}
+
+// Snapshot is a portable dump of the chain state at a height: the block
+// it was taken at, the full UTXO set and a commitment over it. A fresh
+// node can bootstrap from a snapshot instead of replaying the whole
+// chain, then sync forward normally.
+type Snapshot struct {
+	Height     uint64          `json:"height"`
+	Hash       bc.Hash         `json:"hash"`
+	RawBlock   []byte          `json:"raw_block"`
+	Commitment bc.Hash         `json:"commitment"`
+	UTXOs      []*SnapshotUTXO `json:"utxos"`
+}
+
+// computeCommitment merkleizes the UTXO set in a deterministic order so
+// a receiver can detect any tampering with the entries. It matches the
+// root served by /get-utxo-commitment at the snapshot's block, so a
+// snapshot can be audited against any trusted node before importing it.
+func (snap *Snapshot) computeCommitment() bc.Hash {
+	utxos := make([]*SnapshotUTXO, len(snap.UTXOs))
+	copy(utxos, snap.UTXOs)
+	sort.Slice(utxos, func(i, j int) bool {
+		return utxos[i].Hash.String() < utxos[j].Hash.String()
+	})
+
+	leaves := []bc.Hash{}
+	for _, utxo := range utxos {
+		entry := storage.NewUtxoEntry(utxo.IsCoinBase, utxo.BlockHeight, false)
+		leaf, err := utxoLeaf(&utxo.Hash, entry)
+		if err != nil {
+			continue
+		}
+		leaves = append(leaves, leaf)
+	}
+	return utxoMerkleRoot(leaves)
+}
+
+// ExportSnapshot dumps the chain state at the current best block.
+func (s *Store) ExportSnapshot() (*Snapshot, error) {
+	status := s.GetStoreStatus()
+	if status.Height == 0 {
+		return nil, errors.New("blockchain is empty")
+	}
+	block, err := s.GetBlock(status.Hash)
+	if err != nil {
+		return nil, err
+	}
+	rawBlock, err := block.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		Height:   status.Height,
+		Hash:     *status.Hash,
+		RawBlock: rawBlock,
+		UTXOs:    []*SnapshotUTXO{},
+	}
+
+	iter := s.db.IteratorPrefix([]byte(utxoPreFix))
+	defer iter.Release()
+	for iter.Next() {
+		var utxo storage.UtxoEntry
+		if err := proto.Unmarshal(iter.Value(), &utxo); err != nil {
+			return nil, errors.Wrap(err, "unmarshaling utxo entry")
+		}
+		if utxo.Spend {
+			continue
+		}
+
+		hash := bc.Hash{}
+		if err := hash.UnmarshalText(bytes.TrimPrefix(iter.Key(), []byte(utxoPreFix))); err != nil {
+			return nil, errors.Wrap(err, "decoding utxo key")
+		}
+		snap.UTXOs = append(snap.UTXOs, &SnapshotUTXO{
+			Hash:        hash,
+			IsCoinBase:  utxo.IsCoinBase,
+			BlockHeight: utxo.BlockHeight,
+		})
+	}
+
+	snap.Commitment = snap.computeCommitment()
+	return snap, nil
+}
+
+// ImportSnapshot verifies the snapshot commitment and installs its block
+// and UTXO set as the chain state. The store must be empty.
+func (s *Store) ImportSnapshot(snap *Snapshot) error {
+	if s.GetStoreStatus().Height != 0 {
+		return errors.New("can't import snapshot into a non-empty store")
+	}
+	if snap.computeCommitment() != snap.Commitment {
+		return errors.New("snapshot commitment mismatch")
+	}
+
+	block := &legacy.Block{}
+	if err := block.UnmarshalText(snap.RawBlock); err != nil {
+		return errors.Wrap(err, "decoding snapshot block")
+	}
+	blockHash := block.Hash()
+	if block.Height != snap.Height || blockHash != snap.Hash {
+		return errors.New("snapshot block disagrees with its manifest")
+	}
+	if err := s.SaveBlock(block); err != nil {
+		return err
+	}
+
+	batch := s.db.NewBatch()
+	for _, utxo := range snap.UTXOs {
+		entry := storage.NewUtxoEntry(utxo.IsCoinBase, utxo.BlockHeight, false)
+		b, err := proto.Marshal(entry)
+		if err != nil {
+			return errors.Wrap(err, "marshaling utxo entry")
+		}
+		batch.Set(calcUtxoKey(&utxo.Hash), b)
+	}
+
+	if err := saveMainchain(batch, map[uint64]*bc.Hash{snap.Height: &snap.Hash}, &snap.Hash); err != nil {
+		return err
+	}
+	BlockStoreStateJSON{Height: snap.Height, Hash: &snap.Hash}.save(batch)
+	batch.Write()
+	return nil
+}
@@ -1,8 +1,10 @@
 package txdb
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/tendermint/tmlibs/common"
 	dbm "github.com/tendermint/tmlibs/db"
@@ -49,10 +51,19 @@ func loadBlockStoreStateJSON(db dbm.DB) BlockStoreStateJSON {
 type Store struct {
 	db    dbm.DB
 	cache blockCache
+
+	// see utxo_cache.go
+	utxoCache utxoCache
+
+	// see commitment.go
+	commitMtx   sync.Mutex
+	commitCache *UtxoCommitment
 }
 
+const blockPreFix = "B:"
+
 func calcBlockKey(hash *bc.Hash) []byte {
-	return []byte(fmt.Sprintf("B:%v", hash.String()))
+	return []byte(fmt.Sprintf("%s%v", blockPreFix, hash.String()))
 }
 
 // GetBlock return the block by given hash
@@ -73,14 +84,27 @@ func NewStore(db dbm.DB) *Store {
 		return GetBlock(db, hash)
 	})
 	return &Store{
-		db:    db,
-		cache: cache,
+		db:        db,
+		cache:     cache,
+		utxoCache: newUtxoCache(),
 	}
 }
 
-// GetUtxo will search the utxo in db
+// GetUtxo will search the utxo in the cache, then in db
 func (s *Store) GetUtxo(hash *bc.Hash) (*storage.UtxoEntry, error) {
-	return getUtxo(s.db, hash)
+	// record the generation before the database read, so an entry
+	// loaded concurrently with a block commit cannot go stale into
+	// the cache
+	gen := s.utxoCache.generation()
+	if entry, ok := s.utxoCache.get(hash); ok {
+		return entry, nil
+	}
+	entry, err := getUtxo(s.db, hash)
+	if err != nil {
+		return nil, err
+	}
+	s.utxoCache.addIfCurrent(hash, entry, gen)
+	return entry, nil
 }
 
 // BlockExist check if the block is stored in disk
@@ -94,9 +118,38 @@ func (s *Store) GetBlock(hash *bc.Hash) (*legacy.Block, error) {
 	return s.cache.lookup(hash)
 }
 
+// ListBlockHashes returns the hash of every block in the store, on the
+// main chain or not.
+func (s *Store) ListBlockHashes() ([]bc.Hash, error) {
+	hashes := []bc.Hash{}
+	iter := s.db.IteratorPrefix([]byte(blockPreFix))
+	defer iter.Release()
+
+	for iter.Next() {
+		var hash bc.Hash
+		if err := hash.UnmarshalText(bytes.TrimPrefix(iter.Key(), []byte(blockPreFix))); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
 // GetTransactionsUtxo will return all the utxo that related to the input txs
 func (s *Store) GetTransactionsUtxo(view *state.UtxoViewpoint, txs []*bc.Tx) error {
-	return getTransactionsUtxo(s.db, view, txs)
+	for _, tx := range txs {
+		for _, prevout := range tx.SpentOutputIDs {
+			if view.HasUtxo(&prevout) {
+				continue
+			}
+			entry, err := s.GetUtxo(&prevout)
+			if err != nil {
+				return err
+			}
+			view.Entries[prevout] = entry
+		}
+	}
+	return nil
 }
 
 // GetStoreStatus return the BlockStoreStateJSON
@@ -138,6 +191,9 @@ func (s *Store) SaveChainStatus(block *legacy.Block, view *state.UtxoViewpoint,
 	BlockStoreStateJSON{Height: block.Height, Hash: &hash}.save(batch)
 	batch.Write()
 
+	// write back the block's utxo changes now that they are durable
+	s.utxoCache.commit(view.Entries)
+
 	cleanMainchainDB(s.db, &hash)
 	return nil
 }
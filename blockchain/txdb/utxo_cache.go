@@ -0,0 +1,100 @@
+package txdb
+
+import (
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+
+	"github.com/bytom/blockchain/txdb/storage"
+	"github.com/bytom/protocol/bc"
+)
+
+// maxCachedUtxos bounds the in-memory utxo cache. Entries are a few
+// bytes each; the bound mostly caps the key overhead.
+const maxCachedUtxos = 4096
+
+// utxoCache keeps recently touched utxo entries in memory, so mempool
+// acceptance and block verification don't hit the database again for
+// hot outputs. It is write-back: SaveChainStatus refreshes it from each
+// block's utxo view after the batch commits.
+type utxoCache struct {
+	mu     sync.Mutex
+	lru    *lru.Cache
+	gen    uint64 // bumped by every commit; see addIfCurrent
+	hits   uint64
+	misses uint64
+}
+
+func newUtxoCache() utxoCache {
+	return utxoCache{
+		lru: lru.New(maxCachedUtxos),
+	}
+}
+
+// get returns a copy of the cached entry, so callers mutating their
+// view can't corrupt the cache.
+func (c *utxoCache) get(hash *bc.Hash) (*storage.UtxoEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.lru.Get(*hash)
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	entry := *cached.(*storage.UtxoEntry)
+	return &entry, true
+}
+
+// generation returns the current cache generation. A read-through
+// caller records it before loading from the database and hands it back
+// to addIfCurrent.
+func (c *utxoCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// addIfCurrent stores a copy of the entry unless the generation
+// advanced since gen was read. A commit in between may have spent the
+// output, and inserting the pre-commit database state would resurrect
+// it as unspent.
+func (c *utxoCache) addIfCurrent(hash *bc.Hash, entry *storage.UtxoEntry, gen uint64) {
+	cloned := *entry
+	c.mu.Lock()
+	if c.gen == gen {
+		c.lru.Add(*hash, &cloned)
+	}
+	c.mu.Unlock()
+}
+
+// commit applies a committed block's utxo changes and advances the
+// generation, so read-through adds that loaded the pre-commit database
+// state are discarded instead of overwriting the write-back.
+func (c *utxoCache) commit(entries map[bc.Hash]*storage.UtxoEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for key, entry := range entries {
+		if entry.Spend && !entry.IsCoinBase {
+			c.lru.Remove(key)
+			continue
+		}
+		cloned := *entry
+		c.lru.Add(key, &cloned)
+	}
+}
+
+// stats returns the hit and miss counters.
+func (c *utxoCache) stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// UtxoCacheStats reports how often utxo reads were served from memory.
+func (s *Store) UtxoCacheStats() (hits, misses uint64) {
+	return s.utxoCache.stats()
+}
@@ -0,0 +1,242 @@
+package txfeed
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/blockchain/query"
+	"github.com/bytom/database"
+	"github.com/bytom/errors"
+)
+
+const (
+	// maxFeedItems bounds the durable queue of one feed; once full the
+	// oldest items are dropped to admit new ones.
+	maxFeedItems = 8192
+)
+
+const (
+	// feedNS is the bucket holding the feed definitions, keyed by
+	// json-encoded alias.
+	feedNS database.Namespace = "TF:"
+	// itemNS is the bucket holding queued feed items, keyed by
+	// json-encoded alias, a slash and the big-endian sequence number.
+	itemNS database.Namespace = "TFI:"
+	// cursorNS is the bucket holding per-consumer acknowledgment
+	// cursors, keyed by json-encoded alias, a slash and the consumer
+	// name.
+	cursorNS database.Namespace = "TFC:"
+)
+
+func init() {
+	// feeds used to live under raw json(alias) keys at the root of the
+	// database; move them into the feed bucket
+	database.RegisterMigration("txfeeds", database.Migration{
+		Version: 1,
+		Name:    "move transaction feeds into the TF: bucket",
+		Apply: func(db dbm.DB) error {
+			type record struct{ key, value []byte }
+			legacy := []record{}
+
+			iter := db.Iterator()
+			for iter.Next() {
+				// legacy keys are json strings; bucket keys and
+				// bookkeeping keys never start with a quote
+				if key := iter.Key(); len(key) > 0 && key[0] == '"' {
+					legacy = append(legacy, record{
+						key:   append([]byte{}, key...),
+						value: append([]byte{}, iter.Value()...),
+					})
+				}
+			}
+			iter.Release()
+
+			batch := db.NewBatch()
+			for _, rec := range legacy {
+				batch.Set(feedNS.Key(rec.key), rec.value)
+				batch.Delete(rec.key)
+			}
+			batch.Write()
+			return nil
+		},
+	})
+}
+
+// ErrAckFuture is returned when a consumer acknowledges a sequence
+// number the feed has not delivered yet.
+var ErrAckFuture = errors.New("acknowledged sequence was never delivered")
+
+// FeedItem is one matched transaction in a feed's durable queue. Seq
+// grows by one per item and is the cursor a consumer acknowledges.
+type FeedItem struct {
+	Seq uint64             `json:"seq"`
+	Tx  *query.AnnotatedTx `json:"transaction"`
+}
+
+// feedQueue is the in-memory head state of one feed's durable queue.
+type feedQueue struct {
+	nextSeq uint64
+	waiter  chan struct{}
+}
+
+// itemKey orders the items of one feed by sequence number. The alias
+// part is a complete json string, so one feed's range can never run
+// into another's.
+func itemKey(alias string, seq uint64) []byte {
+	key, _ := json.Marshal(alias)
+	key = append(key, '/')
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], seq)
+	return append(key, buf[:]...)
+}
+
+// cursorKey stores one consumer's acknowledgment cursor on one feed.
+func cursorKey(alias, consumer string) []byte {
+	key, _ := json.Marshal(alias)
+	key = append(key, '/')
+	return append(key, consumer...)
+}
+
+// queue returns the head state for alias, creating it on first use.
+// The caller must hold t.mtx.
+func (t *Tracker) queue(alias string) *feedQueue {
+	q, ok := t.queues[alias]
+	if !ok {
+		q = &feedQueue{waiter: make(chan struct{})}
+		t.queues[alias] = q
+	}
+	return q
+}
+
+// loadQueues rebuilds the per-feed sequence counters from the stored
+// items, so restarts continue numbering where they stopped.
+func (t *Tracker) loadQueues() {
+	iter := t.items.Iterator()
+	defer iter.Release()
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for iter.Next() {
+		// the key layout is itemNS + json(alias) + '/' + 8-byte seq
+		key := iter.Key()[len(itemNS):]
+		if len(key) < 9 {
+			continue
+		}
+		var alias string
+		if err := json.Unmarshal(key[:len(key)-9], &alias); err != nil {
+			continue
+		}
+		seq := binary.BigEndian.Uint64(key[len(key)-8:])
+		if q := t.queue(alias); seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+}
+
+// appendItem stores the matched transaction at the tail of the feed's
+// queue, drops the oldest item past the cap, and wakes any waiters.
+func (t *Tracker) appendItem(alias string, tx *query.AnnotatedTx) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	q := t.queue(alias)
+	raw, err := json.Marshal(&FeedItem{Seq: q.nextSeq, Tx: tx})
+	if err != nil {
+		return err
+	}
+	t.items.Set(itemKey(alias, q.nextSeq), raw)
+	if q.nextSeq >= maxFeedItems {
+		t.items.Delete(itemKey(alias, q.nextSeq-maxFeedItems))
+	}
+	q.nextSeq++
+
+	close(q.waiter)
+	q.waiter = make(chan struct{})
+	return nil
+}
+
+// ItemWaiter returns a channel that closes when the next item is
+// appended to the feed. Take the waiter before reading ItemsAfter, or
+// an item landing between the two is missed until the one after.
+func (t *Tracker) ItemWaiter(alias string) <-chan struct{} {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.queue(alias).waiter
+}
+
+// ItemsAfter returns up to limit queued items with sequence numbers at
+// or past cursor, oldest first. Items already dropped by the queue cap
+// are skipped, so a stalled consumer resumes at the oldest retained
+// item.
+func (t *Tracker) ItemsAfter(alias string, cursor uint64, limit int) []*FeedItem {
+	t.mtx.Lock()
+	next := t.queue(alias).nextSeq
+	t.mtx.Unlock()
+
+	items := []*FeedItem{}
+	for seq := cursor; seq < next && len(items) < limit; seq++ {
+		raw := t.items.Get(itemKey(alias, seq))
+		if raw == nil {
+			continue
+		}
+		item := &FeedItem{}
+		if err := json.Unmarshal(raw, item); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// Cursor returns the consumer's acknowledgment cursor on the feed:
+// items with sequence number at or past the cursor are undelivered. A
+// consumer that never acknowledged starts at zero.
+func (t *Tracker) Cursor(alias, consumer string) uint64 {
+	raw := t.cursors.Get(cursorKey(alias, consumer))
+	if raw == nil {
+		return 0
+	}
+	cursor, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return cursor
+}
+
+// Ack records that the consumer has processed every item with sequence
+// number below seq, persisting the cursor across restarts.
+func (t *Tracker) Ack(alias, consumer string, seq uint64) error {
+	t.mtx.Lock()
+	next := t.queue(alias).nextSeq
+	t.mtx.Unlock()
+
+	if seq > next {
+		return errors.WithDetailf(ErrAckFuture, "seq %d past queue tail %d", seq, next)
+	}
+	t.cursors.SetSync(cursorKey(alias, consumer), []byte(strconv.FormatUint(seq, 10)))
+	return nil
+}
+
+// dropQueue removes a deleted feed's queued items and cursors.
+func (t *Tracker) dropQueue(alias string) {
+	t.mtx.Lock()
+	delete(t.queues, alias)
+	t.mtx.Unlock()
+
+	prefix, _ := json.Marshal(alias)
+	prefix = append(prefix, '/')
+	for _, bucket := range []*database.Bucket{t.items, t.cursors} {
+		iter := bucket.IteratorPrefix(prefix)
+		keys := [][]byte{}
+		for iter.Next() {
+			keys = append(keys, append([]byte{}, iter.Key()...))
+		}
+		iter.Release()
+		for _, key := range keys {
+			t.DB.Delete(key)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package txfeed
+
+import (
+	"context"
+	"testing"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/blockchain/query"
+	"github.com/bytom/protocol/bc"
+)
+
+func testItem(n byte) *query.AnnotatedTx {
+	return &query.AnnotatedTx{ID: bc.NewHash([32]byte{n})}
+}
+
+func TestQueueAppendAndAck(t *testing.T) {
+	tracker := NewTracker(dbm.NewMemDB(), nil)
+
+	for n := byte(0); n < 3; n++ {
+		if err := tracker.appendItem("feed", testItem(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items := tracker.ItemsAfter("feed", 0, 10)
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3", len(items))
+	}
+	for i, item := range items {
+		if item.Seq != uint64(i) {
+			t.Errorf("item %d has seq %d", i, item.Seq)
+		}
+		if item.Tx.ID != testItem(byte(i)).ID {
+			t.Errorf("item %d has tx %v", i, item.Tx.ID)
+		}
+	}
+
+	// acknowledge the first two; only the third is redelivered
+	lastSeen := items[1].Seq + 1
+	if err := tracker.Ack("feed", "worker", lastSeen); err != nil {
+		t.Fatal(err)
+	}
+	if cursor := tracker.Cursor("feed", "worker"); cursor != lastSeen {
+		t.Errorf("cursor = %d, want %d", cursor, lastSeen)
+	}
+	items = tracker.ItemsAfter("feed", tracker.Cursor("feed", "worker"), 10)
+	if len(items) != 1 || items[0].Seq != 2 {
+		t.Fatalf("after ack got %v", items)
+	}
+
+	// a second consumer has its own cursor
+	if cursor := tracker.Cursor("feed", "other"); cursor != 0 {
+		t.Errorf("fresh consumer cursor = %d, want 0", cursor)
+	}
+
+	// acknowledging past the tail is rejected
+	if err := tracker.Ack("feed", "worker", 10); err == nil {
+		t.Error("ack past the queue tail succeeded")
+	}
+}
+
+func TestQueueSurvivesRestart(t *testing.T) {
+	db := dbm.NewMemDB()
+
+	tracker := NewTracker(db, nil)
+	for n := byte(0); n < 2; n++ {
+		if err := tracker.appendItem("feed", testItem(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tracker.Ack("feed", "worker", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new tracker on the same database resumes seq numbering and
+	// keeps the consumer cursor
+	tracker = NewTracker(db, nil)
+	if err := tracker.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.appendItem("feed", testItem(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	items := tracker.ItemsAfter("feed", tracker.Cursor("feed", "worker"), 10)
+	if len(items) != 2 {
+		t.Fatalf("got %d items after restart, want 2", len(items))
+	}
+	if items[0].Seq != 1 || items[1].Seq != 2 {
+		t.Errorf("got seqs %d, %d, want 1, 2", items[0].Seq, items[1].Seq)
+	}
+}
+
+func TestQueueWaiter(t *testing.T) {
+	tracker := NewTracker(dbm.NewMemDB(), nil)
+
+	waiter := tracker.ItemWaiter("feed")
+	select {
+	case <-waiter:
+		t.Fatal("waiter fired before any item")
+	default:
+	}
+
+	if err := tracker.appendItem("feed", testItem(0)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-waiter:
+	default:
+		t.Fatal("waiter did not fire on append")
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"strconv"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 	dbm "github.com/tendermint/tmlibs/db"
 
 	"github.com/bytom/blockchain/query"
+	"github.com/bytom/database"
 	"github.com/bytom/errors"
 	"github.com/bytom/protocol"
 	"github.com/bytom/protocol/bc"
@@ -38,6 +40,17 @@ type Tracker struct {
 	TxFeeds  []*TxFeed
 	chain    *protocol.Chain
 	txfeedCh chan *legacy.Tx
+
+	bucket  *database.Bucket
+	items   *database.Bucket
+	cursors *database.Bucket
+
+	mtx    sync.Mutex
+	queues map[string]*feedQueue
+
+	// resolveAccount maps a control program to the owning local account
+	// ID, empty when foreign; nil when the node runs no wallet.
+	resolveAccount func(program []byte) string
 }
 
 type rawOutput struct {
@@ -59,11 +72,14 @@ type TxFeed struct {
 	Param  filter `json:"param,omitempty"`
 }
 
+// the filter fields are unexported, so json tags would never apply;
+// the filter string itself is what serializes
 type filter struct {
-	assetID          string `json:"assetid,omitempty"`
-	amountLowerLimit uint64 `json:"lowerlimit,omitempty"`
-	amountUpperLimit uint64 `json:"upperlimit,omitempty"`
-	transType        string `json:"transtype,omitempty"`
+	assetID          string
+	accountID        string
+	amountLowerLimit uint64
+	amountUpperLimit uint64
+	transType        string
 }
 
 //NewTracker create new txfeed tracker.
@@ -73,13 +89,23 @@ func NewTracker(db dbm.DB, chain *protocol.Chain) *Tracker {
 		TxFeeds:  make([]*TxFeed, 0, 10),
 		chain:    chain,
 		txfeedCh: make(chan *legacy.Tx, maxNewTxfeedChSize),
+		bucket:   database.NewBucket(db, feedNS),
+		items:    database.NewBucket(db, itemNS),
+		cursors:  database.NewBucket(db, cursorNS),
+		queues:   make(map[string]*feedQueue),
 	}
 
 	return s
 }
 
-func loadTxFeed(db dbm.DB, txFeeds []*TxFeed) ([]*TxFeed, error) {
-	iter := db.Iterator()
+// SetAccountResolver installs the control program to account ID lookup
+// used to annotate outputs, so filters can select on account_id.
+func (t *Tracker) SetAccountResolver(resolve func(program []byte) string) {
+	t.resolveAccount = resolve
+}
+
+func loadTxFeed(bucket *database.Bucket, txFeeds []*TxFeed) ([]*TxFeed, error) {
+	iter := bucket.Iterator()
 	defer iter.Release()
 
 	for iter.Next() {
@@ -109,6 +135,9 @@ func parseFilter(ft string) (filter, error) {
 		if strings.Contains(value, "asset_id") {
 			res.assetID = param
 		}
+		if strings.Contains(value, "account_id") {
+			res.accountID = param
+		}
 		if strings.Contains(value, "amount_lower_limit") {
 			tmp, _ := strconv.ParseInt(param, 10, 64)
 			res.amountLowerLimit = uint64(tmp)
@@ -134,11 +163,11 @@ func getParam(str, substr string) string {
 	return ""
 }
 
-func parseTxfeed(db dbm.DB, filters []filter) error {
+func parseTxfeed(bucket *database.Bucket, filters []filter) error {
 	var txFeed TxFeed
 	var index int
 
-	iter := db.Iterator()
+	iter := bucket.Iterator()
 	defer iter.Release()
 
 	for iter.Next() {
@@ -156,6 +185,9 @@ func parseTxfeed(db dbm.DB, filters []filter) error {
 			if strings.Contains(value, "asset_id") {
 				filters[index].assetID = param
 			}
+			if strings.Contains(value, "account_id") {
+				filters[index].accountID = param
+			}
 			if strings.Contains(value, "amount_lower_limit") {
 				tmp, _ := strconv.ParseInt(param, 10, 64)
 				filters[index].amountLowerLimit = uint64(tmp)
@@ -176,8 +208,12 @@ func parseTxfeed(db dbm.DB, filters []filter) error {
 //Prepare load and parse filters.
 func (t *Tracker) Prepare(ctx context.Context) error {
 	var err error
-	t.TxFeeds, err = loadTxFeed(t.DB, t.TxFeeds)
-	return err
+	t.TxFeeds, err = loadTxFeed(t.bucket, t.TxFeeds)
+	if err != nil {
+		return err
+	}
+	t.loadQueues()
+	return nil
 }
 
 //GetTxfeedCh return a txfeed channel.
@@ -218,22 +254,22 @@ func (t *Tracker) Create(ctx context.Context, alias, fil string) error {
 	}
 	feed.Param = filter
 	t.TxFeeds = append(t.TxFeeds, feed)
-	return insertTxFeed(t.DB, feed)
+	return insertTxFeed(t.bucket, feed)
 }
 
-func deleteTxFeed(db dbm.DB, alias string) error {
+func deleteTxFeed(bucket *database.Bucket, alias string) error {
 	key, err := json.Marshal(alias)
 	if err != nil {
 		return err
 	}
-	db.Delete(key)
+	bucket.Delete(key)
 	return nil
 }
 
 // insertTxFeed adds the txfeed to the database. If the txfeed has a client token,
 // and there already exists a txfeed with that client token, insertTxFeed will
 // lookup and return the existing txfeed instead.
-func insertTxFeed(db dbm.DB, feed *TxFeed) error {
+func insertTxFeed(bucket *database.Bucket, feed *TxFeed) error {
 	// var err error
 	key, err := json.Marshal(feed.Alias)
 	if err != nil {
@@ -244,7 +280,7 @@ func insertTxFeed(db dbm.DB, feed *TxFeed) error {
 		return err
 	}
 
-	db.Set(key, value)
+	bucket.Set(key, value)
 	return nil
 }
 
@@ -262,6 +298,11 @@ func (t *Tracker) Get(ctx context.Context, alias string) (*TxFeed, error) {
 	return nil, nil
 }
 
+//List list all txfeed filters.
+func (t *Tracker) List(ctx context.Context) ([]*TxFeed, error) {
+	return loadTxFeed(t.bucket, make([]*TxFeed, 0, len(t.TxFeeds)))
+}
+
 //Delete delete txfeed with alias.
 func (t *Tracker) Delete(ctx context.Context, alias string) error {
 	log.WithField("delete", alias).Info("delete txfeed")
@@ -273,7 +314,8 @@ func (t *Tracker) Delete(ctx context.Context, alias string) error {
 	for i, txfeed := range t.TxFeeds {
 		if txfeed.Alias == alias {
 			t.TxFeeds = append(t.TxFeeds[:i], t.TxFeeds[i+1:]...)
-			return deleteTxFeed(t.DB, alias)
+			t.dropQueue(alias)
+			return deleteTxFeed(t.bucket, alias)
 		}
 	}
 	return nil
@@ -285,6 +327,9 @@ func outputFilter(txfeed *TxFeed, value *query.AnnotatedOutput) bool {
 	if 0 != strings.Compare(txfeed.Param.assetID, assetidstr) && txfeed.Param.assetID != "" {
 		return false
 	}
+	if 0 != strings.Compare(txfeed.Param.accountID, value.AccountID) && txfeed.Param.accountID != "" {
+		return false
+	}
 	if 0 != strings.Compare(txfeed.Param.transType, value.Type) && txfeed.Param.transType != "" {
 		return false
 	}
@@ -303,8 +348,13 @@ func (t *Tracker) TxFilter(tx *legacy.Tx) error {
 	var annotatedTx *query.AnnotatedTx
 	// Build the fully annotated transaction.
 	annotatedTx = buildAnnotatedTransaction(tx)
-	for _, output := range annotatedTx.Outputs {
-		for _, filter := range t.TxFeeds {
+	if t.resolveAccount != nil {
+		for _, output := range annotatedTx.Outputs {
+			output.AccountID = t.resolveAccount(output.ControlProgram)
+		}
+	}
+	for _, filter := range t.TxFeeds {
+		for _, output := range annotatedTx.Outputs {
 			if match := outputFilter(filter, output); !match {
 				continue
 			}
@@ -313,12 +363,23 @@ func (t *Tracker) TxFilter(tx *legacy.Tx) error {
 				return err
 			}
 			log.WithField("filter", string(b)).Info("find new tx match filter")
+			if err := t.appendItem(filter.Alias, annotatedTx); err != nil {
+				return err
+			}
 			t.txfeedCh <- tx
+			// one queue item per feed, however many outputs match
+			break
 		}
 	}
 	return nil
 }
 
+// BuildAnnotatedTransaction annotates a raw transaction for external
+// consumers; the event sink exporter shares it with the tx filter.
+func BuildAnnotatedTransaction(tx *legacy.Tx) *query.AnnotatedTx {
+	return buildAnnotatedTransaction(tx)
+}
+
 var emptyJSONObject = json.RawMessage(`{}`)
 
 func buildAnnotatedTransaction(orig *legacy.Tx) *query.AnnotatedTx {
@@ -378,6 +439,8 @@ func buildAnnotatedOutput(tx *legacy.Tx, idx int) *query.AnnotatedOutput {
 
 	if vmutil.IsUnspendable(out.ControlProgram) {
 		out.Type = "retire"
+	} else if vmutil.IsHTLCProgram(out.ControlProgram) {
+		out.Type = "htlc"
 	} else {
 		out.Type = "control"
 	}
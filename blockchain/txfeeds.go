@@ -3,11 +3,14 @@ package blockchain
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
 
 	"github.com/bytom/blockchain/query"
 	"github.com/bytom/blockchain/txfeed"
+	chainjson "github.com/bytom/encoding/json"
 )
 
 // POST /create-txfeed
@@ -23,22 +26,7 @@ func (bcr *BlockchainReactor) createTxFeed(ctx context.Context, in struct {
 }
 
 func (bcr *BlockchainReactor) getTxFeedByAlias(ctx context.Context, filter string) (*txfeed.TxFeed, error) {
-	txFeed := &txfeed.TxFeed{}
-
-	jf, err := json.Marshal(filter)
-	if err != nil {
-		return nil, err
-	}
-
-	value := bcr.txFeedTracker.DB.Get(jf)
-	if value == nil {
-		return nil, nil
-	}
-
-	if err := json.Unmarshal(value, txFeed); err != nil {
-		return nil, err
-	}
-	return txFeed, nil
+	return bcr.txFeedTracker.Get(ctx, filter)
 }
 
 // POST /get-transaction-feed
@@ -96,18 +84,7 @@ func txAfterIsBefore(a, b string) (bool, error) {
 }
 
 func (bcr *BlockchainReactor) getTxFeeds() ([]*txfeed.TxFeed, error) {
-	txFeeds := make([]*txfeed.TxFeed, 0)
-	iter := bcr.txFeedTracker.DB.Iterator()
-	defer iter.Release()
-
-	for iter.Next() {
-		txFeed := &txfeed.TxFeed{}
-		if err := json.Unmarshal(iter.Value(), txFeed); err != nil {
-			return nil, err
-		}
-		txFeeds = append(txFeeds, txFeed)
-	}
-	return txFeeds, nil
+	return bcr.txFeedTracker.List(context.Background())
 }
 
 // listTxFeeds is an http handler for listing txfeeds. It does not take a filter.
@@ -119,3 +96,119 @@ func (bcr *BlockchainReactor) listTxFeeds(ctx context.Context, in requestQuery)
 	}
 	return jsendWrapper(txfeeds, SUCCESS, "")
 }
+
+const (
+	// txFeedItemPageSize caps one page of queued feed items.
+	txFeedItemPageSize = 100
+	// maxTxFeedPollWait caps how long a long-poll request is parked
+	// before it returns empty-handed.
+	maxTxFeedPollWait = 90 * time.Second
+)
+
+// getTxFeedItems serves the consumer's undelivered queue items. With a
+// timeout it long-polls: the request parks until an item arrives or the
+// timeout passes. Consumers advance with /ack-transaction-feed; an
+// unacknowledged crash redelivers from the last cursor.
+// POST /get-transaction-feed-items
+func (bcr *BlockchainReactor) getTxFeedItems(ctx context.Context, in struct {
+	Alias    string             `json:"alias"`
+	Consumer string             `json:"consumer"`
+	Timeout  chainjson.Duration `json:"timeout"`
+	Limit    int                `json:"limit"`
+}) interface{} {
+	feed, err := bcr.txFeedTracker.Get(ctx, in.Alias)
+	if err != nil {
+		return jsendWrapper(nil, ERROR, err.Error())
+	}
+	if feed == nil {
+		return jsendWrapper(nil, ERROR, "transaction feed not found")
+	}
+
+	limit := in.Limit
+	if limit <= 0 || limit > txFeedItemPageSize {
+		limit = txFeedItemPageSize
+	}
+	wait := in.Timeout.Duration
+	if wait > maxTxFeedPollWait {
+		wait = maxTxFeedPollWait
+	}
+
+	cursor := bcr.txFeedTracker.Cursor(in.Alias, in.Consumer)
+	var timeout <-chan time.Time
+	for {
+		// take the waiter before reading, so an item landing in
+		// between still wakes this request
+		waiter := bcr.txFeedTracker.ItemWaiter(in.Alias)
+		items := bcr.txFeedTracker.ItemsAfter(in.Alias, cursor, limit)
+		if len(items) > 0 || wait == 0 {
+			return jsendWrapper(struct {
+				Items  []*txfeed.FeedItem `json:"items"`
+				Cursor uint64             `json:"cursor"`
+			}{items, cursor}, SUCCESS, "")
+		}
+
+		if timeout == nil {
+			timeout = time.After(wait)
+		}
+		select {
+		case <-waiter:
+		case <-timeout:
+			wait = 0
+		case <-ctx.Done():
+			wait = 0
+		}
+	}
+}
+
+// ackTxFeed records that the consumer processed every item below seq.
+// POST /ack-transaction-feed
+func (bcr *BlockchainReactor) ackTxFeed(ctx context.Context, in struct {
+	Alias    string `json:"alias"`
+	Consumer string `json:"consumer"`
+	Seq      uint64 `json:"seq"`
+}) interface{} {
+	feed, err := bcr.txFeedTracker.Get(ctx, in.Alias)
+	if err != nil {
+		return jsendWrapper(nil, ERROR, err.Error())
+	}
+	if feed == nil {
+		return jsendWrapper(nil, ERROR, "transaction feed not found")
+	}
+	if err := bcr.txFeedTracker.Ack(in.Alias, in.Consumer, in.Seq); err != nil {
+		return jsendWrapper(nil, ERROR, err.Error())
+	}
+	return jsendWrapper("success", SUCCESS, "")
+}
+
+// txFeedSocket streams feed items to one websocket subscriber, starting
+// from the consumer's acknowledged cursor. The stream only moves the
+// session's own position; durable progress is still recorded with
+// /ack-transaction-feed, so a dropped connection resumes where the
+// consumer last acknowledged.
+func (bcr *BlockchainReactor) txFeedSocket(ws *websocket.Conn) {
+	defer ws.Close()
+
+	params := ws.Request().URL.Query()
+	alias, consumer := params.Get("alias"), params.Get("consumer")
+	feed, err := bcr.txFeedTracker.Get(ws.Request().Context(), alias)
+	if err != nil || feed == nil {
+		return
+	}
+
+	cursor := bcr.txFeedTracker.Cursor(alias, consumer)
+	enc := json.NewEncoder(ws)
+	for {
+		waiter := bcr.txFeedTracker.ItemWaiter(alias)
+		items := bcr.txFeedTracker.ItemsAfter(alias, cursor, txFeedItemPageSize)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return
+			}
+			cursor = item.Seq + 1
+		}
+		if len(items) == txFeedItemPageSize {
+			continue
+		}
+		<-waiter
+	}
+}
@@ -80,7 +80,6 @@ func annotateTxsAccount(txs []*query.AnnotatedTx, walletDB db.DB) {
 }
 
 func getAccountFromUTXO(outputID bc.Hash, walletDB db.DB) (*account.Account, error) {
-	accountUTXO := account.UTXO{}
 	localAccount := account.Account{}
 
 	accountUTXOValue := walletDB.Get(account.UTXOKey(outputID))
@@ -88,7 +87,8 @@ func getAccountFromUTXO(outputID bc.Hash, walletDB db.DB) (*account.Account, err
 		return nil, errors.Wrap(fmt.Errorf("failed get account utxo:%x ", outputID))
 	}
 
-	if err := json.Unmarshal(accountUTXOValue, &accountUTXO); err != nil {
+	accountUTXO, err := account.DecodeUTXO(accountUTXOValue)
+	if err != nil {
 		return nil, errors.Wrap(err)
 	}
 
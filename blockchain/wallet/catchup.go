@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+const (
+	// catchupThreshold is how many blocks behind the chain the wallet
+	// must be before WalletUpdate switches to the pipelined catch-up.
+	catchupThreshold = 128
+
+	// catchupDepth bounds how far the prefetcher may run ahead of the
+	// commit loop.
+	catchupDepth = 64
+)
+
+// Progress reports how far the wallet indexer has gotten relative to
+// the chain; it backs the /wallet-info API.
+type Progress struct {
+	WalletHeight uint64 `json:"wallet_height"`
+	ChainHeight  uint64 `json:"chain_height"`
+	CatchingUp   bool   `json:"catching_up"`
+}
+
+// Progress returns a snapshot of the indexer position for the API.
+func (w *Wallet) Progress(c *protocol.Chain) Progress {
+	w.progressMtx.Lock()
+	defer w.progressMtx.Unlock()
+	return Progress{
+		WalletHeight: w.status.Height,
+		ChainHeight:  c.Height(),
+		CatchingUp:   w.catchingUp,
+	}
+}
+
+func (w *Wallet) setCatchingUp(catchingUp bool) {
+	w.progressMtx.Lock()
+	w.catchingUp = catchingUp
+	w.progressMtx.Unlock()
+}
+
+// catchup drains a long backlog of blocks up to target. A prefetcher
+// loads and decodes blocks ahead of the commit loop, which indexes and
+// commits them strictly in order; the per-transaction annotation inside
+// each block already fans out on the worker pool. Commits stay in block
+// order because annotating a block reads the UTXOs its predecessors
+// committed.
+func (w *Wallet) catchup(c *protocol.Chain, target uint64) {
+	w.setCatchingUp(true)
+	defer w.setCatchingUp(false)
+
+	blocks := make(chan *legacy.Block, catchupDepth)
+	start := w.status.Height
+	go func() {
+		defer close(blocks)
+		for height := start + 1; height <= target; height++ {
+			block, err := c.GetBlockByHeight(height)
+			if err != nil {
+				log.WithField("err", err).Error("wallet catchup get block by height")
+				return
+			}
+			blocks <- block
+		}
+	}()
+
+	for block := range blocks {
+		//the chain reorganized under us; let WalletUpdate roll back
+		if block.PreviousBlockHash != w.status.Hash {
+			break
+		}
+
+		storeBatch := w.DB.NewBatch()
+		if err := indexTransactions(&storeBatch, block, w); err != nil {
+			log.WithField("err", err).Error("indexing transactions")
+			break
+		}
+		buildAccountUTXOs(&storeBatch, block, w)
+
+		w.setStatus(block.Height, block.Hash())
+		if err := w.commitWalletInfo(&storeBatch); err != nil {
+			break
+		}
+	}
+
+	//unblock the prefetcher so it can exit
+	for range blocks {
+	}
+}
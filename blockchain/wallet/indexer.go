@@ -8,7 +8,6 @@ import (
 	"github.com/tendermint/tmlibs/db"
 
 	"github.com/bytom/blockchain/account"
-	"github.com/bytom/blockchain/query"
 	"github.com/bytom/crypto/sha3pool"
 	"github.com/bytom/errors"
 	"github.com/bytom/protocol/bc"
@@ -111,13 +110,13 @@ func reverseAccountUTXOs(batch *db.Batch, b *legacy.Block, w *Wallet) {
 }
 
 //indexTransactions saves all annotated transactions to the database.
+//The per-transaction annotation work runs on the worker pool; see
+//pool.go.
 func indexTransactions(batch *db.Batch, b *legacy.Block, w *Wallet) error {
-	annotatedTxs := filterAccountTxs(b, w)
-	annotateTxsAsset(annotatedTxs, w.DB)
-	annotateTxsAccount(annotatedTxs, w.DB)
+	annotatedTxs := w.annotatePool.annotateBlockTxs(b, w)
 
 	for pos, tx := range annotatedTxs {
-		rawTx, err := json.MarshalIndent(tx, "", "    ")
+		rawTx, err := EncodeAnnotatedTx(tx)
 		if err != nil {
 			return errors.Wrap(err, "inserting annotated_txs to db")
 		}
@@ -195,6 +194,10 @@ func loadAccountInfo(outs []*rawOutput, w *Wallet) []*accountOutput {
 	var hash [32]byte
 	for s := range outsByScript {
 		sha3pool.Sum256(hash[:], []byte(s))
+		if !w.cpFilter.MatchHash(hash) {
+			continue
+		}
+
 		bytes := w.DB.Get(account.CPKey(hash))
 		if bytes == nil {
 			continue
@@ -242,7 +245,7 @@ func upsertConfirmedAccountOutputs(outs []*accountOutput, block *legacy.Block, b
 			RefData:      out.refData.Bytes(),
 			Change:       out.change}
 
-		rawUTXO, err := json.Marshal(u)
+		rawUTXO, err := u.Encode()
 		if err != nil {
 			return errors.Wrap(err, "failed marshal accountutxo")
 		}
@@ -251,38 +254,3 @@ func upsertConfirmedAccountOutputs(outs []*accountOutput, block *legacy.Block, b
 	}
 	return nil
 }
-
-// filt related and build the fully annotated transactions.
-func filterAccountTxs(b *legacy.Block, w *Wallet) []*query.AnnotatedTx {
-	annotatedTxs := make([]*query.AnnotatedTx, 0, len(b.Transactions))
-	for pos, tx := range b.Transactions {
-		local := false
-		for _, v := range tx.Outputs {
-			var hash [32]byte
-
-			sha3pool.Sum256(hash[:], v.ControlProgram)
-			if bytes := w.DB.Get(account.CPKey(hash)); bytes != nil {
-				annotatedTxs = append(annotatedTxs, buildAnnotatedTransaction(tx, b, uint32(pos)))
-				local = true
-				break
-			}
-		}
-
-		if local == true {
-			continue
-		}
-
-		for _, v := range tx.Inputs {
-			outid, err := v.SpentOutputID()
-			if err != nil {
-				continue
-			}
-			if bytes := w.DB.Get(account.UTXOKey(outid)); bytes != nil {
-				annotatedTxs = append(annotatedTxs, buildAnnotatedTransaction(tx, b, uint32(pos)))
-				break
-			}
-		}
-	}
-
-	return annotatedTxs
-}
@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/bytom/blockchain/account"
+	"github.com/bytom/blockchain/query"
+	"github.com/bytom/crypto/sha3pool"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// The annotation pool fans the per-transaction indexing work - deciding
+// whether a transaction touches the wallet and building its annotated
+// form - out over a bounded set of workers, so the wallet doesn't fall
+// behind the chain tip on blocks full of transactions. Results come
+// back in transaction order and the per-block database commit is
+// unchanged; only the lookup- and CPU-heavy annotation runs in
+// parallel.
+
+// annotateJob asks a worker to annotate one transaction of a block,
+// leaving the result (or nil for an unrelated transaction) in the slot
+// the collector handed out.
+type annotateJob struct {
+	block  *legacy.Block
+	pos    int
+	wallet *Wallet
+	result **query.AnnotatedTx
+	wg     *sync.WaitGroup
+}
+
+type annotatePool struct {
+	jobs chan *annotateJob
+}
+
+func newAnnotatePool() *annotatePool {
+	workers := runtime.NumCPU()
+	p := &annotatePool{jobs: make(chan *annotateJob, workers)}
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+func (p *annotatePool) worker() {
+	for job := range p.jobs {
+		*job.result = annotateTx(job.block, job.pos, job.wallet)
+		job.wg.Done()
+	}
+}
+
+// annotateBlockTxs builds the annotated form of every wallet-related
+// transaction of the block, in block order, using the worker pool.
+func (p *annotatePool) annotateBlockTxs(b *legacy.Block, w *Wallet) []*query.AnnotatedTx {
+	results := make([]*query.AnnotatedTx, len(b.Transactions))
+	var wg sync.WaitGroup
+	wg.Add(len(b.Transactions))
+	for pos := range b.Transactions {
+		p.jobs <- &annotateJob{block: b, pos: pos, wallet: w, result: &results[pos], wg: &wg}
+	}
+	wg.Wait()
+
+	annotatedTxs := make([]*query.AnnotatedTx, 0, len(results))
+	for _, tx := range results {
+		if tx != nil {
+			annotatedTxs = append(annotatedTxs, tx)
+		}
+	}
+	return annotatedTxs
+}
+
+// annotateTx reports whether the transaction at the given position
+// touches the wallet - paying to one of its control programs or
+// spending one of its UTXOs - and if so returns it fully annotated
+// with asset and account data. Unrelated transactions return nil.
+func annotateTx(b *legacy.Block, pos int, w *Wallet) *query.AnnotatedTx {
+	tx := b.Transactions[pos]
+	related := false
+	for _, v := range tx.Outputs {
+		var hash [32]byte
+		sha3pool.Sum256(hash[:], v.ControlProgram)
+		// the filter rules out foreign programs without a db lookup
+		if !w.cpFilter.MatchHash(hash) {
+			continue
+		}
+		if bytes := w.DB.Get(account.CPKey(hash)); bytes != nil {
+			related = true
+			break
+		}
+	}
+	if !related {
+		for _, v := range tx.Inputs {
+			outid, err := v.SpentOutputID()
+			if err != nil {
+				continue
+			}
+			if bytes := w.DB.Get(account.UTXOKey(outid)); bytes != nil {
+				related = true
+				break
+			}
+		}
+	}
+	if !related {
+		return nil
+	}
+
+	annotated := []*query.AnnotatedTx{buildAnnotatedTransaction(tx, b, uint32(pos))}
+	annotateTxsAsset(annotated, w.DB)
+	annotateTxsAccount(annotated, w.DB)
+	return annotated[0]
+}
@@ -0,0 +1,216 @@
+package wallet
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/blockchain/account"
+	"github.com/bytom/blockchain/query"
+	"github.com/bytom/blockchain/wallet/storage"
+	"github.com/bytom/database"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+)
+
+// The wallet persists annotated transactions in the compact protobuf
+// form of blockchain/wallet/storage and renders JSON only at the API
+// boundary. JSON sub-documents travel as verbatim bytes; an empty
+// field round-trips to a nil pointer.
+
+func init() {
+	// account utxos and annotated transactions used to be stored as
+	// json; re-encode them so readers only ever see protobuf
+	database.RegisterMigration("wallet", database.Migration{
+		Version: 1,
+		Name:    "re-encode account utxos and annotated transactions as protobuf",
+		Apply:   reencodeWalletRecords,
+	})
+}
+
+// reencodeWalletRecords rewrites every legacy json record under the
+// account utxo and transaction prefixes in its protobuf form. Legacy
+// values are recognized by their leading '{'; the protobuf records
+// never start with one.
+func reencodeWalletRecords(walletDB dbm.DB) error {
+	batch := walletDB.NewBatch()
+
+	utxoIter := walletDB.IteratorPrefix([]byte(account.UTXONS))
+	defer utxoIter.Release()
+	for utxoIter.Next() {
+		value := utxoIter.Value()
+		if len(value) == 0 || value[0] != '{' {
+			continue
+		}
+		utxo := new(account.UTXO)
+		if err := json.Unmarshal(value, utxo); err != nil {
+			return errors.Wrap(err, "decoding legacy account utxo")
+		}
+		data, err := utxo.Encode()
+		if err != nil {
+			return errors.Wrap(err, "re-encoding account utxo")
+		}
+		batch.Set(append([]byte{}, utxoIter.Key()...), data)
+	}
+
+	txIter := walletDB.IteratorPrefix([]byte(TxPreFix))
+	defer txIter.Release()
+	for txIter.Next() {
+		value := txIter.Value()
+		if len(value) == 0 || value[0] != '{' {
+			continue
+		}
+		annotatedTx := new(query.AnnotatedTx)
+		if err := json.Unmarshal(value, annotatedTx); err != nil {
+			return errors.Wrap(err, "decoding legacy annotated transaction")
+		}
+		data, err := EncodeAnnotatedTx(annotatedTx)
+		if err != nil {
+			return errors.Wrap(err, "re-encoding annotated transaction")
+		}
+		batch.Set(append([]byte{}, txIter.Key()...), data)
+	}
+
+	batch.Write()
+	return nil
+}
+
+func rawJSONBytes(p *json.RawMessage) []byte {
+	if p == nil {
+		return nil
+	}
+	return []byte(*p)
+}
+
+func jsonPointer(raw []byte) *json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	msg := json.RawMessage(raw)
+	return &msg
+}
+
+func hashFromBytes(raw []byte) bc.Hash {
+	var b32 [32]byte
+	copy(b32[:], raw)
+	return bc.NewHash(b32)
+}
+
+func assetIDFromBytes(raw []byte) bc.AssetID {
+	var b32 [32]byte
+	copy(b32[:], raw)
+	return bc.NewAssetID(b32)
+}
+
+// EncodeAnnotatedTx packs an annotated transaction into its persisted
+// form.
+func EncodeAnnotatedTx(tx *query.AnnotatedTx) ([]byte, error) {
+	record := &storage.AnnotatedTx{
+		Id:            tx.ID.Bytes(),
+		TimestampMS:   uint64(tx.Timestamp.UnixNano() / int64(time.Millisecond)),
+		BlockID:       tx.BlockID.Bytes(),
+		BlockHeight:   tx.BlockHeight,
+		Position:      tx.Position,
+		BlockTxCount:  tx.BlockTransactionsCount,
+		ReferenceData: rawJSONBytes(tx.ReferenceData),
+	}
+	for _, in := range tx.Inputs {
+		rin := &storage.AnnotatedInput{
+			Type:            in.Type,
+			AssetID:         in.AssetID.Bytes(),
+			AssetAlias:      in.AssetAlias,
+			AssetDefinition: rawJSONBytes(in.AssetDefinition),
+			AssetTags:       rawJSONBytes(in.AssetTags),
+			Amount:          in.Amount,
+			IssuanceProgram: in.IssuanceProgram,
+			ControlProgram:  in.ControlProgram,
+			AccountID:       in.AccountID,
+			AccountAlias:    in.AccountAlias,
+			AccountTags:     rawJSONBytes(in.AccountTags),
+			ReferenceData:   rawJSONBytes(in.ReferenceData),
+		}
+		if in.SpentOutputID != nil {
+			rin.SpentOutputID = in.SpentOutputID.Bytes()
+		}
+		record.Inputs = append(record.Inputs, rin)
+	}
+	for _, out := range tx.Outputs {
+		record.Outputs = append(record.Outputs, &storage.AnnotatedOutput{
+			Type:            out.Type,
+			OutputID:        out.OutputID.Bytes(),
+			Position:        int64(out.Position),
+			AssetID:         out.AssetID.Bytes(),
+			AssetAlias:      out.AssetAlias,
+			AssetDefinition: rawJSONBytes(out.AssetDefinition),
+			AssetTags:       rawJSONBytes(out.AssetTags),
+			Amount:          out.Amount,
+			AccountID:       out.AccountID,
+			AccountAlias:    out.AccountAlias,
+			AccountTags:     rawJSONBytes(out.AccountTags),
+			ControlProgram:  out.ControlProgram,
+			ReferenceData:   rawJSONBytes(out.ReferenceData),
+		})
+	}
+	return proto.Marshal(record)
+}
+
+// DecodeAnnotatedTx unpacks a persisted annotated transaction.
+func DecodeAnnotatedTx(data []byte) (*query.AnnotatedTx, error) {
+	record := &storage.AnnotatedTx{}
+	if err := proto.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+
+	tx := &query.AnnotatedTx{
+		ID:                     hashFromBytes(record.Id),
+		Timestamp:              time.Unix(0, int64(record.TimestampMS)*int64(time.Millisecond)).UTC(),
+		BlockID:                hashFromBytes(record.BlockID),
+		BlockHeight:            record.BlockHeight,
+		Position:               record.Position,
+		BlockTransactionsCount: record.BlockTxCount,
+		ReferenceData:          jsonPointer(record.ReferenceData),
+		Inputs:                 make([]*query.AnnotatedInput, 0, len(record.Inputs)),
+		Outputs:                make([]*query.AnnotatedOutput, 0, len(record.Outputs)),
+	}
+	for _, rin := range record.Inputs {
+		in := &query.AnnotatedInput{
+			Type:            rin.Type,
+			AssetID:         assetIDFromBytes(rin.AssetID),
+			AssetAlias:      rin.AssetAlias,
+			AssetDefinition: jsonPointer(rin.AssetDefinition),
+			AssetTags:       jsonPointer(rin.AssetTags),
+			Amount:          rin.Amount,
+			IssuanceProgram: rin.IssuanceProgram,
+			ControlProgram:  rin.ControlProgram,
+			AccountID:       rin.AccountID,
+			AccountAlias:    rin.AccountAlias,
+			AccountTags:     jsonPointer(rin.AccountTags),
+			ReferenceData:   jsonPointer(rin.ReferenceData),
+		}
+		if len(rin.SpentOutputID) > 0 {
+			spent := hashFromBytes(rin.SpentOutputID)
+			in.SpentOutputID = &spent
+		}
+		tx.Inputs = append(tx.Inputs, in)
+	}
+	for _, rout := range record.Outputs {
+		tx.Outputs = append(tx.Outputs, &query.AnnotatedOutput{
+			Type:            rout.Type,
+			OutputID:        hashFromBytes(rout.OutputID),
+			Position:        int(rout.Position),
+			AssetID:         assetIDFromBytes(rout.AssetID),
+			AssetAlias:      rout.AssetAlias,
+			AssetDefinition: jsonPointer(rout.AssetDefinition),
+			AssetTags:       jsonPointer(rout.AssetTags),
+			Amount:          rout.Amount,
+			AccountID:       rout.AccountID,
+			AccountAlias:    rout.AccountAlias,
+			AccountTags:     jsonPointer(rout.AccountTags),
+			ControlProgram:  rout.ControlProgram,
+			ReferenceData:   jsonPointer(rout.ReferenceData),
+		})
+	}
+	return tx, nil
+}
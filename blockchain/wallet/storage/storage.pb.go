@@ -0,0 +1,92 @@
+// Package storage holds the compact persisted encodings of the
+// wallet's records. The types mirror storage.proto and are maintained
+// by hand in the generated style so building the tree does not require
+// protoc; they marshal through github.com/golang/protobuf using the
+// struct tags.
+package storage
+
+import proto "github.com/golang/protobuf/proto"
+
+// UtxoEntry is the persisted form of an account unspent output.
+type UtxoEntry struct {
+	OutputID     []byte `protobuf:"bytes,1,opt,name=outputID,proto3" json:"outputID,omitempty"`
+	AssetID      []byte `protobuf:"bytes,2,opt,name=assetID,proto3" json:"assetID,omitempty"`
+	Amount       uint64 `protobuf:"varint,3,opt,name=amount" json:"amount,omitempty"`
+	AccountID    string `protobuf:"bytes,4,opt,name=accountID,proto3" json:"accountID,omitempty"`
+	ProgramIndex uint64 `protobuf:"varint,5,opt,name=programIndex" json:"programIndex,omitempty"`
+	Program      []byte `protobuf:"bytes,6,opt,name=program,proto3" json:"program,omitempty"`
+	SourceID     []byte `protobuf:"bytes,7,opt,name=sourceID,proto3" json:"sourceID,omitempty"`
+	SourcePos    uint64 `protobuf:"varint,8,opt,name=sourcePos" json:"sourcePos,omitempty"`
+	RefData      []byte `protobuf:"bytes,9,opt,name=refData,proto3" json:"refData,omitempty"`
+	Change       bool   `protobuf:"varint,10,opt,name=change" json:"change,omitempty"`
+}
+
+func (m *UtxoEntry) Reset()         { *m = UtxoEntry{} }
+func (m *UtxoEntry) String() string { return proto.CompactTextString(m) }
+func (*UtxoEntry) ProtoMessage()    {}
+
+// AnnotatedTx is the persisted form of a wallet-related transaction.
+// JSON sub-documents (reference data, asset definitions and tags) are
+// kept verbatim as bytes; an empty field means the value was absent.
+type AnnotatedTx struct {
+	Id            []byte             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TimestampMS   uint64             `protobuf:"varint,2,opt,name=timestampMS" json:"timestampMS,omitempty"`
+	BlockID       []byte             `protobuf:"bytes,3,opt,name=blockID,proto3" json:"blockID,omitempty"`
+	BlockHeight   uint64             `protobuf:"varint,4,opt,name=blockHeight" json:"blockHeight,omitempty"`
+	Position      uint32             `protobuf:"varint,5,opt,name=position" json:"position,omitempty"`
+	BlockTxCount  uint32             `protobuf:"varint,6,opt,name=blockTxCount" json:"blockTxCount,omitempty"`
+	ReferenceData []byte             `protobuf:"bytes,7,opt,name=referenceData,proto3" json:"referenceData,omitempty"`
+	Inputs        []*AnnotatedInput  `protobuf:"bytes,8,rep,name=inputs" json:"inputs,omitempty"`
+	Outputs       []*AnnotatedOutput `protobuf:"bytes,9,rep,name=outputs" json:"outputs,omitempty"`
+}
+
+func (m *AnnotatedTx) Reset()         { *m = AnnotatedTx{} }
+func (m *AnnotatedTx) String() string { return proto.CompactTextString(m) }
+func (*AnnotatedTx) ProtoMessage()    {}
+
+type AnnotatedInput struct {
+	Type            string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	AssetID         []byte `protobuf:"bytes,2,opt,name=assetID,proto3" json:"assetID,omitempty"`
+	AssetAlias      string `protobuf:"bytes,3,opt,name=assetAlias,proto3" json:"assetAlias,omitempty"`
+	AssetDefinition []byte `protobuf:"bytes,4,opt,name=assetDefinition,proto3" json:"assetDefinition,omitempty"`
+	AssetTags       []byte `protobuf:"bytes,5,opt,name=assetTags,proto3" json:"assetTags,omitempty"`
+	Amount          uint64 `protobuf:"varint,6,opt,name=amount" json:"amount,omitempty"`
+	IssuanceProgram []byte `protobuf:"bytes,7,opt,name=issuanceProgram,proto3" json:"issuanceProgram,omitempty"`
+	ControlProgram  []byte `protobuf:"bytes,8,opt,name=controlProgram,proto3" json:"controlProgram,omitempty"`
+	SpentOutputID   []byte `protobuf:"bytes,9,opt,name=spentOutputID,proto3" json:"spentOutputID,omitempty"`
+	AccountID       string `protobuf:"bytes,10,opt,name=accountID,proto3" json:"accountID,omitempty"`
+	AccountAlias    string `protobuf:"bytes,11,opt,name=accountAlias,proto3" json:"accountAlias,omitempty"`
+	AccountTags     []byte `protobuf:"bytes,12,opt,name=accountTags,proto3" json:"accountTags,omitempty"`
+	ReferenceData   []byte `protobuf:"bytes,13,opt,name=referenceData,proto3" json:"referenceData,omitempty"`
+}
+
+func (m *AnnotatedInput) Reset()         { *m = AnnotatedInput{} }
+func (m *AnnotatedInput) String() string { return proto.CompactTextString(m) }
+func (*AnnotatedInput) ProtoMessage()    {}
+
+type AnnotatedOutput struct {
+	Type            string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	OutputID        []byte `protobuf:"bytes,2,opt,name=outputID,proto3" json:"outputID,omitempty"`
+	Position        int64  `protobuf:"varint,3,opt,name=position" json:"position,omitempty"`
+	AssetID         []byte `protobuf:"bytes,4,opt,name=assetID,proto3" json:"assetID,omitempty"`
+	AssetAlias      string `protobuf:"bytes,5,opt,name=assetAlias,proto3" json:"assetAlias,omitempty"`
+	AssetDefinition []byte `protobuf:"bytes,6,opt,name=assetDefinition,proto3" json:"assetDefinition,omitempty"`
+	AssetTags       []byte `protobuf:"bytes,7,opt,name=assetTags,proto3" json:"assetTags,omitempty"`
+	Amount          uint64 `protobuf:"varint,8,opt,name=amount" json:"amount,omitempty"`
+	AccountID       string `protobuf:"bytes,9,opt,name=accountID,proto3" json:"accountID,omitempty"`
+	AccountAlias    string `protobuf:"bytes,10,opt,name=accountAlias,proto3" json:"accountAlias,omitempty"`
+	AccountTags     []byte `protobuf:"bytes,11,opt,name=accountTags,proto3" json:"accountTags,omitempty"`
+	ControlProgram  []byte `protobuf:"bytes,12,opt,name=controlProgram,proto3" json:"controlProgram,omitempty"`
+	ReferenceData   []byte `protobuf:"bytes,13,opt,name=referenceData,proto3" json:"referenceData,omitempty"`
+}
+
+func (m *AnnotatedOutput) Reset()         { *m = AnnotatedOutput{} }
+func (m *AnnotatedOutput) String() string { return proto.CompactTextString(m) }
+func (*AnnotatedOutput) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterType((*UtxoEntry)(nil), "chain.core.wallet.internal.storage.UtxoEntry")
+	proto.RegisterType((*AnnotatedTx)(nil), "chain.core.wallet.internal.storage.AnnotatedTx")
+	proto.RegisterType((*AnnotatedInput)(nil), "chain.core.wallet.internal.storage.AnnotatedInput")
+	proto.RegisterType((*AnnotatedOutput)(nil), "chain.core.wallet.internal.storage.AnnotatedOutput")
+}
@@ -2,10 +2,12 @@ package wallet
 
 import (
 	"encoding/json"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/tendermint/tmlibs/db"
 
+	"github.com/bytom/blockchain/account"
 	"github.com/bytom/protocol"
 	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/bc/legacy"
@@ -21,14 +23,22 @@ type StatusInfo struct {
 
 //Wallet is related to storing account unspent outputs
 type Wallet struct {
-	DB     db.DB
-	status StatusInfo
+	DB           db.DB
+	status       StatusInfo
+	annotatePool *annotatePool
+	cpFilter     *account.CPFilter
+
+	// progressMtx guards status and catchingUp for readers outside the
+	// update goroutine; see Progress in catchup.go
+	progressMtx sync.Mutex
+	catchingUp  bool
 }
 
 //NewWallet return a new wallet instance
 func NewWallet(walletDB db.DB) *Wallet {
 	w := &Wallet{
-		DB: walletDB,
+		DB:           walletDB,
+		annotatePool: newAnnotatePool(),
 	}
 	walletInfo, err := w.GetWalletInfo()
 	if err != nil {
@@ -39,6 +49,13 @@ func NewWallet(walletDB db.DB) *Wallet {
 	return w
 }
 
+//SetCPFilter hands the wallet the account manager's control program
+//filter so block indexing can pre-screen transactions; a nil filter
+//matches everything
+func (w *Wallet) SetCPFilter(filter *account.CPFilter) {
+	w.cpFilter = filter
+}
+
 //GetWalletInfo return stored wallet info and nil,if error,
 //return initial wallet info and err
 func (w *Wallet) GetWalletInfo() (StatusInfo, error) {
@@ -57,6 +74,13 @@ func (w *Wallet) GetWalletInfo() (StatusInfo, error) {
 
 }
 
+func (w *Wallet) setStatus(height uint64, hash bc.Hash) {
+	w.progressMtx.Lock()
+	w.status.Height = height
+	w.status.Hash = hash
+	w.progressMtx.Unlock()
+}
+
 func (w *Wallet) commitWalletInfo(batch *db.Batch) error {
 	var info StatusInfo
 
@@ -80,8 +104,6 @@ func (w *Wallet) WalletUpdate(c *protocol.Chain) {
 	var err error
 	var block *legacy.Block
 
-	storeBatch := w.DB.NewBatch()
-
 LOOP:
 
 	for !c.InMainChain(w.status.Height, w.status.Hash) {
@@ -90,13 +112,16 @@ LOOP:
 			return
 		}
 
+		// every block gets its own batch, committed atomically with the
+		// status marker last, so a crash can't leave half-indexed state
+		storeBatch := w.DB.NewBatch()
+
 		//Reverse this block
 		reverseAccountUTXOs(&storeBatch, block, w)
 		deleteTransactions(&storeBatch, w.status.Height, block, w)
 		log.WithField("Height", w.status.Height).Info("start rollback this block")
 
-		w.status.Height = block.Height - 1
-		w.status.Hash = block.PreviousBlockHash
+		w.setStatus(block.Height-1, block.PreviousBlockHash)
 
 		//update wallet info and commit batch write
 		if err := w.commitWalletInfo(&storeBatch); err != nil {
@@ -104,6 +129,13 @@ LOOP:
 		}
 	}
 
+	//after long downtime, drain the backlog through the pipelined
+	//catch-up instead of one block per loop; see catchup.go
+	if bestHeight := c.Height(); bestHeight > w.status.Height+catchupThreshold {
+		w.catchup(c, bestHeight)
+		goto LOOP
+	}
+
 	block, _ = c.GetBlockByHeight(w.status.Height + 1)
 	//if we already handled the tail of the chain, we wait
 	if block == nil {
@@ -117,10 +149,13 @@ LOOP:
 	//if false, means that rollback operation is necessary,then goto LOOP
 	if block.PreviousBlockHash == w.status.Hash {
 		//next loop will save
-		w.status.Height = block.Height
-		w.status.Hash = block.Hash()
+		w.setStatus(block.Height, block.Hash())
 
-		indexTransactions(&storeBatch, block, w)
+		storeBatch := w.DB.NewBatch()
+		if err := indexTransactions(&storeBatch, block, w); err != nil {
+			log.WithField("err", err).Error("indexing transactions")
+			return
+		}
 		buildAccountUTXOs(&storeBatch, block, w)
 
 		//update wallet info and commit batch write
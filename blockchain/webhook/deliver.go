@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+)
+
+var errBadStatus = errors.New("webhook endpoint returned non-2xx status")
+
+const (
+	deliveryQueueSize = 1000
+	// maxAttempts bounds redelivery; after that the event is dropped.
+	maxAttempts = 5
+	// retryBase is the first retry delay; it doubles per attempt.
+	retryBase      = 10 * time.Second
+	requestTimeout = 10 * time.Second
+)
+
+type delivery struct {
+	sub      *Subscription
+	event    string
+	payload  []byte
+	attempts int
+}
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// deliverLoop drains the queue one delivery at a time. Failed attempts
+// are requeued after an exponential backoff instead of blocking the
+// loop, so one dead endpoint cannot starve the others.
+func (m *Manager) deliverLoop() {
+	for d := range m.queue {
+		if err := m.post(d); err == nil {
+			continue
+		}
+		d.attempts++
+		if d.attempts >= maxAttempts {
+			log.WithFields(log.Fields{"id": d.sub.ID, "event": d.event}).Error("webhook delivery gave up after max attempts")
+			continue
+		}
+		go m.requeue(d)
+	}
+}
+
+// backoff is the delay before the next attempt: retryBase doubled per
+// attempt already made.
+func backoff(attempts int) time.Duration {
+	return retryBase << uint(attempts-1)
+}
+
+func (m *Manager) requeue(d *delivery) {
+	time.Sleep(backoff(d.attempts))
+	select {
+	case m.queue <- d:
+	default:
+		log.WithFields(log.Fields{"id": d.sub.ID, "event": d.event}).Warn("webhook delivery queue full, dropping retry")
+	}
+}
+
+func (m *Manager) post(d *delivery) error {
+	req, err := http.NewRequest("POST", d.sub.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Bytom-Event", d.event)
+	if d.sub.Secret != "" {
+		req.Header.Set("X-Bytom-Signature", sign(d.sub.Secret, d.payload))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{"id": d.sub.ID, "event": d.event, "error": err}).Warn("webhook delivery failed")
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.WithFields(log.Fields{"id": d.sub.ID, "event": d.event, "status": resp.StatusCode}).Warn("webhook endpoint returned non-2xx")
+		return errBadStatus
+	}
+	return nil
+}
+
+// sign returns the hex HMAC-SHA256 of the payload under the secret;
+// receivers recompute it to authenticate the sender.
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
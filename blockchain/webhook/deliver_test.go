@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bytom/errors"
+)
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256("secret", `{"a":1}`), pinned so the signature scheme
+	// cannot drift under receivers that verify it
+	const want = "aa9e2e3575f5d7098b6caccd790888c36d5fdb63342a73bada2d6a51747a8494"
+	got := sign("secret", []byte(`{"a":1}`))
+	if got != want {
+		t.Errorf("sign = %s, want %s", got, want)
+	}
+	if sign("other", []byte(`{"a":1}`)) == got {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestPostSignsPayload(t *testing.T) {
+	type seen struct {
+		event, signature, contentType, body string
+	}
+	got := make(chan seen, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got <- seen{
+			event:       r.Header.Get("X-Bytom-Event"),
+			signature:   r.Header.Get("X-Bytom-Signature"),
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+		}
+	}))
+	defer server.Close()
+
+	m := &Manager{}
+	payload := []byte(`{"height":7}`)
+	d := &delivery{
+		sub:     &Subscription{ID: "a", URL: server.URL, Secret: "topsecret"},
+		event:   EventNewBlock,
+		payload: payload,
+	}
+	if err := m.post(d); err != nil {
+		t.Fatal(err)
+	}
+	request := <-got
+	if request.event != EventNewBlock || request.contentType != "application/json" || request.body != string(payload) {
+		t.Errorf("request = %+v", request)
+	}
+	if request.signature != sign("topsecret", payload) {
+		t.Errorf("signature = %s, want %s", request.signature, sign("topsecret", payload))
+	}
+
+	// without a secret the payload goes unsigned
+	d.sub = &Subscription{ID: "b", URL: server.URL}
+	if err := m.post(d); err != nil {
+		t.Fatal(err)
+	}
+	if request = <-got; request.signature != "" {
+		t.Errorf("unsigned delivery carried signature %s", request.signature)
+	}
+}
+
+func TestPostBadStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	m := &Manager{}
+	d := &delivery{sub: &Subscription{ID: "a", URL: server.URL}, event: EventNewBlock}
+	if err := m.post(d); errors.Root(err) != errBadStatus {
+		t.Errorf("got error %v, want errBadStatus", err)
+	}
+}
+
+func TestBackoffDoubles(t *testing.T) {
+	for i, want := range []time.Duration{retryBase, 2 * retryBase, 4 * retryBase, 8 * retryBase} {
+		if got := backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+// TestDeliverLoopGivesUp pins the retry bound: once a delivery has
+// burned its attempts it is dropped, not requeued forever.
+func TestDeliverLoopGivesUp(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	m := &Manager{queue: make(chan *delivery, deliveryQueueSize)}
+	go m.deliverLoop()
+
+	m.queue <- &delivery{
+		sub:      &Subscription{ID: "a", URL: server.URL},
+		event:    EventNewBlock,
+		attempts: maxAttempts - 1,
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&requests) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("delivery never attempted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the loop keeps draining, so a requeue would show up here
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt64(&requests); got != 1 {
+		t.Errorf("endpoint saw %d requests, want 1", got)
+	}
+	if len(m.queue) != 0 {
+		t.Errorf("queue still holds %d deliveries", len(m.queue))
+	}
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// NewBlockData is the payload of EventNewBlock.
+type NewBlockData struct {
+	Height  uint64 `json:"height"`
+	Hash    string `json:"hash"`
+	TxCount int    `json:"tx_count"`
+}
+
+// WalletTxData is the payload of EventWalletTx.
+type WalletTxData struct {
+	TxID        string `json:"tx_id"`
+	BlockHeight uint64 `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+}
+
+// ConfirmationData is the payload of EventConfirmation.
+type ConfirmationData struct {
+	BlockHeight   uint64 `json:"block_height"`
+	BlockHash     string `json:"block_hash"`
+	Confirmations uint64 `json:"confirmations"`
+}
+
+// ReorgData is the payload of EventReorg.
+type ReorgData struct {
+	OldTip      string   `json:"old_tip"`
+	NewTip      string   `json:"new_tip"`
+	Depth       uint64   `json:"depth"`
+	DetachedTxs []string `json:"detached_txs"`
+}
+
+// FollowChain walks the main chain from the current tip and fires
+// new_block, wallet_tx and confirmation events as blocks connect. The
+// isLocal predicate reports whether a control program belongs to this
+// node's wallet; it may be nil when no wallet is running. Reorg events
+// come from the chain's reorg handler, not from this loop, so the
+// follower only ever moves forward.
+func (m *Manager) FollowChain(c *protocol.Chain, isLocal func(program []byte) bool) {
+	height := c.Height()
+	for {
+		<-c.BlockWaiter(height + 1)
+		height++
+		block, err := c.GetBlockByHeight(height)
+		if err != nil {
+			// the tip moved backwards during a reorg; the replacement
+			// blocks fire when the chain passes this height again
+			log.WithFields(log.Fields{"height": height, "error": err}).Debug("webhook follower waiting for reorged height")
+			height--
+			continue
+		}
+
+		blockHash := block.Hash()
+		m.Notify(EventNewBlock, 0, &NewBlockData{
+			Height:  block.Height,
+			Hash:    blockHash.String(),
+			TxCount: len(block.Transactions),
+		})
+
+		if isLocal != nil {
+			for _, tx := range block.Transactions {
+				if !touchesWallet(tx, isLocal) {
+					continue
+				}
+				m.Notify(EventWalletTx, 0, &WalletTxData{
+					TxID:        tx.Tx.ID.String(),
+					BlockHeight: block.Height,
+					BlockHash:   blockHash.String(),
+				})
+			}
+		}
+
+		for _, depth := range m.confirmationDepths() {
+			if height < depth {
+				continue
+			}
+			confirmed, err := c.GetBlockByHeight(height - depth + 1)
+			if err != nil {
+				continue
+			}
+			confirmedHash := confirmed.Hash()
+			m.Notify(EventConfirmation, depth, &ConfirmationData{
+				BlockHeight:   confirmed.Height,
+				BlockHash:     confirmedHash.String(),
+				Confirmations: depth,
+			})
+		}
+	}
+}
+
+// touchesWallet reports whether any input or output control program of
+// the transaction belongs to the wallet.
+func touchesWallet(tx *legacy.Tx, isLocal func(program []byte) bool) bool {
+	for _, input := range tx.Inputs {
+		if program := input.ControlProgram(); program != nil && isLocal(program) {
+			return true
+		}
+	}
+	for _, output := range tx.Outputs {
+		if isLocal(output.ControlProgram) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+// Package webhook delivers node events to subscriber-registered HTTP
+// endpoints. Subscriptions are managed over the RPC API and persisted in
+// their own database, so they survive restarts. Payloads are JSON and
+// carry an HMAC signature computed with the per-subscription secret, so
+// the receiver can verify who sent them.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/errors"
+)
+
+// Event types a subscription can ask for.
+const (
+	// EventNewBlock fires for every block connected to the main chain.
+	EventNewBlock = "new_block"
+	// EventWalletTx fires for confirmed transactions that touch a
+	// control program owned by this node's wallet accounts.
+	EventWalletTx = "wallet_tx"
+	// EventConfirmation fires when a block reaches the subscription's
+	// configured confirmation depth.
+	EventConfirmation = "confirmation"
+	// EventReorg fires when the main chain reorganizes.
+	EventReorg = "reorg"
+)
+
+// SubscriptionNumMax caps the number of registered webhooks.
+const SubscriptionNumMax = 128
+
+var (
+	// ErrBadURL means the callback URL could not be parsed or uses an
+	// unsupported scheme.
+	ErrBadURL = errors.New("invalid webhook url")
+	// ErrBadEvent means an unknown event type was requested.
+	ErrBadEvent = errors.New("unknown webhook event type")
+	// ErrNoMatchID means no subscription exists with the given id.
+	ErrNoMatchID = errors.New("no webhook with that id")
+	// ErrNumExceedlimit means the webhook number exceeds the limit.
+	ErrNumExceedlimit = errors.New("webhook number exceed limit")
+)
+
+var knownEvents = map[string]bool{
+	EventNewBlock:     true,
+	EventWalletTx:     true,
+	EventConfirmation: true,
+	EventReorg:        true,
+}
+
+// Subscription describes one registered callback endpoint.
+type Subscription struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs every payload sent to this subscription; it is
+	// returned only on creation and redacted from listings.
+	Secret string `json:"secret,omitempty"`
+	// Confirmations is the depth at which EventConfirmation fires.
+	Confirmations uint64    `json:"confirmations,omitempty"`
+	Created       time.Time `json:"created_at"`
+}
+
+func (s *Subscription) wants(event string) bool {
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Manager stores webhook subscriptions and delivers event payloads to
+// them with retry and backoff.
+type Manager struct {
+	DB dbm.DB
+
+	mtx  sync.Mutex
+	subs []*Subscription
+
+	queue chan *delivery
+}
+
+// NewManager loads the persisted subscriptions and starts the delivery
+// worker.
+func NewManager(db dbm.DB) (*Manager, error) {
+	m := &Manager{
+		DB:    db,
+		queue: make(chan *delivery, deliveryQueueSize),
+	}
+
+	iter := db.Iterator()
+	defer iter.Release()
+	for iter.Next() {
+		sub := &Subscription{}
+		if err := json.Unmarshal(iter.Value(), sub); err != nil {
+			return nil, err
+		}
+		m.subs = append(m.subs, sub)
+	}
+
+	go m.deliverLoop()
+	return m, nil
+}
+
+// Create registers a new subscription and returns it, including the
+// generated id. An empty secret means payloads are sent unsigned.
+func (m *Manager) Create(rawURL string, events []string, secret string, confirmations uint64) (*Subscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.WithDetailf(ErrBadURL, "url %q must be http or https", rawURL)
+	}
+	if len(events) == 0 {
+		return nil, errors.WithDetail(ErrBadEvent, "at least one event type is required")
+	}
+	for _, event := range events {
+		if !knownEvents[event] {
+			return nil, errors.WithDetailf(ErrBadEvent, "unknown event type %q", event)
+		}
+	}
+
+	var idBytes [8]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	sub := &Subscription{
+		ID:            hex.EncodeToString(idBytes[:]),
+		URL:           rawURL,
+		Events:        events,
+		Secret:        secret,
+		Confirmations: confirmations,
+		Created:       time.Now(),
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if len(m.subs) >= SubscriptionNumMax {
+		return nil, errors.WithDetail(ErrNumExceedlimit, "webhook number exceed limit")
+	}
+	if err := insertSubscription(m.DB, sub); err != nil {
+		return nil, err
+	}
+	m.subs = append(m.subs, sub)
+	return sub, nil
+}
+
+// Delete removes the subscription with the given id.
+func (m *Manager) Delete(id string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for i, sub := range m.subs {
+		if sub.ID == id {
+			m.subs = append(m.subs[:i], m.subs[i+1:]...)
+			key, err := json.Marshal(id)
+			if err != nil {
+				return err
+			}
+			m.DB.Delete(key)
+			return nil
+		}
+	}
+	return errors.WithDetailf(ErrNoMatchID, "delete webhook id %q nonexisting", id)
+}
+
+// List returns every subscription with the secret redacted.
+func (m *Manager) List() []*Subscription {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	subs := make([]*Subscription, 0, len(m.subs))
+	for _, sub := range m.subs {
+		redacted := *sub
+		redacted.Secret = ""
+		subs = append(subs, &redacted)
+	}
+	return subs
+}
+
+func insertSubscription(db dbm.DB, sub *Subscription) error {
+	key, err := json.Marshal(sub.ID)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	db.Set(key, value)
+	return nil
+}
+
+// confirmationDepths returns the distinct confirmation depths wanted by
+// current subscriptions, so the chain follower fetches each block once.
+func (m *Manager) confirmationDepths() []uint64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	seen := map[uint64]bool{}
+	depths := []uint64{}
+	for _, sub := range m.subs {
+		if sub.wants(EventConfirmation) && sub.Confirmations > 0 && !seen[sub.Confirmations] {
+			seen[sub.Confirmations] = true
+			depths = append(depths, sub.Confirmations)
+		}
+	}
+	return depths
+}
+
+// Notify queues the event for every subscription that asked for it.
+// The depth argument is matched against Confirmations for
+// EventConfirmation and ignored for other events. Payloads are dropped
+// with a log line when the delivery queue is full; webhooks are
+// best-effort and must never stall block processing.
+func (m *Manager) Notify(event string, depth uint64, data interface{}) {
+	payload, err := json.Marshal(&eventPayload{
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		log.WithFields(log.Fields{"event": event, "error": err}).Error("marshal webhook payload")
+		return
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, sub := range m.subs {
+		if !sub.wants(event) {
+			continue
+		}
+		if event == EventConfirmation && sub.Confirmations != depth {
+			continue
+		}
+		select {
+		case m.queue <- &delivery{sub: sub, event: event, payload: payload}:
+		default:
+			log.WithFields(log.Fields{"id": sub.ID, "event": event}).Warn("webhook delivery queue full, dropping event")
+		}
+	}
+}
+
+type eventPayload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/errors"
+)
+
+// newTestManager builds a manager without the delivery worker, so
+// queued deliveries stay inspectable.
+func newTestManager() *Manager {
+	return &Manager{
+		DB:    dbm.NewMemDB(),
+		queue: make(chan *delivery, deliveryQueueSize),
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	m := newTestManager()
+	cases := []struct {
+		url    string
+		events []string
+		want   error
+	}{
+		{"ftp://example.com/hook", []string{EventNewBlock}, ErrBadURL},
+		{"not a url at all\x7f", []string{EventNewBlock}, ErrBadURL},
+		{"http://example.com/hook", nil, ErrBadEvent},
+		{"http://example.com/hook", []string{"eclipse"}, ErrBadEvent},
+	}
+	for _, tc := range cases {
+		if _, err := m.Create(tc.url, tc.events, "", 0); errors.Root(err) != tc.want {
+			t.Errorf("Create(%q, %v): got error %v, want %v", tc.url, tc.events, err, tc.want)
+		}
+	}
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	m := newTestManager()
+	sub, err := m.Create("https://example.com/hook", []string{EventNewBlock, EventConfirmation}, "s3cret", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ID == "" || sub.Secret != "s3cret" || sub.Confirmations != 6 {
+		t.Errorf("created subscription = %+v", sub)
+	}
+
+	// listings redact the secret
+	list := m.List()
+	if len(list) != 1 || list[0].ID != sub.ID || list[0].Secret != "" {
+		t.Errorf("list = %+v, want one subscription with the secret redacted", list)
+	}
+
+	// subscriptions survive a manager restart
+	reloaded, err := NewManager(m.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list = reloaded.List(); len(list) != 1 || list[0].ID != sub.ID {
+		t.Errorf("reloaded list = %+v", list)
+	}
+
+	if err := m.Delete("feedfacefeedface"); errors.Root(err) != ErrNoMatchID {
+		t.Errorf("got error %v, want ErrNoMatchID", err)
+	}
+	if err := m.Delete(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if list = m.List(); len(list) != 0 {
+		t.Errorf("list after delete = %+v, want none", list)
+	}
+	// the delete reached the database too
+	if reloaded, err = NewManager(m.DB); err != nil {
+		t.Fatal(err)
+	}
+	if list = reloaded.List(); len(list) != 0 {
+		t.Errorf("reloaded list after delete = %+v, want none", list)
+	}
+}
+
+func TestNotifyFilters(t *testing.T) {
+	m := newTestManager()
+	blockSub, err := m.Create("http://example.com/a", []string{EventNewBlock}, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("http://example.com/b", []string{EventConfirmation}, "", 6); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Notify(EventNewBlock, 0, map[string]uint64{"height": 7})
+	if len(m.queue) != 1 {
+		t.Fatalf("queued %d deliveries, want 1", len(m.queue))
+	}
+	d := <-m.queue
+	if d.sub.ID != blockSub.ID || d.event != EventNewBlock {
+		t.Errorf("delivery = sub %s event %s", d.sub.ID, d.event)
+	}
+	payload := &eventPayload{}
+	if err := json.Unmarshal(d.payload, payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != EventNewBlock {
+		t.Errorf("payload event = %s", payload.Event)
+	}
+
+	// confirmation events only reach subscriptions at the same depth
+	m.Notify(EventConfirmation, 3, nil)
+	if len(m.queue) != 0 {
+		t.Errorf("depth-3 confirmation queued %d deliveries, want 0", len(m.queue))
+	}
+	m.Notify(EventConfirmation, 6, nil)
+	if len(m.queue) != 1 {
+		t.Errorf("depth-6 confirmation queued %d deliveries, want 1", len(m.queue))
+	}
+}
+
+func TestConfirmationDepths(t *testing.T) {
+	m := newTestManager()
+	for _, depth := range []uint64{6, 1, 6} {
+		if _, err := m.Create("http://example.com/hook", []string{EventConfirmation}, "", depth); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a subscription without the confirmation event contributes nothing
+	if _, err := m.Create("http://example.com/hook", []string{EventNewBlock}, "", 9); err != nil {
+		t.Fatal(err)
+	}
+
+	depths := m.confirmationDepths()
+	if len(depths) != 2 || depths[0] != 6 || depths[1] != 1 {
+		t.Errorf("depths = %v, want [6 1]", depths)
+	}
+}
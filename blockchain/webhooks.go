@@ -0,0 +1,52 @@
+package blockchain
+
+import (
+	"github.com/bytom/blockchain/webhook"
+	"github.com/bytom/errors"
+)
+
+// POST /create-webhook
+// The secret is echoed back once here and never returned again; payloads
+// sent to the URL carry an HMAC-SHA256 of the body under it.
+func (bcr *BlockchainReactor) createWebhook(in struct {
+	URL           string   `json:"url"`
+	Events        []string `json:"events"`
+	Secret        string   `json:"secret"`
+	Confirmations uint64   `json:"confirmations"`
+}) (*webhook.Subscription, error) {
+	if bcr.webhooks == nil {
+		return nil, errors.New("webhooks are not enabled on this node")
+	}
+	if in.URL == "" {
+		return nil, errors.WithDetail(errBadAction, "url is required")
+	}
+	return bcr.webhooks.Create(in.URL, in.Events, in.Secret, in.Confirmations)
+}
+
+// POST /list-webhooks
+func (bcr *BlockchainReactor) listWebhooks() ([]*webhook.Subscription, error) {
+	if bcr.webhooks == nil {
+		return nil, errors.New("webhooks are not enabled on this node")
+	}
+	return bcr.webhooks.List(), nil
+}
+
+// POST /delete-webhook
+func (bcr *BlockchainReactor) deleteWebhook(in struct {
+	ID string `json:"id"`
+}) (*struct {
+	Msg string `json:"msg"`
+}, error) {
+	if bcr.webhooks == nil {
+		return nil, errors.New("webhooks are not enabled on this node")
+	}
+	if in.ID == "" {
+		return nil, errors.WithDetail(errBadAction, "id is required")
+	}
+	if err := bcr.webhooks.Delete(in.ID); err != nil {
+		return nil, err
+	}
+	return &struct {
+		Msg string `json:"msg"`
+	}{Msg: "webhook deleted"}, nil
+}
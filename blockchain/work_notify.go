@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/bytom/types"
+)
+
+const (
+	// how often the mempool is checked for newly arrived fees.
+	workPollInterval = 5 * time.Second
+	// total mempool fee growth (in asset units) that makes the current
+	// work stale enough to notify miners about.
+	workNotifyFeeDelta = uint64(100000000)
+)
+
+// SetEventSwitch implements types.Eventable.
+func (bcR *BlockchainReactor) SetEventSwitch(evsw types.EventSwitch) {
+	bcR.evsw = evsw
+}
+
+// poolFees returns the sum of the fees currently in the mempool.
+func (bcR *BlockchainReactor) poolFees() uint64 {
+	fees := uint64(0)
+	for _, txDesc := range bcR.txPool.GetTransactions() {
+		fees += txDesc.Fee
+	}
+	return fees
+}
+
+// workNotifyRoutine fires a NewWork event whenever the block template
+// becomes stale: the chain advanced, or enough new fees arrived in the
+// mempool. Miners subscribe over the websocket endpoint instead of
+// polling get-block-template.
+func (bcR *BlockchainReactor) workNotifyRoutine() {
+	ticker := time.NewTicker(workPollInterval)
+	defer ticker.Stop()
+
+	blockWaiter := bcR.chain.BlockWaiter(bcR.chain.Height() + 1)
+	lastFees := bcR.poolFees()
+	for {
+		reason := ""
+		select {
+		case <-blockWaiter:
+			blockWaiter = bcR.chain.BlockWaiter(bcR.chain.Height() + 1)
+			lastFees = bcR.poolFees()
+			reason = "new_block"
+
+		case <-ticker.C:
+			fees := bcR.poolFees()
+			if fees < lastFees+workNotifyFeeDelta {
+				continue
+			}
+			lastFees = fees
+			reason = "new_fees"
+		}
+
+		if bcR.evsw != nil {
+			types.FireEventNewWork(bcR.evsw, types.EventDataNewWork{
+				Height:            bcR.chain.Height() + 1,
+				PreviousBlockHash: bcR.chain.BestBlockHash().String(),
+				Reason:            reason,
+			})
+		}
+	}
+}
+
+// miningWorkSocket streams NewWork events to one websocket subscriber.
+func (bcR *BlockchainReactor) miningWorkSocket(ws *websocket.Conn) {
+	defer ws.Close()
+	if bcR.evsw == nil {
+		return
+	}
+
+	listenerID := fmt.Sprintf("mining-work-ws-%p", ws)
+	workCh := make(chan types.EventDataNewWork, 16)
+	types.AddListenerForEvent(bcR.evsw, listenerID, types.EventStringNewWork(), func(data types.TMEventData) {
+		if work, ok := data.Unwrap().(types.EventDataNewWork); ok {
+			select {
+			case workCh <- work:
+			default:
+			}
+		}
+	})
+	defer bcR.evsw.RemoveListener(listenerID)
+
+	enc := json.NewEncoder(ws)
+	for work := range workCh {
+		if err := enc.Encode(work); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package clock is the node's view of wall time. Time-dependent logic
+// — block template timestamps, access token expiry, UTXO reservation
+// TTLs — asks clock.Now instead of time.Now, so tests and regtest
+// nodes (via /set-mock-time) can freeze and step time to exercise
+// timelock and expiry behavior deterministically. An unmocked clock
+// is exactly time.Now.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	mtx  sync.RWMutex
+	mock *time.Time
+)
+
+// Now returns the current time: the mock time when one is set,
+// otherwise wall time.
+func Now() time.Time {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	if mock != nil {
+		return *mock
+	}
+	return time.Now()
+}
+
+// SetMock freezes the clock at t; every Now call returns t until
+// Advance, another SetMock or Reset.
+func SetMock(t time.Time) {
+	mtx.Lock()
+	mock = &t
+	mtx.Unlock()
+}
+
+// Advance steps a frozen clock forward by d. On an unmocked clock it
+// freezes at wall time plus d.
+func Advance(d time.Duration) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	base := time.Now()
+	if mock != nil {
+		base = *mock
+	}
+	stepped := base.Add(d)
+	mock = &stepped
+}
+
+// Reset returns the clock to wall time.
+func Reset() {
+	mtx.Lock()
+	mock = nil
+	mtx.Unlock()
+}
+
+// IsMocked reports whether the clock is frozen.
+func IsMocked() bool {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	return mock != nil
+}
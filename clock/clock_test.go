@@ -0,0 +1,40 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMockAndReset(t *testing.T) {
+	defer Reset()
+
+	if IsMocked() {
+		t.Fatal("clock mocked before SetMock")
+	}
+
+	frozen := time.Unix(1500000000, 0)
+	SetMock(frozen)
+	if !IsMocked() {
+		t.Fatal("clock not mocked after SetMock")
+	}
+	if got := Now(); !got.Equal(frozen) {
+		t.Errorf("Now() = %v, want %v", got, frozen)
+	}
+	// a frozen clock does not tick
+	if got := Now(); !got.Equal(frozen) {
+		t.Errorf("second Now() = %v, want %v", got, frozen)
+	}
+
+	Advance(time.Hour)
+	if got, want := Now(), frozen.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Now() after Advance = %v, want %v", got, want)
+	}
+
+	Reset()
+	if IsMocked() {
+		t.Fatal("clock still mocked after Reset")
+	}
+	if d := time.Since(Now()); d < 0 || d > time.Minute {
+		t.Errorf("Now() after Reset is %v from wall time", d)
+	}
+}
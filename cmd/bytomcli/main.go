@@ -17,12 +17,14 @@ import (
 	"time"
 
 	"github.com/bytom/blockchain"
+	"github.com/bytom/blockchain/accesstoken"
 	"github.com/bytom/blockchain/query"
 	"github.com/bytom/blockchain/rpc"
 	"github.com/bytom/blockchain/txbuilder"
 	"github.com/bytom/cmd/bytomcli/example"
 	"github.com/bytom/config"
 	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/database"
 	"github.com/bytom/encoding/json"
 	"github.com/bytom/env"
 	"github.com/bytom/errors"
@@ -30,8 +32,9 @@ import (
 
 // config vars
 var (
-	home    = blockchain.HomeDirFromEnvironment()
-	coreURL = env.String("BYTOM_URL", "http://0.0.0.0:9888")
+	home        = blockchain.HomeDirFromEnvironment()
+	coreURL     = env.String("BYTOM_URL", "http://0.0.0.0:9888")
+	accessToken = env.String("BYTOM_ACCESS_TOKEN", "")
 
 	// build vars; initialized by the linker
 	buildTag    = "?"
@@ -94,6 +97,13 @@ var commands = map[string]*command{
 	"get-best-block-hash":      {getBestBlockHash},
 	"get-block-header-by-hash": {getBlockHeaderByHash},
 	"get-block-by-hash":        {getBlockByHash},
+	"compact-database":         {compactDatabase},
+	"db-repair":                {dbRepair},
+	"stop":                     {stopNode},
+	"reload-config":            {reloadConfig},
+	"set-log-level":            {setLogLevel},
+	"send":                     {send},
+	"node-status":              {nodeStatus},
 }
 
 func main() {
@@ -112,9 +122,16 @@ func main() {
 		return
 	}
 
+	// --json before the command switches every response to raw JSON
+	// on stdout for scripts
+	if len(os.Args) >= 2 && (os.Args[1] == "--json" || os.Args[1] == "-json") {
+		jsonOutput = true
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	if len(os.Args) < 2 {
 		help(os.Stdout)
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 	cmd := commands[os.Args[1]]
 	if cmd == nil {
@@ -122,7 +139,7 @@ func main() {
 			fmt.Fprintln(os.Stderr, "unknown command:", os.Args[1])
 		}
 		help(os.Stderr)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 	cmd.f(mustRPCClient(), os.Args[2:])
 }
@@ -207,7 +224,7 @@ func mustRPCClient() *rpc.Client {
 	keyFile := filepath.Join(home, "tls.key")
 	config, err := blockchain.TLSConfig(certFile, keyFile, "")
 	if err == blockchain.ErrNoTLS {
-		return &rpc.Client{BaseURL: *coreURL}
+		return &rpc.Client{BaseURL: *coreURL, AccessToken: *accessToken}
 	} else if err != nil {
 		fatalln("error: loading TLS cert:", err)
 	}
@@ -231,14 +248,20 @@ func mustRPCClient() *rpc.Client {
 	}
 
 	return &rpc.Client{
-		BaseURL: url,
-		Client:  &http.Client{Transport: t},
+		BaseURL:     url,
+		AccessToken: *accessToken,
+		Client:      &http.Client{Transport: t},
 	}
 }
 
 func fatalln(v ...interface{}) {
-	fmt.Printf("%v", v)
-	os.Exit(2)
+	fmt.Fprintln(os.Stderr, v...)
+	os.Exit(exitUsage)
+}
+
+func fatalf(format string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", v...)
+	os.Exit(exitUsage)
 }
 
 func dieOnRPCError(err error, prefixes ...interface{}) {
@@ -251,15 +274,21 @@ func dieOnRPCError(err error, prefixes ...interface{}) {
 	}
 
 	if msgErr, ok := errors.Root(err).(rpc.ErrStatusCode); ok && msgErr.ErrorData != nil {
-		fmt.Fprintln(os.Stderr, "RPC error:", msgErr.ErrorData.ChainCode, msgErr.ErrorData.Message)
-		if msgErr.ErrorData.Detail != "" {
-			fmt.Fprintln(os.Stderr, "Detail:", msgErr.ErrorData.Detail)
+		if jsonOutput {
+			stdjson.NewEncoder(os.Stderr).Encode(msgErr.ErrorData)
+		} else {
+			fmt.Fprintln(os.Stderr, "RPC error:", msgErr.ErrorData.ChainCode, msgErr.ErrorData.Message)
+			if msgErr.ErrorData.Detail != "" {
+				fmt.Fprintln(os.Stderr, "Detail:", msgErr.ErrorData.Detail)
+			}
 		}
+	} else if jsonOutput {
+		stdjson.NewEncoder(os.Stderr).Encode(map[string]string{"error": err.Error()})
 	} else {
 		fmt.Fprintln(os.Stderr, "RPC error:", err)
 	}
 
-	os.Exit(2)
+	os.Exit(errExitCode(err))
 }
 
 func help(w io.Writer) {
@@ -267,6 +296,8 @@ func help(w io.Writer) {
 	for name := range commands {
 		fmt.Fprintln(w, "\t", name)
 	}
+	fmt.Fprint(w, "\nGlobal flags (before the command):\n\n")
+	fmt.Fprintln(w, "\t", "--json\tprint raw JSON responses for scripts")
 	fmt.Fprint(w, "\nVersion:0.2.0\n")
 	fmt.Fprintln(w)
 }
@@ -449,7 +480,7 @@ func updateAccountTags(client *rpc.Client, args []string) {
 	ins.Tags = map[string]interface{}{tags[0]: tags[1]}
 	responses := make([]interface{}, 50)
 	client.Call(context.Background(), "/update-account-tags", &[]Ins{ins}, &responses)
-	fmt.Printf("responses:%v\n", responses)
+	printResponse("responses", responses)
 }
 
 func updateAssetTags(client *rpc.Client, args []string) {
@@ -469,7 +500,7 @@ func updateAssetTags(client *rpc.Client, args []string) {
 	ins.Tags = map[string]interface{}{"test_tag": "v0"}
 	responses := make([]interface{}, 50)
 	client.Call(context.Background(), "/update-asset-tags", &[]Ins{ins}, &responses)
-	fmt.Printf("responses:%v\n", responses)
+	printResponse("responses", responses)
 }
 
 func buildTransaction(client *rpc.Client, args []string) {
@@ -550,7 +581,7 @@ func submitCreateIssueTransaction(client *rpc.Client, args []string) {
 	var submitResponse interface{}
 	submitArg := blockchain.SubmitArg{signResp, json.Duration{time.Duration(1000000)}, "none"}
 	client.Call(context.Background(), "/submit-transaction", submitArg, &submitResponse)
-	fmt.Printf("submit transaction:%v\n", submitResponse)
+	printResponse("submit transaction", submitResponse)
 }
 
 func submitReceiverTransaction(client *rpc.Client, args []string) {
@@ -588,7 +619,7 @@ func submitReceiverTransaction(client *rpc.Client, args []string) {
 	var submitResponse interface{}
 	submitArg := blockchain.SubmitArg{Transactions: signResp, Wait: json.Duration{Duration: time.Duration(1000000)}, WaitUntil: "none"}
 	client.Call(context.Background(), "/submit-transaction", submitArg, &submitResponse)
-	fmt.Printf("submit transaction:%v\n", submitResponse)
+	printResponse("submit transaction", submitResponse)
 }
 
 func submitSpendTransaction(client *rpc.Client, args []string) {
@@ -626,7 +657,7 @@ func submitSpendTransaction(client *rpc.Client, args []string) {
 	var submitResponse interface{}
 	submitArg := blockchain.SubmitArg{Transactions: signResp, Wait: json.Duration{Duration: time.Duration(1000000)}, WaitUntil: "none"}
 	client.Call(context.Background(), "/submit-transaction", submitArg, &submitResponse)
-	fmt.Printf("submit transaction:%v\n", submitResponse)
+	printResponse("submit transaction", submitResponse)
 }
 
 func createControlProgram(client *rpc.Client, args []string) {
@@ -641,7 +672,7 @@ func createControlProgram(client *rpc.Client, args []string) {
 	//TODO:undefined arguments to ins
 	responses := make([]interface{}, 50)
 	client.Call(context.Background(), "/create-control-program", &[]Ins{ins}, &responses)
-	fmt.Printf("responses:%v\n", responses)
+	printResponse("responses", responses)
 }
 
 func createAccountReceiver(client *rpc.Client, args []string) {
@@ -661,7 +692,7 @@ func createAccountReceiver(client *rpc.Client, args []string) {
 	ins.AccountInfo = args[0]
 
 	client.Call(context.Background(), "/create-account-receiver", &ins, &response)
-	fmt.Printf("responses:%v\n", response)
+	printResponse("responses", response)
 }
 
 func createTxFeed(client *rpc.Client, args []string) {
@@ -923,9 +954,25 @@ func listUnspentOutputs(client *rpc.Client, args []string) {
 }
 
 func createAccessToken(client *rpc.Client, args []string) {
+	usage := "usage: create-access-token [-local db-dir] [id]"
+	var flags flag.FlagSet
+	local := flags.String("local", "", "create the token directly in the accesstoken database under the given directory, for when the API is locked out; the node must be stopped")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	args = flags.Args()
 	if len(args) != 1 {
-		fatalln("error:createAccessToken use args id")
+		fatalln(usage)
 	}
+
+	if *local != "" {
+		createAccessTokenLocal(*local, args[0])
+		return
+	}
+
 	type Token struct {
 		ID   string `json:"id"`
 		Type string `json:"type"`
@@ -939,6 +986,27 @@ func createAccessToken(client *rpc.Client, args []string) {
 	fmt.Println(response)
 }
 
+// createAccessTokenLocal writes the token straight into the data
+// directory's accesstoken database, the recovery path for an operator
+// without a working token. The database is locked while bytomd runs,
+// so the node has to be stopped first.
+func createAccessTokenLocal(dir, id string) {
+	db, err := database.NewDB("accesstoken", "leveldb", dir)
+	if err != nil {
+		fatalln("error: open accesstoken database (is bytomd still running?):", err)
+	}
+	defer db.Close()
+	if err := database.Migrate("accesstoken", db); err != nil {
+		fatalln("error: migrate accesstoken database:", err)
+	}
+
+	secret, err := accesstoken.NewStore(db).Create(context.Background(), id, "client")
+	if err != nil {
+		fatalln("error: create access token:", err)
+	}
+	fmt.Println(*secret)
+}
+
 func listAccessTokens(client *rpc.Client, args []string) {
 	if len(args) != 0 {
 		fatalln("error:listAccessTokens not use args")
@@ -1009,7 +1077,7 @@ func deleteKey(client *rpc.Client, args []string) {
 	xpub := new(chainkd.XPub)
 	data, err := hex.DecodeString(args[1])
 	if err != nil {
-		fatalln("error: deletKey %v", err)
+		fatalf("error: deleteKey %v", err)
 	}
 	copy(xpub[:], data)
 	key.Password = args[0]
@@ -1097,7 +1165,7 @@ func signTransactions(client *rpc.Client, args []string) {
 
 	var response = make([]interface{}, 1)
 	client.Call(context.Background(), "/sign-transactions", &in, &response)
-	fmt.Printf("sign response:%v\n", response)
+	printResponse("sign response", response)
 }
 
 func resetPassword(client *rpc.Client, args []string) {
@@ -1113,7 +1181,7 @@ func resetPassword(client *rpc.Client, args []string) {
 	xpub := new(chainkd.XPub)
 	data, err := hex.DecodeString(args[2])
 	if err != nil {
-		fatalln("error: resetPassword %v", err)
+		fatalf("error: resetPassword %v", err)
 	}
 	copy(xpub[:], data)
 	key.OldPassword = args[0]
@@ -1135,7 +1203,7 @@ func updateAlias(client *rpc.Client, args []string) {
 	xpub := new(chainkd.XPub)
 	data, err := hex.DecodeString(args[2])
 	if err != nil {
-		fatalln("error: resetPassword %v", err)
+		fatalf("error: resetPassword %v", err)
 	}
 	copy(xpub[:], data)
 	key.Password = args[0]
@@ -1147,13 +1215,123 @@ func updateAlias(client *rpc.Client, args []string) {
 func netInfo(client *rpc.Client, args []string) {
 	var response interface{}
 	client.Call(context.Background(), "/net-info", nil, &response)
-	fmt.Printf("net info:%v\n", response)
+	printResponse("net info", response)
+}
+
+// setLogLevel changes the default or one module's log level; with no
+// arguments it prints the current levels.
+func setLogLevel(client *rpc.Client, args []string) {
+	var in struct {
+		Module string `json:"module"`
+		Level  string `json:"level"`
+	}
+	switch len(args) {
+	case 0:
+	case 1:
+		in.Level = args[0]
+	case 2:
+		in.Module, in.Level = args[0], args[1]
+	default:
+		fatalln("error: set-log-level args not valid: set-log-level [module] [level]")
+	}
+	var response interface{}
+	client.Call(context.Background(), "/set-log-level", &in, &response)
+	printResponse("log levels", response)
+}
+
+// send wraps build, sign and submit in one call for the common
+// transfer; the node returns the transaction id.
+func send(client *rpc.Client, args []string) {
+	if len(args) < 5 || len(args) > 6 {
+		fatalln("error: send args not valid: send [account id] [asset id] [amount] [address] [password] [fee]")
+	}
+	var in struct {
+		AccountID string `json:"account_id"`
+		AssetID   string `json:"asset_id"`
+		Amount    uint64 `json:"amount"`
+		Address   string `json:"address"`
+		Fee       uint64 `json:"fee"`
+		Password  string `json:"password"`
+	}
+	in.AccountID = args[0]
+	in.AssetID = args[1]
+	amount, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		fatalln("error: send amount not valid: ", err)
+	}
+	in.Amount = amount
+	in.Address = args[3]
+	in.Password = args[4]
+	if len(args) == 6 {
+		fee, err := strconv.ParseUint(args[5], 10, 64)
+		if err != nil {
+			fatalln("error: send fee not valid: ", err)
+		}
+		in.Fee = fee
+	}
+
+	var response interface{}
+	client.Call(context.Background(), "/send", &in, &response)
+	printResponse("send", response)
+}
+
+// nodeStatus reports whether the node answers and what it says about
+// itself; handy against a daemonized bytomd.
+func nodeStatus(client *rpc.Client, args []string) {
+	if len(args) != 0 {
+		fatalln("error: node-status takes no args")
+	}
+	var response interface{}
+	if err := client.Call(context.Background(), "/node-info", nil, &response); err != nil {
+		dieOnRPCError(err)
+	}
+	printResponse("node status", response)
+}
+
+func stopNode(client *rpc.Client, args []string) {
+	var response interface{}
+	client.Call(context.Background(), "/stop", nil, &response)
+	printResponse("stop", response)
+}
+
+func reloadConfig(client *rpc.Client, args []string) {
+	var response interface{}
+	err := client.Call(context.Background(), "/reload-config", nil, &response)
+	dieOnRPCError(err)
+	printResponse("reload-config", response)
+}
+
+// dbRepair works directly on a database directory, not through the
+// node's RPC: a node with a corrupt database usually cannot start.
+func dbRepair(client *rpc.Client, args []string) {
+	if len(args) != 1 {
+		fatalln("error: db-repair args not valid: db-repair [path-to-db-directory]")
+	}
+	if err := database.RepairPath(args[0]); err != nil {
+		fatalln("error: repair failed: ", err)
+	}
+	fmt.Printf("repaired %s; records past the corruption point were dropped\n", args[0])
+}
+
+func compactDatabase(client *rpc.Client, args []string) {
+	if len(args) > 1 {
+		fatalln("error: compact-database args not valid: compact-database [name]")
+	}
+	var in struct {
+		Name string `json:"name"`
+	}
+	if len(args) == 1 {
+		in.Name = args[0]
+	}
+	var response interface{}
+	client.Call(context.Background(), "/compact-database", &in, &response)
+	printResponse("compact results", response)
 }
 
 func getBestBlockHash(client *rpc.Client, args []string) {
 	var response interface{}
 	client.Call(context.Background(), "/get-best-block-hash", nil, &response)
-	fmt.Printf("best-block-hash:%v\n", response)
+	printResponse("best-block-hash", response)
 }
 
 func getBlockHeaderByHash(client *rpc.Client, args []string) {
@@ -1162,7 +1340,7 @@ func getBlockHeaderByHash(client *rpc.Client, args []string) {
 	}
 	var response interface{}
 	client.Call(context.Background(), "/get-block-header-by-hash", args[0], &response)
-	fmt.Printf("block header: %v\n", response)
+	printResponse("block header", response)
 }
 
 func getBlockByHash(client *rpc.Client, args []string) {
@@ -0,0 +1,46 @@
+package main
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bytom/blockchain/rpc"
+	"github.com/bytom/errors"
+)
+
+// Exit codes, stable so shell scripts and CI pipelines can branch on
+// the error class.
+const (
+	exitOK      = 0
+	exitUsage   = 1 // unknown command or bad arguments
+	exitRPC     = 2 // could not reach the node
+	exitNodeErr = 3 // the node processed the request and returned an error
+)
+
+// jsonOutput is set by the --json flag: responses are printed as raw
+// JSON on stdout with no labels or pretty formatting.
+var jsonOutput = false
+
+// printResponse prints one labeled response, or just the raw JSON when
+// --json is set.
+func printResponse(label string, response interface{}) {
+	if jsonOutput {
+		enc := stdjson.NewEncoder(os.Stdout)
+		if err := enc.Encode(response); err != nil {
+			fmt.Fprintln(os.Stderr, "error encoding response:", err)
+			os.Exit(exitRPC)
+		}
+		return
+	}
+	fmt.Printf("%s:%v\n", label, response)
+}
+
+// errExitCode classifies an RPC error: errors the node reported are
+// distinguished from failures to reach it at all.
+func errExitCode(err error) int {
+	if _, ok := errors.Root(err).(rpc.ErrStatusCode); ok {
+		return exitNodeErr
+	}
+	return exitRPC
+}
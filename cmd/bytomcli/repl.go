@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/bytom/blockchain/rpc"
+)
+
+// repl completes command names out of the commands map, so it is
+// registered here to avoid an initialization cycle.
+func init() {
+	commands["repl"] = &command{repl}
+}
+
+// repl runs an interactive session: a prompt with command and alias
+// completion, history, and per-session node URL and access token.
+// Every line dispatches to the normal commands, so the REPL offers
+// exactly what the one-shot CLI does.
+func repl(client *rpc.Client, args []string) {
+	if len(args) != 0 {
+		fatalln("error: repl takes no args")
+	}
+
+	session := &replSession{
+		client: client,
+		url:    *coreURL,
+	}
+
+	oldState, err := terminal.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		fatalln("error: repl needs a terminal: ", err)
+	}
+	defer terminal.Restore(int(os.Stdin.Fd()), oldState)
+
+	term := terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "bytomcli> ")
+	term.AutoCompleteCallback = session.complete
+
+	fmt.Fprintln(term, "interactive mode; \"help\" lists commands, \"exit\" leaves")
+	for {
+		line, err := term.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "exit", "quit":
+			return
+		case "help":
+			help(term)
+		case "set-url":
+			if len(fields) != 2 {
+				fmt.Fprintln(term, "usage: set-url [node url]")
+				continue
+			}
+			session.url = fields[1]
+			session.aliases = nil
+		case "set-token":
+			if len(fields) != 2 {
+				fmt.Fprintln(term, "usage: set-token [id:secret]")
+				continue
+			}
+			session.token = fields[1]
+			session.aliases = nil
+		default:
+			if commands[fields[0]] == nil {
+				fmt.Fprintln(term, "unknown command:", fields[0])
+				continue
+			}
+			// commands exit the process on failure, so each line runs
+			// in a child process; the terminal leaves raw mode while
+			// it prints
+			terminal.Restore(int(os.Stdin.Fd()), oldState)
+			session.run(fields)
+			oldState, _ = terminal.MakeRaw(int(os.Stdin.Fd()))
+		}
+	}
+}
+
+type replSession struct {
+	client *rpc.Client
+	url    string
+	token  string
+
+	// account and asset aliases fetched from the node for completion
+	aliases []string
+}
+
+// run executes one command line with the session's node URL and token.
+func (s *replSession) run(fields []string) {
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return
+	}
+	cmd := exec.Command(exe, fields...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "BYTOM_URL="+s.sessionURL())
+	cmd.Run()
+}
+
+// sessionURL embeds the access token as basic-auth credentials.
+func (s *replSession) sessionURL() string {
+	if s.token == "" {
+		return s.url
+	}
+	if i := strings.Index(s.url, "://"); i >= 0 {
+		return s.url[:i+3] + s.token + "@" + s.url[i+3:]
+	}
+	return s.url
+}
+
+// complete offers command names for the first word and account or
+// asset aliases for later words on tab.
+func (s *replSession) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || pos != len(line) {
+		return "", 0, false
+	}
+
+	candidates := []string{}
+	start := strings.LastIndex(line[:pos], " ") + 1
+	word := line[start:pos]
+	if start == 0 {
+		for name := range commands {
+			candidates = append(candidates, name)
+		}
+		candidates = append(candidates, "help", "exit", "set-url", "set-token")
+	} else {
+		candidates = s.fetchAliases()
+	}
+
+	matches := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) != 1 {
+		return "", 0, false
+	}
+	newLine := line[:start] + matches[0]
+	return newLine, len(newLine), true
+}
+
+// fetchAliases pulls account and asset aliases from the node once per
+// session; completion quietly offers nothing when the node is away.
+func (s *replSession) fetchAliases() []string {
+	if s.aliases != nil {
+		return s.aliases
+	}
+	s.aliases = []string{}
+	client := &rpc.Client{BaseURL: s.sessionURL(), Client: s.client.Client}
+	for _, path := range []string{"/list-accounts", "/list-assets"} {
+		var response map[string]interface{}
+		if err := client.Call(context.Background(), path, nil, &response); err != nil {
+			continue
+		}
+		items, _ := response["items"].([]interface{})
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				if alias, ok := m["alias"].(string); ok && alias != "" {
+					s.aliases = append(s.aliases, alias)
+				}
+			}
+		}
+	}
+	sort.Strings(s.aliases)
+	return s.aliases
+}
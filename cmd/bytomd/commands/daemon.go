@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// daemonEnv marks the re-executed child so it runs the node instead of
+// forking again.
+const daemonEnv = "BYTOMD_DAEMON"
+
+// daemonize re-executes bytomd detached from the terminal in its own
+// session and returns once the child is running. The child's PID is in
+// bytomd.pid under the root directory; logs only survive when log_file
+// is configured.
+func daemonize() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	child := exec.Command(exe, os.Args[1:]...)
+	child.Env = append(os.Environ(), daemonEnv+"=1")
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("Failed to start daemon: %v", err)
+	}
+
+	fmt.Printf("bytomd started in the background, pid %d\n", child.Process.Pid)
+	return nil
+}
+
+func pidFilePath() string {
+	return filepath.Join(config.RootDir, "bytomd.pid")
+}
+
+func writePIDFile() error {
+	pid := fmt.Sprintf("%d\n", os.Getpid())
+	return ioutil.WriteFile(pidFilePath(), []byte(pid), 0644)
+}
@@ -20,7 +20,7 @@ var initFilesCmd = &cobra.Command{
 }
 
 func init() {
-	initFilesCmd.Flags().String("chain_id", config.ChainID, "Select [mainnet] or [testnet]")
+	initFilesCmd.Flags().String("chain_id", config.ChainID, "Select [mainnet], [testnet] or [regtest]")
 
 	RootCmd.AddCommand(initFilesCmd)
 }
@@ -28,6 +28,8 @@ func init() {
 func initFiles(cmd *cobra.Command, args []string) {
 	if config.ChainID == "mainnet" {
 		cfg.EnsureRoot(config.RootDir, "mainnet")
+	} else if config.ChainID == "regtest" {
+		cfg.EnsureRoot(config.RootDir, "regtest")
 	} else {
 		cfg.EnsureRoot(config.RootDir, "testnet")
 	}
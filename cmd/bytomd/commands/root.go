@@ -1,10 +1,13 @@
 package commands
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	cfg "github.com/bytom/config"
+	"github.com/bytom/logging"
 )
 
 var (
@@ -15,11 +18,36 @@ var RootCmd = &cobra.Command{
 	Use:   "bytomd",
 	Short: "Multiple asset management.",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		err := viper.Unmarshal(config)
-		if err != nil {
+		if err := viper.Unmarshal(config); err != nil {
 			return err
 		}
+		// reject typoed keys from the config file; flags and env
+		// variables are bound to known keys by construction
+		if file := viper.ConfigFileUsed(); file != "" {
+			fileViper := viper.New()
+			fileViper.SetConfigFile(file)
+			if err := fileViper.ReadInConfig(); err != nil {
+				return err
+			}
+			if err := cfg.ValidateKeys(fileViper.AllKeys()); err != nil {
+				return err
+			}
+		}
 		config.SetRoot(config.RootDir)
+		if err := config.Validate(); err != nil {
+			return err
+		}
+		// the legacy BYTOM_DEBUG switch still wins over the config file
+		level := config.LogLevel
+		if os.Getenv("BYTOM_DEBUG") != "" {
+			level = "debug"
+		}
+		if err := logging.Init(level, config.LogFormat); err != nil {
+			return err
+		}
+		if config.LogFile != "" {
+			return logging.InitFile(config.LogFilePath(), config.LogRotateSizeMB, config.LogRotateKeepDays, config.LogRotateCompress)
+		}
 		return nil
 	},
 }
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -21,6 +22,8 @@ var runNodeCmd = &cobra.Command{
 func init() {
 	runNodeCmd.Flags().String("prof_laddr", config.ProfListenAddress, "Use http to profile bytomd programs")
 	runNodeCmd.Flags().Bool("mining", config.Mining, "Enable mining")
+	runNodeCmd.Flags().Bool("daemon", false, "Run detached from the terminal, writing the PID to bytomd.pid under the root directory; set log_file to keep logs")
+	runNodeCmd.Flags().Uint64("simulate", config.Simulate, "Serve a deterministic in-memory regtest chain of the given height, for integration tests")
 
 	runNodeCmd.Flags().Bool("wallet.enable", config.Wallet.Enable, "Enable wallet")
 
@@ -36,25 +39,51 @@ func init() {
 	RootCmd.AddCommand(runNodeCmd)
 }
 
-func runNode(cmd *cobra.Command, args []string) error {
+// loadGenesis reads the genesis doc into the global config; every
+// command touching the chain store needs the chain id and genesis time.
+func loadGenesis() error {
 	genDocFile := config.GenesisFile()
-	if cmn.FileExists(genDocFile) {
-		jsonBlob, err := ioutil.ReadFile(genDocFile)
-		if err != nil {
-			return fmt.Errorf("Couldn't read GenesisDoc file: %v", err)
-		}
-		genDoc, err := types.GenesisDocFromJSON(jsonBlob)
-		if err != nil {
-			return fmt.Errorf("Error reading GenesisDoc: %v", err)
+	if !cmn.FileExists(genDocFile) {
+		return fmt.Errorf("not find genesis.json")
+	}
+	jsonBlob, err := ioutil.ReadFile(genDocFile)
+	if err != nil {
+		return fmt.Errorf("Couldn't read GenesisDoc file: %v", err)
+	}
+	genDoc, err := types.GenesisDocFromJSON(jsonBlob)
+	if err != nil {
+		return fmt.Errorf("Error reading GenesisDoc: %v", err)
+	}
+	if genDoc.ChainID == "" {
+		return fmt.Errorf("Genesis doc %v must include non-empty chain_id", genDocFile)
+	}
+	config.ChainID = genDoc.ChainID
+	config.PrivateKey = genDoc.PrivateKey
+	config.Time = genDoc.GenesisTime
+	return nil
+}
+
+func runNode(cmd *cobra.Command, args []string) error {
+	if daemon, _ := cmd.Flags().GetBool("daemon"); daemon {
+		if os.Getenv(daemonEnv) == "" {
+			return daemonize()
 		}
-		if genDoc.ChainID == "" {
-			return fmt.Errorf("Genesis doc %v must include non-empty chain_id", genDocFile)
+		// the detached child records its own PID for operators and
+		// init scripts not using systemd
+		if err := writePIDFile(); err != nil {
+			return err
 		}
-		config.ChainID = genDoc.ChainID
-		config.PrivateKey = genDoc.PrivateKey
-		config.Time = genDoc.GenesisTime
-	} else {
-		return fmt.Errorf("not find genesis.json")
+	}
+
+	if config.Simulate > 0 {
+		// a simulated node is a throwaway for integration tests: force
+		// the regtest profile and keep everything in memory
+		config.ChainID = "regtest"
+		config.DBBackend = "memdb"
+		config.ChainDBBackend = ""
+		config.WalletDBBackend = ""
+	} else if err := loadGenesis(); err != nil {
+		return err
 	}
 
 	// Create & start node
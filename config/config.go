@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
 	"time"
 )
@@ -9,9 +10,17 @@ type Config struct {
 	// Top level options use an anonymous struct
 	BaseConfig `mapstructure:",squash"`
 	// Options for services
-	RPC    *RPCConfig    `mapstructure:"rpc"`
-	P2P    *P2PConfig    `mapstructure:"p2p"`
-	Wallet *WalletConfig `mapstructure:"wallet"`
+	RPC       *RPCConfig       `mapstructure:"rpc"`
+	P2P       *P2PConfig       `mapstructure:"p2p"`
+	Wallet    *WalletConfig    `mapstructure:"wallet"`
+	Stratum   *StratumConfig   `mapstructure:"stratum"`
+	Policy    *PolicyConfig    `mapstructure:"policy"`
+	Consensus *ConsensusConfig `mapstructure:"consensus"`
+	Regtest   *RegtestConfig   `mapstructure:"regtest"`
+	Faucet    *FaucetConfig    `mapstructure:"faucet"`
+	Explorer  *ExplorerConfig  `mapstructure:"explorer"`
+	EventSink *EventSinkConfig `mapstructure:"event_sink"`
+	Database  *DatabaseConfig  `mapstructure:"database"`
 }
 
 func DefaultConfig() *Config {
@@ -20,6 +29,14 @@ func DefaultConfig() *Config {
 		RPC:        DefaultRPCConfig(),
 		P2P:        DefaultP2PConfig(),
 		Wallet:     DefaultWalletConfig(),
+		Stratum:    DefaultStratumConfig(),
+		Policy:     DefaultPolicyConfig(),
+		Consensus:  DefaultConsensusConfig(),
+		Regtest:    DefaultRegtestConfig(),
+		Faucet:     DefaultFaucetConfig(),
+		Explorer:   DefaultExplorerConfig(),
+		EventSink:  DefaultEventSinkConfig(),
+		Database:   DefaultDatabaseConfig(),
 	}
 }
 
@@ -29,6 +46,9 @@ func TestConfig() *Config {
 		RPC:        TestRPCConfig(),
 		P2P:        TestP2PConfig(),
 		Wallet:     TestWalletConfig(),
+		Stratum:    DefaultStratumConfig(),
+		Policy:     DefaultPolicyConfig(),
+		Consensus:  DefaultConsensusConfig(),
 	}
 }
 
@@ -57,12 +77,36 @@ type BaseConfig struct {
 	// A JSON file containing the private key to use as a validator in the consensus protocol
 	PrivateKey string `mapstructure:"private_key"`
 
+	// A state snapshot file to bootstrap an empty node from, instead of
+	// replaying the whole chain
+	Snapshot string `mapstructure:"snapshot_file"`
+
 	// A custom human readable name for this node
 	Moniker string `mapstructure:"moniker"`
 
 	// TCP or UNIX socket address for the profiling server to listen on
 	ProfListenAddress string `mapstructure:"prof_laddr"`
 
+	// Serve pprof and runtime diagnostics under /debug on the API
+	// listener, gated by an admin-scoped access token
+	DebugEndpoints bool `mapstructure:"debug_endpoints"`
+
+	// TCP or UNIX socket address to stream raw block/tx notifications
+	// on (rawblock/rawtx/hashblock/hashtx); empty disables the stream
+	RawNotifyAddr string `mapstructure:"raw_notify_addr"`
+
+	// TCP or UNIX socket address to serve the Electrum light-wallet
+	// protocol on; requires the explorer, empty disables the server
+	ElectrumAddr string `mapstructure:"electrum_addr"`
+
+	// TCP or UNIX socket address to stream validated blocks to
+	// read-replica nodes on; empty disables the replication listener
+	ReplicationListen string `mapstructure:"replication_listen"`
+
+	// Address of a primary node's replication listener to follow as a
+	// read replica; empty runs this node standalone
+	ReplicationPrimary string `mapstructure:"replication_primary"`
+
 	// If this node is many blocks behind the tip of the chain, FastSync
 	// allows them to catchup quickly by downloading blocks in parallel
 	// and verifying their commits
@@ -70,18 +114,84 @@ type BaseConfig struct {
 
 	Mining bool `mapstructure:"mining"`
 
+	// Run as a light (SPV) node: sync and validate headers only, and
+	// check wallet-relevant transactions against the header chain with
+	// merkle proofs served by full-node peers
+	Light bool `mapstructure:"light"`
+
+	// Built-in miner tuning: worker goroutines and the percentage of
+	// CPU time each worker may spend hashing
+	MiningWorkers  int32 `mapstructure:"mining_workers"`
+	MiningThrottle int32 `mapstructure:"mining_throttle"`
+
+	// Pause mining when the tip is older than this many minutes or no
+	// peers are connected; 0 disables the guard
+	MiningPauseStale int32 `mapstructure:"mining_pause_stale"`
+
+	// Fixed coinbase payout program (hex) and extra data embedded in
+	// mined blocks; empty keeps the wallet-derived destination
+	CoinbaseProgram   string `mapstructure:"coinbase_program"`
+	CoinbaseExtraData string `mapstructure:"coinbase_extra_data"`
+
+	// How block templates pick mempool transactions: fee_rate | arrival
+	TxSelectionPolicy string `mapstructure:"tx_selection_policy"`
+
+	// Memory budget for the unconfirmed transaction pool in bytes; the
+	// cheapest transactions are evicted beyond it. 0 keeps the default
+	MempoolMaxBytes uint64 `mapstructure:"mempool_max_bytes"`
+
+	// Run witness programs even for blocks buried under a checkpoint,
+	// trading a slower initial sync for full verification
+	FullValidation bool `mapstructure:"full_validation"`
+
+	// Additional checkpoints validation enforces, each "height:hash".
+	// Private networks use these to protect against deep reorgs
+	Checkpoints []string `mapstructure:"checkpoints"`
+
+	// Refuse chain reorganizations deeper than this many blocks and
+	// keep the current chain until an operator intervenes; 0 means
+	// unlimited
+	MaxReorgDepth uint64 `mapstructure:"max_reorg_depth"`
+
+	// Discard block bodies deeper than this many blocks below the tip,
+	// keeping headers and the UTXO set; 0 keeps the node archival. A
+	// pruned node stops advertising the archival service bit to peers
+	PruneDepth uint64 `mapstructure:"prune_depth"`
+
+	// Crawler mode turns the node into a network crawler and address
+	// server: no wallet, aggressive PEX, and an address dump API for
+	// feeding DNS seed infrastructure
+	Crawler bool `mapstructure:"crawler"`
+
+	// Simulate > 0 turns the node into a throwaway integration-test
+	// instance: an in-memory regtest chain pre-populated with this many
+	// deterministic blocks, no genesis.json and no disk state
+	Simulate uint64 `mapstructure:"simulate"`
+
 	FilterPeers bool `mapstructure:"filter_peers"` // false
 
 	// What indexer to use for transactions
 	TxIndex string `mapstructure:"tx_index"`
 
-	// Database backend: leveldb | memdb
+	// Database backend: leveldb | memdb, or any backend registered in
+	// the database package
 	DBBackend string `mapstructure:"db_backend"`
 
+	// Per-store backend overrides; empty falls back to DBBackend. The
+	// chain store is the first to outgrow goleveldb on large UTXO sets
+	ChainDBBackend  string `mapstructure:"chain_db_backend"`
+	WalletDBBackend string `mapstructure:"wallet_db_backend"`
+
 	// Database directory
 	DBPath string `mapstructure:"db_dir"`
 
-	// Keystore directory
+	// Per-store directory overrides; empty falls back to DBPath.
+	// Absolute paths let operators put the large chain data on cheap
+	// storage while the wallet stays on an encrypted volume
+	ChainDBPath  string `mapstructure:"chain_db_dir"`
+	WalletDBPath string `mapstructure:"wallet_db_dir"`
+
+	// Keystore directory; may also be absolute
 	KeysPath string `mapstructure:"keys_dir"`
 
 	// remote HSM url
@@ -89,9 +199,32 @@ type BaseConfig struct {
 
 	ApiAddress string `mapstructure:"api_addr"`
 
+	// Accept unauthenticated API requests from loopback addresses, as
+	// releases before the bootstrap token did. The supported way in is
+	// the one-time token generated on first start
+	AuthDisable bool `mapstructure:"auth_disable"`
+
+	// Default log level and output format: text | json. Per-module
+	// levels are set at runtime through the set-log-level API
+	LogLevel  string `mapstructure:"log_level"`
+	LogFormat string `mapstructure:"log_format"`
+
+	// Optional log file with built-in rotation: the file rotates at
+	// log_rotate_size_mb megabytes, rotated files are gzipped when
+	// log_rotate_compress is set and removed after log_rotate_keep_days
+	// days (0 keeps them forever). Empty log_file logs to stderr only
+	LogFile           string `mapstructure:"log_file"`
+	LogRotateSizeMB   int    `mapstructure:"log_rotate_size_mb"`
+	LogRotateKeepDays int    `mapstructure:"log_rotate_keep_days"`
+	LogRotateCompress bool   `mapstructure:"log_rotate_compress"`
+
 	Time time.Time
 }
 
+// defaultDBPath is the db_dir value under which databases are split
+// into per-network subdirectories; see DBDir.
+const defaultDBPath = "data"
+
 func DefaultBaseConfig() BaseConfig {
 	return BaseConfig{
 		Genesis:           "genesis.json",
@@ -100,11 +233,17 @@ func DefaultBaseConfig() BaseConfig {
 		FastSync:          true,
 		FilterPeers:       false,
 		Mining:            false,
+		MiningWorkers:     1,
+		MiningThrottle:    100,
+		TxSelectionPolicy: "fee_rate",
 		TxIndex:           "kv",
 		DBBackend:         "leveldb",
-		DBPath:            "data",
+		DBPath:            defaultDBPath,
 		KeysPath:          "keystore",
 		HsmUrl:            "",
+		LogLevel:          DefaultLogLevel(),
+		LogFormat:         "text",
+		LogRotateSizeMB:   100,
 	}
 }
 
@@ -120,8 +259,77 @@ func (b BaseConfig) GenesisFile() string {
 	return rootify(b.Genesis, b.RootDir)
 }
 
+// NetDirName maps the configured chain id to the canonical per-network
+// directory name, collapsing the id aliases SelectNetParams accepts.
+func (b BaseConfig) NetDirName() string {
+	switch b.ChainID {
+	case "test", "testnet", "bytom_test":
+		return "testnet"
+	case "solo", "solonet":
+		return "solonet"
+	case "regtest":
+		return "regtest"
+	default:
+		return "mainnet"
+	}
+}
+
+// DBDir returns the directory holding this network's databases. Each
+// network gets its own subdirectory under the default db_dir, so
+// switching chain_id cannot mix or corrupt stores. Two escapes keep
+// older setups working: an explicitly configured db_dir is used
+// verbatim, and a flat layout left by releases before the split (a
+// chain store directly under the default dir) keeps being used.
 func (b BaseConfig) DBDir() string {
-	return rootify(b.DBPath, b.RootDir)
+	base := rootify(b.DBPath, b.RootDir)
+	if b.DBPath != defaultDBPath {
+		return base
+	}
+	if _, err := os.Stat(filepath.Join(base, "txdb.db")); err == nil {
+		return base
+	}
+	return filepath.Join(base, b.NetDirName())
+}
+
+// ChainDBDir returns the directory holding the chain store.
+func (b BaseConfig) ChainDBDir() string {
+	if b.ChainDBPath != "" {
+		return rootify(b.ChainDBPath, b.RootDir)
+	}
+	return b.DBDir()
+}
+
+// WalletDBDir returns the directory holding the wallet store.
+func (b BaseConfig) WalletDBDir() string {
+	if b.WalletDBPath != "" {
+		return rootify(b.WalletDBPath, b.RootDir)
+	}
+	return b.DBDir()
+}
+
+// ChainDBBackendStr returns the backend for the chain store.
+func (b BaseConfig) ChainDBBackendStr() string {
+	if b.ChainDBBackend != "" {
+		return b.ChainDBBackend
+	}
+	return b.DBBackend
+}
+
+// WalletDBBackendStr returns the backend for the wallet store.
+func (b BaseConfig) WalletDBBackendStr() string {
+	if b.WalletDBBackend != "" {
+		return b.WalletDBBackend
+	}
+	return b.DBBackend
+}
+
+// LogFilePath resolves the log file relative to the root directory;
+// empty when file logging is disabled.
+func (b BaseConfig) LogFilePath() string {
+	if b.LogFile == "" {
+		return ""
+	}
+	return rootify(b.LogFile, b.RootDir)
 }
 
 func (b BaseConfig) KeysDir() string {
@@ -168,27 +376,44 @@ func TestRPCConfig() *RPCConfig {
 // P2PConfig
 
 type P2PConfig struct {
-	RootDir        string `mapstructure:"home"`
-	ListenAddress  string `mapstructure:"laddr"`
-	Seeds          string `mapstructure:"seeds"`
-	SkipUPNP       bool   `mapstructure:"skip_upnp"`
-	AddrBook       string `mapstructure:"addr_book_file"`
-	AddrBookStrict bool   `mapstructure:"addr_book_strict"`
-	PexReactor     bool   `mapstructure:"pex"`
-	MaxNumPeers    int    `mapstructure:"max_num_peers"`
-	HandshakeTimeout int `mapstructure:"handshake_timeout"`
-	DialTimeout      int `mapstructure:"dial_timeout"`
+	RootDir          string `mapstructure:"home"`
+	ListenAddress    string `mapstructure:"laddr"`
+	Seeds            string `mapstructure:"seeds"`
+	SkipUPNP         bool   `mapstructure:"skip_upnp"`
+	AddrBook         string `mapstructure:"addr_book_file"`
+	AddrBookStrict   bool   `mapstructure:"addr_book_strict"`
+	PexReactor       bool   `mapstructure:"pex"`
+	MaxNumPeers      int    `mapstructure:"max_num_peers"`
+	HandshakeTimeout int    `mapstructure:"handshake_timeout"`
+	DialTimeout      int    `mapstructure:"dial_timeout"`
+
+	// Rate limits applied to every peer connection, in bytes/second.
+	// A value of 0 keeps the connection layer default.
+	SendRate int64 `mapstructure:"send_rate"`
+	RecvRate int64 `mapstructure:"recv_rate"`
+
+	// Comma separated list of trusted peer addresses. Trusted peers are
+	// always reconnected and exempt from banning and rate limits.
+	TrustedPeers string `mapstructure:"trusted_peers"`
+
+	// Connect-only mode: refuse every peer that is not in TrustedPeers,
+	// for private or consortium networks.
+	ConnectOnly bool `mapstructure:"connect_only"`
 }
 
 func DefaultP2PConfig() *P2PConfig {
 	return &P2PConfig{
-		ListenAddress:  "tcp://0.0.0.0:46656",
-		AddrBook:       "addrbook.json",
-		AddrBookStrict: true,
-		SkipUPNP:       false,
-		MaxNumPeers:    50,
+		ListenAddress:    "tcp://0.0.0.0:46656",
+		AddrBook:         "addrbook.json",
+		AddrBookStrict:   true,
+		SkipUPNP:         false,
+		MaxNumPeers:      50,
 		HandshakeTimeout: 30,
 		DialTimeout:      3,
+		SendRate:         0,
+		RecvRate:         0,
+		TrustedPeers:     "",
+		ConnectOnly:      false,
 	}
 }
 
@@ -203,6 +428,177 @@ func (p *P2PConfig) AddrBookFile() string {
 	return rootify(p.AddrBook, p.RootDir)
 }
 
+//-----------------------------------------------------------------------------
+// StratumConfig
+
+type StratumConfig struct {
+	// Serve work to external stratum miners
+	Enable bool `mapstructure:"enable"`
+
+	// TCP address for the stratum server to listen on
+	ListenAddress string `mapstructure:"laddr"`
+
+	// Require a network access token as worker credentials, so a
+	// publicly reachable mining port can't be used by freeloaders
+	RequireAuth bool `mapstructure:"require_auth"`
+}
+
+func DefaultStratumConfig() *StratumConfig {
+	return &StratumConfig{
+		Enable:        false,
+		ListenAddress: "0.0.0.0:46663",
+	}
+}
+
+//-----------------------------------------------------------------------------
+// ConsensusConfig
+
+// ConsensusConfig overrides individual consensus parameters of the
+// profile selected by chain ID, for private chains. Zero values keep
+// the profile value. Every node on the chain must agree on these.
+// Setting any of them on a public network is a startup error.
+type ConsensusConfig struct {
+	TargetSecondsPerBlock    uint64 `mapstructure:"target_seconds_per_block"`
+	BlocksPerRetarget        uint64 `mapstructure:"blocks_per_retarget"`
+	MaxBlockGas              int64  `mapstructure:"max_block_gas"`
+	SubsidyReductionInterval uint64 `mapstructure:"subsidy_reduction_interval"`
+	BaseSubsidy              uint64 `mapstructure:"base_subsidy"`
+	InitialBlockSubsidy      uint64 `mapstructure:"initial_block_subsidy"`
+}
+
+func DefaultConsensusConfig() *ConsensusConfig {
+	return &ConsensusConfig{}
+}
+
+//-----------------------------------------------------------------------------
+// RegtestConfig
+
+// RegtestConfig customizes the genesis block of a local regtest chain.
+// It only applies when chain_id is "regtest"; zero values keep the
+// stock genesis.
+type RegtestConfig struct {
+	// Control program (hex) receiving the genesis allocation
+	GenesisProgram string `mapstructure:"genesis_program"`
+
+	// Size of the genesis allocation in base units
+	GenesisAmount uint64 `mapstructure:"genesis_amount"`
+
+	// Genesis block timestamp in milliseconds
+	GenesisTimestampMS uint64 `mapstructure:"genesis_timestamp_ms"`
+}
+
+func DefaultRegtestConfig() *RegtestConfig {
+	return &RegtestConfig{}
+}
+
+//-----------------------------------------------------------------------------
+// FaucetConfig
+
+// FaucetConfig drives the built-in test-network faucet. The faucet
+// refuses to run on the main network regardless of these settings.
+type FaucetConfig struct {
+	Enable bool `mapstructure:"enable"`
+
+	// Funding account and its key password
+	AccountID string `mapstructure:"account_id"`
+	Password  string `mapstructure:"password"`
+
+	// Payout per request in base units; one request per address per day
+	Amount uint64 `mapstructure:"amount"`
+
+	// Fee attached to each payout transaction
+	Fee uint64 `mapstructure:"fee"`
+}
+
+func DefaultFaucetConfig() *FaucetConfig {
+	return &FaucetConfig{
+		Amount: 500000000,
+	}
+}
+
+//-----------------------------------------------------------------------------
+// ExplorerConfig
+
+// ExplorerConfig enables the chain-wide explorer indexes: address and
+// asset transaction lookups and the rich list, kept in their own
+// database next to the chain store.
+type ExplorerConfig struct {
+	Enable bool `mapstructure:"enable"`
+}
+
+func DefaultExplorerConfig() *ExplorerConfig {
+	return &ExplorerConfig{}
+}
+
+//-----------------------------------------------------------------------------
+// EventSinkConfig
+
+// EventSinkConfig points the event exporter at an external message bus.
+// A non-empty URL enables the exporter; the scheme selects the
+// transport (http/https reach a Kafka REST Proxy out of the box).
+type EventSinkConfig struct {
+	URL         string `mapstructure:"url"`
+	TopicPrefix string `mapstructure:"topic_prefix"`
+}
+
+func DefaultEventSinkConfig() *EventSinkConfig {
+	return &EventSinkConfig{
+		TopicPrefix: "bytom.",
+	}
+}
+
+//-----------------------------------------------------------------------------
+// DatabaseConfig
+
+// DatabaseConfig tunes the LevelDB engine under the node's stores.
+// Zero values keep the engine defaults (8MB block cache, 4MB write
+// buffer, 500 open files, snappy compression), which suit small nodes;
+// archival nodes on SSDs usually want far larger caches.
+type DatabaseConfig struct {
+	// Uncompressed block cache size, in megabytes
+	BlockCacheSize int `mapstructure:"block_cache_size"`
+
+	// Memtable size, in megabytes; larger buffers batch more writes
+	// per compaction
+	WriteBufferSize int `mapstructure:"write_buffer_size"`
+
+	// Cap on cached table file descriptors; raise it alongside the
+	// process's file descriptor limit on large databases
+	MaxOpenFiles int `mapstructure:"max_open_files"`
+
+	// Block compression: "snappy" (default) or "none". On fast disks
+	// the CPU saved by "none" can outweigh the extra bytes read.
+	Compression string `mapstructure:"compression"`
+}
+
+func DefaultDatabaseConfig() *DatabaseConfig {
+	return &DatabaseConfig{}
+}
+
+//-----------------------------------------------------------------------------
+// PolicyConfig
+
+// PolicyConfig tunes the relay standardness rules in the policy
+// package. Zero values keep the built-in defaults; none of these
+// affect block validation.
+type PolicyConfig struct {
+	// Largest serialized transaction relayed, in bytes
+	MaxTxSize uint64 `mapstructure:"max_tx_size"`
+
+	// Fee rate a transaction must pay to enter the pool, per KB
+	MinRelayFeePerKB uint64 `mapstructure:"min_relay_fee_per_kb"`
+
+	// Reject BTM outputs below this amount
+	DustThreshold uint64 `mapstructure:"dust_threshold"`
+
+	// Only relay transactions whose output programs are known templates
+	RejectNonStandard bool `mapstructure:"reject_non_standard"`
+}
+
+func DefaultPolicyConfig() *PolicyConfig {
+	return &PolicyConfig{}
+}
+
 //-----------------------------------------------------------------------------
 // WalletConfig
 
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -22,3 +25,31 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal("/opt/data", cfg.DBDir())
 
 }
+
+func TestDBDirPerNetwork(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cfg := DefaultConfig()
+	cfg.SetRoot(root)
+
+	// each network gets its own subdirectory under the default db_dir
+	assert.Equal(filepath.Join(root, "data", "mainnet"), cfg.DBDir())
+	cfg.ChainID = "testnet"
+	assert.Equal(filepath.Join(root, "data", "testnet"), cfg.DBDir())
+	cfg.ChainID = "bytom_test"
+	assert.Equal(filepath.Join(root, "data", "testnet"), cfg.DBDir())
+	cfg.ChainID = "solo"
+	assert.Equal(filepath.Join(root, "data", "solonet"), cfg.DBDir())
+
+	// a flat layout from an older release keeps being used
+	if err := os.MkdirAll(filepath.Join(root, "data", "txdb.db"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(filepath.Join(root, "data"), cfg.DBDir())
+}
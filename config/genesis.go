@@ -1,14 +1,51 @@
 package config
 
 import (
+	"encoding/hex"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/bytom/consensus"
 	"github.com/bytom/crypto/sha3pool"
+	"github.com/bytom/errors"
 	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/bc/legacy"
 )
 
+// The genesis coinbase is fixed for the public networks; regtest
+// chains may override it through ApplyRegtestGenesis before the first
+// GenerateGenesisBlock call.
+var (
+	genesisProgram            = []byte{81}
+	genesisAmount      uint64 = 1470000000000000000
+	genesisTimestampMS uint64 = 1511318565142
+	genesisBits        uint64 = 2161727821138738707
+)
+
+// ApplyRegtestGenesis installs the regtest genesis allocation and the
+// trivially minable difficulty. It must run after SelectNetParams and
+// before anything derives the genesis block or transaction.
+func ApplyRegtestGenesis(regtest *RegtestConfig) error {
+	genesisBits = consensus.ActiveNetParams.PowMinBits
+	if regtest == nil {
+		return nil
+	}
+	if regtest.GenesisProgram != "" {
+		program, err := hex.DecodeString(regtest.GenesisProgram)
+		if err != nil {
+			return errors.WithDetailf(errors.New("bad regtest.genesis_program"), "not valid hex: %v", err)
+		}
+		genesisProgram = program
+	}
+	if regtest.GenesisAmount > 0 {
+		genesisAmount = regtest.GenesisAmount
+	}
+	if regtest.GenesisTimestampMS > 0 {
+		genesisTimestampMS = regtest.GenesisTimestampMS
+	}
+	return nil
+}
+
 // Generate genesis transaction
 func GenerateGenesisTx() *legacy.Tx {
 	txData := legacy.TxData{
@@ -21,10 +58,10 @@ func GenerateGenesisTx() *legacy.Tx {
 				OutputCommitment: legacy.OutputCommitment{
 					AssetAmount: bc.AssetAmount{
 						AssetId: consensus.BTMAssetID,
-						Amount:  1470000000000000000,
+						Amount:  genesisAmount,
 					},
 					VMVersion:      1,
-					ControlProgram: []byte{81},
+					ControlProgram: genesisProgram,
 				},
 			},
 		},
@@ -49,11 +86,11 @@ func GenerateGenesisBlock() *legacy.Block {
 			Version:     1,
 			Height:      1,
 			Seed:        bc.NewHash(seed),
-			TimestampMS: 1511318565142,
+			TimestampMS: genesisTimestampMS,
 			BlockCommitment: legacy.BlockCommitment{
 				TransactionsMerkleRoot: merkleRoot,
 			},
-			Bits: 2161727821138738707,
+			Bits: genesisBits,
 		},
 		Transactions: []*legacy.Tx{genesisCoinbaseTx},
 	}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+)
+
+// LoadFile parses the config file at path on top of the defaults,
+// applying the same key and value validation the startup path uses.
+// It is used by the runtime reload path; note that unlike startup it
+// sees only the file, not command-line flags or environment variables.
+func LoadFile(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	if err := ValidateKeys(v.AllKeys()); err != nil {
+		return nil, err
+	}
+	config := DefaultConfig()
+	if err := v.Unmarshal(config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
@@ -21,12 +21,136 @@ func EnsureRoot(rootDir string, network string) {
 
 var defaultConfigTmpl = `# This is a TOML config file.
 # For more information, see https://github.com/toml-lang/toml
+#
+# bytomd also accepts config.yaml or config.json with the same keys.
+# Every key can be overridden by an environment variable (TM_<KEY>,
+# dots becoming underscores, e.g. TM_P2P_LADDR) or by a CLI flag.
+# Unknown keys fail startup with an error naming the key.
+
 fast_sync = true
-db_backend = "leveldb"
 api_addr = "0.0.0.0:9888"
 
+# Every API request needs an access token; the first start prints a
+# one-time bootstrap token and writes it to bootstrap.token in this
+# directory. auth_disable restores unauthenticated loopback access.
+#auth_disable = false
+
+# Run as a light (SPV) node: sync headers only and verify
+# wallet-relevant transactions with merkle proofs from full-node peers.
+#light = false
+
+# Logging. Format "json" emits one JSON object per line for log
+# aggregation; per-module levels are adjusted at runtime with
+# the set-log-level API.
+#log_level = "info"
+#log_format = "text"
+# Setting log_file mirrors logs into a file with built-in rotation.
+#log_file = ""
+#log_rotate_size_mb = 100
+#log_rotate_keep_days = 0
+#log_rotate_compress = false
+
+# Serve pprof and runtime diagnostics under /debug on the API listener.
+# Requests must carry an admin-scoped access token, even from localhost.
+#debug_endpoints = false
+
+# Stream raw block and transaction notifications (rawblock, rawtx,
+# hashblock, hashtx topics) to subscribers on this tcp:// or unix://
+# address. Empty disables the stream.
+#raw_notify_addr = ""
+
+# Serve the Electrum light-wallet protocol (scripthash subscriptions,
+# history, broadcast, headers) on this tcp:// or unix:// address.
+# Requires explorer.enable; empty disables the server.
+#electrum_addr = ""
+
+# Read scaling: a primary streams validated blocks to replicas on
+# replication_listen (tcp:// or unix://); a replica follows the primary
+# given in replication_primary and serves query endpoints off its own
+# indexes. A node cannot be both, and a replica cannot mine.
+#replication_listen = ""
+#replication_primary = ""
+
+# Database engine and layout. Per-store overrides let the chain store
+# live on a different backend or volume than the wallet.
+db_backend = "leveldb"
+#chain_db_backend = "leveldb"
+#wallet_db_backend = "leveldb"
+# Databases live in a per-network subdirectory of db_dir (mainnet,
+# testnet, ...), so switching chain_id never mixes stores. Setting
+# db_dir explicitly uses the given directory as-is.
+#db_dir = "data"
+#chain_db_dir = ""
+#wallet_db_dir = ""
+#keys_dir = "keystore"
+
+# Validation and storage policy.
+#full_validation = false
+#max_reorg_depth = 0
+#prune_depth = 0
+
+# Mining.
+#mining = false
+#mining_workers = 1
+#mining_throttle = 100
+#coinbase_program = ""
+#tx_selection_policy = "fee_rate"
+
+[rpc]
+#laddr = "tcp://0.0.0.0:46657"
+
+[wallet]
+#enable = false
+
+[stratum]
+#enable = false
+#laddr = "0.0.0.0:46663"
+#require_auth = false
+
+# Self-serve coin faucet; refuses to run on the main network.
+[faucet]
+#enable = false
+#account_id = ""
+#password = ""
+#amount = 500000000
+#fee = 0
+
+# Genesis overrides for local regtest chains (chain_id = "regtest").
+[regtest]
+#genesis_program = ""
+#genesis_amount = 0
+#genesis_timestamp_ms = 0
+
+# Chain-wide explorer indexes: address and asset transaction lookups
+# and the rich list, for running a public explorer off this node.
+[explorer]
+#enable = false
+
+# Publish block and transaction events to an external message bus with
+# at-least-once delivery. http:// and https:// URLs reach a Kafka REST
+# Proxy; an empty URL disables the exporter.
+[event_sink]
+#url = ""
+#topic_prefix = "bytom."
+
+# LevelDB engine tuning for the node's stores. Commented values are the
+# engine defaults; archival nodes on SSDs usually want a far larger
+# block cache and write buffer. Sizes are in megabytes.
+[database]
+#block_cache_size = 8
+#write_buffer_size = 4
+#max_open_files = 500
+#compression = "snappy"
+
+# p2p stays last: the network-specific seeds line is appended to it.
 [p2p]
 laddr = "tcp://0.0.0.0:46656"
+#max_num_peers = 50
+#pex = true
+#send_rate = 0
+#recv_rate = 0
+#trusted_peers = ""
+#connect_only = false
 `
 
 var testnetSeeds = `
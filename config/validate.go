@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bytom/database"
+)
+
+// KnownKeys returns every config key bytomd understands, in the dotted
+// form viper reports ("p2p.laddr"). It is derived from the mapstructure
+// tags so it never drifts from the Config struct.
+func KnownKeys() map[string]bool {
+	keys := map[string]bool{}
+	collectKeys(reflect.TypeOf(Config{}), "", keys)
+	return keys
+}
+
+func collectKeys(t reflect.Type, prefix string, keys map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == ",squash" {
+			collectKeys(field.Type, prefix, keys)
+			continue
+		}
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+			collectKeys(fieldType, prefix+tag+".", keys)
+			continue
+		}
+		keys[prefix+tag] = true
+	}
+}
+
+// ValidateKeys rejects config keys bytomd does not understand, naming
+// the offending key, so a typo in the config file fails startup instead
+// of silently using the default.
+func ValidateKeys(keys []string) error {
+	known := KnownKeys()
+	for _, key := range keys {
+		if !known[key] {
+			return fmt.Errorf("unknown config key %q; check for typos against the documented config file", key)
+		}
+	}
+	return nil
+}
+
+// Validate checks config values that would otherwise only fail deep in
+// startup, and names the offending key.
+func (cfg *Config) Validate() error {
+	backends := map[string]bool{}
+	for _, name := range database.Backends() {
+		backends[name] = true
+	}
+	for key, value := range map[string]string{
+		"db_backend":        cfg.DBBackend,
+		"chain_db_backend":  cfg.ChainDBBackend,
+		"wallet_db_backend": cfg.WalletDBBackend,
+	} {
+		if value != "" && !backends[value] {
+			return fmt.Errorf("config key %q: unknown database backend %q, available: %v", key, value, database.Backends())
+		}
+	}
+
+	if cfg.Light && cfg.Mining {
+		return fmt.Errorf("config key \"mining\": a light node keeps no UTXO set and cannot mine")
+	}
+
+	if cfg.Light && cfg.Explorer != nil && cfg.Explorer.Enable {
+		return fmt.Errorf("config key \"explorer.enable\": a light node stores no block bodies to index")
+	}
+
+	if cfg.Light && cfg.EventSink != nil && cfg.EventSink.URL != "" {
+		return fmt.Errorf("config key \"event_sink.url\": a light node stores no block bodies to export")
+	}
+
+	if cfg.ElectrumAddr != "" && (cfg.Explorer == nil || !cfg.Explorer.Enable) {
+		return fmt.Errorf("config key \"electrum_addr\": the electrum server answers from the explorer index and needs \"explorer.enable\"")
+	}
+
+	if cfg.ReplicationListen != "" && cfg.ReplicationPrimary != "" {
+		return fmt.Errorf("config key \"replication_primary\": a node cannot be a replication primary and a replica at once")
+	}
+
+	if cfg.ReplicationPrimary != "" && cfg.Mining {
+		return fmt.Errorf("config key \"mining\": a read replica follows its primary and cannot mine")
+	}
+
+	if cfg.PruneDepth > 0 && cfg.Explorer != nil && cfg.Explorer.Enable {
+		return fmt.Errorf("config key \"explorer.enable\": the explorer must index the whole chain and cannot run in pruned mode")
+	}
+
+	if cfg.PruneDepth > 0 && cfg.MaxReorgDepth > 0 && cfg.PruneDepth < cfg.MaxReorgDepth {
+		return fmt.Errorf("config key \"prune_depth\": %d is below \"max_reorg_depth\" %d; a reorganization could need pruned block bodies", cfg.PruneDepth, cfg.MaxReorgDepth)
+	}
+
+	if cfg.Database != nil {
+		switch cfg.Database.Compression {
+		case "", "snappy", "none":
+		default:
+			return fmt.Errorf("config key \"database.compression\": unknown value %q, available: snappy, none", cfg.Database.Compression)
+		}
+	}
+
+	for _, entry := range cfg.Checkpoints {
+		if !strings.Contains(entry, ":") {
+			return fmt.Errorf("config key \"checkpoints\": entry %q is not of the form height:hash", entry)
+		}
+	}
+	return nil
+}
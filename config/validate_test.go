@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateKeys(t *testing.T) {
+	if err := ValidateKeys([]string{"db_backend", "p2p.laddr", "wallet.enable"}); err != nil {
+		t.Errorf("known keys rejected: %v", err)
+	}
+
+	err := ValidateKeys([]string{"db_backned"})
+	if err == nil {
+		t.Fatal("typoed key accepted")
+	}
+	if !strings.Contains(err.Error(), "db_backned") {
+		t.Errorf("error %q does not name the offending key", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("default config invalid: %v", err)
+	}
+
+	cfg.WalletDBBackend = "no-such-engine"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("bad backend accepted")
+	}
+	if !strings.Contains(err.Error(), "wallet_db_backend") {
+		t.Errorf("error %q does not name the offending key", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.MaxReorgDepth = 100
+	cfg.PruneDepth = 10
+	if err := cfg.Validate(); err == nil {
+		t.Error("prune depth below max reorg depth accepted")
+	}
+}
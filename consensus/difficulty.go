@@ -29,7 +29,7 @@ func HashToBig(hash *bc.Hash) *big.Int {
 //	| 8 bits [63-56] | 1 bit [55] | 55 bits [54-00] |
 //	-------------------------------------------------
 //
-// 	N = (-1^sign) * mantissa * 256^(exponent-3)
+//	N = (-1^sign) * mantissa * 256^(exponent-3)
 func CompactToBig(compact uint64) *big.Int {
 	// Extract the mantissa, sign bit, and exponent.
 	mantissa := compact & 0x007fffffffffffff
@@ -87,12 +87,12 @@ func CheckProofOfWork(hash *bc.Hash, bits uint64) bool {
 
 func CalcNextRequiredDifficulty(lastBH, compareBH *legacy.BlockHeader) uint64 {
 	if lastBH == nil {
-		return powMinBits
-	} else if (lastBH.Height)%BlocksPerRetarget != 0 {
+		return ActiveNetParams.PowMinBits
+	} else if (lastBH.Height)%ActiveNetParams.BlocksPerRetarget != 0 {
 		return lastBH.Bits
 	}
 
-	targetTimeSpan := int64(BlocksPerRetarget * targetSecondsPerBlock)
+	targetTimeSpan := int64(ActiveNetParams.BlocksPerRetarget * ActiveNetParams.TargetSecondsPerBlock)
 	actualTimeSpan := int64(lastBH.Time().Sub(compareBH.Time()).Seconds())
 
 	oldTarget := CompactToBig(lastBH.Bits)
@@ -6,7 +6,7 @@ import (
 	"github.com/bytom/protocol/bc"
 )
 
-//consensus variables
+// consensus variables
 const (
 	// define the Max transaction size and Max block size
 	MaxTxSize    = uint64(1048576)
@@ -14,14 +14,6 @@ const (
 
 	//config parameter for coinbase reward
 	CoinbasePendingBlockNumber = uint64(6)
-	subsidyReductionInterval   = uint64(560640)
-	baseSubsidy                = uint64(624000000000)
-	initialBlockSubsidy        = uint64(1470000000000000000)
-
-	// config for pow mining
-	powMinBits            = uint64(2161727821138738707)
-	BlocksPerRetarget     = uint64(1024)
-	targetSecondsPerBlock = uint64(60)
 )
 
 // BTMAssetID is BTM's asset id, the soul asset of Bytom
@@ -35,9 +27,9 @@ var BTMAssetID = &bc.AssetID{
 // BlockSubsidy calculate the coinbase rewards on given block height
 func BlockSubsidy(height uint64) uint64 {
 	if height == 1 {
-		return initialBlockSubsidy
+		return ActiveNetParams.InitialBlockSubsidy
 	}
-	return baseSubsidy >> uint(height/subsidyReductionInterval)
+	return ActiveNetParams.BaseSubsidy >> uint(height/ActiveNetParams.SubsidyReductionInterval)
 }
 
 // IsBech32SegwitPrefix returns whether the prefix is a known prefix for segwit
@@ -53,16 +45,99 @@ type Params struct {
 	// Name defines a human-readable identifier for the network.
 	Name            string
 	Bech32HRPSegwit string
+
+	// TargetSecondsPerBlock is the desired block interval.
+	TargetSecondsPerBlock uint64
+
+	// BlocksPerRetarget is the difficulty adjustment window.
+	BlocksPerRetarget uint64
+
+	// MaxBlockGas bounds the gas the programs validated within one
+	// transaction may consume; no single transaction can exceed it.
+	MaxBlockGas int64
+
+	// Coinbase subsidy schedule: the reward starts at BaseSubsidy
+	// (after the InitialBlockSubsidy premine in block 1) and halves
+	// every SubsidyReductionInterval blocks.
+	SubsidyReductionInterval uint64
+	BaseSubsidy              uint64
+	InitialBlockSubsidy      uint64
+
+	// PowMinBits is the easiest difficulty target allowed.
+	PowMinBits uint64
+
+	// Version-bits soft fork signaling: deployment states advance on
+	// MinerConfirmationWindow boundaries, and a deployment locks in
+	// when at least RuleChangeActivationThreshold blocks in a window
+	// signal for it.
+	MinerConfirmationWindow       uint64
+	RuleChangeActivationThreshold uint64
 }
 
-// MainNetParams is the config for production
-var MainNetParams = Params{
-	Name:            "main",
-	Bech32HRPSegwit: "bm",
+// defaultConsensusParams are the consensus knobs every built-in profile
+// starts from; profiles only deviate where the network genuinely does.
+func defaultConsensusParams() Params {
+	return Params{
+		TargetSecondsPerBlock:    60,
+		BlocksPerRetarget:        1024,
+		MaxBlockGas:              80000,
+		SubsidyReductionInterval: 560640,
+		BaseSubsidy:              624000000000,
+		InitialBlockSubsidy:      1470000000000000000,
+		PowMinBits:               2161727821138738707,
+		// a window per retarget period, locking in at 75% signaling
+		MinerConfirmationWindow:       1024,
+		RuleChangeActivationThreshold: 768,
+	}
+}
+
+func makeParams(name, bech32HRP string) Params {
+	params := defaultConsensusParams()
+	params.Name = name
+	params.Bech32HRPSegwit = bech32HRP
+	return params
 }
 
+// MainNetParams is the config for production
+var MainNetParams = makeParams("main", "bm")
+
 // TestNetParams is the config for test-net
-var TestNetParams = Params{
-	Name:            "test",
-	Bech32HRPSegwit: "tm",
+var TestNetParams = makeParams("test", "tm")
+
+// SoloNetParams is the config for single-node development chains
+var SoloNetParams = makeParams("solo", "sm")
+
+// RegtestParams is the config for throwaway local integration-test
+// chains: any hash satisfies proof of work, so blocks mine instantly
+// and tests run deterministically.
+var RegtestParams = makeRegtestParams()
+
+func makeRegtestParams() Params {
+	params := makeParams("regtest", "rm")
+	// a target above 2^256, so every hash passes
+	params.PowMinBits = 0x207fffffffffffff
+	params.TargetSecondsPerBlock = 1
+	return params
+}
+
+// ActiveNetParams holds the consensus parameters the node runs with.
+// It is selected by chain ID at startup and may be overridden for
+// private chains from config, and must not change afterwards.
+var ActiveNetParams = MainNetParams
+
+// SelectNetParams installs the consensus parameter profile for the
+// given chain ID. Unknown IDs keep the production profile.
+func SelectNetParams(chainID string) {
+	switch chainID {
+	case "main", "mainnet":
+		ActiveNetParams = MainNetParams
+	case "test", "testnet", "bytom_test":
+		ActiveNetParams = TestNetParams
+	case "solo", "solonet":
+		ActiveNetParams = SoloNetParams
+	case "regtest":
+		ActiveNetParams = RegtestParams
+	default:
+		ActiveNetParams = MainNetParams
+	}
 }
@@ -0,0 +1,126 @@
+package ed25519
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"strconv"
+
+	"github.com/bytom/crypto/ed25519/ecmath"
+)
+
+// curveOrder is the order of the prime-order subgroup, in the
+// little-endian form ecmath scalars use.
+var curveOrder = ecmath.Scalar{
+	0xed, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+	0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+}
+
+// isTorsionFree reports whether p lies in the prime-order subgroup,
+// i.e. carries no small-order component.
+func isTorsionFree(p *ecmath.Point) bool {
+	var t ecmath.Point
+	t.ScMul(p, &curveOrder)
+	return t.ConstTimeEqual(&ecmath.ZeroPoint)
+}
+
+// VerifyBatch reports whether every signature in the batch is a valid
+// signature of its message by its public key. Instead of checking each
+// verification equation separately it checks one random linear
+// combination of them:
+//
+//	[sum z_i*s_i]B == sum [z_i]R_i + sum [z_i*h_i]A_i
+//
+// for fresh random coefficients z_i, which holds for dishonest inputs
+// only with negligible probability. The short coefficients roughly
+// halve the curve work per signature and the per-signature base-point
+// multiplications collapse into a single one.
+//
+// Signatures whose R or public key carry a small-order component are
+// rejected outright: opposite small-order defects in two signatures
+// can cancel under the random coefficients, which would let a batch
+// accept what Verify rejects. Refusing to judge them keeps the batch
+// in agreement with Verify on every input.
+//
+// A false result only means at least one signature failed (or the
+// batch could not be formed); callers that need the culprit, or an
+// authoritative per-signature answer, must fall back to Verify on each
+// entry. VerifyBatch panics if the slice lengths differ or a public
+// key has the wrong length, mirroring Verify.
+func VerifyBatch(publicKeys []PublicKey, messages, sigs [][]byte) bool {
+	if len(messages) != len(publicKeys) || len(sigs) != len(publicKeys) {
+		panic("ed25519: mismatched batch lengths")
+	}
+	if len(publicKeys) == 0 {
+		return true
+	}
+
+	var lhsScalar ecmath.Scalar // accumulates sum z_i*s_i
+	rhs := ecmath.ZeroPoint
+	for i, publicKey := range publicKeys {
+		if l := len(publicKey); l != PublicKeySize {
+			panic("ed25519: bad public key length: " + strconv.Itoa(l))
+		}
+		sig := sigs[i]
+		if len(sig) != SignatureSize || sig[63]&224 != 0 {
+			return false
+		}
+
+		var A, R ecmath.Point
+		var buf [32]byte
+		copy(buf[:], publicKey)
+		if _, ok := A.Decode(buf); !ok {
+			return false
+		}
+		copy(buf[:], sig[:32])
+		if _, ok := R.Decode(buf); !ok {
+			return false
+		}
+		// Verify compares the encoding of R, not the point, so a
+		// non-canonical encoding must not slip through the batch.
+		if enc := R.Encode(); !bytes.Equal(enc[:], sig[:32]) {
+			return false
+		}
+		// see the doc comment: small-order components could cancel
+		// between signatures, so leave them to the inline fallback
+		if !isTorsionFree(&A) || !isTorsionFree(&R) {
+			return false
+		}
+
+		h := sha512.New()
+		h.Write(sig[:32])
+		h.Write(publicKey)
+		h.Write(messages[i])
+		var digest [64]byte
+		h.Sum(digest[:0])
+		var hReduced ecmath.Scalar
+		hReduced.Reduce(&digest)
+
+		// 128-bit random coefficient, forced odd so it can never be
+		// zero, which would drop the signature from the combination.
+		var z ecmath.Scalar
+		if _, err := cryptorand.Read(z[:16]); err != nil {
+			return false
+		}
+		z[0] |= 1
+
+		var s ecmath.Scalar
+		copy(s[:], sig[32:])
+		lhsScalar.MulAdd(&z, &s, &lhsScalar)
+
+		var zh ecmath.Scalar
+		zh.MulAdd(&z, &hReduced, &ecmath.Zero)
+
+		var t ecmath.Point
+		t.ScMul(&R, &z)
+		rhs.Add(&rhs, &t)
+		t.ScMul(&A, &zh)
+		rhs.Add(&rhs, &t)
+	}
+
+	var lhs ecmath.Point
+	lhs.ScMulBase(&lhsScalar)
+	return lhs.ConstTimeEqual(&rhs)
+}
@@ -0,0 +1,156 @@
+package ed25519
+
+import (
+	"crypto/sha512"
+	"testing"
+
+	"github.com/bytom/crypto/ed25519/internal/edwards25519"
+)
+
+func makeBatch(t *testing.T, n int) ([]PublicKey, [][]byte, [][]byte) {
+	pubs := make([]PublicKey, n)
+	messages := make([][]byte, n)
+	sigs := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		pub, priv, err := GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs[i] = pub
+		messages[i] = []byte{byte(i), 'm', 's', 'g'}
+		sigs[i] = Sign(priv, messages[i])
+	}
+	return pubs, messages, sigs
+}
+
+func TestVerifyBatch(t *testing.T) {
+	pubs, messages, sigs := makeBatch(t, 16)
+	if !VerifyBatch(pubs, messages, sigs) {
+		t.Error("batch of valid signatures rejected")
+	}
+	if !VerifyBatch(nil, nil, nil) {
+		t.Error("empty batch rejected")
+	}
+}
+
+func TestVerifyBatchRejects(t *testing.T) {
+	pubs, messages, sigs := makeBatch(t, 8)
+
+	messages[3] = []byte("wrong message")
+	if VerifyBatch(pubs, messages, sigs) {
+		t.Error("batch with a mismatched message accepted")
+	}
+	messages[3] = []byte{3, 'm', 's', 'g'}
+
+	sigs[5][10] ^= 0x40
+	if VerifyBatch(pubs, messages, sigs) {
+		t.Error("batch with a corrupted signature accepted")
+	}
+	sigs[5][10] ^= 0x40
+
+	sigs[2] = sigs[2][:40]
+	if VerifyBatch(pubs, messages, sigs) {
+		t.Error("batch with a truncated signature accepted")
+	}
+}
+
+// signWithTorsionedR mirrors Sign but adds a small-order point to R
+// before deriving the challenge, producing a signature whose defect
+// lies entirely outside the prime-order subgroup.
+func signWithTorsionedR(priv PrivateKey, message []byte, torsion *[32]byte) []byte {
+	h := sha512.New()
+	h.Write(priv[:32])
+
+	var digest1, messageDigest, hramDigest [64]byte
+	var expandedSecretKey [32]byte
+	h.Sum(digest1[:0])
+	copy(expandedSecretKey[:], digest1[:])
+	expandedSecretKey[0] &= 248
+	expandedSecretKey[31] &= 63
+	expandedSecretKey[31] |= 64
+
+	h.Reset()
+	h.Write(digest1[32:])
+	h.Write(message)
+	h.Sum(messageDigest[:0])
+
+	var messageDigestReduced [32]byte
+	edwards25519.ScReduce(&messageDigestReduced, &messageDigest)
+	var R, T edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&R, &messageDigestReduced)
+	if !T.FromBytes(torsion) {
+		panic("bad torsion point encoding")
+	}
+	var cached edwards25519.CachedGroupElement
+	T.ToCached(&cached)
+	var sum edwards25519.CompletedGroupElement
+	edwards25519.GeAdd(&sum, &R, &cached)
+	sum.ToExtended(&R)
+
+	var encodedR [32]byte
+	R.ToBytes(&encodedR)
+
+	h.Reset()
+	h.Write(encodedR[:])
+	h.Write(priv[32:])
+	h.Write(message)
+	h.Sum(hramDigest[:0])
+	var hramDigestReduced [32]byte
+	edwards25519.ScReduce(&hramDigestReduced, &hramDigest)
+
+	var s [32]byte
+	edwards25519.ScMulAdd(&s, &hramDigestReduced, &expandedSecretKey, &messageDigestReduced)
+
+	sig := make([]byte, SignatureSize)
+	copy(sig, encodedR[:])
+	copy(sig[32:], s[:])
+	return sig
+}
+
+// TestSmallOrderComponentAgreement checks that a signature whose R
+// carries a small-order component gets the same answer from Verify and
+// VerifyBatch on every run: Verify rejects it, and the batch refuses
+// to judge it rather than gamble on the random coefficients.
+func TestSmallOrderComponentAgreement(t *testing.T) {
+	// an order-8 point, canonically encoded
+	torsion := [32]byte{
+		0xc7, 0x17, 0x6a, 0x70, 0x3d, 0x4d, 0xd8, 0x4f,
+		0xba, 0x3c, 0x0b, 0x76, 0x0d, 0x10, 0x67, 0x0f,
+		0x2a, 0x20, 0x53, 0xfa, 0x2c, 0x39, 0xcc, 0xc6,
+		0x4e, 0xc7, 0xfd, 0x77, 0x92, 0xac, 0x03, 0x7a,
+	}
+
+	pub, priv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("torsioned nonce point")
+	sig := signWithTorsionedR(priv, message, &torsion)
+
+	if Verify(pub, message, sig) {
+		t.Fatal("Verify accepted a signature with a small-order component in R")
+	}
+	pubs, messages, sigs := makeBatch(t, 4)
+	pubs = append(pubs, pub)
+	messages = append(messages, message)
+	sigs = append(sigs, sig)
+	for i := 0; i < 64; i++ {
+		if VerifyBatch(pubs, messages, sigs) {
+			t.Fatalf("run %d: VerifyBatch accepted a batch Verify would not", i)
+		}
+	}
+}
+
+// TestVerifyBatchAgreesWithVerify cross-checks that a batch accepted
+// wholesale is also accepted signature by signature.
+func TestVerifyBatchAgreesWithVerify(t *testing.T) {
+	pubs, messages, sigs := makeBatch(t, 8)
+	if !VerifyBatch(pubs, messages, sigs) {
+		t.Fatal("batch of valid signatures rejected")
+	}
+	for i := range sigs {
+		if !Verify(pubs[i], messages[i], sigs[i]) {
+			t.Errorf("signature %d accepted in batch but rejected individually", i)
+		}
+	}
+}
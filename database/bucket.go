@@ -0,0 +1,70 @@
+package database
+
+import (
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// A Namespace is the key prefix isolating one bucket of related records
+// inside a shared database. The convention across stores is a short
+// uppercase tag followed by a colon, e.g. "ACC:".
+type Namespace string
+
+// Key prepends the namespace to a record key.
+func (n Namespace) Key(k []byte) []byte {
+	return append([]byte(n), k...)
+}
+
+// StringKey prepends the namespace to a string record key.
+func (n Namespace) StringKey(k string) []byte {
+	return []byte(string(n) + k)
+}
+
+// A Bucket binds a namespace to a database, so the typed stores get
+// isolated key ranges: iteration over one bucket can never see another
+// bucket's records, or bookkeeping keys like the schema version.
+type Bucket struct {
+	db dbm.DB
+	ns Namespace
+}
+
+// NewBucket returns the bucket for the given namespace of db.
+func NewBucket(db dbm.DB, ns Namespace) *Bucket {
+	return &Bucket{db: db, ns: ns}
+}
+
+// Key prepends the bucket's namespace to a record key.
+func (b *Bucket) Key(k []byte) []byte {
+	return b.ns.Key(k)
+}
+
+// Get reads the record stored under key, nil if there is none.
+func (b *Bucket) Get(k []byte) []byte {
+	return b.db.Get(b.ns.Key(k))
+}
+
+// Set writes the record stored under key.
+func (b *Bucket) Set(k, v []byte) {
+	b.db.Set(b.ns.Key(k), v)
+}
+
+// SetSync writes the record stored under key and syncs to disk.
+func (b *Bucket) SetSync(k, v []byte) {
+	b.db.SetSync(b.ns.Key(k), v)
+}
+
+// Delete removes the record stored under key.
+func (b *Bucket) Delete(k []byte) {
+	b.db.Delete(b.ns.Key(k))
+}
+
+// Iterator iterates every record in the bucket. Keys carry the
+// namespace prefix, as with IteratorPrefix on the raw database.
+func (b *Bucket) Iterator() dbm.Iterator {
+	return b.db.IteratorPrefix([]byte(b.ns))
+}
+
+// IteratorPrefix iterates the records in the bucket whose keys start
+// with prefix. Keys carry the namespace prefix, as with Iterator.
+func (b *Bucket) IteratorPrefix(prefix []byte) dbm.Iterator {
+	return b.db.IteratorPrefix(b.ns.Key(prefix))
+}
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// Compact runs a full compaction on the given database, reclaiming the
+// space deleted keys still occupy. Only engines with a compaction
+// facility support it; the in-memory backend is a no-op.
+func Compact(db dbm.DB) error {
+	switch db := db.(type) {
+	case *dbm.GoLevelDB:
+		return db.DB().CompactRange(util.Range{})
+	case *dbm.MemDB:
+		return nil
+	default:
+		return fmt.Errorf("database backend %T does not support online compaction", db)
+	}
+}
@@ -0,0 +1,81 @@
+// Package database selects the key-value engine backing the node's
+// stores. The tendermint dbm backends (goleveldb, cleveldb, memdb) are
+// always available; heavier engines such as BadgerDB or RocksDB hook in
+// through Register, typically from an init function in a build-tagged
+// file, so default builds do not carry their dependencies.
+package database
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// Creator opens (or creates) the named database in dir.
+type Creator func(name, dir string) (dbm.DB, error)
+
+var (
+	mtx      sync.Mutex
+	backends = map[string]Creator{}
+)
+
+// builtin backends tmlibs provides out of the box.
+var builtin = map[string]bool{
+	dbm.LevelDBBackendStr:   true,
+	dbm.CLevelDBBackendStr:  true,
+	dbm.GoLevelDBBackendStr: true,
+	dbm.MemDBBackendStr:     true,
+}
+
+// Register makes a backend selectable by name in config. Backends are
+// wired at init time; registering a name twice is a programming error
+// and panics.
+func Register(backend string, creator Creator) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if backends[backend] != nil || builtin[backend] {
+		panic(fmt.Sprintf("database backend %q registered twice", backend))
+	}
+	backends[backend] = creator
+}
+
+// Backends lists every selectable backend name.
+func Backends() []string {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	names := []string{}
+	for name := range builtin {
+		names = append(names, name)
+	}
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewDB opens the named database with the given backend. Unlike
+// dbm.NewDB it fails with a clear error on an unknown backend name
+// instead of panicking inside the engine.
+func NewDB(name, backend, dir string) (dbm.DB, error) {
+	mtx.Lock()
+	creator := backends[backend]
+	mtx.Unlock()
+
+	if creator != nil {
+		return creator(name, dir)
+	}
+	// open the LevelDB-backed builtins ourselves: dbm.NewDB panics on a
+	// corrupt database, openLevelDB detects and repairs it
+	if backend == dbm.LevelDBBackendStr || backend == dbm.GoLevelDBBackendStr {
+		return openLevelDB(name, dir)
+	}
+	if builtin[backend] {
+		return dbm.NewDB(name, backend, dir), nil
+	}
+	return nil, fmt.Errorf("unknown database backend %q, available: %v", backend, Backends())
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// schemaVersionKey stores each database's schema version as a decimal
+// string. A missing key means version 0: data written before schema
+// versioning existed.
+var schemaVersionKey = []byte("schemaVersion")
+
+// A Migration upgrades one database from the previous schema version to
+// Version. Apply must tolerate an empty database, since fresh nodes run
+// the full chain of migrations on first start.
+type Migration struct {
+	Version uint64
+	Name    string
+	Apply   func(db dbm.DB) error
+}
+
+var migrations = map[string][]Migration{}
+
+// RegisterMigration adds a migration for the named database, typically
+// from an init function next to the code whose key format changed.
+// Versions must be unique per database; duplicates panic.
+func RegisterMigration(dbName string, m Migration) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	for _, existing := range migrations[dbName] {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migration version %d registered twice for database %q", m.Version, dbName))
+		}
+	}
+	migrations[dbName] = append(migrations[dbName], m)
+}
+
+// SchemaVersion returns the schema version recorded in the database.
+func SchemaVersion(db dbm.DB) uint64 {
+	raw := db.Get(schemaVersionKey)
+	if raw == nil {
+		return 0
+	}
+	version, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// Migrate brings the named database up to the latest registered schema
+// version, applying pending migrations in order. The version key is
+// advanced after each migration commits, so an interrupted run resumes
+// where it stopped.
+func Migrate(dbName string, db dbm.DB) error {
+	mtx.Lock()
+	pending := append([]Migration{}, migrations[dbName]...)
+	mtx.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+
+	current := SchemaVersion(db)
+	for _, m := range pending {
+		if m.Version <= current {
+			continue
+		}
+		log.WithFields(log.Fields{"database": dbName, "version": m.Version, "name": m.Name}).Info("Applying database migration")
+		if err := m.Apply(db); err != nil {
+			return fmt.Errorf("migration %d (%s) of database %q failed: %v", m.Version, m.Name, dbName, err)
+		}
+		db.SetSync(schemaVersionKey, []byte(strconv.FormatUint(m.Version, 10)))
+		current = m.Version
+	}
+	return nil
+}
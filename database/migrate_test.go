@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+func TestMigrate(t *testing.T) {
+	db := dbm.NewMemDB()
+	applied := []uint64{}
+
+	// registered out of order; must apply in version order
+	RegisterMigration("migratetest", Migration{
+		Version: 2,
+		Name:    "second",
+		Apply: func(db dbm.DB) error {
+			applied = append(applied, 2)
+			return nil
+		},
+	})
+	RegisterMigration("migratetest", Migration{
+		Version: 1,
+		Name:    "first",
+		Apply: func(db dbm.DB) error {
+			applied = append(applied, 1)
+			return nil
+		},
+	})
+
+	if err := Migrate("migratetest", db); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0] != 1 || applied[1] != 2 {
+		t.Errorf("migrations applied in order %v, want [1 2]", applied)
+	}
+	if version := SchemaVersion(db); version != 2 {
+		t.Errorf("schema version = %d, want 2", version)
+	}
+
+	// a second run must be a no-op
+	if err := Migrate("migratetest", db); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("migrations reapplied: %v", applied)
+	}
+}
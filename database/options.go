@@ -0,0 +1,64 @@
+package database
+
+import (
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// LevelDBOptions is the tunable subset of the engine options for the
+// LevelDB-backed stores. The zero value keeps the engine defaults,
+// which suit small nodes; archival nodes on SSDs usually want a much
+// larger block cache and write buffer.
+type LevelDBOptions struct {
+	// BlockCacheMB sizes the uncompressed block cache, in megabytes.
+	BlockCacheMB int
+
+	// WriteBufferMB sizes the memtable, in megabytes. Larger buffers
+	// batch more writes per compaction at the cost of memory and
+	// recovery time.
+	WriteBufferMB int
+
+	// MaxOpenFiles caps the table file descriptor cache.
+	MaxOpenFiles int
+
+	// DisableCompression turns off snappy block compression; on fast
+	// disks the CPU saved can outweigh the extra bytes read.
+	DisableCompression bool
+}
+
+var ldbOptions LevelDBOptions
+
+// SetLevelDBOptions installs the tuning options applied to every
+// LevelDB the node opens from then on. Call it once, before the first
+// NewDB.
+func SetLevelDBOptions(o LevelDBOptions) {
+	mtx.Lock()
+	ldbOptions = o
+	mtx.Unlock()
+}
+
+// levelDBOpt renders the installed options in goleveldb's form, or nil
+// when everything is left at the engine default.
+func levelDBOpt() *opt.Options {
+	mtx.Lock()
+	o := ldbOptions
+	mtx.Unlock()
+
+	if o == (LevelDBOptions{}) {
+		return nil
+	}
+
+	ldbOpt := &opt.Options{}
+	if o.BlockCacheMB > 0 {
+		ldbOpt.BlockCacheCapacity = o.BlockCacheMB * opt.MiB
+	}
+	if o.WriteBufferMB > 0 {
+		ldbOpt.WriteBuffer = o.WriteBufferMB * opt.MiB
+	}
+	if o.MaxOpenFiles > 0 {
+		ldbOpt.OpenFilesCacheCapacity = o.MaxOpenFiles
+	}
+	if o.DisableCompression {
+		ldbOpt.Compression = opt.NoCompression
+	}
+	return ldbOpt
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb"
+	ldberrors "github.com/syndtr/goleveldb/leveldb/errors"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// IsCorrupted reports whether an open error indicates a truncated or
+// otherwise corrupt LevelDB rather than a missing or locked one.
+func IsCorrupted(err error) bool {
+	return ldberrors.IsCorrupted(err)
+}
+
+// RepairPath runs LevelDB's recovery on the database directory at path,
+// dropping unreadable tail records and rebuilding the manifest. Data
+// written after the corruption point is lost; callers reindex it from
+// the chain afterwards.
+func RepairPath(path string) error {
+	db, err := leveldb.RecoverFile(path, nil)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// openLevelDB opens the named LevelDB, detecting corruption and
+// attempting recovery once before giving up, so a truncated database
+// surfaces as a clear error instead of an opaque panic deep in the
+// engine.
+func openLevelDB(name, dir string) (dbm.DB, error) {
+	opts := levelDBOpt()
+	db, err := dbm.NewGoLevelDBWithOpts(name, dir, opts)
+	if err == nil {
+		return db, nil
+	}
+	if !IsCorrupted(err) {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, name+".db")
+	log.WithFields(log.Fields{"database": name, "error": err}).Warn("Database is corrupt; attempting recovery")
+	if repairErr := RepairPath(path); repairErr != nil {
+		return nil, fmt.Errorf("database %q is corrupt (%v) and recovery failed: %v", name, err, repairErr)
+	}
+
+	db, err = dbm.NewGoLevelDBWithOpts(name, dir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database %q still fails to open after recovery: %v", name, err)
+	}
+	log.WithField("database", name).Warn("Database recovered; records past the corruption point were dropped and will be reindexed")
+	return db, nil
+}
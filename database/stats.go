@@ -0,0 +1,50 @@
+package database
+
+import (
+	"bytes"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// maxPrefixLen bounds how far CountKeysByPrefix looks for a namespace
+// separator; the store namespaces are short tags like "ACU:".
+const maxPrefixLen = 8
+
+// SizeOnDisk returns the approximate on-disk size of the database in
+// bytes, or -1 when the engine cannot report it.
+func SizeOnDisk(db dbm.DB) int64 {
+	gldb, ok := db.(*dbm.GoLevelDB)
+	if !ok {
+		return -1
+	}
+	sizes, err := gldb.DB().SizeOf([]util.Range{{}})
+	if err != nil {
+		return -1
+	}
+	return sizes.Sum()
+}
+
+// CountKeysByPrefix walks every key and tallies them by namespace: the
+// prefix up to and including the first colon, or "other" for keys
+// without one. It scans the whole database, so callers treat it as an
+// operator-grade query, not something to poll.
+func CountKeysByPrefix(db dbm.DB) map[string]int {
+	counts := map[string]int{}
+	iter := db.Iterator()
+	defer iter.Release()
+
+	for iter.Next() {
+		key := iter.Key()
+		limit := len(key)
+		if limit > maxPrefixLen {
+			limit = maxPrefixLen
+		}
+		if sep := bytes.IndexByte(key[:limit], ':'); sep >= 0 {
+			counts[string(key[:sep+1])]++
+		} else {
+			counts["other"]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,548 @@
+// Package equity implements a compiler for a minimal Equity-style
+// contract language. A contract declares typed parameters and one or
+// more clauses; each clause body is a sequence of verify statements
+// compiled down to BVM opcodes.
+//
+// Witness layout at run time, bottom to top: the contract parameters in
+// declaration order, then the spending clause's arguments, then - when
+// the contract has more than one clause - the clause selector.
+package equity
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/vm"
+)
+
+// ErrSyntax wraps all parse failures.
+var ErrSyntax = errors.New("syntax error")
+
+// parameter types the language accepts.
+var paramTypes = map[string]bool{
+	"Amount":    true,
+	"Asset":     true,
+	"Boolean":   true,
+	"Hash":      true,
+	"Integer":   true,
+	"Program":   true,
+	"PublicKey": true,
+	"Signature": true,
+	"String":    true,
+}
+
+// Param is one typed contract or clause parameter.
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Clause is one spend path of a compiled contract with the program
+// offset where its body starts.
+type Clause struct {
+	Name    string  `json:"name"`
+	Params  []Param `json:"params"`
+	EntryPC uint64  `json:"entry_pc"`
+}
+
+// Contract is the result of compiling one contract source.
+type Contract struct {
+	Name     string    `json:"name"`
+	Params   []Param   `json:"params"`
+	Clauses  []*Clause `json:"clauses"`
+	Program  []byte    `json:"-"`
+	Assembly string    `json:"assembly"`
+}
+
+// Compile parses and compiles one contract source.
+func Compile(source string) (*Contract, error) {
+	p := &parser{tokens: tokenize(source)}
+	decl, err := p.parseContract()
+	if err != nil {
+		return nil, err
+	}
+
+	contract := &Contract{Name: decl.name, Params: decl.params}
+	for _, clause := range decl.clauses {
+		params := clause.params
+		if params == nil {
+			params = []Param{}
+		}
+		contract.Clauses = append(contract.Clauses, &Clause{Name: clause.name, Params: params})
+	}
+	if err := generate(decl, contract); err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
+// ---- lexer ----
+
+func tokenize(source string) []string {
+	isWord := func(r byte) bool {
+		return r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	}
+
+	tokens := []string{}
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '/' && i+1 < len(source) && source[i+1] == '/':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case isWord(c):
+			j := i
+			for j < len(source) && isWord(source[j]) {
+				j++
+			}
+			tokens = append(tokens, source[i:j])
+			i = j
+		case strings.ContainsRune("=!<>", rune(c)) && i+1 < len(source) && source[i+1] == '=':
+			tokens = append(tokens, source[i:i+2])
+			i += 2
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// ---- parser ----
+
+type verifyStmt struct {
+	expr expr
+}
+
+type clauseDecl struct {
+	name   string
+	params []Param
+	stmts  []*verifyStmt
+}
+
+type contractDecl struct {
+	name    string
+	params  []Param
+	clauses []*clauseDecl
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *parser) expect(token string) error {
+	if got := p.next(); got != token {
+		return errors.WithDetailf(ErrSyntax, "expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+func isIdent(token string) bool {
+	if token == "" {
+		return false
+	}
+	c := token[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (p *parser) parseParams() ([]Param, error) {
+	params := []Param{}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for p.peek() != ")" {
+		if len(params) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		name := p.next()
+		if !isIdent(name) {
+			return nil, errors.WithDetailf(ErrSyntax, "bad parameter name %q", name)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ := p.next()
+		if !paramTypes[typ] {
+			return nil, errors.WithDetailf(ErrSyntax, "unknown type %q", typ)
+		}
+		params = append(params, Param{Name: name, Type: typ})
+	}
+	p.next() // consume ")"
+	return params, nil
+}
+
+func (p *parser) parseContract() (*contractDecl, error) {
+	if err := p.expect("contract"); err != nil {
+		return nil, err
+	}
+	contract := &contractDecl{name: p.next()}
+	if !isIdent(contract.name) {
+		return nil, errors.WithDetailf(ErrSyntax, "bad contract name %q", contract.name)
+	}
+
+	var err error
+	if contract.params, err = p.parseParams(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for p.peek() != "}" {
+		clause, err := p.parseClause()
+		if err != nil {
+			return nil, err
+		}
+		contract.clauses = append(contract.clauses, clause)
+	}
+	if len(contract.clauses) == 0 {
+		return nil, errors.WithDetail(ErrSyntax, "contract has no clauses")
+	}
+	return contract, nil
+}
+
+func (p *parser) parseClause() (*clauseDecl, error) {
+	if err := p.expect("clause"); err != nil {
+		return nil, err
+	}
+	clause := &clauseDecl{name: p.next()}
+	if !isIdent(clause.name) {
+		return nil, errors.WithDetailf(ErrSyntax, "bad clause name %q", clause.name)
+	}
+
+	var err error
+	if clause.params, err = p.parseParams(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for p.peek() != "}" {
+		if err := p.expect("verify"); err != nil {
+			return nil, err
+		}
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		clause.stmts = append(clause.stmts, &verifyStmt{expr: e})
+	}
+	p.next() // consume "}"
+	if len(clause.stmts) == 0 {
+		return nil, errors.WithDetailf(ErrSyntax, "clause %q is empty", clause.name)
+	}
+	return clause, nil
+}
+
+// ---- expressions ----
+
+type expr interface{}
+
+type varExpr struct{ name string }
+
+type intExpr struct{ literal string }
+
+type callExpr struct {
+	name string
+	args []expr
+}
+
+type binExpr struct {
+	op       string
+	lhs, rhs expr
+}
+
+// binary operators and the opcode sequence each compiles to.
+var binOps = map[string]string{
+	"==": "EQUAL",
+	"!=": "EQUAL NOT",
+	"<":  "LESSTHAN",
+	">":  "GREATERTHAN",
+	"<=": "LESSTHANOREQUAL",
+	">=": "GREATERTHANOREQUAL",
+	"+":  "ADD",
+	"-":  "SUB",
+}
+
+func (p *parser) parseExpr() (expr, error) {
+	lhs, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := binOps[p.peek()]; !ok {
+		return lhs, nil
+	}
+
+	op := p.next()
+	rhs, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &binExpr{op: op, lhs: lhs, rhs: rhs}, nil
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	token := p.next()
+	switch {
+	case token == "(":
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case isIdent(token):
+		if p.peek() != "(" {
+			return &varExpr{name: token}, nil
+		}
+		call := &callExpr{name: token}
+		p.next() // consume "("
+		for p.peek() != ")" {
+			if len(call.args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+		}
+		p.next() // consume ")"
+		return call, nil
+	case token != "" && token[0] >= '0' && token[0] <= '9':
+		return &intExpr{literal: token}, nil
+	}
+	return nil, errors.WithDetailf(ErrSyntax, "unexpected token %q", token)
+}
+
+// ---- code generation ----
+
+// codegen tracks the runtime stack so identifiers can be fetched with
+// PICK at the right depth.
+type codegen struct {
+	names     []string // stack positions of the named values, bottom first
+	stackSize int
+	out       []string
+}
+
+func (cg *codegen) emit(tokens string) {
+	cg.out = append(cg.out, tokens)
+}
+
+func (cg *codegen) compileExpr(e expr) error {
+	switch e := e.(type) {
+	case *varExpr:
+		for index := len(cg.names) - 1; index >= 0; index-- {
+			if cg.names[index] == e.name {
+				cg.emit(fmt.Sprintf("%d PICK", cg.stackSize-1-index))
+				cg.stackSize++
+				return nil
+			}
+		}
+		return errors.WithDetailf(ErrSyntax, "undefined variable %q", e.name)
+
+	case *intExpr:
+		cg.emit(e.literal)
+		cg.stackSize++
+		return nil
+
+	case *binExpr:
+		ops, ok := binOps[e.op]
+		if !ok {
+			return errors.WithDetailf(ErrSyntax, "unknown operator %q", e.op)
+		}
+		if err := cg.compileExpr(e.lhs); err != nil {
+			return err
+		}
+		if err := cg.compileExpr(e.rhs); err != nil {
+			return err
+		}
+		cg.emit(ops)
+		cg.stackSize--
+		return nil
+
+	case *callExpr:
+		return cg.compileCall(e)
+	}
+	return errors.WithDetail(ErrSyntax, "unknown expression")
+}
+
+func (cg *codegen) compileCall(call *callExpr) error {
+	argc := func(n int) error {
+		if len(call.args) != n {
+			return errors.WithDetailf(ErrSyntax, "%s takes %d arguments", call.name, n)
+		}
+		return nil
+	}
+
+	switch call.name {
+	case "checkTxSig":
+		// CHECKSIG pops pubkey, message, signature.
+		if err := argc(2); err != nil {
+			return err
+		}
+		if err := cg.compileExpr(call.args[1]); err != nil {
+			return err
+		}
+		cg.emit("TXSIGHASH")
+		cg.stackSize++
+		if err := cg.compileExpr(call.args[0]); err != nil {
+			return err
+		}
+		cg.emit("CHECKSIG")
+		cg.stackSize -= 2
+		return nil
+
+	case "sha3", "sha256":
+		if err := argc(1); err != nil {
+			return err
+		}
+		if err := cg.compileExpr(call.args[0]); err != nil {
+			return err
+		}
+		cg.emit(strings.ToUpper(call.name))
+		return nil
+
+	case "below":
+		if err := argc(1); err != nil {
+			return err
+		}
+		cg.emit("BLOCKHEIGHT")
+		cg.stackSize++
+		if err := cg.compileExpr(call.args[0]); err != nil {
+			return err
+		}
+		cg.emit("LESSTHAN")
+		cg.stackSize--
+		return nil
+
+	case "above":
+		if err := argc(1); err != nil {
+			return err
+		}
+		cg.emit("BLOCKHEIGHT")
+		cg.stackSize++
+		if err := cg.compileExpr(call.args[0]); err != nil {
+			return err
+		}
+		cg.emit("GREATERTHAN")
+		cg.stackSize--
+		return nil
+	}
+	return errors.WithDetailf(ErrSyntax, "unknown function %q", call.name)
+}
+
+// segmentLength assembles one segment with jump targets replaced by
+// same-sized placeholders, to compute clause entry offsets.
+func segmentLength(segment string) (uint64, error) {
+	tokens := strings.Fields(segment)
+	resolved := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, "JUMP:$"):
+			resolved = append(resolved, "JUMP:0")
+		case strings.HasPrefix(token, "JUMPIF:$"):
+			resolved = append(resolved, "JUMPIF:0")
+		case strings.HasPrefix(token, "$"):
+		default:
+			resolved = append(resolved, token)
+		}
+	}
+	code, err := vm.Assemble(strings.Join(resolved, " "))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(code)), nil
+}
+
+func generate(decl *contractDecl, contract *Contract) error {
+	multiClause := len(decl.clauses) > 1
+
+	// Dispatch on the clause selector, the topmost witness argument.
+	segments := []string{}
+	if multiClause {
+		dispatch := []string{}
+		for i := 0; i < len(decl.clauses)-1; i++ {
+			dispatch = append(dispatch, fmt.Sprintf("DUP %d NUMEQUAL JUMPIF:$clause_%d", i, i))
+		}
+		dispatch = append(dispatch, fmt.Sprintf("JUMP:$clause_%d", len(decl.clauses)-1))
+		segments = append(segments, strings.Join(dispatch, " "))
+	} else {
+		segments = append(segments, "")
+	}
+
+	for i, clause := range decl.clauses {
+		cg := &codegen{}
+		for _, param := range decl.params {
+			cg.names = append(cg.names, param.Name)
+		}
+		for _, param := range clause.params {
+			cg.names = append(cg.names, param.Name)
+		}
+		cg.stackSize = len(cg.names)
+
+		if multiClause {
+			cg.emit(fmt.Sprintf("$clause_%d DROP", i))
+		}
+		for _, stmt := range clause.stmts {
+			if err := cg.compileExpr(stmt.expr); err != nil {
+				return errors.WithDetailf(err, "in clause %q", clause.name)
+			}
+			cg.emit("VERIFY")
+			cg.stackSize--
+		}
+		cg.emit("TRUE")
+		if multiClause && i < len(decl.clauses)-1 {
+			cg.emit("JUMP:$end")
+		}
+		segments = append(segments, strings.Join(cg.out, " "))
+	}
+	if multiClause {
+		segments = append(segments, "$end")
+	}
+
+	assembly := strings.Join(segments, " ")
+	program, err := vm.Assemble(assembly)
+	if err != nil {
+		return errors.Wrap(err, "assembling contract")
+	}
+
+	contract.Program = program
+	contract.Assembly = assembly
+
+	// Entry points: the accumulated length of everything before each
+	// clause segment.
+	offset, err := segmentLength(segments[0])
+	if err != nil {
+		return err
+	}
+	for i, clause := range contract.Clauses {
+		clause.EntryPC = offset
+		length, err := segmentLength(segments[i+1])
+		if err != nil {
+			return err
+		}
+		offset += length
+	}
+	return nil
+}
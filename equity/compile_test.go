@@ -0,0 +1,94 @@
+package equity
+
+import (
+	"testing"
+
+	"github.com/bytom/protocol/vm"
+)
+
+func TestCompileSingleClause(t *testing.T) {
+	source := `
+		contract LockWithPublicKey(pubKey: PublicKey) {
+			clause spend(sig: Signature) {
+				verify checkTxSig(pubKey, sig)
+			}
+		}
+	`
+	contract, err := Compile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contract.Name != "LockWithPublicKey" {
+		t.Errorf("got contract name %q", contract.Name)
+	}
+	if len(contract.Params) != 1 || contract.Params[0].Type != "PublicKey" {
+		t.Errorf("got params %v", contract.Params)
+	}
+	if len(contract.Clauses) != 1 || contract.Clauses[0].Name != "spend" {
+		t.Errorf("got clauses %v", contract.Clauses)
+	}
+	if contract.Clauses[0].EntryPC != 0 {
+		t.Errorf("single clause entry = %d, want 0", contract.Clauses[0].EntryPC)
+	}
+	if _, err := vm.ParseProgram(contract.Program); err != nil {
+		t.Errorf("compiled program does not parse: %v", err)
+	}
+}
+
+func TestCompileMultiClause(t *testing.T) {
+	source := `
+		contract LockUntil(pubKey: PublicKey, deadline: Integer) {
+			clause spend(sig: Signature) {
+				verify above(deadline)
+				verify checkTxSig(pubKey, sig)
+			}
+			clause cancel(sig: Signature) {
+				verify checkTxSig(pubKey, sig)
+			}
+		}
+	`
+	contract, err := Compile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contract.Clauses) != 2 {
+		t.Fatalf("got %d clauses, want 2", len(contract.Clauses))
+	}
+	if contract.Clauses[0].EntryPC == 0 {
+		t.Error("dispatched clause entry should follow the dispatch prefix")
+	}
+	if contract.Clauses[1].EntryPC <= contract.Clauses[0].EntryPC {
+		t.Errorf("clause entries not increasing: %d, %d", contract.Clauses[0].EntryPC, contract.Clauses[1].EntryPC)
+	}
+
+	insts, err := vm.ParseProgram(contract.Program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[uint64]bool{}
+	pc := uint64(0)
+	for _, inst := range insts {
+		entries[pc] = true
+		pc += uint64(inst.Len)
+	}
+	for _, clause := range contract.Clauses {
+		if !entries[clause.EntryPC] {
+			t.Errorf("clause %q entry %d is not an instruction boundary", clause.Name, clause.EntryPC)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"contract Foo() {}",
+		"contract Foo(x: Nope) { clause c() { verify x } }",
+		"contract Foo(x: Integer) { clause c() { verify bogus(x) } }",
+		"contract Foo(x: Integer) { clause c() { verify y } }",
+	}
+	for _, source := range cases {
+		if _, err := Compile(source); err == nil {
+			t.Errorf("Compile(%q) succeeded, want error", source)
+		}
+	}
+}
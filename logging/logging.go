@@ -0,0 +1,204 @@
+// Package logging centralizes bytomd's logrus setup: the output format
+// (text or JSON) and the global plus per-module log levels, which can
+// be adjusted at runtime through the set-log-level API.
+package logging
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+)
+
+// modulePaths maps package paths to the module names operators tune,
+// checked in order so the more specific paths win.
+var modulePaths = []struct {
+	pathPart string
+	module   string
+}{
+	{"bytom/p2p", "p2p"},
+	{"bytom/mining", "mining"},
+	{"bytom/blockchain/wallet", "wallet"},
+	{"bytom/blockchain/account", "wallet"},
+	{"bytom/blockchain/asset", "wallet"},
+	{"bytom/blockchain", "rpc"},
+	{"bytom/protocol", "chain"},
+	{"bytom/node", "node"},
+}
+
+var (
+	mu           sync.RWMutex
+	defaultLevel = log.InfoLevel
+	moduleLevels = map[string]log.Level{}
+)
+
+// Init applies the configured default level and output format and
+// installs the module hook and filter. It is called once at startup;
+// later adjustments go through SetLevel.
+func Init(level, format string) error {
+	if level != "" {
+		lvl, err := log.ParseLevel(level)
+		if err != nil {
+			return errors.WithDetailf(errors.New("bad log_level"), "unknown log level %q", level)
+		}
+		mu.Lock()
+		defaultLevel = lvl
+		mu.Unlock()
+	}
+
+	var formatter log.Formatter
+	switch format {
+	case "", "text":
+		formatter = &log.TextFormatter{FullTimestamp: true}
+	case "json":
+		formatter = &log.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	default:
+		return errors.WithDetailf(errors.New("bad log_format"), "unknown log format %q; want text or json", format)
+	}
+
+	log.AddHook(moduleHook{})
+	log.SetFormatter(&filterFormatter{inner: formatter})
+	applyLoggerLevel()
+	return nil
+}
+
+// SetLevel changes the level of one module, or the default level for
+// every module without an override when module is empty.
+func SetLevel(module, level string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return errors.WithDetailf(errors.New("bad level"), "unknown log level %q", level)
+	}
+
+	mu.Lock()
+	if module == "" {
+		defaultLevel = lvl
+	} else {
+		if !knownModule(module) {
+			mu.Unlock()
+			return errors.WithDetailf(errors.New("bad module"), "unknown module %q; known modules: %s", module, strings.Join(Modules(), ", "))
+		}
+		moduleLevels[module] = lvl
+	}
+	mu.Unlock()
+
+	applyLoggerLevel()
+	return nil
+}
+
+// Levels reports the default level and every per-module override.
+func Levels() map[string]string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	levels := map[string]string{"default": defaultLevel.String()}
+	for module, lvl := range moduleLevels {
+		levels[module] = lvl.String()
+	}
+	return levels
+}
+
+// Modules lists the module names SetLevel accepts.
+func Modules() []string {
+	seen := map[string]bool{}
+	modules := []string{}
+	for _, mp := range modulePaths {
+		if !seen[mp.module] {
+			seen[mp.module] = true
+			modules = append(modules, mp.module)
+		}
+	}
+	return modules
+}
+
+func knownModule(module string) bool {
+	for _, mp := range modulePaths {
+		if mp.module == module {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLoggerLevel keeps the logrus gate at the most verbose configured
+// level; the filter formatter enforces the per-module limits below it.
+func applyLoggerLevel() {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	max := defaultLevel
+	for _, lvl := range moduleLevels {
+		if lvl > max {
+			max = lvl
+		}
+	}
+	log.SetLevel(max)
+}
+
+// moduleHook tags every entry with the module it came from, derived
+// from the caller's package, so the filter and log aggregators can key
+// on a stable field.
+type moduleHook struct{}
+
+func (moduleHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (moduleHook) Fire(entry *log.Entry) error {
+	if _, ok := entry.Data["module"]; ok {
+		return nil
+	}
+	if module := callerModule(); module != "" {
+		entry.Data["module"] = module
+	}
+	return nil
+}
+
+func callerModule() string {
+	pc := make([]uintptr, 16)
+	cnt := runtime.Callers(4, pc)
+	for i := 0; i < cnt; i++ {
+		fu := runtime.FuncForPC(pc[i] - 1)
+		if fu == nil {
+			continue
+		}
+		name := fu.Name()
+		if strings.Contains(name, "sirupsen/logrus") || strings.Contains(name, "bytom/logging") {
+			continue
+		}
+		for _, mp := range modulePaths {
+			if strings.Contains(name, mp.pathPart+".") || strings.Contains(name, mp.pathPart+"/") {
+				return mp.module
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// filterFormatter drops entries below the effective level of their
+// module before the inner formatter renders them. It sits in the
+// formatter because logrus hooks cannot suppress output.
+type filterFormatter struct {
+	inner log.Formatter
+}
+
+func (f *filterFormatter) Format(entry *log.Entry) ([]byte, error) {
+	mu.RLock()
+	limit := defaultLevel
+	if module, ok := entry.Data["module"].(string); ok {
+		if lvl, ok := moduleLevels[module]; ok {
+			limit = lvl
+		}
+	}
+	mu.RUnlock()
+
+	if entry.Level > limit {
+		return nil, nil
+	}
+	return f.inner.Format(entry)
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSetLevel(t *testing.T) {
+	if err := SetLevel("p2p", "debug"); err != nil {
+		t.Fatalf("set p2p level: %v", err)
+	}
+	if levels := Levels(); levels["p2p"] != "debug" {
+		t.Errorf("p2p level = %q, want debug", levels["p2p"])
+	}
+	// the logger gate must open up to the most verbose module
+	if log.GetLevel() != log.DebugLevel {
+		t.Errorf("logger level = %v, want debug", log.GetLevel())
+	}
+
+	if err := SetLevel("no-such-module", "debug"); err == nil {
+		t.Error("unknown module accepted")
+	}
+	if err := SetLevel("p2p", "no-such-level"); err == nil {
+		t.Error("unknown level accepted")
+	}
+}
+
+func TestFilterFormatter(t *testing.T) {
+	moduleLevels = map[string]log.Level{"wallet": log.WarnLevel}
+	defer func() { moduleLevels = map[string]log.Level{} }()
+
+	f := &filterFormatter{inner: &log.TextFormatter{}}
+	entry := &log.Entry{
+		Logger: log.StandardLogger(),
+		Data:   log.Fields{"module": "wallet"},
+		Level:  log.InfoLevel,
+	}
+	out, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Errorf("info entry for a warn-level module was not dropped: %q", out)
+	}
+
+	entry.Level = log.ErrorLevel
+	if out, err = f.Format(entry); err != nil || len(out) == 0 {
+		t.Errorf("error entry dropped: out=%q err=%v", out, err)
+	}
+}
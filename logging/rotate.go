@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+)
+
+// InitFile mirrors the log output into the given file with built-in
+// rotation, so long-running nodes need no external logrotate setup.
+// The active file rotates once it reaches sizeMB megabytes; rotated
+// files are optionally gzipped and dropped after keepDays days
+// (0 keeps them forever). Logs still go to stderr as well.
+func InitFile(path string, sizeMB, keepDays int, compress bool) error {
+	if sizeMB <= 0 {
+		return errors.WithDetailf(errors.New("bad log_rotate_size_mb"), "rotation size must be positive, got %d", sizeMB)
+	}
+	w := &rotatingWriter{
+		path:     path,
+		maxBytes: int64(sizeMB) * 1024 * 1024,
+		keepDays: keepDays,
+		compress: compress,
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, w))
+	return nil
+}
+
+// rotatingWriter appends to one log file and renames it aside with a
+// timestamp suffix when it grows past maxBytes. Compression and pruning
+// of rotated files happen in the background so a rotation never stalls
+// the logging caller.
+type rotatingWriter struct {
+	path     string
+	maxBytes int64
+	keepDays int
+	compress bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (w *rotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate is called with the mutex held.
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	// nanoseconds keep the name unique even when rotations land in the
+	// same second
+	rotated := w.path + "." + time.Now().Format("2006-01-02T15-04-05.000000000")
+	if err := os.Rename(w.path, rotated); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	go func() {
+		if w.compress {
+			gzipFile(rotated)
+		}
+		w.prune()
+	}()
+	return nil
+}
+
+// prune removes rotated files older than the retention window.
+func (w *rotatingWriter) prune() {
+	if w.keepDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -w.keepDays)
+	rotated, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return
+	}
+	for _, name := range rotated {
+		info, err := os.Stat(name)
+		if err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(name)
+		}
+	}
+}
+
+// gzipFile replaces a file with its gzipped form; on any error the
+// original is left in place uncompressed.
+func gzipFile(path string) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &rotatingWriter{
+		path:     filepath.Join(dir, "bytomd.log"),
+		maxBytes: 64,
+	}
+	if err := w.open(); err != nil {
+		t.Fatal(err)
+	}
+
+	line := bytes.Repeat([]byte("x"), 48)
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rotated, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) == 0 {
+		t.Error("no rotated file after exceeding the size limit")
+	}
+	info, err := os.Stat(w.path)
+	if err != nil {
+		t.Fatalf("active log file missing after rotation: %v", err)
+	}
+	if info.Size() > w.maxBytes {
+		t.Errorf("active file size %d exceeds limit %d", info.Size(), w.maxBytes)
+	}
+}
@@ -0,0 +1,48 @@
+package mining
+
+import (
+	"sync"
+)
+
+// Runtime-configurable coinbase settings. When a payout program is set
+// it overrides the account manager (and the anyone-can-spend fallback)
+// as the destination of the block reward; the extra data is embedded in
+// the coinbase output as reference data, typically as a pool tag.
+var (
+	coinbaseMtx       sync.RWMutex
+	coinbaseProgram   []byte
+	coinbaseExtraData []byte
+)
+
+// SetCoinbaseProgram overrides the control program the block reward is
+// paid to. A nil or empty program restores the default destination.
+// Note the program is used as-is: it should enforce the coinbase
+// maturity rule itself.
+func SetCoinbaseProgram(program []byte) {
+	coinbaseMtx.Lock()
+	defer coinbaseMtx.Unlock()
+	coinbaseProgram = program
+}
+
+// SetCoinbaseExtraData sets the reference data embedded in the coinbase
+// output of every mined block.
+func SetCoinbaseExtraData(data []byte) {
+	coinbaseMtx.Lock()
+	defer coinbaseMtx.Unlock()
+	coinbaseExtraData = data
+}
+
+// CoinbaseProgram returns the configured payout program, or nil when
+// the default destination is in effect.
+func CoinbaseProgram() []byte {
+	coinbaseMtx.RLock()
+	defer coinbaseMtx.RUnlock()
+	return coinbaseProgram
+}
+
+// CoinbaseExtraData returns the configured coinbase reference data.
+func CoinbaseExtraData() []byte {
+	coinbaseMtx.RLock()
+	defer coinbaseMtx.RUnlock()
+	return coinbaseExtraData
+}
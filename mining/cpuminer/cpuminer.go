@@ -5,6 +5,7 @@
 package cpuminer
 
 import (
+	"errors"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/bytom/consensus/algorithm"
 	"github.com/bytom/mining"
 	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/bc/legacy"
 )
 
@@ -22,6 +24,9 @@ const (
 	maxNonce          = ^uint64(0) // 2^32 - 1
 	defaultNumWorkers = 1
 	hashUpdateSecs    = 1
+	defaultThrottle   = 100
+	// number of nonces tried between throttle sleeps
+	throttleBatch = 256
 )
 
 // CPUMiner provides facilities for solving blocks (mining) using the CPU in
@@ -32,6 +37,8 @@ type CPUMiner struct {
 	accountManager    *account.Manager
 	txPool            *protocol.TxPool
 	numWorkers        uint64
+	throttle          int32
+	blocksFound       uint64
 	started           bool
 	discreteMining    bool
 	wg                sync.WaitGroup
@@ -55,17 +62,30 @@ func (m *CPUMiner) solveBlock(block *legacy.Block, ticker *time.Ticker, quit cha
 		return false
 	}
 
+	batchStart := time.Now()
+	hashesCompleted := uint64(0)
 	for i := uint64(0); i <= maxNonce; i++ {
 		select {
 		case <-quit:
 			return false
 		case <-ticker.C:
+			m.updateHashes <- hashesCompleted
+			hashesCompleted = 0
 			if m.chain.Height() >= header.Height {
 				return false
 			}
 		default:
 		}
 
+		// Give the CPU back proportionally to the throttle percentage.
+		if i%throttleBatch == throttleBatch-1 {
+			if throttle := m.Throttle(); throttle < 100 {
+				elapsed := time.Since(batchStart)
+				time.Sleep(elapsed * time.Duration(100-throttle) / time.Duration(throttle))
+			}
+			batchStart = time.Now()
+		}
+
 		header.Nonce = i
 		headerHash := header.Hash()
 		proofHash, err := algorithm.AIHash(header.Height, &headerHash, seedCache)
@@ -73,8 +93,10 @@ func (m *CPUMiner) solveBlock(block *legacy.Block, ticker *time.Ticker, quit cha
 			log.Errorf("Mining: failed on AIHash: %v", err)
 			return false
 		}
+		hashesCompleted++
 
 		if consensus.CheckProofOfWork(proofHash, header.Bits) {
+			m.updateHashes <- hashesCompleted
 			return true
 		}
 	}
@@ -108,6 +130,9 @@ out:
 
 		if m.solveBlock(block, ticker, quit) {
 			if isOrphan, err := m.chain.ProcessBlock(block); err == nil {
+				m.Lock()
+				m.blocksFound++
+				m.Unlock()
 				log.WithFields(log.Fields{
 					"height":   block.BlockHeader.Height,
 					"isOrphan": isOrphan,
@@ -122,6 +147,69 @@ out:
 	m.workerWg.Done()
 }
 
+// speedMonitor handles tracking the number of hashes per second the mining
+// process is performing.  It must be run as a goroutine.
+func (m *CPUMiner) speedMonitor() {
+	var hashesPerSec float64
+	var totalHashes uint64
+	ticker := time.NewTicker(time.Second * hashUpdateSecs)
+	defer ticker.Stop()
+
+out:
+	for {
+		select {
+		// Periodic updates from the workers with how many hashes they
+		// have performed.
+		case numHashes := <-m.updateHashes:
+			totalHashes += numHashes
+
+		// Time to update the hashes per second.
+		case <-ticker.C:
+			curHashesPerSec := float64(totalHashes) / hashUpdateSecs
+			if hashesPerSec == 0 {
+				hashesPerSec = curHashesPerSec
+			}
+			hashesPerSec = (hashesPerSec + curHashesPerSec) / 2
+			totalHashes = 0
+
+		// Request for the number of hashes per second.
+		case m.queryHashesPerSec <- hashesPerSec:
+			// Nothing to do.
+
+		case <-m.speedMonitorQuit:
+			break out
+		}
+	}
+
+	m.wg.Done()
+}
+
+// HashesPerSecond returns the number of hashes per second the mining process
+// is performing.  0 is returned if the miner is not currently running.
+//
+// This function is safe for concurrent access.
+func (m *CPUMiner) HashesPerSecond() float64 {
+	m.Lock()
+	defer m.Unlock()
+
+	// Nothing to do if the miner is not currently running.
+	if !m.started {
+		return 0
+	}
+	return <-m.queryHashesPerSec
+}
+
+// BlocksFound returns how many blocks this miner connected to the chain
+// since the process started.
+//
+// This function is safe for concurrent access.
+func (m *CPUMiner) BlocksFound() uint64 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.blocksFound
+}
+
 // miningWorkerController launches the worker goroutines that are used to
 // generate block templates and solve them.  It also provides the ability to
 // dynamically adjust the number of running worker goroutines.
@@ -201,7 +289,8 @@ func (m *CPUMiner) Start() {
 
 	m.quit = make(chan struct{})
 	m.speedMonitorQuit = make(chan struct{})
-	m.wg.Add(1)
+	m.wg.Add(2)
+	go m.speedMonitor()
 	go m.miningWorkerController()
 
 	m.started = true
@@ -280,6 +369,95 @@ func (m *CPUMiner) NumWorkers() int32 {
 	return int32(m.numWorkers)
 }
 
+// SetThrottle caps the fraction of CPU time each worker spends hashing,
+// as a percentage between 1 and 100.  Out of range values are clamped.
+//
+// This function is safe for concurrent access.
+func (m *CPUMiner) SetThrottle(throttle int32) {
+	if throttle < 1 {
+		throttle = 1
+	}
+	if throttle > 100 {
+		throttle = 100
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.throttle = throttle
+}
+
+// Throttle returns the configured CPU throttle percentage.
+//
+// This function is safe for concurrent access.
+func (m *CPUMiner) Throttle() int32 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.throttle
+}
+
+// GenerateNBlocks mines the requested number of blocks synchronously and
+// returns their hashes.  It refuses to run while the continuous miner is
+// active, and only one discrete generation may run at a time.
+//
+// This function is safe for concurrent access.
+func (m *CPUMiner) GenerateNBlocks(n uint64) ([]*bc.Hash, error) {
+	if n == 0 {
+		return nil, errors.New("invalid number of blocks")
+	}
+
+	m.Lock()
+	if m.started || m.discreteMining {
+		m.Unlock()
+		return nil, errors.New("server is already CPU mining")
+	}
+	m.started = true
+	m.discreteMining = true
+	m.speedMonitorQuit = make(chan struct{})
+	m.wg.Add(1)
+	go m.speedMonitor()
+	m.Unlock()
+
+	log.WithField("num", n).Info("Generating blocks")
+
+	blockHashes := make([]*bc.Hash, 0, n)
+	ticker := time.NewTicker(time.Second * hashUpdateSecs)
+	defer ticker.Stop()
+
+	for uint64(len(blockHashes)) < n {
+		block, err := mining.NewBlockTemplate(m.chain, m.txPool, m.accountManager)
+		if err != nil {
+			log.Errorf("Mining: failed on create NewBlockTemplate: %v", err)
+			continue
+		}
+
+		if m.solveBlock(block, ticker, nil) {
+			if isOrphan, err := m.chain.ProcessBlock(block); err == nil && !isOrphan {
+				m.Lock()
+				m.blocksFound++
+				m.Unlock()
+				blockHash := block.Hash()
+				blockHashes = append(blockHashes, &blockHash)
+			} else if err != nil {
+				log.WithField("height", block.BlockHeader.Height).Errorf("Miner fail on ProcessBlock %v", err)
+			}
+		}
+	}
+
+	m.Lock()
+	close(m.speedMonitorQuit)
+	m.Unlock()
+	m.wg.Wait()
+
+	m.Lock()
+	m.started = false
+	m.discreteMining = false
+	m.Unlock()
+
+	log.WithField("num", len(blockHashes)).Info("Generated blocks")
+	return blockHashes, nil
+}
+
 // NewCPUMiner returns a new instance of a CPU miner for the provided configuration.
 // Use Start to begin the mining process.  See the documentation for CPUMiner
 // type for more details.
@@ -289,6 +467,7 @@ func NewCPUMiner(c *protocol.Chain, accountManager *account.Manager, txPool *pro
 		accountManager:    accountManager,
 		txPool:            txPool,
 		numWorkers:        defaultNumWorkers,
+		throttle:          defaultThrottle,
 		updateNumWorkers:  make(chan struct{}),
 		queryHashesPerSec: make(chan float64),
 		updateHashes:      make(chan uint64),
@@ -5,12 +5,11 @@
 package mining
 
 import (
-	"time"
-
 	log "github.com/sirupsen/logrus"
 
 	"github.com/bytom/blockchain/account"
 	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/clock"
 	"github.com/bytom/consensus"
 	"github.com/bytom/consensus/algorithm"
 	"github.com/bytom/errors"
@@ -29,18 +28,20 @@ func createCoinbaseTx(accountManager *account.Manager, amount uint64, blockHeigh
 	amount += consensus.BlockSubsidy(blockHeight)
 	unlockHeight := blockHeight + consensus.CoinbasePendingBlockNumber
 
-	var script []byte
-	if accountManager == nil {
-		script, err = vmutil.CoinbaseProgram(nil, 0, unlockHeight)
-	} else {
-		script, err = accountManager.GetCoinbaseControlProgram(unlockHeight)
-	}
-	if err != nil {
-		return
+	script := CoinbaseProgram()
+	if len(script) == 0 {
+		if accountManager == nil {
+			script, err = vmutil.CoinbaseProgram(nil, 0, unlockHeight)
+		} else {
+			script, err = accountManager.GetCoinbaseControlProgram(unlockHeight)
+		}
+		if err != nil {
+			return
+		}
 	}
 
-	builder := txbuilder.NewBuilder(time.Now())
-	builder.AddOutput(legacy.NewTxOutput(*consensus.BTMAssetID, amount, script, nil))
+	builder := txbuilder.NewBuilder(clock.Now())
+	builder.AddOutput(legacy.NewTxOutput(*consensus.BTMAssetID, amount, script, CoinbaseExtraData()))
 	_, txData, err := builder.Build()
 	if err != nil {
 		return
@@ -63,32 +64,34 @@ func NewBlockTemplate(c *protocol.Chain, txPool *protocol.TxPool, accountManager
 	preBcBlock := legacy.MapBlock(preBlock)
 	nextBlockHeight := preBlock.BlockHeader.Height + 1
 	nextBlockSeed := algorithm.CreateSeed(nextBlockHeight, preBcBlock.Seed, []*bc.Hash{&preBcBlock.ID})
-	txDescs := txPool.GetTransactions()
+	txDescs := orderTxDescs(txPool.GetTransactions())
 	txEntries := make([]*bc.Tx, 0, len(txDescs))
 	blockWeight := uint64(0)
 	txFee := uint64(0)
 
 	var compareDiffBH *legacy.BlockHeader
-	if compareDiffBlock, err := c.GetBlockByHeight(nextBlockHeight - consensus.BlocksPerRetarget); err == nil {
+	if compareDiffBlock, err := c.GetBlockByHeight(nextBlockHeight - consensus.ActiveNetParams.BlocksPerRetarget); err == nil {
 		compareDiffBH = &compareDiffBlock.BlockHeader
 	}
 
 	b := &legacy.Block{
 		BlockHeader: legacy.BlockHeader{
-			Version:           1,
+			Version:           c.ComputeBlockVersion(),
 			Height:            nextBlockHeight,
 			PreviousBlockHash: preBlock.Hash(),
 			Seed:              *nextBlockSeed,
-			TimestampMS:       bc.Millis(time.Now()),
+			TimestampMS:       bc.Millis(clock.Now()),
 			BlockCommitment:   legacy.BlockCommitment{},
 			Bits:              consensus.CalcNextRequiredDifficulty(&preBlock.BlockHeader, compareDiffBH),
 		},
 		Transactions: make([]*legacy.Tx, 0, len(txDescs)),
 	}
 
+	// Transactions are kept in selection order so that a transaction
+	// spending the output of another one in this block follows it.
 	appendTx := func(tx *legacy.Tx, weight, fee uint64) {
-		b.Transactions = append([]*legacy.Tx{tx}, b.Transactions...)
-		txEntries = append([]*bc.Tx{tx.Tx}, txEntries...)
+		b.Transactions = append(b.Transactions, tx)
+		txEntries = append(txEntries, tx.Tx)
 		blockWeight += weight
 		txFee += fee
 	}
@@ -122,7 +125,8 @@ func NewBlockTemplate(c *protocol.Chain, txPool *protocol.TxPool, accountManager
 	if err != nil {
 		return nil, errors.Wrap(err, "fail on createCoinbaseTx")
 	}
-	appendTx(cbTx, 0, 0)
+	b.Transactions = append([]*legacy.Tx{cbTx}, b.Transactions...)
+	txEntries = append([]*bc.Tx{cbTx.Tx}, txEntries...)
 
 	b.BlockHeader.BlockCommitment.TransactionsMerkleRoot, err = bc.MerkleRoot(txEntries)
 	if err != nil {
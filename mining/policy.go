@@ -0,0 +1,112 @@
+package mining
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+)
+
+// selection policies for picking mempool transactions into a template.
+const (
+	// PolicyFeeRate picks the highest fee-per-weight transactions first.
+	PolicyFeeRate = "fee_rate"
+	// PolicyArrival picks transactions in the order they entered the pool.
+	PolicyArrival = "arrival"
+)
+
+var (
+	policyMtx       sync.RWMutex
+	selectionPolicy = PolicyFeeRate
+)
+
+// SetSelectionPolicy switches how block templates pick transactions from
+// the mempool. Unknown values fall back to the fee-rate policy.
+func SetSelectionPolicy(policy string) {
+	policyMtx.Lock()
+	defer policyMtx.Unlock()
+	if policy == PolicyArrival {
+		selectionPolicy = PolicyArrival
+	} else {
+		selectionPolicy = PolicyFeeRate
+	}
+}
+
+// SelectionPolicy returns the active transaction selection policy.
+func SelectionPolicy() string {
+	policyMtx.RLock()
+	defer policyMtx.RUnlock()
+	return selectionPolicy
+}
+
+// feeRate returns the fee paid per weight unit, the quantity the fee-rate
+// policy maximizes.
+func feeRate(txDesc *protocol.TxDesc) float64 {
+	if txDesc.Weight == 0 {
+		return 0
+	}
+	return float64(txDesc.Fee) / float64(txDesc.Weight)
+}
+
+// orderTxDescs returns the mempool transactions in the order the active
+// policy wants them included. Under the fee-rate policy a transaction
+// spending the output of another pooled transaction is always placed
+// after its parent, so templates stay valid.
+func orderTxDescs(txDescs []*protocol.TxDesc) []*protocol.TxDesc {
+	if SelectionPolicy() == PolicyArrival {
+		ordered := make([]*protocol.TxDesc, len(txDescs))
+		copy(ordered, txDescs)
+		sort.Slice(ordered, func(i, j int) bool {
+			return ordered[i].Added.Before(ordered[j].Added)
+		})
+		return ordered
+	}
+
+	// Map every output created inside the pool to its producing
+	// transaction so in-pool dependencies can be honored.
+	producers := make(map[bc.Hash]int, len(txDescs))
+	for i, txDesc := range txDescs {
+		for _, resultID := range txDesc.Tx.Tx.ResultIds {
+			producers[*resultID] = i
+		}
+	}
+
+	deps := make([][]int, len(txDescs))
+	blocked := make([]int, len(txDescs))
+	for i, txDesc := range txDescs {
+		for _, spentID := range txDesc.Tx.Tx.SpentOutputIDs {
+			if parent, ok := producers[spentID]; ok && parent != i {
+				deps[parent] = append(deps[parent], i)
+				blocked[i]++
+			}
+		}
+	}
+
+	// Repeatedly pick the best-paying transaction whose in-pool parents
+	// were all picked already.
+	ordered := make([]*protocol.TxDesc, 0, len(txDescs))
+	picked := make([]bool, len(txDescs))
+	for len(ordered) < len(txDescs) {
+		best := -1
+		for i := range txDescs {
+			if picked[i] || blocked[i] > 0 {
+				continue
+			}
+			if best < 0 || feeRate(txDescs[i]) > feeRate(txDescs[best]) {
+				best = i
+			}
+		}
+		if best < 0 {
+			// Only dependency cycles remain; they can never validate.
+			break
+		}
+
+		picked[best] = true
+		ordered = append(ordered, txDescs[best])
+		for _, child := range deps[best] {
+			blocked[child]--
+		}
+	}
+	return ordered
+}
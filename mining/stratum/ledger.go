@@ -0,0 +1,105 @@
+package stratum
+
+import (
+	"sync"
+
+	"github.com/bytom/errors"
+)
+
+// number of most recent shares a PPLNS payout is computed over.
+const defaultPplnsWindow = 8192
+
+// ErrBadScheme is returned when a payout is requested with an unknown
+// scheme name.
+var ErrBadScheme = errors.New("unknown payout scheme")
+
+// share is one accepted share, weighted by its difficulty so workers on
+// different vardiff levels are compared fairly.
+type share struct {
+	worker string
+	weight float64
+}
+
+// shareLedger keeps enough recent share history to split a reward among
+// workers: a ring of the last N shares for PPLNS, and the shares of the
+// current round (since the last found block) for proportional payouts.
+type shareLedger struct {
+	mtx         sync.Mutex
+	shares      []share
+	next        int
+	filled      bool
+	roundShares map[string]float64
+}
+
+func newShareLedger() *shareLedger {
+	return &shareLedger{
+		shares:      make([]share, defaultPplnsWindow),
+		roundShares: make(map[string]float64),
+	}
+}
+
+// record adds one accepted share. The weight is the share difficulty:
+// a lower multiplier means a harder, more valuable share.
+func (l *shareLedger) record(worker string, multiplier uint64) {
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	weight := 1 / float64(multiplier)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.shares[l.next] = share{worker: worker, weight: weight}
+	l.next++
+	if l.next == len(l.shares) {
+		l.next = 0
+		l.filled = true
+	}
+	l.roundShares[worker] += weight
+}
+
+// startRound resets the proportional counters after a block is found.
+// The PPLNS window deliberately survives round boundaries.
+func (l *shareLedger) startRound() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.roundShares = make(map[string]float64)
+}
+
+// payouts returns each worker's fraction of the reward under the given
+// scheme ("pplns" or "proportional"). The fractions sum to 1 unless no
+// shares were recorded, in which case the map is empty.
+func (l *shareLedger) payouts(scheme string) (map[string]float64, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	weights := make(map[string]float64)
+	switch scheme {
+	case "", "pplns":
+		count := l.next
+		if l.filled {
+			count = len(l.shares)
+		}
+		for i := 0; i < count; i++ {
+			weights[l.shares[i].worker] += l.shares[i].weight
+		}
+	case "proportional", "prop":
+		for worker, weight := range l.roundShares {
+			weights[worker] = weight
+		}
+	default:
+		return nil, errors.WithDetailf(ErrBadScheme, "scheme %q", scheme)
+	}
+
+	total := float64(0)
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		return map[string]float64{}, nil
+	}
+	for worker := range weights {
+		weights[worker] /= total
+	}
+	return weights, nil
+}
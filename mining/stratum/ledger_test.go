@@ -0,0 +1,106 @@
+package stratum
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bytom/errors"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLedgerPplnsPayouts(t *testing.T) {
+	ledger := newShareLedger()
+	// two full-weight shares for a, one half-weight share for b
+	ledger.record("a", 1)
+	ledger.record("a", 1)
+	ledger.record("b", 2)
+
+	for _, scheme := range []string{"", "pplns"} {
+		payouts, err := ledger.payouts(scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !almostEqual(payouts["a"], 0.8) || !almostEqual(payouts["b"], 0.2) {
+			t.Errorf("scheme %q: got a=%v b=%v, want a=0.8 b=0.2", scheme, payouts["a"], payouts["b"])
+		}
+	}
+}
+
+func TestLedgerProportionalRound(t *testing.T) {
+	ledger := newShareLedger()
+	ledger.record("a", 1)
+	ledger.record("b", 1)
+	ledger.startRound()
+	ledger.record("b", 1)
+
+	// the proportional round only sees shares since the found block
+	payouts, err := ledger.payouts("proportional")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(payouts["b"], 1) || payouts["a"] != 0 {
+		t.Errorf("proportional: got a=%v b=%v, want a=0 b=1", payouts["a"], payouts["b"])
+	}
+
+	// the PPLNS window deliberately survives the round boundary
+	payouts, err = ledger.payouts("pplns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(payouts["a"], 1.0/3) || !almostEqual(payouts["b"], 2.0/3) {
+		t.Errorf("pplns: got a=%v b=%v, want a=1/3 b=2/3", payouts["a"], payouts["b"])
+	}
+}
+
+func TestLedgerWindowDropsOldest(t *testing.T) {
+	ledger := &shareLedger{
+		shares:      make([]share, 4),
+		roundShares: make(map[string]float64),
+	}
+	for i := 0; i < 4; i++ {
+		ledger.record("a", 1)
+	}
+	ledger.record("b", 1)
+	ledger.record("b", 1)
+
+	// the ring now holds the last four shares: two of a, two of b
+	payouts, err := ledger.payouts("pplns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(payouts["a"], 0.5) || !almostEqual(payouts["b"], 0.5) {
+		t.Errorf("got a=%v b=%v, want a=0.5 b=0.5", payouts["a"], payouts["b"])
+	}
+}
+
+func TestLedgerZeroMultiplier(t *testing.T) {
+	ledger := newShareLedger()
+	ledger.record("a", 0)
+	ledger.record("b", 1)
+
+	payouts, err := ledger.payouts("pplns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(payouts["a"], 0.5) || !almostEqual(payouts["b"], 0.5) {
+		t.Errorf("got a=%v b=%v, want a=0.5 b=0.5", payouts["a"], payouts["b"])
+	}
+}
+
+func TestLedgerEmptyAndBadScheme(t *testing.T) {
+	ledger := newShareLedger()
+	payouts, err := ledger.payouts("pplns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payouts) != 0 {
+		t.Errorf("empty ledger paid out %v", payouts)
+	}
+
+	if _, err := ledger.payouts("slush"); errors.Root(err) != ErrBadScheme {
+		t.Errorf("got error %v, want ErrBadScheme", err)
+	}
+}
@@ -0,0 +1,542 @@
+package stratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/blockchain/account"
+	"github.com/bytom/consensus"
+	"github.com/bytom/consensus/algorithm"
+	"github.com/bytom/errors"
+	"github.com/bytom/mining"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+const (
+	// how often the current job is refreshed against the mempool even
+	// when no new block arrives.
+	jobRefreshInterval = 30 * time.Second
+	// per-connection share difficulty adjustment window.
+	vardiffInterval = time.Minute
+	// shares per vardiff window the server steers every connection to.
+	vardiffTargetShares = 6
+	// starting share difficulty: the block target multiplied by this.
+	defaultShareMultiplier = 65536
+	maxShareMultiplier     = uint64(1) << 40
+)
+
+// WorkerStats accumulates the shares one authorized worker submitted.
+type WorkerStats struct {
+	Accepted  uint64    `json:"accepted"`
+	Rejected  uint64    `json:"rejected"`
+	LastShare time.Time `json:"last_share"`
+}
+
+// job is one unit of work handed to the miners: a full block template
+// whose nonce the miners iterate.
+type job struct {
+	id    string
+	block *legacy.Block
+}
+
+// request/response follow the line-based stratum JSON framing.
+type request struct {
+	ID     *uint64  `json:"id"`
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
+type response struct {
+	ID     *uint64     `json:"id"`
+	Method string      `json:"method,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+	Params interface{} `json:"params,omitempty"`
+	Error  *string     `json:"error,omitempty"`
+}
+
+// AuthFunc validates a worker's credentials. A nil error authorizes
+// the worker.
+type AuthFunc func(worker, secret string) error
+
+// conn is one miner connection with its own share difficulty.
+type conn struct {
+	id     uint64
+	nc     net.Conn
+	enc    *json.Encoder
+	encMtx sync.Mutex
+
+	mtx             sync.Mutex
+	worker          string
+	authorized      bool
+	shareMultiplier uint64
+	sharesInWindow  uint64
+	windowStart     time.Time
+}
+
+// Server distributes block templates to stratum miners, tracks their
+// shares and submits any full solution to the chain.
+type Server struct {
+	sync.Mutex
+	chain          *protocol.Chain
+	accountManager *account.Manager
+	txPool         *protocol.TxPool
+	addr           string
+
+	started  bool
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	listener net.Listener
+
+	connMtx    sync.Mutex
+	conns      map[uint64]*conn
+	nextConnID uint64
+
+	jobMtx  sync.RWMutex
+	curJob  *job
+	nextJob uint64
+
+	statsMtx    sync.Mutex
+	workers     map[string]*WorkerStats
+	blocksFound uint64
+
+	ledger *shareLedger
+	auth   AuthFunc
+}
+
+// NewServer returns a stratum server listening on addr once started.
+func NewServer(chain *protocol.Chain, accountManager *account.Manager, txPool *protocol.TxPool, addr string) *Server {
+	return &Server{
+		chain:          chain,
+		accountManager: accountManager,
+		txPool:         txPool,
+		addr:           addr,
+		conns:          make(map[uint64]*conn),
+		workers:        make(map[string]*WorkerStats),
+		ledger:         newShareLedger(),
+	}
+}
+
+// SetAuth makes the server require credentials on mining.authorize and
+// refuse shares from unauthorized connections. Must be called before
+// Start.
+func (s *Server) SetAuth(auth AuthFunc) {
+	s.auth = auth
+}
+
+// Start begins listening for miner connections.
+func (s *Server) Start() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.started {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.quit = make(chan struct{})
+
+	s.wg.Add(2)
+	go s.acceptLoop()
+	go s.notifyLoop()
+
+	s.started = true
+	log.WithField("addr", s.addr).Info("Stratum server started")
+	return nil
+}
+
+// Stop closes the listener and every miner connection.
+func (s *Server) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	if !s.started {
+		return
+	}
+
+	close(s.quit)
+	s.listener.Close()
+	s.connMtx.Lock()
+	for _, c := range s.conns {
+		c.nc.Close()
+	}
+	s.connMtx.Unlock()
+	s.wg.Wait()
+	s.started = false
+	log.Info("Stratum server stopped")
+}
+
+// Workers returns a snapshot of the per-worker share counters.
+func (s *Server) Workers() map[string]*WorkerStats {
+	s.statsMtx.Lock()
+	defer s.statsMtx.Unlock()
+
+	workers := make(map[string]*WorkerStats, len(s.workers))
+	for name, stats := range s.workers {
+		statsCopy := *stats
+		workers[name] = &statsCopy
+	}
+	return workers
+}
+
+// Payouts splits a reward among the workers according to their recorded
+// shares: each worker name maps to its fraction of the reward.
+func (s *Server) Payouts(scheme string) (map[string]float64, error) {
+	return s.ledger.payouts(scheme)
+}
+
+// BlocksFound returns how many blocks stratum miners connected to the
+// chain since the process started.
+func (s *Server) BlocksFound() uint64 {
+	s.statsMtx.Lock()
+	defer s.statsMtx.Unlock()
+	return s.blocksFound
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+				log.WithField("error", err).Error("Stratum accept failed")
+				continue
+			}
+		}
+
+		s.connMtx.Lock()
+		s.nextConnID++
+		c := &conn{
+			id:              s.nextConnID,
+			nc:              nc,
+			enc:             json.NewEncoder(nc),
+			shareMultiplier: defaultShareMultiplier,
+			windowStart:     time.Now(),
+		}
+		s.conns[c.id] = c
+		s.connMtx.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(c)
+	}
+}
+
+// notifyLoop rebuilds the block template whenever the chain advances
+// (and periodically in between) and pushes it to every connection.
+func (s *Server) notifyLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(jobRefreshInterval)
+	defer ticker.Stop()
+
+	blockWaiter := s.chain.BlockWaiter(s.chain.Height() + 1)
+	for {
+		clean := false
+		select {
+		case <-blockWaiter:
+			blockWaiter = s.chain.BlockWaiter(s.chain.Height() + 1)
+			clean = true
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+
+		if err := s.refreshJob(); err != nil {
+			log.WithField("error", err).Error("Stratum fail on create block template")
+			continue
+		}
+		s.broadcastJob(clean)
+	}
+}
+
+func (s *Server) refreshJob() error {
+	block, err := mining.NewBlockTemplate(s.chain, s.txPool, s.accountManager)
+	if err != nil {
+		return err
+	}
+
+	s.jobMtx.Lock()
+	s.nextJob++
+	s.curJob = &job{id: strconv.FormatUint(s.nextJob, 16), block: block}
+	s.jobMtx.Unlock()
+	return nil
+}
+
+func (s *Server) currentJob() *job {
+	s.jobMtx.RLock()
+	defer s.jobMtx.RUnlock()
+	return s.curJob
+}
+
+func (s *Server) broadcastJob(clean bool) {
+	j := s.currentJob()
+	if j == nil {
+		return
+	}
+
+	s.connMtx.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for _, c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.connMtx.Unlock()
+
+	for _, c := range conns {
+		if err := c.notify(j, clean); err != nil {
+			log.WithField("error", err).Debug("Stratum fail on notify miner")
+		}
+	}
+}
+
+func (s *Server) serveConn(c *conn) {
+	defer s.wg.Done()
+	defer func() {
+		c.nc.Close()
+		s.connMtx.Lock()
+		delete(s.conns, c.id)
+		s.connMtx.Unlock()
+	}()
+
+	scanner := bufio.NewScanner(c.nc)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for scanner.Scan() {
+		req := &request{}
+		if err := json.Unmarshal(scanner.Bytes(), req); err != nil {
+			c.reply(nil, nil, errors.New("parse error"))
+			continue
+		}
+
+		switch req.Method {
+		case "mining.subscribe":
+			c.reply(req.ID, []string{strconv.FormatUint(c.id, 16)}, nil)
+			c.setDifficulty(c.multiplier())
+			if j := s.currentJob(); j != nil {
+				c.notify(j, true)
+			}
+
+		case "mining.authorize":
+			if len(req.Params) < 1 || req.Params[0] == "" {
+				c.reply(req.ID, false, errors.New("missing worker name"))
+				continue
+			}
+			if s.auth != nil {
+				secret := ""
+				if len(req.Params) > 1 {
+					secret = req.Params[1]
+				}
+				if err := s.auth(req.Params[0], secret); err != nil {
+					log.WithFields(log.Fields{
+						"worker": req.Params[0],
+						"error":  err,
+					}).Warn("Stratum refused worker credentials")
+					c.reply(req.ID, false, err)
+					continue
+				}
+			}
+			c.mtx.Lock()
+			c.worker = req.Params[0]
+			c.authorized = true
+			c.mtx.Unlock()
+			c.reply(req.ID, true, nil)
+
+		case "mining.submit":
+			if s.auth != nil && !c.isAuthorized() {
+				c.reply(req.ID, false, errors.New("unauthorized worker"))
+				continue
+			}
+			if err := s.handleSubmit(c, req); err != nil {
+				s.recordShare(c, false)
+				c.reply(req.ID, false, err)
+				continue
+			}
+			s.recordShare(c, true)
+			c.reply(req.ID, true, nil)
+
+		default:
+			c.reply(req.ID, nil, errors.New("unknown method"))
+		}
+	}
+}
+
+// handleSubmit checks a submitted nonce against the share target and,
+// when it also meets the block target, processes the solved block.
+func (s *Server) handleSubmit(c *conn, req *request) error {
+	if len(req.Params) < 3 {
+		return errors.New("wrong number of params")
+	}
+	jobID, nonceHex := req.Params[1], req.Params[2]
+
+	j := s.currentJob()
+	if j == nil || j.id != jobID {
+		return errors.New("stale job")
+	}
+	nonce, err := strconv.ParseUint(nonceHex, 16, 64)
+	if err != nil {
+		return errors.New("malformed nonce")
+	}
+
+	block := *j.block
+	header := block.BlockHeader
+	header.Nonce = nonce
+	block.BlockHeader = header
+
+	seedCache, err := s.chain.SeedCaches().Get(&header.Seed)
+	if err != nil {
+		return err
+	}
+	headerHash := header.Hash()
+	proofHash, err := algorithm.AIHash(header.Height, &headerHash, seedCache)
+	if err != nil {
+		return err
+	}
+
+	if !meetsTarget(proofHash, header.Bits, c.multiplier()) {
+		return errors.New("low difficulty share")
+	}
+
+	if consensus.CheckProofOfWork(proofHash, header.Bits) {
+		if isOrphan, err := s.chain.ProcessBlock(&block); err == nil {
+			s.statsMtx.Lock()
+			s.blocksFound++
+			s.statsMtx.Unlock()
+			s.ledger.startRound()
+			log.WithFields(log.Fields{
+				"height":   header.Height,
+				"isOrphan": isOrphan,
+				"worker":   c.workerName(),
+			}).Info("Stratum miner solved block")
+		} else {
+			log.WithField("height", header.Height).Errorf("Stratum fail on ProcessBlock %v", err)
+		}
+	}
+	return nil
+}
+
+// recordShare updates the worker counters and retunes the connection
+// difficulty once per vardiff window.
+func (s *Server) recordShare(c *conn, accepted bool) {
+	worker := c.workerName()
+	if worker == "" {
+		worker = "anonymous." + strconv.FormatUint(c.id, 16)
+	}
+
+	s.statsMtx.Lock()
+	stats, ok := s.workers[worker]
+	if !ok {
+		stats = &WorkerStats{}
+		s.workers[worker] = stats
+	}
+	if accepted {
+		stats.Accepted++
+	} else {
+		stats.Rejected++
+	}
+	stats.LastShare = time.Now()
+	s.statsMtx.Unlock()
+
+	if accepted {
+		s.ledger.record(worker, c.multiplier())
+	}
+
+	if newMultiplier, changed := c.retune(accepted); changed {
+		c.setDifficulty(newMultiplier)
+	}
+}
+
+func (c *conn) workerName() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.worker
+}
+
+func (c *conn) isAuthorized() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.authorized
+}
+
+func (c *conn) multiplier() uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.shareMultiplier
+}
+
+// retune doubles or halves the share multiplier when the connection
+// submits far more or far fewer shares than the vardiff target.
+func (c *conn) retune(accepted bool) (uint64, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if accepted {
+		c.sharesInWindow++
+	}
+	if time.Since(c.windowStart) < vardiffInterval {
+		return 0, false
+	}
+
+	shares := c.sharesInWindow
+	c.sharesInWindow = 0
+	c.windowStart = time.Now()
+
+	switch {
+	case shares > vardiffTargetShares*2 && c.shareMultiplier > 1:
+		c.shareMultiplier /= 2
+	case shares < vardiffTargetShares/2 && c.shareMultiplier < maxShareMultiplier:
+		c.shareMultiplier *= 2
+	default:
+		return 0, false
+	}
+	return c.shareMultiplier, true
+}
+
+func (c *conn) reply(id *uint64, result interface{}, err error) {
+	resp := &response{ID: id, Result: result}
+	if err != nil {
+		msg := err.Error()
+		resp.Error = &msg
+	}
+	c.send(resp)
+}
+
+func (c *conn) setDifficulty(multiplier uint64) {
+	c.send(&response{Method: "mining.set_difficulty", Params: []uint64{multiplier}})
+}
+
+// notify pushes the current job: its ID, the raw block header the miner
+// iterates the nonce of, and whether previous work must be dropped.
+func (c *conn) notify(j *job, clean bool) error {
+	rawHeader, err := j.block.BlockHeader.MarshalText()
+	if err != nil {
+		return err
+	}
+	return c.send(&response{
+		Method: "mining.notify",
+		Params: []interface{}{j.id, string(rawHeader), clean},
+	})
+}
+
+func (c *conn) send(resp *response) error {
+	c.encMtx.Lock()
+	defer c.encMtx.Unlock()
+	c.nc.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.enc.Encode(resp)
+}
+
+// meetsTarget reports whether the proof hash satisfies the share target:
+// the block target relaxed by the connection's share multiplier.
+func meetsTarget(proofHash *bc.Hash, bits uint64, multiplier uint64) bool {
+	target := new(big.Int).Mul(consensus.CompactToBig(bits), new(big.Int).SetUint64(multiplier))
+	return consensus.HashToBig(proofHash).Cmp(target) <= 0
+}
@@ -0,0 +1,189 @@
+package stratum
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bytom/errors"
+)
+
+func newTestConn() *conn {
+	return &conn{
+		id:              1,
+		shareMultiplier: defaultShareMultiplier,
+		windowStart:     time.Now(),
+	}
+}
+
+func TestRetuneHalvesOnFastShares(t *testing.T) {
+	c := newTestConn()
+	for i := 0; i < vardiffTargetShares*2; i++ {
+		if _, changed := c.retune(true); changed {
+			t.Fatal("retuned before the vardiff window elapsed")
+		}
+	}
+
+	c.windowStart = time.Now().Add(-2 * vardiffInterval)
+	multiplier, changed := c.retune(true)
+	if !changed || multiplier != defaultShareMultiplier/2 {
+		t.Errorf("got multiplier %d changed %v, want %d true", multiplier, changed, defaultShareMultiplier/2)
+	}
+	if c.sharesInWindow != 0 {
+		t.Error("share counter not reset with the window")
+	}
+}
+
+func TestRetuneDoublesOnSlowShares(t *testing.T) {
+	c := newTestConn()
+	c.windowStart = time.Now().Add(-2 * vardiffInterval)
+	multiplier, changed := c.retune(false)
+	if !changed || multiplier != defaultShareMultiplier*2 {
+		t.Errorf("got multiplier %d changed %v, want %d true", multiplier, changed, defaultShareMultiplier*2)
+	}
+}
+
+func TestRetuneRespectsBounds(t *testing.T) {
+	c := newTestConn()
+	c.shareMultiplier = 1
+	for i := 0; i < vardiffTargetShares*2; i++ {
+		c.retune(true)
+	}
+	c.windowStart = time.Now().Add(-2 * vardiffInterval)
+	if _, changed := c.retune(true); changed || c.shareMultiplier != 1 {
+		t.Errorf("multiplier dropped below 1: %d", c.shareMultiplier)
+	}
+
+	c = newTestConn()
+	c.shareMultiplier = maxShareMultiplier
+	c.windowStart = time.Now().Add(-2 * vardiffInterval)
+	if _, changed := c.retune(false); changed || c.shareMultiplier != maxShareMultiplier {
+		t.Errorf("multiplier rose above the cap: %d", c.shareMultiplier)
+	}
+}
+
+func TestRecordShareStats(t *testing.T) {
+	s := NewServer(nil, nil, nil, "")
+
+	c := newTestConn()
+	c.worker = "alice"
+	s.recordShare(c, true)
+	s.recordShare(c, true)
+	s.recordShare(c, false)
+
+	// an unauthorized connection is tracked under a synthetic name
+	anon := newTestConn()
+	anon.id = 7
+	s.recordShare(anon, true)
+
+	workers := s.Workers()
+	if stats := workers["alice"]; stats == nil || stats.Accepted != 2 || stats.Rejected != 1 {
+		t.Errorf("alice stats = %+v, want 2 accepted 1 rejected", stats)
+	}
+	if stats := workers["anonymous.7"]; stats == nil || stats.Accepted != 1 {
+		t.Errorf("anonymous.7 stats = %+v, want 1 accepted", stats)
+	}
+
+	// only accepted shares reach the ledger
+	payouts, err := s.Payouts("pplns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !almostEqual(payouts["alice"], 2.0/3) || !almostEqual(payouts["anonymous.7"], 1.0/3) {
+		t.Errorf("payouts = %v, want alice=2/3 anonymous.7=1/3", payouts)
+	}
+}
+
+func TestHandleSubmitRejectsBadShares(t *testing.T) {
+	s := NewServer(nil, nil, nil, "")
+	s.curJob = &job{id: "a"}
+	c := newTestConn()
+
+	cases := []struct {
+		params []string
+		want   string
+	}{
+		{[]string{"worker", "a"}, "wrong number of params"},
+		{[]string{"worker", "b", "0"}, "stale job"},
+		{[]string{"worker", "a", "nonhex"}, "malformed nonce"},
+	}
+	for _, tc := range cases {
+		err := s.handleSubmit(c, &request{Params: tc.params})
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("params %v: got error %v, want %q", tc.params, err, tc.want)
+		}
+	}
+}
+
+// TestServeConnProtocol drives one connection through the framing:
+// subscribe, a refused and an accepted authorize, and submits both
+// before and after authorization.
+func TestServeConnProtocol(t *testing.T) {
+	s := NewServer(nil, nil, nil, "")
+	s.SetAuth(func(worker, secret string) error {
+		if secret != "good" {
+			return errors.New("bad credentials")
+		}
+		return nil
+	})
+
+	server, client := net.Pipe()
+	defer client.Close()
+	c := newTestConn()
+	c.nc = server
+	c.enc = json.NewEncoder(server)
+	s.wg.Add(1)
+	go s.serveConn(c)
+
+	enc := json.NewEncoder(client)
+	dec := json.NewDecoder(client)
+	id := uint64(0)
+	call := func(method string, params ...string) *response {
+		id++
+		reqID := id
+		if err := enc.Encode(&request{ID: &reqID, Method: method, Params: params}); err != nil {
+			t.Fatal(err)
+		}
+		resp := &response{}
+		if err := dec.Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := call("mining.subscribe")
+	if resp.Error != nil || resp.ID == nil || *resp.ID != 1 {
+		t.Fatalf("subscribe reply = %+v", resp)
+	}
+	// subscribing pushes the connection's share difficulty
+	notif := &response{}
+	if err := dec.Decode(notif); err != nil {
+		t.Fatal(err)
+	}
+	if notif.Method != "mining.set_difficulty" {
+		t.Fatalf("got notification %+v, want mining.set_difficulty", notif)
+	}
+
+	if resp = call("mining.submit", "alice", "a", "0"); resp.Error == nil || *resp.Error != "unauthorized worker" {
+		t.Fatalf("unauthorized submit reply = %+v", resp)
+	}
+	if resp = call("mining.authorize", "alice", "wrong"); resp.Error == nil || *resp.Error != "bad credentials" {
+		t.Fatalf("bad authorize reply = %+v", resp)
+	}
+	if resp = call("mining.authorize", "alice", "good"); resp.Error != nil || resp.Result != true {
+		t.Fatalf("authorize reply = %+v", resp)
+	}
+
+	// no current job, so an authorized submit is a stale rejected share
+	if resp = call("mining.submit", "alice", "a", "0"); resp.Error == nil || *resp.Error != "stale job" {
+		t.Fatalf("stale submit reply = %+v", resp)
+	}
+	if stats := s.Workers()["alice"]; stats == nil || stats.Rejected != 1 {
+		t.Errorf("alice stats = %+v, want 1 rejected", stats)
+	}
+
+	if resp = call("mining.frobnicate"); resp.Error == nil || *resp.Error != "unknown method" {
+		t.Fatalf("unknown method reply = %+v", resp)
+	}
+}
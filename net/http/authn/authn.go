@@ -16,8 +16,6 @@ import (
 
 const tokenExpiry = time.Minute * 5
 
-var loopbackOn = true
-
 var (
 	//ErrInvalidToken is returned when authenticate is called with invalide token.
 	ErrInvalidToken = errors.New("invalid token")
@@ -31,6 +29,11 @@ type API struct {
 	crosscoreRPCPrefix string
 	rootCAs            *x509.CertPool
 
+	// loopback accepts requests from loopback addresses without
+	// credentials; the secure default is off, with the bootstrap
+	// token generated on first start as the way in.
+	loopback bool
+
 	tokenMu  sync.Mutex // protects the following
 	tokenMap map[string]tokenResult
 }
@@ -41,9 +44,10 @@ type tokenResult struct {
 }
 
 //NewAPI create a token authenticate object.
-func NewAPI(tokens *accesstoken.CredentialStore) *API {
+func NewAPI(tokens *accesstoken.CredentialStore, allowLoopback bool) *API {
 	return &API{
 		tokens:   tokens,
+		loopback: allowLoopback,
 		tokenMap: make(map[string]tokenResult),
 	}
 }
@@ -63,11 +67,10 @@ func (a *API) Authenticate(req *http.Request) (*http.Request, error) {
 		ctx = newContextWithLocalhost(ctx)
 	}
 	// Temporary workaround. Dashboard is always ok.
-	// See loopbackOn comment above.
 	if strings.HasPrefix(req.URL.Path, "/dashboard/") || req.URL.Path == "/dashboard" {
 		return req.WithContext(ctx), nil
 	}
-	if loopbackOn && local {
+	if a.loopback && local {
 		return req.WithContext(ctx), nil
 	}
 
@@ -33,7 +33,7 @@ func TestAuthenticate(t *testing.T) {
 		{"alice", "alice:abcsdsdfassdfsefsfsfesfesfefsefa", ErrInvalidToken},
 	}
 
-	api := NewAPI(accessTokens)
+	api := NewAPI(accessTokens, false)
 
 	for _, c := range cases {
 		var username, password string
@@ -31,6 +31,9 @@ type Formatter struct {
 	Default     Info
 	IsTemporary func(info Info, err error) bool
 	Errors      map[error]Info
+	// Translations maps a locale (e.g. "zh-CN") to a catalog of
+	// messages keyed by chain code; see FormatLocalized.
+	Translations map[string]map[string]string
 }
 
 // Format builds an error Response body describing err by consulting
@@ -60,10 +63,12 @@ func (f Formatter) Format(err error) (body Response) {
 }
 
 // Write writes a json encoded Response to the ResponseWriter.
-// It uses the status code associated with the error.
+// It uses the status code associated with the error, and localizes the
+// message when the request carried an Accept-Language the catalogs
+// cover.
 //
 // Write may be used as an ErrorWriter in the httpjson package.
 func (f Formatter) Write(ctx context.Context, w http.ResponseWriter, err error) {
-	resp := f.Format(err)
+	resp := f.FormatLocalized(ctx, err)
 	httpjson.Write(ctx, w, resp.HTTPStatus, resp)
 }
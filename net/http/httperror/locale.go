@@ -0,0 +1,64 @@
+package httperror
+
+import (
+	"context"
+	"strings"
+)
+
+// key is an unexported context key type, so only this package can set
+// the Accept-Language value.
+type key int
+
+const acceptLanguageKey key = iota
+
+// WithAcceptLanguage stores the request's Accept-Language header on the
+// context, so error responses written later in the request can be
+// localized.
+func WithAcceptLanguage(ctx context.Context, header string) context.Context {
+	if header == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, acceptLanguageKey, header)
+}
+
+func acceptLanguage(ctx context.Context) string {
+	header, _ := ctx.Value(acceptLanguageKey).(string)
+	return header
+}
+
+// negotiate picks the first locale from the Accept-Language header that
+// f.Translations covers, matching case-insensitively and falling back
+// from a full tag to its primary subtag (zh matches zh-CN). An empty
+// result means the default (English) messages.
+func (f Formatter) negotiate(header string) string {
+	if header == "" || len(f.Translations) == 0 {
+		return ""
+	}
+	for _, part := range strings.Split(header, ",") {
+		// strip the quality value; header order already ranks the tags
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		for locale := range f.Translations {
+			if strings.EqualFold(tag, locale) || strings.EqualFold(tag, strings.SplitN(locale, "-", 2)[0]) {
+				return locale
+			}
+		}
+	}
+	return ""
+}
+
+// FormatLocalized builds an error Response like Format, translating the
+// message into the language negotiated from the request context. The
+// chain code and detail are never translated: codes are the stable
+// contract, and details interpolate runtime values.
+func (f Formatter) FormatLocalized(ctx context.Context, err error) Response {
+	body := f.Format(err)
+	if locale := f.negotiate(acceptLanguage(ctx)); locale != "" {
+		if message, ok := f.Translations[locale][body.ChainCode]; ok {
+			body.Message = message
+		}
+	}
+	return body
+}
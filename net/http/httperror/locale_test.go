@@ -0,0 +1,49 @@
+package httperror
+
+import (
+	"context"
+	"testing"
+)
+
+var localizedFormatter = Formatter{
+	Default:     Info{500, "CH000", "Internal server error"},
+	IsTemporary: func(Info, error) bool { return false },
+	Errors: map[error]Info{
+		errNotFound: {400, "CH002", "Not found"},
+	},
+	Translations: map[string]map[string]string{
+		"zh-CN": {"CH002": "未找到"},
+	},
+}
+
+func TestFormatLocalized(t *testing.T) {
+	cases := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "Not found"},
+		{"en-US,en;q=0.9", "Not found"},
+		{"zh-CN", "未找到"},
+		{"zh-cn", "未找到"},
+		{"zh", "未找到"},
+		{"zh-CN;q=0.8,en;q=0.9", "未找到"}, // header order ranks, q ignored
+		{"fr-FR, zh-CN;q=0.5", "未找到"},
+		{"*", "Not found"},
+	}
+
+	for _, test := range cases {
+		ctx := WithAcceptLanguage(context.Background(), test.acceptLanguage)
+		resp := localizedFormatter.FormatLocalized(ctx, errNotFound)
+		if resp.Message != test.want {
+			t.Errorf("FormatLocalized(%q) message = %q want %q", test.acceptLanguage, resp.Message, test.want)
+		}
+	}
+}
+
+func TestFormatLocalizedMissingCode(t *testing.T) {
+	ctx := WithAcceptLanguage(context.Background(), "zh-CN")
+	resp := localizedFormatter.FormatLocalized(ctx, nil)
+	if resp.Message != "Internal server error" {
+		t.Errorf("untranslated code should keep the default message, got %q", resp.Message)
+	}
+}
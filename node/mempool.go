@@ -0,0 +1,74 @@
+package node
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+const mempoolFile = "mempool.json"
+
+// mempoolPath returns where the unconfirmed pool is persisted between
+// restarts. It lives next to the databases so each network keeps its
+// own pool.
+func (n *Node) mempoolPath() string {
+	return filepath.Join(n.config.DBDir(), mempoolFile)
+}
+
+// saveMempool writes the unconfirmed transactions to disk so a restart
+// doesn't silently drop them.
+func saveMempool(path string, txPool *protocol.TxPool) error {
+	txs := []string{}
+	for _, desc := range txPool.GetTransactions() {
+		raw, err := desc.Tx.MarshalText()
+		if err != nil {
+			continue
+		}
+		txs = append(txs, string(raw))
+	}
+
+	data, err := json.Marshal(txs)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// loadMempool reads the persisted pool back and revalidates every
+// transaction against the current tip; transactions that confirmed or
+// became invalid while the node was down are dropped. The file is
+// removed afterwards so a crash can't replay it twice.
+func loadMempool(path string, chain *protocol.Chain) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		// nothing was saved
+		return
+	}
+
+	txs := []string{}
+	if err := json.Unmarshal(data, &txs); err != nil {
+		log.WithField("error", err).Warn("Ignoring corrupt persisted mempool")
+		os.Remove(path)
+		return
+	}
+
+	restored := 0
+	for _, rawTx := range txs {
+		tx := &legacy.Tx{}
+		if err := tx.UnmarshalText([]byte(rawTx)); err != nil {
+			continue
+		}
+		if err := chain.ValidateTx(tx); err != nil {
+			continue
+		}
+		restored++
+	}
+	log.WithFields(log.Fields{"saved": len(txs), "restored": restored}).Info("Restored mempool from disk")
+	os.Remove(path)
+}
@@ -3,9 +3,15 @@ package node
 import (
 	"context"
 	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -21,16 +27,29 @@ import (
 	"github.com/bytom/blockchain/accesstoken"
 	"github.com/bytom/blockchain/account"
 	"github.com/bytom/blockchain/asset"
+	"github.com/bytom/blockchain/deposit"
+	"github.com/bytom/blockchain/electrum"
+	"github.com/bytom/blockchain/eventsink"
+	"github.com/bytom/blockchain/explorer"
+	"github.com/bytom/blockchain/peg"
 	"github.com/bytom/blockchain/pseudohsm"
+	"github.com/bytom/blockchain/rawpub"
+	"github.com/bytom/blockchain/replication"
 	"github.com/bytom/blockchain/txdb"
 	"github.com/bytom/blockchain/txfeed"
 	w "github.com/bytom/blockchain/wallet"
+	"github.com/bytom/blockchain/webhook"
 	cfg "github.com/bytom/config"
+	"github.com/bytom/consensus"
+	"github.com/bytom/database"
 	"github.com/bytom/env"
 	"github.com/bytom/errors"
+	"github.com/bytom/mining"
+	"github.com/bytom/mining/stratum"
 	"github.com/bytom/net/http/authn"
 	"github.com/bytom/p2p"
 	"github.com/bytom/protocol"
+	"github.com/bytom/testutil/simulator"
 	"github.com/bytom/types"
 	"github.com/bytom/version"
 )
@@ -54,8 +73,15 @@ type Node struct {
 	evsw       types.EventSwitch // pub/sub for services
 	blockStore *txdb.Store
 	bcReactor  *bc.BlockchainReactor
+	chain      *protocol.Chain
+	txPool     *protocol.TxPool
 	accounts   *account.Manager
 	assets     *asset.Registry
+	stratumSrv *stratum.Server // work server for external miners
+
+	// ordered shutdown; see OnStop
+	rpcShutdown func()
+	dbs         map[string]dbm.DB
 }
 
 func NewNodeDefault(config *cfg.Config) *Node {
@@ -87,9 +113,9 @@ func (wh *waitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	wh.h.ServeHTTP(w, req)
 }
 
-func AuthHandler(handler http.Handler, accessTokens *accesstoken.CredentialStore) http.Handler {
+func AuthHandler(handler http.Handler, accessTokens *accesstoken.CredentialStore, allowLoopback bool) http.Handler {
 
-	authenticator := authn.NewAPI(accessTokens)
+	authenticator := authn.NewAPI(accessTokens, allowLoopback)
 
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		// TODO(tessr): check that this path exists; return early if this path isn't legit
@@ -103,7 +129,7 @@ func AuthHandler(handler http.Handler, accessTokens *accesstoken.CredentialStore
 	})
 }
 
-func rpcInit(h *bc.BlockchainReactor, config *cfg.Config, accessTokens *accesstoken.CredentialStore) {
+func rpcInit(h *bc.BlockchainReactor, config *cfg.Config, accessTokens *accesstoken.CredentialStore) (shutdown func()) {
 	// The waitHandler accepts incoming requests, but blocks until its underlying
 	// handler is set, when the second phase is complete.
 	var coreHandler waitHandler
@@ -112,7 +138,7 @@ func rpcInit(h *bc.BlockchainReactor, config *cfg.Config, accessTokens *accessto
 	mux.Handle("/", &coreHandler)
 
 	var handler http.Handler = mux
-	handler = AuthHandler(handler, accessTokens)
+	handler = AuthHandler(handler, accessTokens, config.AuthDisable)
 	handler = RedirectHandler(handler)
 
 	secureheader.DefaultConfig.PermitClearLoopback = true
@@ -140,22 +166,188 @@ func rpcInit(h *bc.BlockchainReactor, config *cfg.Config, accessTokens *accessto
 	// it's blocking and we need to proceed to the rest of the core setup after
 	// we call it.
 	go func() {
-		if err := server.Serve(listener); err != nil {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.WithField("error", errors.Wrap(err, "Serve")).Error("Rpc server")
 		}
 	}()
 	coreHandler.Set(h)
+
+	return func() {
+		// stop accepting new requests; in-flight handlers keep their
+		// connections until they return
+		server.Close()
+	}
+}
+
+// importSnapshot bootstraps an empty store from the state snapshot file
+// named in the config, cross-checking the snapshot block against any
+// checkpoint registered at its height.
+func importSnapshot(store *txdb.Store, config *cfg.Config) error {
+	data, err := ioutil.ReadFile(config.Snapshot)
+	if err != nil {
+		return err
+	}
+	snap := &txdb.Snapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return err
+	}
+
+	for _, checkpoint := range protocol.DefaultCheckpoints(config.ChainID) {
+		if checkpoint.Height == snap.Height && checkpoint.Hash != snap.Hash {
+			return errors.New("snapshot block mismatches registered checkpoint")
+		}
+	}
+
+	if err := store.ImportSnapshot(snap); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"height": snap.Height, "hash": snap.Hash.String()}).Info("Imported state snapshot")
+	return nil
+}
+
+// bootstrapAccessToken seeds an empty credential store with one admin
+// token on the node's first start. The secret is printed to the console
+// and written next to the config with owner-only permissions; only its
+// hash is stored, so a lost secret means deleting the token and
+// restarting. With auth_disable unset this token is the only way into
+// the API from the start.
+func bootstrapAccessToken(ctx context.Context, accessTokens *accesstoken.CredentialStore, config *cfg.Config) {
+	tokens, err := accessTokens.List(ctx)
+	if err != nil || len(tokens) > 0 {
+		return
+	}
+
+	secret, err := accessTokens.Create(ctx, "bootstrap", "client")
+	if err != nil {
+		log.WithField("error", err).Error("Failed to create bootstrap access token")
+		return
+	}
+
+	tokenFile := filepath.Join(config.RootDir, "bootstrap.token")
+	if err := ioutil.WriteFile(tokenFile, []byte(*secret+"\n"), 0600); err != nil {
+		log.WithFields(log.Fields{"path": tokenFile, "error": err}).Error("Failed to write bootstrap access token file")
+		tokenFile = ""
+	}
+
+	fmt.Printf("Generated bootstrap access token, shown only once:\n\n    %s\n\n", *secret)
+	if tokenFile != "" {
+		fmt.Printf("A copy was written to %s; delete it once the secret is stored safely.\n", tokenFile)
+	}
+}
+
+// tokenAuthFunc authenticates stratum workers against the access token
+// store. Workers authorize as "tokenID" or "tokenID.rigName" with the
+// token secret (hex) as password.
+func tokenAuthFunc(accessTokens *accesstoken.CredentialStore) stratum.AuthFunc {
+	return func(worker, secret string) error {
+		id := worker
+		if i := strings.Index(worker, "."); i > 0 {
+			id = worker[:i]
+		}
+		secretBytes, err := hex.DecodeString(secret)
+		if err != nil {
+			return errors.New("malformed token secret")
+		}
+		valid, err := accessTokens.Check(context.Background(), id, secretBytes)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("invalid credentials")
+		}
+		return nil
+	}
+}
+
+// openDB opens one of the node's databases with the configured backend,
+// exiting with a clear message when the backend is unknown.
+// rebuildableDBs hold only data derived from the chain, so one that is
+// corrupt beyond repair can be moved aside and reindexed instead of
+// keeping the node down.
+var rebuildableDBs = map[string]bool{"wallet": true, "txfeeds": true}
+
+func openDB(name, backend, dir string) dbm.DB {
+	db, err := database.NewDB(name, backend, dir)
+	if err != nil && rebuildableDBs[name] {
+		broken := filepath.Join(dir, name+".db")
+		log.WithFields(log.Fields{"database": name, "error": err}).Error("Database is beyond repair; moving it aside and reindexing from the chain")
+		if renameErr := os.Rename(broken, broken+".corrupt"); renameErr == nil {
+			db, err = database.NewDB(name, backend, dir)
+		}
+	}
+	if err != nil {
+		cmn.Exit(cmn.Fmt("Failed to open %s database: %v", name, err))
+	}
+	if err := database.Migrate(name, db); err != nil {
+		cmn.Exit(cmn.Fmt("Failed to migrate %s database: %v", name, err))
+	}
+	return db
 }
 
 func NewNode(config *cfg.Config) *Node {
 	ctx := context.Background()
 
+	// Install the consensus parameter profile before anything derives
+	// from it
+	consensus.SelectNetParams(config.ChainID)
+	if config.ChainID == "regtest" {
+		if err := cfg.ApplyRegtestGenesis(config.Regtest); err != nil {
+			cmn.Exit(cmn.Fmt("Failed to apply regtest genesis: %v", err))
+		}
+	}
+	if c := config.Consensus; c != nil && *c != (cfg.ConsensusConfig{}) {
+		// overrides fork the node off the stock consensus rules, so
+		// they are confined to private chains
+		switch config.ChainID {
+		case "solo", "solonet", "regtest":
+		default:
+			cmn.Exit(cmn.Fmt("Consensus parameter overrides are for private chains only, not chain %q", config.ChainID))
+		}
+		if c.TargetSecondsPerBlock > 0 {
+			consensus.ActiveNetParams.TargetSecondsPerBlock = c.TargetSecondsPerBlock
+		}
+		if c.BlocksPerRetarget > 0 {
+			consensus.ActiveNetParams.BlocksPerRetarget = c.BlocksPerRetarget
+		}
+		if c.MaxBlockGas > 0 {
+			consensus.ActiveNetParams.MaxBlockGas = c.MaxBlockGas
+		}
+		if c.SubsidyReductionInterval > 0 {
+			consensus.ActiveNetParams.SubsidyReductionInterval = c.SubsidyReductionInterval
+		}
+		if c.BaseSubsidy > 0 {
+			consensus.ActiveNetParams.BaseSubsidy = c.BaseSubsidy
+		}
+		if c.InitialBlockSubsidy > 0 {
+			consensus.ActiveNetParams.InitialBlockSubsidy = c.InitialBlockSubsidy
+		}
+	}
+
+	// Install the engine tuning before the first database opens
+	if d := config.Database; d != nil {
+		database.SetLevelDBOptions(database.LevelDBOptions{
+			BlockCacheMB:       d.BlockCacheSize,
+			WriteBufferMB:      d.WriteBufferSize,
+			MaxOpenFiles:       d.MaxOpenFiles,
+			DisableCompression: d.Compression == "none",
+		})
+	}
+
 	// Get store
-	txDB := dbm.NewDB("txdb", config.DBBackend, config.DBDir())
+	txDB := openDB("txdb", config.ChainDBBackendStr(), config.ChainDBDir())
 	store := txdb.NewStore(txDB)
 
-	tokenDB := dbm.NewDB("accesstoken", config.DBBackend, config.DBDir())
+	if config.Snapshot != "" && store.GetStoreStatus().Height == 0 {
+		if err := importSnapshot(store, config); err != nil {
+			cmn.Exit(cmn.Fmt("Failed to import state snapshot: %v", err))
+		}
+	}
+
+	tokenDB := openDB("accesstoken", config.DBBackend, config.DBDir())
 	accessTokens := accesstoken.NewStore(tokenDB)
+	if !config.AuthDisable {
+		bootstrapAccessToken(ctx, accessTokens, config)
+	}
 
 	privKey := crypto.GenPrivKeyEd25519()
 
@@ -171,10 +363,56 @@ func NewNode(config *cfg.Config) *Node {
 	genesisBlock := cfg.GenerateGenesisBlock()
 
 	txPool := protocol.NewTxPool()
+	txPool.SetMaxPoolBytes(config.MempoolMaxBytes)
 	chain, err := protocol.NewChain(genesisBlock.Hash(), store, txPool)
 	if err != nil {
 		cmn.Exit(cmn.Fmt("Failed to create chain structure: %v", err))
 	}
+	chain.AddCheckpoints(protocol.DefaultCheckpoints(config.ChainID))
+	if len(config.Checkpoints) > 0 {
+		custom := make([]protocol.Checkpoint, 0, len(config.Checkpoints))
+		for _, entry := range config.Checkpoints {
+			checkpoint, err := protocol.ParseCheckpoint(entry)
+			if err != nil {
+				cmn.Exit(cmn.Fmt("Failed to parse checkpoint %q: %v", entry, err))
+			}
+			custom = append(custom, checkpoint)
+		}
+		chain.AddCheckpoints(custom)
+		log.WithField("count", len(custom)).Info("Registered configured checkpoints")
+	}
+	chain.SetFullValidation(config.FullValidation)
+	chain.SetMaxReorgDepth(config.MaxReorgDepth)
+	if config.PruneDepth > 0 {
+		chain.SetPruneDepth(config.PruneDepth)
+		log.WithField("depth", config.PruneDepth).Info("Running in pruned mode; old block bodies will be discarded")
+	}
+	chain.AddDeployments(protocol.DefaultDeployments(config.ChainID))
+	if config.Light {
+		chain.SetHeaderOnly()
+		log.Info("Running as a light (SPV) node; syncing headers only")
+	}
+
+	chain.SetPolicyChecker(relayPolicyFromConfig(config).CheckTx)
+	chain.SetReorgHandler(func(ev *protocol.ReorgEvent) {
+		detached := make([]string, 0, len(ev.DetachedTxs))
+		for _, txID := range ev.DetachedTxs {
+			detached = append(detached, txID.String())
+		}
+		types.FireEventReorg(eventSwitch, types.EventDataReorg{
+			OldTip:      ev.OldTip.String(),
+			NewTip:      ev.NewTip.String(),
+			Depth:       ev.Depth,
+			DetachedTxs: detached,
+		})
+	})
+	chain.SetDoubleSpendHandler(func(ev *protocol.DoubleSpendEvent) {
+		types.FireEventDoubleSpend(eventSwitch, types.EventDataDoubleSpend{
+			TxID:         ev.TxID.String(),
+			ConflictTxID: ev.ConflictTxID.String(),
+			OutputID:     ev.OutputID.String(),
+		})
+	})
 
 	if chain.Height() == 0 {
 		if err := chain.SaveBlock(genesisBlock); err != nil {
@@ -185,12 +423,19 @@ func NewNode(config *cfg.Config) *Node {
 		}
 	}
 
+	if config.Simulate > 0 && chain.Height() < config.Simulate {
+		log.WithField("height", config.Simulate).Info("Generating simulated chain")
+		if err := simulator.Populate(chain, config.Simulate); err != nil {
+			cmn.Exit(cmn.Fmt("Failed to generate simulated chain: %v", err))
+		}
+	}
+
 	var accounts *account.Manager = nil
 	var assets *asset.Registry = nil
 	var wallet *w.Wallet = nil
 	var txFeed *txfeed.Tracker = nil
 
-	txFeedDB := dbm.NewDB("txfeeds", config.DBBackend, config.DBDir())
+	txFeedDB := openDB("txfeeds", config.DBBackend, config.DBDir())
 	txFeed = txfeed.NewTracker(txFeedDB, chain)
 
 	if err = txFeed.Prepare(ctx); err != nil {
@@ -198,14 +443,18 @@ func NewNode(config *cfg.Config) *Node {
 		return nil
 	}
 
-	if config.Wallet.Enable {
+	// a crawler node exists to serve addresses, it never runs a wallet
+	var walletDB dbm.DB
+	if config.Wallet.Enable && !config.Crawler {
 
-		walletDB := dbm.NewDB("wallet", config.DBBackend, config.DBDir())
+		walletDB = openDB("wallet", config.WalletDBBackendStr(), config.WalletDBDir())
 
 		accounts = account.NewManager(walletDB, chain)
 		assets = asset.NewRegistry(walletDB, chain)
 
 		wallet = w.NewWallet(walletDB)
+		wallet.SetCPFilter(accounts.CPFilter())
+		txFeed.SetAccountResolver(accounts.AccountIDByProgram)
 
 		go wallet.WalletUpdate(chain)
 
@@ -227,16 +476,135 @@ func NewNode(config *cfg.Config) *Node {
 		cmn.Exit(cmn.Fmt("initialize HSM failed: %v", err))
 	}
 	bcReactor := bc.NewBlockchainReactor(chain, txPool, accounts, assets, sw, hsm, wallet, txFeed, accessTokens, config.Mining)
+	bcReactor.RegisterSnapshotStore(store)
+	bcReactor.RegisterDatabase("txdb", txDB)
+	bcReactor.RegisterDatabase("accesstoken", tokenDB)
+	bcReactor.RegisterDatabase("txfeeds", txFeedDB)
+	if walletDB != nil {
+		bcReactor.RegisterDatabase("wallet", walletDB)
+	}
+	if f := config.Faucet; f != nil && f.Enable {
+		if config.ChainID == "main" || config.ChainID == "mainnet" {
+			cmn.Exit("The faucet cannot run on the main network")
+		}
+		if f.AccountID == "" {
+			cmn.Exit("The faucet needs faucet.account_id to pay from")
+		}
+		bcReactor.RegisterFaucet(f.AccountID, f.Password, f.Amount, f.Fee)
+		log.WithFields(log.Fields{"account": f.AccountID, "amount": f.Amount}).Info("Faucet enabled")
+	}
+	if config.RawNotifyAddr != "" {
+		pub, err := rawpub.NewPublisher(config.RawNotifyAddr)
+		if err != nil {
+			cmn.Exit(cmn.Fmt("Failed to start raw notification publisher: %v", err))
+		}
+		go pub.FollowChain(chain)
+		bcReactor.RegisterRawPublisher(pub)
+	}
+
+	depositDB := openDB("deposits", config.DBBackend, config.DBDir())
+	depositTracker, err := deposit.NewTracker(depositDB)
+	if err != nil {
+		cmn.Exit(cmn.Fmt("Failed to load deposit watches: %v", err))
+	}
+	go depositTracker.Update(chain)
+	bcReactor.RegisterDepositTracker(depositTracker)
+	bcReactor.RegisterDatabase("deposits", depositDB)
+
+	swapDB := openDB("swaps", config.DBBackend, config.DBDir())
+	bcReactor.RegisterSwapStore(swapDB)
+	bcReactor.RegisterDatabase("swaps", swapDB)
+
+	pegDB := openDB("peg", config.DBBackend, config.DBDir())
+	pegTracker, err := peg.NewTracker(pegDB)
+	if err != nil {
+		cmn.Exit(cmn.Fmt("Failed to load peg federation: %v", err))
+	}
+	go pegTracker.Update(chain)
+	bcReactor.RegisterPegTracker(pegTracker)
+	bcReactor.RegisterDatabase("peg", pegDB)
+
+	webhookDB := openDB("webhooks", config.DBBackend, config.DBDir())
+	hooks, err := webhook.NewManager(webhookDB)
+	if err != nil {
+		cmn.Exit(cmn.Fmt("Failed to load webhook subscriptions: %v", err))
+	}
+	bcReactor.RegisterWebhooks(hooks)
+	bcReactor.RegisterDatabase("webhooks", webhookDB)
+	types.AddListenerForEvent(eventSwitch, "webhook", types.EventStringReorg(), func(data types.TMEventData) {
+		if reorg, ok := data.Unwrap().(types.EventDataReorg); ok {
+			hooks.Notify(webhook.EventReorg, 0, &webhook.ReorgData{
+				OldTip:      reorg.OldTip,
+				NewTip:      reorg.NewTip,
+				Depth:       reorg.Depth,
+				DetachedTxs: reorg.DetachedTxs,
+			})
+		}
+	})
+	// accounts is nil without a wallet; the follower then skips wallet_tx
+	var isLocalProgram func([]byte) bool
+	if accounts != nil {
+		isLocalProgram = accounts.IsLocalControlProgram
+	}
+	go hooks.FollowChain(chain, isLocalProgram)
+
+	var eventSinkDB dbm.DB
+	if config.EventSink != nil && config.EventSink.URL != "" {
+		sink, err := eventsink.NewSink(config.EventSink.URL)
+		if err != nil {
+			cmn.Exit(cmn.Fmt("Failed to build event sink: %v", err))
+		}
+		eventSinkDB = openDB("eventsink", config.DBBackend, config.DBDir())
+		exporter := eventsink.NewExporter(eventSinkDB, sink, config.EventSink.TopicPrefix)
+		go exporter.Run(chain)
+		bcReactor.RegisterDatabase("eventsink", eventSinkDB)
+		log.WithField("url", config.EventSink.URL).Info("Event sink exporter enabled")
+	}
+
+	var explorerDB dbm.DB
+	if config.Explorer != nil && config.Explorer.Enable {
+		explorerDB = openDB("explorer", config.DBBackend, config.DBDir())
+		exp := explorer.NewExplorer(explorerDB)
+		go exp.Update(chain)
+		bcReactor.RegisterExplorer(exp)
+		bcReactor.RegisterDatabase("explorer", explorerDB)
+		log.Info("Explorer indexes enabled")
+
+		if config.ElectrumAddr != "" {
+			srv, err := electrum.NewServer(config.ElectrumAddr, chain, exp)
+			if err != nil {
+				cmn.Exit(cmn.Fmt("Failed to start electrum server: %v", err))
+			}
+			go srv.FollowChain()
+		}
+	}
+	if config.ReplicationListen != "" {
+		if _, err := replication.NewPrimary(config.ReplicationListen, chain); err != nil {
+			cmn.Exit(cmn.Fmt("Failed to start replication listener: %v", err))
+		}
+	}
+	if config.ReplicationPrimary != "" {
+		replica, err := replication.NewReplica(config.ReplicationPrimary, chain)
+		if err != nil {
+			cmn.Exit(cmn.Fmt("Failed to set up replication: %v", err))
+		}
+		go replica.Run()
+	}
+
+	bcReactor.ConfigureMiner(config.MiningWorkers, config.MiningThrottle, config.MiningPauseStale)
+	SetEventSwitch(eventSwitch, bcReactor)
 
 	sw.AddReactor("BLOCKCHAIN", bcReactor)
 
-	rpcInit(bcReactor, config, accessTokens)
+	rpcShutdown := rpcInit(bcReactor, config, accessTokens)
 	// Optionally, start the pex reactor
 	var addrBook *p2p.AddrBook
-	if config.P2P.PexReactor {
+	if config.P2P.PexReactor || config.Crawler {
 		addrBook = p2p.NewAddrBook(config.P2P.AddrBookFile(), config.P2P.AddrBookStrict)
 		pexReactor := p2p.NewPEXReactor(addrBook)
+		pexReactor.SetCrawlerMode(config.Crawler)
 		sw.AddReactor("PEX", pexReactor)
+		sw.SetAddrBook(addrBook)
 	}
 
 	// run the profile server
@@ -249,6 +617,33 @@ func NewNode(config *cfg.Config) *Node {
 		}()
 	}
 
+	if config.CoinbaseProgram != "" {
+		program, err := hex.DecodeString(config.CoinbaseProgram)
+		if err != nil {
+			cmn.Exit(cmn.Fmt("Failed to decode coinbase program: %v", err))
+		}
+		mining.SetCoinbaseProgram(program)
+	}
+	if config.CoinbaseExtraData != "" {
+		mining.SetCoinbaseExtraData([]byte(config.CoinbaseExtraData))
+	}
+	mining.SetSelectionPolicy(config.TxSelectionPolicy)
+	if config.ChainID != "main" {
+		bcReactor.EnableBlockGeneration()
+	}
+	if config.DebugEndpoints {
+		bcReactor.EnableDebugEndpoints()
+	}
+
+	var stratumSrv *stratum.Server
+	if config.Stratum != nil && config.Stratum.Enable {
+		stratumSrv = stratum.NewServer(chain, accounts, txPool, config.Stratum.ListenAddress)
+		if config.Stratum.RequireAuth {
+			stratumSrv.SetAuth(tokenAuthFunc(accessTokens))
+		}
+		bcReactor.RegisterStratumServer(stratumSrv)
+	}
+
 	node := &Node{
 		config: config,
 
@@ -259,10 +654,41 @@ func NewNode(config *cfg.Config) *Node {
 		evsw:       eventSwitch,
 		bcReactor:  bcReactor,
 		blockStore: store,
+		chain:      chain,
+		txPool:     txPool,
 		accounts:   accounts,
 		assets:     assets,
+		stratumSrv: stratumSrv,
+
+		rpcShutdown: rpcShutdown,
+		dbs: map[string]dbm.DB{
+			"txdb":        txDB,
+			"accesstoken": tokenDB,
+			"txfeeds":     txFeedDB,
+		},
+	}
+	if walletDB != nil {
+		node.dbs["wallet"] = walletDB
+	}
+	if explorerDB != nil {
+		node.dbs["explorer"] = explorerDB
+	}
+	node.dbs["deposits"] = depositDB
+	node.dbs["swaps"] = swapDB
+	node.dbs["peg"] = pegDB
+	node.dbs["webhooks"] = webhookDB
+	if eventSinkDB != nil {
+		node.dbs["eventsink"] = eventSinkDB
 	}
 	node.BaseService = *cmn.NewBaseService(nil, "Node", node)
+	// the /stop RPC tears the node down the same way a signal would
+	bcReactor.RegisterShutdown(func() {
+		node.Stop()
+		os.Exit(0)
+	})
+	// the /reload-config RPC and SIGHUP apply the same subset; see reload.go
+	bcReactor.RegisterReload(node.ReloadConfig)
+	node.watchReloadSignal()
 	return node
 }
 
@@ -275,29 +701,62 @@ func (n *Node) OnStart() error {
 	// Start the switch
 	n.sw.SetNodeInfo(n.makeNodeInfo())
 	n.sw.SetNodePrivKey(n.privKey)
+	// we sync full blocks, so only keep outbound peers that serve them
+	n.sw.SetRequiredServices(p2p.SFFullNode)
 	_, err := n.sw.Start()
 	if err != nil {
 		return err
 	}
 
+	// Always reconnect to trusted peers
+	if err := n.sw.DialTrustedPeers(); err != nil {
+		return err
+	}
+
 	// If seeds exist, add them to the address book and dial out
-	if n.config.P2P.Seeds != "" {
+	if !n.config.P2P.ConnectOnly && n.config.P2P.Seeds != "" {
 		// dial out
 		seeds := strings.Split(n.config.P2P.Seeds, ",")
 		if err := n.DialSeeds(seeds); err != nil {
 			return err
 		}
 	}
+
+	// Serve work to external miners
+	if n.stratumSrv != nil {
+		if err := n.stratumSrv.Start(); err != nil {
+			return err
+		}
+	}
+
+	// Refill the pool with the transactions that were pending when the
+	// node last shut down
+	go loadMempool(n.mempoolPath(), n.chain)
 	return nil
 }
 
 func (n *Node) OnStop() {
 	n.BaseService.OnStop()
 
+	// Teardown is ordered: first stop taking new work (miners, RPC),
+	// then the p2p switch and its reactors, then flush the mempool,
+	// and close the databases only once nothing writes to them.
 	log.Info("Stopping Node")
+	if n.stratumSrv != nil {
+		n.stratumSrv.Stop()
+	}
+	if n.rpcShutdown != nil {
+		n.rpcShutdown()
+	}
 	// TODO: gracefully disconnect from peers.
 	n.sw.Stop()
-
+	if err := saveMempool(n.mempoolPath(), n.txPool); err != nil {
+		log.WithField("error", err).Error("Fail to persist mempool")
+	}
+	for name, db := range n.dbs {
+		log.WithField("db", name).Debug("Closing database")
+		db.Close()
+	}
 }
 
 func (n *Node) RunForever() {
@@ -330,11 +789,22 @@ func (n *Node) EventSwitch() types.EventSwitch {
 }
 
 func (n *Node) makeNodeInfo() *p2p.NodeInfo {
+	services := p2p.DefaultServices
+	if n.config.PruneDepth > 0 {
+		// a pruned node cannot serve the historic chain
+		services &^= p2p.SFArchivalNode
+	}
+	if n.config.Light {
+		// a light node keeps headers only and can serve neither full
+		// blocks nor the historic chain
+		services &^= p2p.SFFullNode | p2p.SFArchivalNode
+	}
 	nodeInfo := &p2p.NodeInfo{
-		PubKey:  n.privKey.PubKey().Unwrap().(crypto.PubKeyEd25519),
-		Moniker: n.config.Moniker,
-		Network: "bytom",
-		Version: version.Version,
+		PubKey:   n.privKey.PubKey().Unwrap().(crypto.PubKeyEd25519),
+		Moniker:  n.config.Moniker,
+		Network:  "bytom",
+		Version:  version.Version,
+		Services: services,
 		Other: []string{
 			cmn.Fmt("wire_version=%v", wire.Version),
 			cmn.Fmt("p2p_version=%v", p2p.Version),
@@ -0,0 +1,76 @@
+package node
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	cfg "github.com/bytom/config"
+	"github.com/bytom/errors"
+	"github.com/bytom/logging"
+	"github.com/bytom/policy"
+)
+
+// relayPolicyFromConfig builds the transaction relay policy from the
+// config's [policy] section; unset knobs keep the defaults.
+func relayPolicyFromConfig(config *cfg.Config) *policy.Policy {
+	relayPolicy := policy.Default()
+	if p := config.Policy; p != nil {
+		if p.MaxTxSize > 0 {
+			relayPolicy.MaxTxSize = p.MaxTxSize
+		}
+		if p.MinRelayFeePerKB > 0 {
+			relayPolicy.MinRelayFeePerKB = p.MinRelayFeePerKB
+		}
+		if p.DustThreshold > 0 {
+			relayPolicy.DustThreshold = p.DustThreshold
+		}
+		relayPolicy.RejectNonStandard = p.RejectNonStandard
+	}
+	return relayPolicy
+}
+
+// ReloadConfig re-reads the config file and applies the settings that
+// are safe to change on a running node: log level and format, the
+// mempool size cap and the relay policy. Everything else — listen
+// addresses, database layout, chain selection — still needs a restart.
+// Triggered by SIGHUP or the /reload-config API, so peers stay
+// connected across a policy change.
+func (n *Node) ReloadConfig() error {
+	path := filepath.Join(n.config.RootDir, "config.toml")
+	newConfig, err := cfg.LoadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "reload config")
+	}
+
+	level := newConfig.LogLevel
+	if os.Getenv("BYTOM_DEBUG") != "" {
+		level = "debug"
+	}
+	if err := logging.Init(level, newConfig.LogFormat); err != nil {
+		return err
+	}
+
+	n.txPool.SetMaxPoolBytes(newConfig.MempoolMaxBytes)
+	n.chain.SetPolicyChecker(relayPolicyFromConfig(newConfig).CheckTx)
+
+	log.WithField("file", path).Info("Configuration reloaded")
+	return nil
+}
+
+// watchReloadSignal applies the reloadable config subset whenever the
+// process receives SIGHUP, the conventional daemon reload signal.
+func (n *Node) watchReloadSignal() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := n.ReloadConfig(); err != nil {
+				log.WithField("error", err).Error("Config reload failed")
+			}
+		}
+	}()
+}
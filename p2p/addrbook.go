@@ -70,6 +70,9 @@ const (
 	// NOTE: this must match "maxPexMessageSize"
 	maxGetSelection = 250
 
+	// addresses sampled from a bucket before picking the best one.
+	pickAddressSamples = 3
+
 	// current version of the on-disk format.
 	serializationVersion = 1
 )
@@ -211,32 +214,41 @@ func (a *AddrBook) PickAddress(newBias int) *NetAddress {
 		for len(bucket) == 0 {
 			bucket = a.addrOld[a.rand.Intn(len(a.addrOld))]
 		}
-		// pick a random ka from bucket.
-		randIndex := a.rand.Intn(len(bucket))
-		for _, ka := range bucket {
-			if randIndex == 0 {
-				return ka.Addr
-			}
-			randIndex--
-		}
-		cmn.PanicSanity("Should not happen")
+		return a.pickFromBucket(bucket)
 	} else {
 		// pick random New bucket.
 		var bucket map[string]*knownAddress = nil
 		for len(bucket) == 0 {
 			bucket = a.addrNew[a.rand.Intn(len(a.addrNew))]
 		}
+		return a.pickFromBucket(bucket)
+	}
+}
+
+// Sample up to pickAddressSamples addresses from the bucket and return the one
+// with the best quality, so that addresses that have worked for us before are
+// preferred while selection stays random.
+func (a *AddrBook) pickFromBucket(bucket map[string]*knownAddress) *NetAddress {
+	var best *knownAddress
+	samples := cmn.MinInt(pickAddressSamples, len(bucket))
+	for i := 0; i < samples; i++ {
 		// pick a random ka from bucket.
 		randIndex := a.rand.Intn(len(bucket))
 		for _, ka := range bucket {
 			if randIndex == 0 {
-				return ka.Addr
+				if best == nil || ka.quality() > best.quality() {
+					best = ka
+				}
+				break
 			}
 			randIndex--
 		}
+	}
+	if best == nil {
 		cmn.PanicSanity("Should not happen")
+		return nil
 	}
-	return nil
+	return best.Addr
 }
 
 func (a *AddrBook) MarkGood(addr *NetAddress) {
@@ -280,6 +292,41 @@ func (a *AddrBook) RemoveAddress(addr *NetAddress) {
 	a.removeFromAllBuckets(ka)
 }
 
+// AddressInfo is a snapshot of one known address and its quality
+// metrics, suitable for JSON encoding in RPC responses.
+type AddressInfo struct {
+	Addr        string    `json:"addr"`
+	Src         string    `json:"src"`
+	Attempts    int32     `json:"attempts"`
+	Successes   int32     `json:"successes"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+	LastSeen    time.Time `json:"last_seen"`
+	Old         bool      `json:"old"`
+}
+
+// ListAddresses returns a snapshot of every address in the book together
+// with its quality metrics. Used by the crawler/seed-node address dump.
+func (a *AddrBook) ListAddresses() []*AddressInfo {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	infos := make([]*AddressInfo, 0, a.size())
+	for _, ka := range a.addrLookup {
+		infos = append(infos, &AddressInfo{
+			Addr:        ka.Addr.String(),
+			Src:         ka.Src.String(),
+			Attempts:    ka.Attempts,
+			Successes:   ka.Successes,
+			LastAttempt: ka.LastAttempt,
+			LastSuccess: ka.LastSuccess,
+			LastSeen:    ka.LastSeen,
+			Old:         ka.isOld(),
+		})
+	}
+	return infos
+}
+
 /* Peer exchange */
 
 // GetSelection randomly selects some addresses (old & new). Suitable for peer-exchange protocols.
@@ -478,7 +525,7 @@ func (a *AddrBook) addToOldBucket(ka *knownAddress, bucketIdx int) bool {
 	}
 
 	addrStr := ka.Addr.String()
-	bucket := a.getBucket(bucketTypeNew, bucketIdx)
+	bucket := a.getBucket(bucketTypeOld, bucketIdx)
 
 	// Already exists?
 	if _, ok := bucket[addrStr]; ok {
@@ -557,6 +604,8 @@ func (a *AddrBook) addAddress(addr, src *NetAddress) {
 	ka := a.addrLookup[addr.String()]
 
 	if ka != nil {
+		// The address is still being announced, remember that.
+		ka.markSeen()
 		// Already old.
 		if ka.isOld() {
 			return
@@ -745,8 +794,10 @@ type knownAddress struct {
 	Addr        *NetAddress
 	Src         *NetAddress
 	Attempts    int32
+	Successes   int32
 	LastAttempt time.Time
 	LastSuccess time.Time
+	LastSeen    time.Time
 	BucketType  byte
 	Buckets     []int
 }
@@ -757,6 +808,7 @@ func newKnownAddress(addr *NetAddress, src *NetAddress) *knownAddress {
 		Src:         src,
 		Attempts:    0,
 		LastAttempt: time.Now(),
+		LastSeen:    time.Now(),
 		BucketType:  bucketTypeNew,
 		Buckets:     nil,
 	}
@@ -780,7 +832,26 @@ func (ka *knownAddress) markGood() {
 	now := time.Now()
 	ka.LastAttempt = now
 	ka.Attempts = 0
+	ka.Successes += 1
 	ka.LastSuccess = now
+	ka.LastSeen = now
+}
+
+func (ka *knownAddress) markSeen() {
+	ka.LastSeen = time.Now()
+}
+
+// quality scores an address between 0 and 1 using its success rate and how
+// recently it was last seen announced on the network.
+func (ka *knownAddress) quality() float64 {
+	rate := float64(ka.Successes) / float64(ka.Successes+ka.Attempts+1)
+	sinceSeen := time.Since(ka.LastSeen)
+	if sinceSeen < 0 {
+		sinceSeen = 0
+	}
+	// halve the score for every week an address goes unseen.
+	decay := math.Pow(0.5, sinceSeen.Hours()/(7*24))
+	return rate * decay
 }
 
 func (ka *knownAddress) addBucketRef(bucketIdx int) int {
@@ -0,0 +1,49 @@
+package p2p
+
+import (
+	"bytes"
+	"sync"
+
+	wire "github.com/tendermint/go-wire"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// Every message sent used to encode into a fresh buffer that died as
+// soon as its last packet hit the wire, which during block floods put
+// megabytes per second of short-lived garbage on the collector. The
+// pool below recycles those encode buffers: Send borrows one, and the
+// send routine returns it after writing the message's final packet (a
+// failed enqueue returns it immediately).
+
+var msgBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// pendingMsg is one encoded message queued on a channel, together with
+// the pooled buffer its bytes live in.
+type pendingMsg struct {
+	bytes []byte
+	buf   *bytes.Buffer
+}
+
+// encodeMsg renders msg in wire form into a pooled buffer. The bytes
+// alias the buffer; recycle it with recycleMsgBuf only once they are
+// fully written out or the message is dropped.
+func encodeMsg(msg interface{}) pendingMsg {
+	buf := msgBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	n, err := int(0), error(nil)
+	wire.WriteBinary(msg, buf, &n, &err)
+	if err != nil {
+		// mirrors the panic in wire.BinaryBytes
+		cmn.PanicSanity(err)
+	}
+	return pendingMsg{bytes: buf.Bytes(), buf: buf}
+}
+
+func recycleMsgBuf(buf *bytes.Buffer) {
+	if buf != nil {
+		msgBufPool.Put(buf)
+	}
+}
@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"math"
@@ -222,7 +223,11 @@ func (c *MConnection) Send(chID byte, msg interface{}) bool {
 		return false
 	}
 
-	success := channel.sendBytes(wire.BinaryBytes(msg))
+	pending := encodeMsg(msg)
+	success := channel.sendBytes(pending)
+	if !success {
+		recycleMsgBuf(pending.buf)
+	}
 	if success {
 		// Wake up sendRoutine if necessary
 		select {
@@ -259,7 +264,11 @@ func (c *MConnection) TrySend(chID byte, msg interface{}) bool {
 		return false
 	}
 
-	ok = channel.trySendBytes(wire.BinaryBytes(msg))
+	pending := encodeMsg(msg)
+	ok = channel.trySendBytes(pending)
+	if !ok {
+		recycleMsgBuf(pending.buf)
+	}
 	if ok {
 		// Wake up sendRoutine if necessary
 		select {
@@ -394,6 +403,7 @@ func (c *MConnection) sendMsgPacket() bool {
 		return true
 	}
 	c.sendMonitor.Update(int(n))
+	globalMessageMetrics.recordSend(leastChannel.id, int(n))
 	c.flushTimer.Set()
 	return false
 }
@@ -404,6 +414,10 @@ func (c *MConnection) sendMsgPacket() bool {
 func (c *MConnection) recvRoutine() {
 	defer c._recover()
 
+	// one packet struct for the whole connection; reading into a fresh
+	// one per packet made it escape to the heap every iteration
+	var pkt msgPacket
+
 FOR_LOOP:
 	for {
 		// Block until .recvMonitor says we can read.
@@ -450,7 +464,7 @@ FOR_LOOP:
 			// do nothing
 			log.Debug("Receive Pong")
 		case packetTypeMsg:
-			pkt, n, err := msgPacket{}, int(0), error(nil)
+			pkt, n, err = msgPacket{}, int(0), error(nil)
 			wire.ReadBinaryPtr(&pkt, c.bufReader, maxMsgPacketTotalSize, &n, &err)
 			c.recvMonitor.Update(int(n))
 			if err != nil {
@@ -487,6 +501,7 @@ FOR_LOOP:
 					"channelID": pkt.ChannelID,
 					"msgBytes":  msgBytes,
 				}).Debug("Received bytes")
+				globalMessageMetrics.recordRecv(pkt.ChannelID, len(msgBytes))
 				c.onReceive(pkt.ChannelID, msgBytes)
 			}
 		default:
@@ -519,6 +534,18 @@ type ChannelStatus struct {
 	RecentlySent      int64
 }
 
+// SetSendRate updates the connection's upload limit, in bytes/second.
+// A value <= 0 disables throttling. Safe for concurrent access.
+func (c *MConnection) SetSendRate(rate int64) {
+	atomic.StoreInt64(&c.config.SendRate, rate)
+}
+
+// SetRecvRate updates the connection's download limit, in bytes/second.
+// A value <= 0 disables throttling. Safe for concurrent access.
+func (c *MConnection) SetRecvRate(rate int64) {
+	atomic.StoreInt64(&c.config.RecvRate, rate)
+}
+
 func (c *MConnection) Status() ConnectionStatus {
 	var status ConnectionStatus
 	status.SendMonitor = c.sendMonitor.Status()
@@ -564,10 +591,11 @@ type Channel struct {
 	conn          *MConnection
 	desc          *ChannelDescriptor
 	id            byte
-	sendQueue     chan []byte
+	sendQueue     chan pendingMsg
 	sendQueueSize int32 // atomic.
 	recving       []byte
 	sending       []byte
+	sendingBuf    *bytes.Buffer // pooled buffer backing sending; see codec_pool.go
 	priority      int
 	recentlySent  int64 // exponential moving average
 }
@@ -581,7 +609,7 @@ func newChannel(conn *MConnection, desc *ChannelDescriptor) *Channel {
 		conn:      conn,
 		desc:      desc,
 		id:        desc.ID,
-		sendQueue: make(chan []byte, desc.SendQueueCapacity),
+		sendQueue: make(chan pendingMsg, desc.SendQueueCapacity),
 		recving:   make([]byte, 0, desc.RecvBufferCapacity),
 		priority:  desc.Priority,
 	}
@@ -590,9 +618,9 @@ func newChannel(conn *MConnection, desc *ChannelDescriptor) *Channel {
 // Queues message to send to this channel.
 // Goroutine-safe
 // Times out (and returns false) after defaultSendTimeout
-func (ch *Channel) sendBytes(bytes []byte) bool {
+func (ch *Channel) sendBytes(msg pendingMsg) bool {
 	select {
-	case ch.sendQueue <- bytes:
+	case ch.sendQueue <- msg:
 		atomic.AddInt32(&ch.sendQueueSize, 1)
 		return true
 	case <-time.After(defaultSendTimeout):
@@ -603,9 +631,9 @@ func (ch *Channel) sendBytes(bytes []byte) bool {
 // Queues message to send to this channel.
 // Nonblocking, returns true if successful.
 // Goroutine-safe
-func (ch *Channel) trySendBytes(bytes []byte) bool {
+func (ch *Channel) trySendBytes(msg pendingMsg) bool {
 	select {
-	case ch.sendQueue <- bytes:
+	case ch.sendQueue <- msg:
 		atomic.AddInt32(&ch.sendQueueSize, 1)
 		return true
 	default:
@@ -632,7 +660,9 @@ func (ch *Channel) isSendPending() bool {
 		if len(ch.sendQueue) == 0 {
 			return false
 		}
-		ch.sending = <-ch.sendQueue
+		pending := <-ch.sendQueue
+		ch.sending = pending.bytes
+		ch.sendingBuf = pending.buf
 	}
 	return true
 }
@@ -663,6 +693,12 @@ func (ch *Channel) writeMsgPacketTo(w io.Writer) (n int, err error) {
 	if err == nil {
 		ch.recentlySent += int64(n)
 	}
+	// the message's last packet is on the wire (or the connection is
+	// about to die); its encode buffer can go back to the pool
+	if ch.sending == nil && ch.sendingBuf != nil {
+		recycleMsgBuf(ch.sendingBuf)
+		ch.sendingBuf = nil
+	}
 	return
 }
 
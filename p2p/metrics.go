@@ -0,0 +1,133 @@
+package p2p
+
+import (
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"sync"
+)
+
+// ChannelMetrics holds the counters kept for one side of one channel.
+type ChannelMetrics struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// MessageMetrics is a snapshot of the per-channel and per-message-type
+// traffic counters, suitable for JSON encoding in RPC responses.
+type MessageMetrics struct {
+	SendByChannel  map[string]*ChannelMetrics `json:"send_by_channel"`
+	RecvByChannel  map[string]*ChannelMetrics `json:"recv_by_channel"`
+	SendByType     map[string]int64           `json:"send_by_type"`
+	DecodeFailures map[string]int64           `json:"decode_failures"`
+}
+
+type messageMetrics struct {
+	mtx            sync.Mutex
+	sendByChannel  map[byte]*ChannelMetrics
+	recvByChannel  map[byte]*ChannelMetrics
+	sendByType     map[string]int64
+	decodeFailures map[byte]int64
+}
+
+var globalMessageMetrics = &messageMetrics{
+	sendByChannel:  make(map[byte]*ChannelMetrics),
+	recvByChannel:  make(map[byte]*ChannelMetrics),
+	sendByType:     make(map[string]int64),
+	decodeFailures: make(map[byte]int64),
+}
+
+func init() {
+	expvar.Publish("p2p_messages", expvar.Func(func() interface{} {
+		return GetMessageMetrics()
+	}))
+}
+
+func channelKey(chID byte) string {
+	switch chID {
+	case PexChannel:
+		return "pex"
+	default:
+		return "0x" + string("0123456789abcdef"[chID>>4]) + string("0123456789abcdef"[chID&0x0f])
+	}
+}
+
+func (m *messageMetrics) recordSend(chID byte, nBytes int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	cm, ok := m.sendByChannel[chID]
+	if !ok {
+		cm = &ChannelMetrics{}
+		m.sendByChannel[chID] = cm
+	}
+	cm.Count++
+	cm.Bytes += int64(nBytes)
+}
+
+func (m *messageMetrics) recordSendType(msg interface{}) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.sendByType[reflect.TypeOf(msg).String()]++
+}
+
+func (m *messageMetrics) recordRecv(chID byte, nBytes int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	cm, ok := m.recvByChannel[chID]
+	if !ok {
+		cm = &ChannelMetrics{}
+		m.recvByChannel[chID] = cm
+	}
+	cm.Count++
+	cm.Bytes += int64(nBytes)
+}
+
+func (m *messageMetrics) recordDecodeFailure(chID byte) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.decodeFailures[chID]++
+}
+
+// RecordDecodeFailure counts a message on the given channel that could
+// not be decoded. Reactors should call this from their Receive handlers.
+func RecordDecodeFailure(chID byte) {
+	globalMessageMetrics.recordDecodeFailure(chID)
+}
+
+// GetMessageMetrics returns a copy of the global p2p traffic counters.
+func GetMessageMetrics() *MessageMetrics {
+	m := globalMessageMetrics
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	snapshot := &MessageMetrics{
+		SendByChannel:  make(map[string]*ChannelMetrics),
+		RecvByChannel:  make(map[string]*ChannelMetrics),
+		SendByType:     make(map[string]int64),
+		DecodeFailures: make(map[string]int64),
+	}
+	for chID, cm := range m.sendByChannel {
+		snapshot.SendByChannel[channelKey(chID)] = &ChannelMetrics{Count: cm.Count, Bytes: cm.Bytes}
+	}
+	for chID, cm := range m.recvByChannel {
+		snapshot.RecvByChannel[channelKey(chID)] = &ChannelMetrics{Count: cm.Count, Bytes: cm.Bytes}
+	}
+	for msgType, count := range m.sendByType {
+		snapshot.SendByType[msgType] = count
+	}
+	for chID, count := range m.decodeFailures {
+		snapshot.DecodeFailures[channelKey(chID)] = count
+	}
+	return snapshot
+}
+
+// String implements expvar.Var.
+func (m *MessageMetrics) String() string {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
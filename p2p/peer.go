@@ -52,11 +52,18 @@ type PeerConfig struct {
 
 // DefaultPeerConfig returns the default config.
 func DefaultPeerConfig(config *cfg.P2PConfig) *PeerConfig {
+	mConfig := DefaultMConnConfig()
+	if config.SendRate > 0 {
+		mConfig.SendRate = config.SendRate
+	}
+	if config.RecvRate > 0 {
+		mConfig.RecvRate = config.RecvRate
+	}
 	return &PeerConfig{
 		AuthEnc:          true,
 		HandshakeTimeout: time.Duration(config.HandshakeTimeout), // * time.Second,
 		DialTimeout:      time.Duration(config.DialTimeout),  // * time.Second,
-		MConfig:          DefaultMConnConfig(),
+		MConfig:          mConfig,
 		Fuzz:             false,
 		FuzzConfig:       DefaultFuzzConnConfig(),
 	}
@@ -142,6 +149,13 @@ func (p *Peer) IsPersistent() bool {
 	return p.persistent
 }
 
+// SetRateLimits overrides the send/recv throttle for this peer only,
+// in bytes/second. A value <= 0 disables the corresponding limit.
+func (p *Peer) SetRateLimits(sendRate, recvRate int64) {
+	p.mconn.SetSendRate(sendRate)
+	p.mconn.SetRecvRate(recvRate)
+}
+
 // HandshakeTimeout performs a handshake between a given node and the peer.
 // NOTE: blocking
 func (p *Peer) HandshakeTimeout(ourNodeInfo *NodeInfo, timeout time.Duration) error {
@@ -234,6 +248,7 @@ func (p *Peer) Send(chID byte, msg interface{}) bool {
 		// them - while we're looping, one peer may be removed and stopped.
 		return false
 	}
+	globalMessageMetrics.recordSendType(msg)
 	return p.mconn.Send(chID, msg)
 }
 
@@ -243,6 +258,7 @@ func (p *Peer) TrySend(chID byte, msg interface{}) bool {
 	if !p.IsRunning() {
 		return false
 	}
+	globalMessageMetrics.recordSendType(msg)
 	return p.mconn.TrySend(chID, msg)
 }
 
@@ -290,6 +306,11 @@ func dial(addr *NetAddress, config *PeerConfig) (net.Conn, error) {
 }
 
 func createMConnection(conn net.Conn, p *Peer, reactorsByCh map[byte]Reactor, chDescs []*ChannelDescriptor, onPeerError func(*Peer, interface{}), config *MConnConfig) *MConnection {
+	// Copy the config so per-peer rate limit changes don't leak into
+	// other connections built from the same PeerConfig.
+	mConfig := *config
+	config = &mConfig
+
 	onReceive := func(chID byte, msgBytes []byte) {
 		reactor := reactorsByCh[chID]
 		if reactor == nil {
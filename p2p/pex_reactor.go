@@ -21,6 +21,9 @@ const (
 	minNumOutboundPeers      = 10
 	maxPexMessageSize        = 1048576 // 1MB
 
+	// period to exchange a selection of addresses with connected peers
+	defaultAddrBroadcastPeriod = 10 * time.Minute
+
 	// maximum messages one peer can send to us during `msgCountByPeerFlushInterval`
 	defaultMaxMsgCountByPeer    = 1000
 	msgCountByPeerFlushInterval = 1 * time.Hour
@@ -48,6 +51,7 @@ type PEXReactor struct {
 	sw                *Switch
 	book              *AddrBook
 	ensurePeersPeriod time.Duration
+	crawler           bool
 
 	// tracks message count by peer, so we can prevent abuse
 	msgCountByPeer    *cmn.CMap
@@ -71,6 +75,7 @@ func (r *PEXReactor) OnStart() error {
 	r.BaseReactor.OnStart()
 	r.book.Start()
 	go r.ensurePeersRoutine()
+	go r.broadcastAddrsRoutine()
 	go r.flushMsgCountByPeer()
 	return nil
 }
@@ -99,7 +104,8 @@ func (r *PEXReactor) AddPeer(p *Peer) {
 		// For outbound peers, the address is already in the books.
 		// Either it was added in DialSeeds or when we
 		// received the peer's address in r.Receive
-		if r.book.NeedMoreAddrs() {
+		r.book.MarkGood(p.Connection().RemoteAddress)
+		if r.crawler || r.book.NeedMoreAddrs() {
 			r.RequestPEX(p)
 		}
 	} else { // For inbound connections, the peer is its own source
@@ -136,6 +142,7 @@ func (r *PEXReactor) Receive(chID byte, src *Peer, msgBytes []byte) {
 
 	_, msg, err := DecodeMessage(msgBytes)
 	if err != nil {
+		RecordDecodeFailure(chID)
 		log.WithField("error", err).Error("Error decoding message")
 		return
 	}
@@ -173,6 +180,16 @@ func (r *PEXReactor) SetEnsurePeersPeriod(d time.Duration) {
 	r.ensurePeersPeriod = d
 }
 
+// SetCrawlerMode makes the reactor crawl the network aggressively: it
+// always asks new peers for addresses and cycles through candidates
+// faster, so the address book maps as much of the network as possible.
+func (r *PEXReactor) SetCrawlerMode(crawler bool) {
+	r.crawler = crawler
+	if crawler {
+		r.ensurePeersPeriod = defaultEnsurePeersPeriod / 3
+	}
+}
+
 // SetMaxMsgCountByPeer sets maximum messages one peer can send to us during 'msgCountByPeerFlushInterval'.
 func (r *PEXReactor) SetMaxMsgCountByPeer(v uint16) {
 	r.maxMsgCountByPeer = v
@@ -244,6 +261,7 @@ func (r *PEXReactor) ensurePeers() {
 	}
 
 	toDial := make(map[string]*NetAddress)
+	dialGroups := make(map[string]bool)
 
 	// Try to pick numToDial addresses to dial.
 	for i := 0; i < numToDial; i++ {
@@ -264,7 +282,10 @@ func (r *PEXReactor) ensurePeers() {
 			_, alreadySelected := toDial[try.IP.String()]
 			alreadyDialing := r.Switch.IsDialing(try)
 			alreadyConnected := r.Switch.Peers().Has(try.IP.String())
-			if alreadySelected || alreadyDialing || alreadyConnected {
+			// Prefer a diverse set of outbound peers by not picking two
+			// addresses from the same network group in one round.
+			sameGroup := dialGroups[r.book.groupKey(try)]
+			if alreadySelected || alreadyDialing || alreadyConnected || sameGroup {
 				continue
 			} else {
 				log.WithField("addr", try).Info("Will dial address")
@@ -276,6 +297,7 @@ func (r *PEXReactor) ensurePeers() {
 			continue
 		}
 		toDial[picked.IP.String()] = picked
+		dialGroups[r.book.groupKey(picked)] = true
 	}
 
 	// Dial picked addresses
@@ -299,6 +321,27 @@ func (r *PEXReactor) ensurePeers() {
 	}
 }
 
+// Periodically shares a selection of our known addresses with a random peer,
+// so addresses keep circulating even on nodes that no longer need more.
+func (r *PEXReactor) broadcastAddrsRoutine() {
+	ticker := time.NewTicker(defaultAddrBroadcastPeriod)
+
+	for {
+		select {
+		case <-ticker.C:
+			if peers := r.Switch.Peers().List(); len(peers) > 0 {
+				peer := peers[rand.Int()%len(peers)]
+				if addrs := r.book.GetSelection(); addrs != nil {
+					r.SendAddrs(peer, addrs)
+				}
+			}
+		case <-r.Quit:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
 func (r *PEXReactor) flushMsgCountByPeer() {
 	ticker := time.NewTicker(msgCountByPeerFlushInterval)
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"strings"
 	"time"
 
 	cfg "github.com/bytom/config"
@@ -71,6 +72,12 @@ type Switch struct {
 	dialing      *cmn.CMap
 	nodeInfo     *NodeInfo             // our node info
 	nodePrivKey  crypto.PrivKeyEd25519 // our node privkey
+	trustedIPs   map[string]bool       // IPs of config.TrustedPeers
+
+	// services an outbound peer must advertise before we keep it
+	requiredServices ServiceFlag
+
+	addrBook *AddrBook // known peers, may be nil when PEX is disabled
 
 	filterConnByAddr   func(net.Addr) error
 	filterConnByPubKey func(crypto.PubKeyEd25519) error
@@ -90,11 +97,34 @@ func NewSwitch(config *cfg.P2PConfig) *Switch {
 		peers:        NewPeerSet(),
 		dialing:      cmn.NewCMap(),
 		nodeInfo:     nil,
+		trustedIPs:   make(map[string]bool),
+	}
+	if config.TrustedPeers != "" {
+		for _, addrStr := range strings.Split(config.TrustedPeers, ",") {
+			if netAddr, err := NewNetAddressString(addrStr); err == nil {
+				sw.trustedIPs[netAddr.IP.String()] = true
+			} else {
+				log.WithFields(log.Fields{
+					"addr":  addrStr,
+					"error": err,
+				}).Error("Invalid trusted peer address")
+			}
+		}
 	}
 	sw.BaseService = *cmn.NewBaseService(nil, "P2P Switch", sw)
 	return sw
 }
 
+// IsTrustedPeer returns true if the address belongs to a configured
+// trusted peer. Trusted peers are exempt from banning and rate limits.
+func (sw *Switch) IsTrustedPeer(addr net.Addr) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+	return sw.trustedIPs[host]
+}
+
 // Not goroutine safe.
 func (sw *Switch) AddReactor(name string, reactor Reactor) Reactor {
 	// Validate the reactor.
@@ -148,6 +178,24 @@ func (sw *Switch) NodeInfo() *NodeInfo {
 	return sw.nodeInfo
 }
 
+// Not goroutine safe.
+// SetRequiredServices restricts outbound peers to nodes advertising
+// all the given service bits.
+func (sw *Switch) SetRequiredServices(services ServiceFlag) {
+	sw.requiredServices = services
+}
+
+// Not goroutine safe.
+func (sw *Switch) SetAddrBook(book *AddrBook) {
+	sw.addrBook = book
+}
+
+// AddrBook returns the address book the switch was configured with,
+// or nil when PEX is disabled.
+func (sw *Switch) AddrBook() *AddrBook {
+	return sw.addrBook
+}
+
 // Not goroutine safe.
 // NOTE: Overwrites sw.nodeInfo.PubKey
 func (sw *Switch) SetNodePrivKey(nodePrivKey crypto.PrivKeyEd25519) {
@@ -199,12 +247,19 @@ func (sw *Switch) OnStop() {
 // NOTE: This performs a blocking handshake before the peer is added.
 // CONTRACT: If error is returned, peer is nil, and conn is immediately closed.
 func (sw *Switch) AddPeer(peer *Peer) error {
-	if err := sw.FilterConnByAddr(peer.Addr()); err != nil {
-		return err
+	trusted := sw.IsTrustedPeer(peer.Addr())
+	if sw.config.ConnectOnly && !trusted {
+		return errors.New("Connect-only mode: peer is not trusted")
 	}
 
-	if err := sw.FilterConnByPubKey(peer.PubKey()); err != nil {
-		return err
+	if !trusted {
+		if err := sw.FilterConnByAddr(peer.Addr()); err != nil {
+			return err
+		}
+
+		if err := sw.FilterConnByPubKey(peer.PubKey()); err != nil {
+			return err
+		}
 	}
 
 	if err := peer.HandshakeTimeout(sw.nodeInfo, time.Duration(sw.peerConfig.HandshakeTimeout*time.Second)); err != nil {
@@ -221,6 +276,12 @@ func (sw *Switch) AddPeer(peer *Peer) error {
 		return err
 	}
 
+	// Outbound peers must provide the services we depend on. Inbound
+	// peers connect for their own needs and are not filtered.
+	if peer.outbound && !peer.NodeInfo.HasService(sw.requiredServices) {
+		return fmt.Errorf("Peer is missing required services. Got %b, expected %b", peer.NodeInfo.Services, sw.requiredServices)
+	}
+
 	// Check for duplicate peer
 	if sw.peers.Has(peer.Key) {
 		return ErrSwitchDuplicatePeer
@@ -239,6 +300,10 @@ func (sw *Switch) AddPeer(peer *Peer) error {
 		return err
 	}
 
+	if trusted {
+		peer.SetRateLimits(0, 0)
+	}
+
 	log.WithField("peer", peer).Info("Added peer")
 	return nil
 }
@@ -307,6 +372,29 @@ func (sw *Switch) DialSeeds(addrBook *AddrBook, seeds []string) error {
 	return nil
 }
 
+// DialTrustedPeers dials the configured trusted peers as persistent
+// peers, so they are always reconnected after a failure.
+func (sw *Switch) DialTrustedPeers() error {
+	if sw.config.TrustedPeers == "" {
+		return nil
+	}
+	netAddrs, err := NewNetAddressStrings(strings.Split(sw.config.TrustedPeers, ","))
+	if err != nil {
+		return err
+	}
+	for _, netAddr := range netAddrs {
+		go func(addr *NetAddress) {
+			if _, err := sw.DialPeerWithAddress(addr, true); err != nil {
+				log.WithFields(log.Fields{
+					"address": addr,
+					"error":   err,
+				}).Error("Error dialing trusted peer")
+			}
+		}(netAddr)
+	}
+	return nil
+}
+
 func (sw *Switch) dialSeed(addr *NetAddress) {
 	peer, err := sw.DialPeerWithAddress(addr, true)
 	if err != nil {
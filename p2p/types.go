@@ -11,6 +11,24 @@ import (
 
 const maxNodeInfoSize = 10240 // 10Kb
 
+// ServiceFlag is a bitmask of capabilities a node advertises during the
+// version handshake, so peers can be selected for what they provide.
+type ServiceFlag uint64
+
+const (
+	// SFFullNode means the node serves full blocks and headers.
+	SFFullNode ServiceFlag = 1 << iota
+	// SFCompactBlocks means the node supports compact block relay.
+	SFCompactBlocks
+	// SFTxFilters means the node serves filtered transaction matching.
+	SFTxFilters
+	// SFArchivalNode means the node keeps the complete historic chain.
+	SFArchivalNode
+)
+
+// DefaultServices are the services advertised by this implementation.
+const DefaultServices = SFFullNode | SFArchivalNode
+
 type NodeInfo struct {
 	PubKey     crypto.PubKeyEd25519 `json:"pub_key"`
 	Moniker    string               `json:"moniker"`
@@ -18,7 +36,13 @@ type NodeInfo struct {
 	RemoteAddr string               `json:"remote_addr"`
 	ListenAddr string               `json:"listen_addr"`
 	Version    string               `json:"version"` // major.minor.revision
-	Other      []string             `json:"other"`   // other application specific data
+	Services   ServiceFlag          `json:"services"`
+	Other      []string             `json:"other"` // other application specific data
+}
+
+// HasService returns true if the node advertises all the given service bits.
+func (info *NodeInfo) HasService(services ServiceFlag) bool {
+	return info.Services&services == services
 }
 
 // CONTRACT: two nodes are compatible if the major/minor versions match and network match
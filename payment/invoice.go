@@ -0,0 +1,71 @@
+package payment
+
+import (
+	"encoding/hex"
+
+	"github.com/bytom/crypto/ed25519/chainkd"
+	"github.com/bytom/errors"
+)
+
+var (
+	// ErrBadPubkey means the invoice pubkey is not a valid xpub.
+	ErrBadPubkey = errors.New("invoice pubkey is not a valid xpub")
+	// ErrBadSignature means the invoice signature does not verify.
+	ErrBadSignature = errors.New("invoice signature does not verify")
+)
+
+// Invoice is a payment request signed by the recipient's key, so the
+// payer can authenticate who they are paying before building a
+// transaction. The signature covers the URI string byte-for-byte,
+// which keeps the signed form canonical without a separate
+// serialization.
+type Invoice struct {
+	// URI is the bytom: payment URI carrying the request itself.
+	URI string `json:"uri"`
+	// Pubkey is the recipient's xpub, hex encoded.
+	Pubkey string `json:"pubkey"`
+	// Signature is the xpub signature over the URI bytes, hex encoded.
+	Signature string `json:"signature"`
+}
+
+// NewInvoice signs the request with the given signer and assembles the
+// invoice. The signer abstracts the key store; the node passes the
+// HSM's signing routine so private keys never leave it.
+func NewInvoice(r *Request, xpub chainkd.XPub, sign func(msg []byte) ([]byte, error)) (*Invoice, error) {
+	uri, err := r.Encode()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign([]byte(uri))
+	if err != nil {
+		return nil, err
+	}
+	return &Invoice{
+		URI:       uri,
+		Pubkey:    xpub.String(),
+		Signature: hex.EncodeToString(sig),
+	}, nil
+}
+
+// Verify checks the signature against the embedded pubkey and returns
+// the decoded request. Expiry is the caller's concern: a correctly
+// signed but expired invoice still verifies.
+func (inv *Invoice) Verify() (*Request, error) {
+	var xpub chainkd.XPub
+	if err := xpub.UnmarshalText([]byte(inv.Pubkey)); err != nil {
+		return nil, errors.WithDetailf(ErrBadPubkey, "pubkey %q: %v", inv.Pubkey, err)
+	}
+	sig, err := hex.DecodeString(inv.Signature)
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadSignature, "signature %q is not hex", inv.Signature)
+	}
+
+	request, err := Parse(inv.URI)
+	if err != nil {
+		return nil, err
+	}
+	if !xpub.Verify([]byte(inv.URI), sig) {
+		return nil, errors.WithDetail(ErrBadSignature, "signature was not made over this uri by this key")
+	}
+	return request, nil
+}
@@ -0,0 +1,138 @@
+// Package payment defines the bytom: payment URI scheme, so wallets
+// can hand each other complete payment requests as a single string or
+// QR code. The form follows the convention other chains use:
+//
+//	bytom:<control-program-hex>?amount=<units>&asset=<asset-id>&memo=<text>&expires=<unix-seconds>
+//
+// The target is the hex control program of the receiver, amount is in
+// base units of the asset, asset defaults to BTM when absent, and an
+// absent expires means the request never expires.
+package payment
+
+import (
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+)
+
+// Scheme is the URI scheme, including the trailing colon.
+const Scheme = "bytom:"
+
+var (
+	// ErrBadScheme means the URI does not start with bytom:.
+	ErrBadScheme = errors.New("payment uri must use the bytom: scheme")
+	// ErrBadProgram means the URI target is not a hex control program.
+	ErrBadProgram = errors.New("payment uri target is not a hex control program")
+	// ErrBadParam means a query parameter could not be parsed.
+	ErrBadParam = errors.New("invalid payment uri parameter")
+)
+
+// Request is one decoded payment request.
+type Request struct {
+	// Program is the hex control program to pay to.
+	Program string `json:"program"`
+	// AssetID identifies the asset; defaults to BTM.
+	AssetID string `json:"asset_id"`
+	// Amount is in base units; zero means the payer chooses.
+	Amount uint64 `json:"amount,omitempty"`
+	// Memo is free-form text shown to the payer.
+	Memo string `json:"memo,omitempty"`
+	// Expires is when the request stops being valid; zero means never.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// Expired reports whether the request's expiry has passed.
+func (r *Request) Expired() bool {
+	return !r.Expires.IsZero() && time.Now().After(r.Expires)
+}
+
+// Encode renders the request as a bytom: URI. Parameters with zero
+// values are omitted, and the BTM asset id is left implicit.
+func (r *Request) Encode() (string, error) {
+	if _, err := hex.DecodeString(r.Program); err != nil || r.Program == "" {
+		return "", errors.WithDetailf(ErrBadProgram, "program %q is not hex", r.Program)
+	}
+	if r.AssetID != "" {
+		if _, err := hex.DecodeString(r.AssetID); err != nil || len(r.AssetID) != 64 {
+			return "", errors.WithDetailf(ErrBadParam, "asset id %q is not a 32-byte hex hash", r.AssetID)
+		}
+	}
+
+	params := url.Values{}
+	if r.Amount > 0 {
+		params.Set("amount", strconv.FormatUint(r.Amount, 10))
+	}
+	if r.AssetID != "" && r.AssetID != consensus.BTMAssetID.String() {
+		params.Set("asset", r.AssetID)
+	}
+	if r.Memo != "" {
+		params.Set("memo", r.Memo)
+	}
+	if !r.Expires.IsZero() {
+		params.Set("expires", strconv.FormatInt(r.Expires.Unix(), 10))
+	}
+
+	uri := Scheme + r.Program
+	if encoded := params.Encode(); encoded != "" {
+		uri += "?" + encoded
+	}
+	return uri, nil
+}
+
+// Parse decodes a bytom: URI. Unknown parameters are rejected so a
+// wallet never silently drops a field the sender considered material.
+func Parse(uri string) (*Request, error) {
+	if !strings.HasPrefix(uri, Scheme) {
+		return nil, errors.WithDetailf(ErrBadScheme, "uri %q", uri)
+	}
+
+	rest := uri[len(Scheme):]
+	program := rest
+	query := ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		program, query = rest[:i], rest[i+1:]
+	}
+	if _, err := hex.DecodeString(program); err != nil || program == "" {
+		return nil, errors.WithDetailf(ErrBadProgram, "target %q is not hex", program)
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadParam, "query %q: %v", query, err)
+	}
+
+	r := &Request{
+		Program: program,
+		AssetID: consensus.BTMAssetID.String(),
+	}
+	for key, values := range params {
+		value := values[len(values)-1]
+		switch key {
+		case "amount":
+			if r.Amount, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return nil, errors.WithDetailf(ErrBadParam, "amount %q is not a base-unit integer", value)
+			}
+		case "asset":
+			if _, err := hex.DecodeString(value); err != nil || len(value) != 64 {
+				return nil, errors.WithDetailf(ErrBadParam, "asset id %q is not a 32-byte hex hash", value)
+			}
+			r.AssetID = value
+		case "memo":
+			r.Memo = value
+		case "expires":
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.WithDetailf(ErrBadParam, "expires %q is not a unix timestamp", value)
+			}
+			r.Expires = time.Unix(seconds, 0)
+		default:
+			return nil, errors.WithDetailf(ErrBadParam, "unknown parameter %q", key)
+		}
+	}
+	return r, nil
+}
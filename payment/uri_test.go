@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bytom/consensus"
+)
+
+func TestEncodeParseRoundTrip(t *testing.T) {
+	want := &Request{
+		Program: "0014e6b7e73d44a6a11bc0f9b1cb99e2e43e38b53049",
+		AssetID: consensus.BTMAssetID.String(),
+		Amount:  150000000,
+		Memo:    "invoice #42",
+		Expires: time.Unix(1900000000, 0),
+	}
+
+	uri, err := want.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Program != want.Program || got.AssetID != want.AssetID || got.Amount != want.Amount || got.Memo != want.Memo || !got.Expires.Equal(want.Expires) {
+		t.Errorf("round trip mismatch: got %+v want %+v", got, want)
+	}
+}
+
+func TestParseMinimal(t *testing.T) {
+	got, err := Parse("bytom:00145c9d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AssetID != consensus.BTMAssetID.String() {
+		t.Errorf("asset should default to BTM, got %s", got.AssetID)
+	}
+	if got.Amount != 0 || got.Expired() {
+		t.Errorf("minimal uri should have no amount or expiry: %+v", got)
+	}
+}
+
+func TestParseRejectsBadInput(t *testing.T) {
+	cases := []string{
+		"bitcoin:00145c9d",
+		"bytom:",
+		"bytom:not-hex",
+		"bytom:00145c9d?amount=1.5",
+		"bytom:00145c9d?asset=abcd",
+		"bytom:00145c9d?surprise=1",
+	}
+	for _, uri := range cases {
+		if _, err := Parse(uri); err == nil {
+			t.Errorf("Parse(%q) should fail", uri)
+		}
+	}
+}
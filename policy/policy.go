@@ -0,0 +1,94 @@
+// Package policy implements the standardness rules a node applies to
+// transactions at mempool and relay time. Unlike the checks in
+// protocol/validation these are not consensus rules: a block may
+// contain transactions every policy here rejects, and operators can
+// tune or disable them without forking the network.
+package policy
+
+import (
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+// Default relay policy knobs. Fee and dust limits default to zero so a
+// bare node behaves exactly like older releases; public relay nodes are
+// expected to raise them in config.
+const (
+	DefaultMaxTxSize = uint64(100 * 1024)
+)
+
+var (
+	// ErrTxTooBig is returned when a transaction exceeds the policy size limit.
+	ErrTxTooBig = errors.New("transaction exceeds policy size limit")
+	// ErrFeeTooLow is returned when a transaction pays under the relay fee rate.
+	ErrFeeTooLow = errors.New("transaction fee rate below relay minimum")
+	// ErrDustOutput is returned when a transaction creates an uneconomic output.
+	ErrDustOutput = errors.New("transaction creates dust output")
+	// ErrNonStandardProgram is returned when an output program isn't a known template.
+	ErrNonStandardProgram = errors.New("transaction output program is not standard")
+)
+
+// Policy holds the relay-time standardness knobs. Zero values disable
+// the corresponding check, except MaxTxSize which always applies.
+type Policy struct {
+	// MaxTxSize is the largest serialized transaction relayed, in bytes.
+	MaxTxSize uint64
+
+	// MinRelayFeePerKB is the fee rate a transaction must pay to enter
+	// the pool, in asset units per 1000 bytes.
+	MinRelayFeePerKB uint64
+
+	// DustThreshold rejects BTM outputs below this amount. Outputs of
+	// other assets are never dust; their economic value is unknowable.
+	DustThreshold uint64
+
+	// RejectNonStandard requires every output program to be a template
+	// this node recognizes (single or multisig signature programs, or
+	// a provably unspendable retirement).
+	RejectNonStandard bool
+}
+
+// Default returns the policy a node applies when the operator
+// configures nothing.
+func Default() *Policy {
+	return &Policy{
+		MaxTxSize: DefaultMaxTxSize,
+	}
+}
+
+// CheckTx applies the policy to a transaction that already passed
+// consensus validation, with the fee validation computed.
+func (p *Policy) CheckTx(tx *legacy.Tx, fee uint64) error {
+	if p.MaxTxSize > 0 && tx.TxData.SerializedSize > p.MaxTxSize {
+		return errors.WithDetailf(ErrTxTooBig, "size %d, limit %d", tx.TxData.SerializedSize, p.MaxTxSize)
+	}
+
+	if p.MinRelayFeePerKB > 0 {
+		feePerKB := fee * 1000 / tx.TxHeader.SerializedSize
+		if feePerKB < p.MinRelayFeePerKB {
+			return errors.WithDetailf(ErrFeeTooLow, "fee rate %d, minimum %d", feePerKB, p.MinRelayFeePerKB)
+		}
+	}
+
+	for _, out := range tx.Outputs {
+		if p.DustThreshold > 0 && *out.AssetId == *consensus.BTMAssetID && out.Amount < p.DustThreshold {
+			return errors.WithDetailf(ErrDustOutput, "amount %d, threshold %d", out.Amount, p.DustThreshold)
+		}
+		if p.RejectNonStandard && !isStandardProgram(out.ControlProgram) {
+			return ErrNonStandardProgram
+		}
+	}
+	return nil
+}
+
+// isStandardProgram reports whether the program is one of the templates
+// relay accepts when non-standard programs are rejected.
+func isStandardProgram(prog []byte) bool {
+	if vmutil.IsUnspendable(prog) {
+		return true
+	}
+	_, _, err := vmutil.ParseP2SPMultiSigProgram(prog)
+	return err == nil
+}
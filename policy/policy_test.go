@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/bytom/consensus"
+	"github.com/bytom/crypto/ed25519"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+func mockTx(size, amount uint64, program []byte) *legacy.Tx {
+	data := &legacy.TxData{
+		SerializedSize: size,
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*consensus.BTMAssetID, amount, program, nil),
+		},
+	}
+	return &legacy.Tx{TxData: *data, Tx: legacy.MapTx(data)}
+}
+
+func TestCheckTx(t *testing.T) {
+	standard, err := vmutil.P2SPMultiSigProgram([]ed25519.PublicKey{make([]byte, 32)}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		policy Policy
+		tx     *legacy.Tx
+		fee    uint64
+		err    error
+	}{
+		{Policy{MaxTxSize: 1000}, mockTx(500, 100, standard), 0, nil},
+		{Policy{MaxTxSize: 1000}, mockTx(2000, 100, standard), 0, ErrTxTooBig},
+		{Policy{MinRelayFeePerKB: 1000}, mockTx(1000, 100, standard), 1000, nil},
+		{Policy{MinRelayFeePerKB: 1000}, mockTx(1000, 100, standard), 500, ErrFeeTooLow},
+		{Policy{DustThreshold: 100}, mockTx(1000, 100, standard), 0, nil},
+		{Policy{DustThreshold: 100}, mockTx(1000, 99, standard), 0, ErrDustOutput},
+		{Policy{RejectNonStandard: true}, mockTx(1000, 100, standard), 0, nil},
+		{Policy{RejectNonStandard: true}, mockTx(1000, 100, []byte{0xbe, 0xef}), 0, ErrNonStandardProgram},
+	}
+
+	for i, c := range cases {
+		err := c.policy.CheckTx(c.tx, c.fee)
+		if errors.Root(err) != c.err {
+			t.Errorf("case %d: got error %v, want %v", i, err, c.err)
+		}
+	}
+}
+
+func TestDefaultKeepsLegacyBehavior(t *testing.T) {
+	p := Default()
+	if err := p.CheckTx(mockTx(1000, 1, []byte{0xbe, 0xef}), 0); err != nil {
+		t.Errorf("default policy rejected a zero-fee non-standard transaction: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 
 	"github.com/bytom/crypto/sha3pool"
+	"github.com/bytom/errors"
 )
 
 var (
@@ -49,6 +50,74 @@ func MerkleRoot(transactions []*Tx) (root Hash, err error) {
 	}
 }
 
+// MerkleProofStep is one level of a merkle path: the root of the
+// sibling subtree and which side of the hash it goes on.
+type MerkleProofStep struct {
+	Hash Hash
+	Left bool
+}
+
+// MerkleProof returns the merkle path proving that the transaction at
+// the given index is part of the tree MerkleRoot builds over the same
+// transactions. The path runs from the leaf towards the root.
+func MerkleProof(transactions []*Tx, index int) ([]MerkleProofStep, error) {
+	if index < 0 || index >= len(transactions) {
+		return nil, errors.New("transaction index out of range")
+	}
+	if len(transactions) == 1 {
+		return []MerkleProofStep{}, nil
+	}
+
+	k := prevPowerOfTwo(len(transactions))
+	if index < k {
+		proof, err := MerkleProof(transactions[:k], index)
+		if err != nil {
+			return nil, err
+		}
+		sibling, err := MerkleRoot(transactions[k:])
+		if err != nil {
+			return nil, err
+		}
+		return append(proof, MerkleProofStep{Hash: sibling, Left: false}), nil
+	}
+
+	proof, err := MerkleProof(transactions[k:], index-k)
+	if err != nil {
+		return nil, err
+	}
+	sibling, err := MerkleRoot(transactions[:k])
+	if err != nil {
+		return nil, err
+	}
+	return append(proof, MerkleProofStep{Hash: sibling, Left: true}), nil
+}
+
+// VerifyMerkleProof checks a path produced by MerkleProof against the
+// merkle root the containing block header commits to.
+func VerifyMerkleProof(txID Hash, proof []MerkleProofStep, root Hash) bool {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+
+	h.Write(leafPrefix)
+	txID.WriteTo(h)
+	var current Hash
+	current.ReadFrom(h)
+
+	for _, step := range proof {
+		h.Reset()
+		h.Write(interiorPrefix)
+		if step.Left {
+			step.Hash.WriteTo(h)
+			current.WriteTo(h)
+		} else {
+			current.WriteTo(h)
+			step.Hash.WriteTo(h)
+		}
+		current.ReadFrom(h)
+	}
+	return current == root
+}
+
 // prevPowerOfTwo returns the largest power of two that is smaller than a given number.
 // In other words, for some input n, the prevPowerOfTwo k is a power of two such that
 // k < n <= 2k. This is a helper function used during the calculation of a merkle tree.
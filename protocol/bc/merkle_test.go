@@ -139,6 +139,46 @@ func TestAllDuplicateLeaves(t *testing.T) {
 	}
 }
 
+func TestMerkleProof(t *testing.T) {
+	var initialBlockHash Hash
+	trueProg := []byte{byte(vm.OP_TRUE)}
+	assetID := ComputeAssetID(trueProg, &initialBlockHash, 1, &EmptyStringHash)
+
+	for _, txCount := range []int{1, 2, 3, 6, 7} {
+		txs := make([]*Tx, txCount)
+		for i := uint64(0); i < uint64(txCount); i++ {
+			now := []byte(time.Now().String())
+			txs[i] = legacy.NewTx(legacy.TxData{
+				Version: 1,
+				Inputs:  []*legacy.TxInput{legacy.NewIssuanceInput(now, i, nil, initialBlockHash, trueProg, nil, nil)},
+				Outputs: []*legacy.TxOutput{legacy.NewTxOutput(assetID, i, trueProg, nil)},
+			}).Tx
+		}
+
+		root, err := MerkleRoot(txs)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+
+		for i := 0; i < txCount; i++ {
+			proof, err := MerkleProof(txs, i)
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if !VerifyMerkleProof(txs[i].ID, proof, root) {
+				t.Errorf("proof for tx %d of %d does not verify", i, txCount)
+			}
+			if VerifyMerkleProof(txs[(i+1)%txCount].ID, proof, root) && txCount > 1 {
+				t.Errorf("proof for tx %d of %d verifies the wrong tx", i, txCount)
+			}
+		}
+	}
+
+	if _, err := MerkleProof([]*Tx{}, 0); err == nil {
+		t.Error("expected error for out-of-range index")
+	}
+}
+
 func mustDecodeHash(s string) (h Hash) {
 	err := h.UnmarshalText([]byte(s))
 	if err != nil {
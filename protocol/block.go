@@ -44,14 +44,34 @@ func (c *Chain) GetBlockByHeight(height uint64) (*legacy.Block, error) {
 // to a snapshot (with ApplyValidBlock) and committing it to the
 // blockchain (with CommitAppliedBlock).
 func (c *Chain) ValidateBlock(block, prev *legacy.Block) error {
+	blockHash := block.Hash()
+	if err := c.checkCheckpoint(block.Height, &blockHash); err != nil {
+		return err
+	}
+
 	blockEnts := legacy.MapBlock(block)
 	prevEnts := legacy.MapBlock(prev)
-	if err := validation.ValidateBlock(blockEnts, prevEnts, c.seedCaches); err != nil {
+	if c.headerOnly {
+		// light mode: the body is absent by construction, so only the
+		// header fields can be checked
+		if err := validation.ValidateBlockHeader(blockEnts, prevEnts, c.seedCaches); err != nil {
+			return errors.Sub(ErrBadBlock, err)
+		}
+		return nil
+	}
+	if err := validation.ValidateBlock(blockEnts, prevEnts, c.seedCaches, c.RunsPrograms(block.Height)); err != nil {
 		return errors.Sub(ErrBadBlock, err)
 	}
 	return nil
 }
 
+// RunsPrograms reports whether witness programs are executed for blocks
+// at the given height: always under full validation, otherwise only
+// above the highest registered checkpoint.
+func (c *Chain) RunsPrograms(height uint64) bool {
+	return c.fullValidation || height > c.lastCheckpointHeight()
+}
+
 // ConnectBlock append block to end of chain
 func (c *Chain) ConnectBlock(block *legacy.Block) error {
 	c.state.cond.L.Lock()
@@ -78,6 +98,17 @@ func (c *Chain) connectBlock(block *legacy.Block) error {
 	for _, tx := range block.Transactions {
 		c.txPool.RemoveTransaction(&tx.Tx.ID)
 	}
+	c.pruneToDepth()
+
+	// outputs just confirmed may be the missing parents of held orphan
+	// transactions; retry them outside the state lock
+	txs := block.Transactions
+	go func() {
+		for _, tx := range txs {
+			c.txPool.RemoveOrphan(&tx.Tx.ID)
+			c.processOrphanTxs(tx)
+		}
+	}()
 	return nil
 }
 
@@ -100,6 +131,20 @@ func (c *Chain) getReorganizeBlocks(block *legacy.Block) ([]*legacy.Block, []*le
 
 func (c *Chain) reorganizeChain(block *legacy.Block) error {
 	attachBlocks, detachBlocks := c.getReorganizeBlocks(block)
+	depth := uint64(len(detachBlocks))
+	newTip := block.Hash()
+	if c.maxReorgDepth > 0 && depth > c.maxReorgDepth {
+		log.WithFields(log.Fields{
+			"depth":   depth,
+			"max":     c.maxReorgDepth,
+			"old_tip": c.state.hash.String(),
+			"new_tip": newTip.String(),
+		}).Error("Refusing chain reorganization deeper than max reorg depth; operator intervention required")
+		return errors.WithDetailf(ErrReorgTooDeep, "depth %d exceeds limit %d", depth, c.maxReorgDepth)
+	}
+
+	oldTip := *c.state.hash
+	detachedTxs := []bc.Hash{}
 	utxoView := state.NewUtxoViewpoint()
 	chainChanges := map[uint64]*bc.Hash{}
 
@@ -111,6 +156,9 @@ func (c *Chain) reorganizeChain(block *legacy.Block) error {
 		if err := utxoView.DetachBlock(detachBlock); err != nil {
 			return err
 		}
+		for _, tx := range detachBlock.Transactions[1:] {
+			detachedTxs = append(detachedTxs, tx.ID)
+		}
 	}
 
 	for _, a := range attachBlocks {
@@ -125,13 +173,41 @@ func (c *Chain) reorganizeChain(block *legacy.Block) error {
 		chainChanges[a.Height] = &aHash
 	}
 
-	return c.setState(block, utxoView, chainChanges)
+	if err := c.setState(block, utxoView, chainChanges); err != nil {
+		return err
+	}
+
+	// signaling windows ending above the fork were counted on the
+	// abandoned branch; recount them from the new main chain
+	c.invalidateThresholdCache(block.Height - uint64(len(attachBlocks)))
+
+	log.WithFields(log.Fields{
+		"depth":   depth,
+		"old_tip": oldTip.String(),
+		"new_tip": newTip.String(),
+	}).Info("Chain reorganized")
+	if c.reorgHandler != nil {
+		// the state lock is held here; notify asynchronously
+		go c.reorgHandler(&ReorgEvent{
+			OldTip:      oldTip,
+			NewTip:      newTip,
+			Depth:       depth,
+			DetachedTxs: detachedTxs,
+		})
+	}
+	return nil
 }
 
 // SaveBlock will validate and save block into storage
 func (c *Chain) SaveBlock(block *legacy.Block) error {
 	preBlock, _ := c.GetBlockByHash(&block.PreviousBlockHash)
 	if err := c.ValidateBlock(block, preBlock); err != nil {
+		blockHash := block.Hash()
+		c.store.SaveRejectedBlock(&blockHash, block.Height)
+		log.WithFields(log.Fields{
+			"height": block.Height,
+			"hash":   blockHash.String(),
+		}).Errorf("Block failed validation and was marked rejected: %v", err)
 		return err
 	}
 	if err := c.store.SaveBlock(block); err != nil {
@@ -176,10 +252,17 @@ func (c *Chain) findBestChainTail(block *legacy.Block) (bestBlock *legacy.Block)
 // ProcessBlock is the entry for handle block insert
 func (c *Chain) ProcessBlock(block *legacy.Block) (bool, error) {
 	blockHash := block.Hash()
+	if c.store.IsBlockRejected(&blockHash) {
+		return false, errors.WithDetailf(ErrBadBlock, "block %s was rejected before", blockHash.String())
+	}
 	if c.BlockExist(&blockHash) {
 		log.WithField("hash", blockHash.String()).Info("Skip process due to block already been handled")
 		return false, nil
 	}
+	if c.store.IsBlockRejected(&block.PreviousBlockHash) {
+		// a descendant of a known-bad block can never become valid
+		return false, errors.WithDetailf(ErrBadBlock, "block %s extends rejected block %s", blockHash.String(), block.PreviousBlockHash.String())
+	}
 	if !c.store.BlockExist(&block.PreviousBlockHash) {
 		c.orphanManage.Add(block)
 		return true, nil
@@ -0,0 +1,91 @@
+package protocol
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+)
+
+// ErrBadCheckpoint is returned when a block hash disagrees with the
+// checkpoint registered at its height.
+var ErrBadCheckpoint = errors.New("block hash mismatches registered checkpoint")
+
+// Checkpoint pins a known-good block hash at a given height. Blocks at
+// or below the highest checkpoint skip witness program execution during
+// initial sync, and any block that disagrees with a checkpoint is
+// rejected outright.
+type Checkpoint struct {
+	Height uint64
+	Hash   bc.Hash
+}
+
+// Checkpoints built into the release, keyed by the chain ID carried in
+// the genesis doc. Entries are appended here as the networks mature.
+var defaultCheckpoints = map[string][]Checkpoint{
+	"mainnet": {},
+	"testnet": {},
+}
+
+// DefaultCheckpoints returns the checkpoints shipped with the release
+// for the given chain ID.
+func DefaultCheckpoints(chainID string) []Checkpoint {
+	return defaultCheckpoints[chainID]
+}
+
+// ParseCheckpoint parses an operator-supplied "height:hash" checkpoint
+// as it appears in the config file.
+func ParseCheckpoint(s string) (Checkpoint, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return Checkpoint{}, errors.WithDetailf(ErrBadCheckpoint, "malformed checkpoint %q, want height:hash", s)
+	}
+
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return Checkpoint{}, errors.WithDetailf(ErrBadCheckpoint, "bad checkpoint height %q", parts[0])
+	}
+	var hash bc.Hash
+	if err := hash.UnmarshalText([]byte(parts[1])); err != nil {
+		return Checkpoint{}, errors.WithDetailf(ErrBadCheckpoint, "bad checkpoint hash %q", parts[1])
+	}
+	return Checkpoint{Height: height, Hash: hash}, nil
+}
+
+// AddCheckpoints registers additional checkpoints with the chain. It
+// must be called before the chain starts processing blocks.
+func (c *Chain) AddCheckpoints(points []Checkpoint) {
+	c.checkpoints = append(c.checkpoints, points...)
+	sort.Slice(c.checkpoints, func(i, j int) bool {
+		return c.checkpoints[i].Height < c.checkpoints[j].Height
+	})
+}
+
+// SetFullValidation forces witness program execution for every block,
+// even below the last checkpoint.
+func (c *Chain) SetFullValidation(full bool) {
+	c.fullValidation = full
+}
+
+// lastCheckpointHeight returns the height of the highest registered
+// checkpoint, or 0 when there is none.
+func (c *Chain) lastCheckpointHeight() uint64 {
+	if len(c.checkpoints) == 0 {
+		return 0
+	}
+	return c.checkpoints[len(c.checkpoints)-1].Height
+}
+
+// checkCheckpoint rejects a block whose hash disagrees with the
+// checkpoint registered at its height, if any.
+func (c *Chain) checkCheckpoint(height uint64, hash *bc.Hash) error {
+	i := sort.Search(len(c.checkpoints), func(i int) bool {
+		return c.checkpoints[i].Height >= height
+	})
+	if i < len(c.checkpoints) && c.checkpoints[i].Height == height && c.checkpoints[i].Hash != *hash {
+		return errors.WithDetailf(ErrBadCheckpoint, "height %d, hash %x", height, hash.Bytes())
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package protocol
+
+import (
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+)
+
+// ErrDoubleSpend is returned when a transaction spends an output that an
+// unconfirmed pool transaction already spends.
+var ErrDoubleSpend = errors.New("transaction double spends a mempool transaction")
+
+// DoubleSpendEvent describes a rejected double spend: the transaction
+// that tried to spend, the pool transaction it conflicts with, and the
+// disputed output. Merchants watching it can delay acceptance of the
+// conflicting payment.
+type DoubleSpendEvent struct {
+	TxID         bc.Hash
+	ConflictTxID bc.Hash
+	OutputID     bc.Hash
+}
+
+// SetDoubleSpendHandler registers fn to be called whenever a double
+// spend against the pool is detected. It must be called before
+// transactions start flowing in.
+func (c *Chain) SetDoubleSpendHandler(fn func(*DoubleSpendEvent)) {
+	c.doubleSpendHandler = fn
+}
@@ -0,0 +1,27 @@
+package protocol
+
+import (
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// SetHeaderOnly puts the chain into light (SPV) mode: blocks are
+// validated and connected on their header fields alone and no bodies
+// are ever stored. The UTXO set stays empty, so a light chain can
+// follow proof of work and serve headers but cannot validate or mine
+// transactions; wallet-relevant transactions are checked against the
+// header chain with merkle proofs instead.
+func (c *Chain) SetHeaderOnly() {
+	c.headerOnly = true
+}
+
+// HeaderOnly reports whether the chain runs in light (SPV) mode.
+func (c *Chain) HeaderOnly() bool {
+	return c.headerOnly
+}
+
+// ProcessBlockHeader is the light-mode entry for a received header: it
+// wraps the header in a bodyless block and runs it through the regular
+// insert path, where validation stops at the header fields.
+func (c *Chain) ProcessBlockHeader(header *legacy.BlockHeader) (bool, error) {
+	return c.ProcessBlock(&legacy.Block{BlockHeader: *header})
+}
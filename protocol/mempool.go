@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"errors"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,11 +12,19 @@ import (
 	"github.com/golang/groupcache/lru"
 )
 
+// defaultMaxPoolBytes bounds the total serialized size of transactions
+// the pool holds; the cheapest transactions are evicted beyond it.
+const defaultMaxPoolBytes = 32 * 1024 * 1024
+
 var (
 	maxCachedErrTxs = 1000
 	maxNewTxChSize  = 1000
+	maxOrphanTxs    = 256
 	// ErrTransactionNotExist is the pre-defined error message
 	ErrTransactionNotExist = errors.New("transaction are not existed in the mempool")
+	// ErrPoolFull is returned when the pool is at its memory budget and
+	// the candidate pays too little to displace anything in it
+	ErrPoolFull = errors.New("transaction fee rate below mempool eviction floor")
 )
 
 // TxDesc store tx and related info for mining strategy
@@ -35,15 +44,36 @@ type TxPool struct {
 	pool        map[bc.Hash]*TxDesc
 	errCache    *lru.Cache
 	newTxCh     chan *legacy.Tx
+
+	// memory budget accounting; see makeRoom
+	poolBytes    uint64
+	maxPoolBytes uint64
+	evictFloor   uint64
+
+	// transactions whose parent outputs are unknown, indexed by the
+	// missing output so they can be retried when the parent arrives
+	orphans       map[bc.Hash]*legacy.Tx
+	orphansByPrev map[bc.Hash]map[bc.Hash]bool
 }
 
 // NewTxPool init a new TxPool
 func NewTxPool() *TxPool {
 	return &TxPool{
-		lastUpdated: time.Now().Unix(),
-		pool:        make(map[bc.Hash]*TxDesc),
-		errCache:    lru.New(maxCachedErrTxs),
-		newTxCh:     make(chan *legacy.Tx, maxNewTxChSize),
+		lastUpdated:   time.Now().Unix(),
+		pool:          make(map[bc.Hash]*TxDesc),
+		errCache:      lru.New(maxCachedErrTxs),
+		newTxCh:       make(chan *legacy.Tx, maxNewTxChSize),
+		maxPoolBytes:  defaultMaxPoolBytes,
+		orphans:       make(map[bc.Hash]*legacy.Tx),
+		orphansByPrev: make(map[bc.Hash]map[bc.Hash]bool),
+	}
+}
+
+// SetMaxPoolBytes overrides the pool memory budget. It must be called
+// before transactions start flowing in.
+func (mp *TxPool) SetMaxPoolBytes(max uint64) {
+	if max > 0 {
+		mp.maxPoolBytes = max
 	}
 }
 
@@ -52,8 +82,10 @@ func (mp *TxPool) GetNewTxCh() chan *legacy.Tx {
 	return mp.newTxCh
 }
 
-// AddTransaction add a verified transaction to pool
-func (mp *TxPool) AddTransaction(tx *legacy.Tx, height, fee uint64) *TxDesc {
+// AddTransaction add a verified transaction to pool. It fails with
+// ErrPoolFull when the pool is at its memory budget and the candidate
+// pays a lower fee rate than everything it would need to displace.
+func (mp *TxPool) AddTransaction(tx *legacy.Tx, height, fee uint64) (*TxDesc, error) {
 	txD := &TxDesc{
 		Tx:       tx,
 		Added:    time.Now(),
@@ -66,11 +98,50 @@ func (mp *TxPool) AddTransaction(tx *legacy.Tx, height, fee uint64) *TxDesc {
 	mp.mtx.Lock()
 	defer mp.mtx.Unlock()
 
+	if err := mp.makeRoom(txD); err != nil {
+		return nil, err
+	}
+
 	mp.pool[tx.Tx.ID] = txD
+	mp.poolBytes += txD.Weight
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
 	mp.newTxCh <- tx
-	return txD
+	return txD, nil
+}
+
+// makeRoom evicts the cheapest transactions until txD fits in the
+// memory budget. The fee rate of the best transaction evicted so far is
+// kept as a floor new candidates must beat, so a stream of cheap
+// transactions can't churn the pool. The floor resets once the pool
+// drains to half its budget. The caller must hold the lock.
+func (mp *TxPool) makeRoom(txD *TxDesc) error {
+	if mp.poolBytes < mp.maxPoolBytes/2 {
+		mp.evictFloor = 0
+	}
+	if mp.evictFloor > 0 && txD.FeePerKB < mp.evictFloor {
+		return ErrPoolFull
+	}
+
+	for mp.poolBytes+txD.Weight > mp.maxPoolBytes {
+		var victimHash bc.Hash
+		var victim *TxDesc
+		for hash, desc := range mp.pool {
+			if victim == nil || desc.FeePerKB < victim.FeePerKB {
+				victimHash, victim = hash, desc
+			}
+		}
+		if victim == nil || victim.FeePerKB >= txD.FeePerKB {
+			return ErrPoolFull
+		}
+
+		delete(mp.pool, victimHash)
+		mp.poolBytes -= victim.Weight
+		if victim.FeePerKB > mp.evictFloor {
+			mp.evictFloor = victim.FeePerKB
+		}
+	}
+	return nil
 }
 
 // AddErrCache add a failed transaction record to lru cache
@@ -98,8 +169,9 @@ func (mp *TxPool) RemoveTransaction(txHash *bc.Hash) {
 	mp.mtx.Lock()
 	defer mp.mtx.Unlock()
 
-	if _, ok := mp.pool[*txHash]; ok {
+	if txD, ok := mp.pool[*txHash]; ok {
 		delete(mp.pool, *txHash)
+		mp.poolBytes -= txD.Weight
 		atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 	}
 }
@@ -155,6 +227,129 @@ func (mp *TxPool) HaveTransaction(txHash *bc.Hash) bool {
 	return mp.IsTransactionInPool(txHash) || mp.IsTransactionInErrCache(txHash)
 }
 
+// AddOrphan holds a transaction whose listed parent outputs are unknown
+// until they arrive. The pool is bounded; an arbitrary orphan is
+// evicted when it is full.
+func (mp *TxPool) AddOrphan(tx *legacy.Tx, missing []*bc.Hash) {
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	if _, ok := mp.orphans[tx.Tx.ID]; ok {
+		return
+	}
+	if len(mp.orphans) >= maxOrphanTxs {
+		for victim := range mp.orphans {
+			mp.removeOrphan(&victim)
+			break
+		}
+	}
+
+	mp.orphans[tx.Tx.ID] = tx
+	for _, prevout := range missing {
+		if mp.orphansByPrev[*prevout] == nil {
+			mp.orphansByPrev[*prevout] = make(map[bc.Hash]bool)
+		}
+		mp.orphansByPrev[*prevout][tx.Tx.ID] = true
+	}
+}
+
+// RemoveOrphan drops a transaction from the orphan pool, for example
+// because it confirmed in a block.
+func (mp *TxPool) RemoveOrphan(txHash *bc.Hash) {
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+	mp.removeOrphan(txHash)
+}
+
+// removeOrphan removes the orphan and its index entries. The caller
+// must hold the lock.
+func (mp *TxPool) removeOrphan(txHash *bc.Hash) {
+	tx, ok := mp.orphans[*txHash]
+	if !ok {
+		return
+	}
+	delete(mp.orphans, *txHash)
+
+	for _, prevout := range tx.Tx.SpentOutputIDs {
+		waiting, ok := mp.orphansByPrev[prevout]
+		if !ok {
+			continue
+		}
+		delete(waiting, *txHash)
+		if len(waiting) == 0 {
+			delete(mp.orphansByPrev, prevout)
+		}
+	}
+}
+
+// TakeOrphansByPrev removes and returns the orphans waiting on the
+// given output, so they can be revalidated now that it exists.
+func (mp *TxPool) TakeOrphansByPrev(outID *bc.Hash) []*legacy.Tx {
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	txs := []*legacy.Tx{}
+	for txHash := range mp.orphansByPrev[*outID] {
+		if tx, ok := mp.orphans[txHash]; ok {
+			txs = append(txs, tx)
+			mp.removeOrphan(&txHash)
+		}
+	}
+	return txs
+}
+
+// IsOrphanInPool check wheather a transaction in orphan pool or not
+func (mp *TxPool) IsOrphanInPool(txHash *bc.Hash) bool {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	_, ok := mp.orphans[*txHash]
+	return ok
+}
+
+// IsOutputInPool reports whether an unconfirmed transaction in the pool
+// creates the given output.
+func (mp *TxPool) IsOutputInPool(outID *bc.Hash) bool {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	for _, desc := range mp.pool {
+		for _, resultID := range desc.Tx.Tx.ResultIds {
+			if *resultID == *outID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindConflict returns the pool transaction that already spends one of
+// the given transaction's prevouts, along with the disputed output, or
+// nils when there is no conflict.
+func (mp *TxPool) FindConflict(tx *legacy.Tx) (conflictTxID, outputID *bc.Hash) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	for _, prevout := range tx.Tx.SpentOutputIDs {
+		for hash, desc := range mp.pool {
+			for _, spent := range desc.Tx.Tx.SpentOutputIDs {
+				if spent == prevout {
+					conflictHash, disputed := hash, prevout
+					return &conflictHash, &disputed
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// OrphanCount return number of transcation in the orphan pool
+func (mp *TxPool) OrphanCount() int {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+	return len(mp.orphans)
+}
+
 // Count return number of transcation in pool
 func (mp *TxPool) Count() int {
 	mp.mtx.RLock()
@@ -163,3 +358,63 @@ func (mp *TxPool) Count() int {
 	count := len(mp.pool)
 	return count
 }
+
+// FeeBucket is one decade of the mempool fee-rate histogram: every
+// transaction paying at least FeePerKB but less than ten times it.
+type FeeBucket struct {
+	FeePerKB uint64 `json:"fee_per_kb"`
+	Count    int    `json:"count"`
+	Bytes    uint64 `json:"bytes"`
+}
+
+// MempoolInfo summarizes pool occupancy, so wallets can judge how
+// competitive a fee must be to confirm.
+type MempoolInfo struct {
+	Count         int         `json:"count"`
+	OrphanCount   int         `json:"orphan_count"`
+	Bytes         uint64      `json:"bytes"`
+	MaxBytes      uint64      `json:"max_bytes"`
+	EvictionFloor uint64      `json:"eviction_floor"`
+	Histogram     []FeeBucket `json:"histogram"`
+}
+
+// Info returns the pool occupancy summary and fee-rate histogram.
+func (mp *TxPool) Info() *MempoolInfo {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	buckets := map[uint64]*FeeBucket{}
+	for _, desc := range mp.pool {
+		lower := uint64(0)
+		if desc.FeePerKB > 0 {
+			lower = 1
+			for lower*10 <= desc.FeePerKB {
+				lower *= 10
+			}
+		}
+		bucket := buckets[lower]
+		if bucket == nil {
+			bucket = &FeeBucket{FeePerKB: lower}
+			buckets[lower] = bucket
+		}
+		bucket.Count++
+		bucket.Bytes += desc.Weight
+	}
+
+	histogram := make([]FeeBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		histogram = append(histogram, *bucket)
+	}
+	sort.Slice(histogram, func(i, j int) bool {
+		return histogram[i].FeePerKB < histogram[j].FeePerKB
+	})
+
+	return &MempoolInfo{
+		Count:         len(mp.pool),
+		OrphanCount:   len(mp.orphans),
+		Bytes:         mp.poolBytes,
+		MaxBytes:      mp.maxPoolBytes,
+		EvictionFloor: mp.evictFloor,
+		Histogram:     histogram,
+	}
+}
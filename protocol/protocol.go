@@ -38,14 +38,27 @@ type Store interface {
 	GetStoreStatus() txdb.BlockStoreStateJSON
 	GetTransactionsUtxo(*state.UtxoViewpoint, []*bc.Tx) error
 	GetUtxo(*bc.Hash) (*storage.UtxoEntry, error)
+	ListBlockHashes() ([]bc.Hash, error)
 
 	SaveBlock(*legacy.Block) error
 	SaveChainStatus(*legacy.Block, *state.UtxoViewpoint, map[uint64]*bc.Hash) error
+
+	SaveRejectedBlock(*bc.Hash, uint64) error
+	IsBlockRejected(*bc.Hash) bool
+	ListRejectedBlocks() ([]*txdb.RejectedBlock, error)
+
+	PruneBlock(*bc.Hash) error
+	IsBlockPruned(*bc.Hash) bool
+	GetBlockHeader(*bc.Hash) (*legacy.BlockHeader, error)
 }
 
+// maxOrphanBlocks bounds the orphan block pool. The pool only fills
+// when the node is badly out of sync or being fed junk; an evicted
+// block can always be re-requested from peers.
+const maxOrphanBlocks = 256
+
 // OrphanManage is use to handle all the orphan block
 type OrphanManage struct {
-	//TODO: add orphan cached block limit
 	orphan     map[bc.Hash]*legacy.Block
 	preOrphans map[bc.Hash][]*bc.Hash
 	mtx        sync.RWMutex
@@ -77,6 +90,14 @@ func (o *OrphanManage) Add(block *legacy.Block) {
 		return
 	}
 
+	if len(o.orphan) >= maxOrphanBlocks {
+		// evict an arbitrary entry to keep memory bounded
+		for victim := range o.orphan {
+			o.delete(&victim)
+			break
+		}
+	}
+
 	o.orphan[blockHash] = block
 	o.preOrphans[block.PreviousBlockHash] = append(o.preOrphans[block.PreviousBlockHash], &blockHash)
 }
@@ -85,6 +106,12 @@ func (o *OrphanManage) Add(block *legacy.Block) {
 func (o *OrphanManage) Delete(hash *bc.Hash) {
 	o.mtx.Lock()
 	defer o.mtx.Unlock()
+	o.delete(hash)
+}
+
+// delete removes the block and its preOrphans index entry. The caller
+// must hold the lock.
+func (o *OrphanManage) delete(hash *bc.Hash) {
 	block, ok := o.orphan[*hash]
 	if !ok {
 		return
@@ -98,7 +125,7 @@ func (o *OrphanManage) Delete(hash *bc.Hash) {
 	}
 
 	for i, preOrphan := range preOrphans {
-		if preOrphan == hash {
+		if *preOrphan == *hash {
 			o.preOrphans[block.PreviousBlockHash] = append(preOrphans[:i], preOrphans[i+1:]...)
 			return
 		}
@@ -124,6 +151,32 @@ type Chain struct {
 	orphanManage *OrphanManage
 	txPool       *TxPool
 
+	// sorted by height; see checkpoint.go
+	checkpoints    []Checkpoint
+	fullValidation bool
+
+	// see reorg.go
+	maxReorgDepth uint64
+	reorgHandler  func(*ReorgEvent)
+
+	// relay-time standardness check; see SetPolicyChecker
+	policyCheck func(*legacy.Tx, uint64) error
+
+	// see doublespend.go
+	doubleSpendHandler func(*DoubleSpendEvent)
+
+	// see prune.go
+	pruneDepth   uint64
+	prunedHeight uint64
+
+	// header-only (SPV) mode; see light.go
+	headerOnly bool
+
+	// soft fork deployments; see versionbits.go
+	deployments []Deployment
+	vbMtx       sync.Mutex
+	vbCache     map[thresholdKey]ThresholdState
+
 	state struct {
 		cond      sync.Cond
 		block     *legacy.Block
@@ -142,6 +195,7 @@ func NewChain(initialBlockHash bc.Hash, store Store, txPool *TxPool) (*Chain, er
 		store:            store,
 		txPool:           txPool,
 		seedCaches:       seed.NewSeedCaches(),
+		vbCache:          make(map[thresholdKey]ThresholdState),
 	}
 	c.state.cond.L = new(sync.Mutex)
 	storeStatus := store.GetStoreStatus()
@@ -225,6 +279,13 @@ func (c *Chain) SeedCaches() *seed.SeedCaches {
 	return c.seedCaches
 }
 
+// IsBlockRejected reports whether a block with the given hash has
+// already failed validation. Such blocks are never worth downloading
+// or validating again.
+func (c *Chain) IsBlockRejected(hash *bc.Hash) bool {
+	return c.store.IsBlockRejected(hash)
+}
+
 // GetUtxo try to find the utxo status in db
 func (c *Chain) GetUtxo(hash *bc.Hash) (*storage.UtxoEntry, error) {
 	return c.store.GetUtxo(hash)
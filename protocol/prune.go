@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// SetPruneDepth turns on pruned mode: once the main chain grows past
+// depth blocks, bodies of older blocks are discarded, keeping only
+// their headers and the UTXO set. A depth of 0 (the default) keeps the
+// node archival. The depth should comfortably exceed the max reorg
+// depth, since a reorganization cannot detach past a pruned body.
+func (c *Chain) SetPruneDepth(depth uint64) {
+	c.state.cond.L.Lock()
+	defer c.state.cond.L.Unlock()
+	c.pruneDepth = depth
+}
+
+// PruneDepth returns the configured prune depth, 0 meaning archival.
+func (c *Chain) PruneDepth() uint64 {
+	c.state.cond.L.Lock()
+	defer c.state.cond.L.Unlock()
+	return c.pruneDepth
+}
+
+// PrunedHeight returns the highest main chain height whose block body
+// has been discarded, or 0 if nothing has been pruned.
+func (c *Chain) PrunedHeight() uint64 {
+	c.state.cond.L.Lock()
+	defer c.state.cond.L.Unlock()
+	return c.prunedHeight
+}
+
+// IsBlockPruned reports whether only the header of the given block
+// remains on disk.
+func (c *Chain) IsBlockPruned(hash *bc.Hash) bool {
+	return c.store.IsBlockPruned(hash)
+}
+
+// GetHeaderByHash returns the header of the given block even when its
+// body has been pruned.
+func (c *Chain) GetHeaderByHash(hash *bc.Hash) (*legacy.BlockHeader, error) {
+	return c.store.GetBlockHeader(hash)
+}
+
+// GetHeaderByHeight returns the header of the main chain block at the
+// given height even when its body has been pruned.
+func (c *Chain) GetHeaderByHeight(height uint64) (*legacy.BlockHeader, error) {
+	c.state.cond.L.Lock()
+	hash, ok := c.state.mainChain[height]
+	c.state.cond.L.Unlock()
+	if !ok {
+		return nil, errors.New("can't find block in given hight")
+	}
+	return c.store.GetBlockHeader(hash)
+}
+
+// pruneToDepth discards the bodies of main chain blocks deeper than the
+// configured prune depth. The caller must hold the state lock.
+func (c *Chain) pruneToDepth() {
+	if c.pruneDepth == 0 || c.state.block == nil {
+		return
+	}
+	if c.state.block.Height <= c.pruneDepth {
+		return
+	}
+
+	target := c.state.block.Height - c.pruneDepth
+	// height 1 is the genesis block; keep its body intact
+	for height := c.prunedHeight + 1; height <= target; height++ {
+		if height == 1 {
+			continue
+		}
+		hash, ok := c.state.mainChain[height]
+		if !ok {
+			continue
+		}
+		if c.store.IsBlockPruned(hash) {
+			continue
+		}
+		if err := c.store.PruneBlock(hash); err != nil {
+			log.WithFields(log.Fields{"height": height, "error": err}).Error("Failed to prune block body")
+			return
+		}
+	}
+	c.prunedHeight = target
+}
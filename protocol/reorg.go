@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
+)
+
+// ErrReorgTooDeep is returned when adopting a side chain would rewind
+// more blocks than the configured max reorg depth allows.
+var ErrReorgTooDeep = errors.New("reorganization deeper than max reorg depth")
+
+// ReorgEvent describes a completed chain reorganization.
+type ReorgEvent struct {
+	OldTip bc.Hash
+	NewTip bc.Hash
+	// Depth is the number of blocks disconnected from the old chain.
+	Depth uint64
+	// DetachedTxs lists the non-coinbase transactions that fell off
+	// the old main chain, so wallets and services can unconfirm them.
+	DetachedTxs []bc.Hash
+}
+
+// SetMaxReorgDepth limits how many blocks a reorganization may rewind.
+// A side chain requiring a deeper rewind is refused, leaving the node
+// on its current chain until an operator intervenes. 0 means no limit.
+// It must be called before the chain starts processing blocks.
+func (c *Chain) SetMaxReorgDepth(depth uint64) {
+	c.maxReorgDepth = depth
+}
+
+// SetReorgHandler registers fn to be called after every completed
+// reorganization. It must be called before the chain starts processing
+// blocks.
+func (c *Chain) SetReorgHandler(fn func(*ReorgEvent)) {
+	c.reorgHandler = fn
+}
@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"sort"
+
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+)
+
+// Chain tip statuses, mirroring how far each branch got.
+const (
+	// the tip of the main chain
+	TipStatusActive = "active"
+	// a fully validated branch that lost to the main chain
+	TipStatusValidFork = "valid-fork"
+	// a block whose ancestry is still unknown; it may connect later
+	TipStatusOrphan = "orphan"
+	// a block that failed validation
+	TipStatusInvalid = "invalid"
+)
+
+// ChainTip is one known end of a block chain branch.
+type ChainTip struct {
+	Hash   bc.Hash
+	Height uint64
+	// Length is the number of blocks on the branch since it forked off
+	// the main chain. It is 0 for the active tip and for tips whose
+	// branch point is unknown.
+	Length uint64
+	Status string
+}
+
+// tips returns the orphan blocks no other orphan builds on.
+func (o *OrphanManage) tips() []*legacy.Block {
+	o.mtx.RLock()
+	defer o.mtx.RUnlock()
+
+	tips := []*legacy.Block{}
+	for hash, block := range o.orphan {
+		if len(o.preOrphans[hash]) > 0 {
+			continue
+		}
+		tips = append(tips, block)
+	}
+	return tips
+}
+
+// ChainTips lists the main chain tip and every known side branch tip:
+// validated forks stored on disk, orphans with unknown ancestry, and
+// blocks marked invalid. It reads every stored block header, so it is
+// an operator inspection call, not something to poll aggressively.
+func (c *Chain) ChainTips() ([]*ChainTip, error) {
+	hashes, err := c.store.ListBlockHashes()
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := map[bc.Hash]*legacy.Block{}
+	hasChild := map[bc.Hash]bool{}
+	for _, hash := range hashes {
+		block, err := c.store.GetBlock(&hash)
+		if err != nil {
+			return nil, err
+		}
+		blocks[hash] = block
+		hasChild[block.PreviousBlockHash] = true
+	}
+
+	tips := []*ChainTip{}
+	bestHash := *c.BestBlockHash()
+	for hash, block := range blocks {
+		if hasChild[hash] {
+			continue
+		}
+
+		if hash == bestHash {
+			tips = append(tips, &ChainTip{Hash: hash, Height: block.Height, Status: TipStatusActive})
+			continue
+		}
+		if c.InMainChain(block.Height, hash) {
+			// a main chain block can only be childless at the tip
+			continue
+		}
+
+		length := uint64(0)
+		for b := block; !c.InMainChain(b.Height, b.Hash()); {
+			length++
+			parent, ok := blocks[b.PreviousBlockHash]
+			if !ok {
+				break
+			}
+			b = parent
+		}
+		tips = append(tips, &ChainTip{Hash: hash, Height: block.Height, Length: length, Status: TipStatusValidFork})
+	}
+
+	for _, block := range c.orphanManage.tips() {
+		blockHash := block.Hash()
+		tips = append(tips, &ChainTip{Hash: blockHash, Height: block.Height, Status: TipStatusOrphan})
+	}
+
+	rejected, err := c.store.ListRejectedBlocks()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range rejected {
+		tips = append(tips, &ChainTip{Hash: entry.Hash, Height: entry.Height, Status: TipStatusInvalid})
+	}
+
+	sort.Slice(tips, func(i, j int) bool {
+		if tips[i].Height != tips[j].Height {
+			return tips[i].Height > tips[j].Height
+		}
+		return tips[i].Hash.String() < tips[j].Hash.String()
+	})
+	return tips, nil
+}
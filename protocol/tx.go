@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc"
 	"github.com/bytom/protocol/bc/legacy"
 	"github.com/bytom/protocol/validation"
 )
@@ -9,6 +10,18 @@ import (
 // ErrBadTx is returned for transactions failing validation
 var ErrBadTx = errors.New("invalid transaction")
 
+// ErrOrphanTx is returned when a transaction spends an output that
+// neither the chain state nor the pool knows about. The transaction is
+// held in the orphan pool and retried when its parents arrive.
+var ErrOrphanTx = errors.New("transaction references unknown parent output")
+
+// SetPolicyChecker installs the relay standardness check applied to
+// transactions entering the pool. Block validation is never subject to
+// it. It must be set before transactions start flowing in.
+func (c *Chain) SetPolicyChecker(fn func(tx *legacy.Tx, fee uint64) error) {
+	c.policyCheck = fn
+}
+
 // ValidateTx validates the given transaction. A cache holds
 // per-transaction validation results and is consulted before
 // performing full validation.
@@ -17,6 +30,27 @@ func (c *Chain) ValidateTx(tx *legacy.Tx) error {
 	if ok := c.txPool.HaveTransaction(&newTx.ID); ok {
 		return c.txPool.GetErrCache(&newTx.ID)
 	}
+	if c.txPool.IsOrphanInPool(&newTx.ID) {
+		return ErrOrphanTx
+	}
+
+	if missing := c.missingParents(tx); len(missing) > 0 {
+		c.txPool.AddOrphan(tx, missing)
+		return ErrOrphanTx
+	}
+
+	if conflictTxID, outputID := c.txPool.FindConflict(tx); conflictTxID != nil {
+		if c.doubleSpendHandler != nil {
+			c.doubleSpendHandler(&DoubleSpendEvent{
+				TxID:         newTx.ID,
+				ConflictTxID: *conflictTxID,
+				OutputID:     *outputID,
+			})
+		}
+		// left out of the error cache: the conflict may resolve if the
+		// rival transaction is evicted or fails to confirm
+		return errors.WithDetailf(ErrDoubleSpend, "output %s already spent by transaction %s", outputID.String(), conflictTxID.String())
+	}
 
 	oldBlock, err := c.GetBlockByHash(c.state.hash)
 	if err != nil {
@@ -30,6 +64,45 @@ func (c *Chain) ValidateTx(tx *legacy.Tx) error {
 		return err
 	}
 
-	c.txPool.AddTransaction(tx, block.BlockHeader.Height, fee)
-	return errors.Sub(ErrBadTx, err)
+	if c.policyCheck != nil {
+		if err := c.policyCheck(tx, fee); err != nil {
+			c.txPool.AddErrCache(&newTx.ID, err)
+			return err
+		}
+	}
+
+	if _, err := c.txPool.AddTransaction(tx, block.BlockHeader.Height, fee); err != nil {
+		// a full pool is not the transaction's fault; leave it out of
+		// the error cache so it can be resubmitted with a better fee
+		return err
+	}
+	c.processOrphanTxs(tx)
+	return nil
+}
+
+// missingParents returns the spent outputs of tx that neither the chain
+// state nor an unconfirmed pool transaction provides.
+func (c *Chain) missingParents(tx *legacy.Tx) []*bc.Hash {
+	missing := []*bc.Hash{}
+	for i, prevout := range tx.Tx.SpentOutputIDs {
+		if _, err := c.store.GetUtxo(&prevout); err == nil {
+			continue
+		}
+		if c.txPool.IsOutputInPool(&prevout) {
+			continue
+		}
+		missing = append(missing, &tx.Tx.SpentOutputIDs[i])
+	}
+	return missing
+}
+
+// processOrphanTxs retries the orphans waiting on outputs the newly
+// arrived transaction created. Any that are still unacceptable land
+// back in the orphan pool or the error cache.
+func (c *Chain) processOrphanTxs(tx *legacy.Tx) {
+	for _, resultID := range tx.Tx.ResultIds {
+		for _, orphan := range c.txPool.TakeOrphansByPrev(resultID) {
+			c.ValidateTx(orphan)
+		}
+	}
 }
@@ -0,0 +1,60 @@
+package validation
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+
+	"github.com/bytom/protocol/bc"
+)
+
+// maximum verified programs remembered. Each hit skips one VM run, so
+// transactions verified at mempool acceptance aren't re-verified when
+// their block arrives.
+const maxCachedVerifications = 10000
+
+var (
+	verifiedMtx      sync.Mutex
+	verifiedPrograms = lru.New(maxCachedVerifications)
+)
+
+// programCacheKey identifies one program run: the transaction (which
+// the signature hash and introspection opcodes depend on), the entry
+// being checked, its code and its witness arguments.
+func programCacheKey(txID, entryID *bc.Hash, prog *bc.Program, args [][]byte) [32]byte {
+	hasher := sha256.New()
+	hasher.Write(txID.Bytes())
+	hasher.Write(entryID.Bytes())
+	binary.Write(hasher, binary.LittleEndian, prog.VmVersion)
+	hasher.Write(prog.Code)
+	for _, arg := range args {
+		binary.Write(hasher, binary.LittleEndian, uint64(len(arg)))
+		hasher.Write(arg)
+	}
+
+	var key [32]byte
+	copy(key[:], hasher.Sum(nil))
+	return key
+}
+
+// lookupVerifiedProgram returns the gas a previously successful run of
+// the program consumed.
+func lookupVerifiedProgram(key [32]byte) (int64, bool) {
+	verifiedMtx.Lock()
+	defer verifiedMtx.Unlock()
+
+	if used, ok := verifiedPrograms.Get(key); ok {
+		return used.(int64), true
+	}
+	return 0, false
+}
+
+// cacheVerifiedProgram remembers a successful program run and the gas
+// it consumed.
+func cacheVerifiedProgram(key [32]byte, gasUsed int64) {
+	verifiedMtx.Lock()
+	defer verifiedMtx.Unlock()
+	verifiedPrograms.Add(key, gasUsed)
+}
@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"github.com/bytom/consensus"
+	"github.com/bytom/protocol/bc"
+)
+
+// InputDiagnosis is the outcome of running one input's witness program
+// in isolation, with its own gas budget, so a failure in one input does
+// not mask diagnostics for the others.
+type InputDiagnosis struct {
+	Index   int    `json:"index"`
+	Type    string `json:"type"`
+	GasUsed int64  `json:"gas_used"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TxDiagnosis is the full report of a diagnostic validation run: the
+// verdict full validation would reach, plus a per-input breakdown.
+type TxDiagnosis struct {
+	TxID    string            `json:"tx_id"`
+	Valid   bool              `json:"valid"`
+	Error   string            `json:"error,omitempty"`
+	Fee     uint64            `json:"fee"`
+	GasUsed int64             `json:"gas_used"`
+	Inputs  []*InputDiagnosis `json:"inputs"`
+}
+
+// DiagnoseTx validates a transaction the same way ValidateTx does, but
+// instead of stopping at the first failure it also re-runs every input's
+// witness program individually and reports each result. It never touches
+// chain state, so it is safe to run on arbitrary submitted transactions.
+func DiagnoseTx(tx *bc.Tx, block *bc.Block) *TxDiagnosis {
+	diagnosis := &TxDiagnosis{TxID: tx.ID.String()}
+	if tx.TxHeader.SerializedSize > consensus.MaxTxSize {
+		diagnosis.Error = errWrongTransactionSize.Error()
+		return diagnosis
+	}
+
+	vs := &validationState{
+		block:    block,
+		tx:       tx,
+		entryID:  tx.ID,
+		runProgs: true,
+		gas: &gasState{
+			gasLeft: consensus.ActiveNetParams.MaxBlockGas,
+		},
+		cache: make(map[bc.Hash]error),
+	}
+	err := checkValid(vs, tx.TxHeader)
+	diagnosis.Valid = err == nil
+	diagnosis.Fee = uint64(vs.gas.BTMValue)
+	diagnosis.GasUsed = vs.gas.gasUsed
+	if err != nil {
+		diagnosis.Error = err.Error()
+	}
+
+	for i, inputID := range tx.InputIDs {
+		entry, ok := tx.Entries[inputID]
+		if !ok {
+			continue
+		}
+
+		inputDiag := &InputDiagnosis{Index: i}
+		ivs := &validationState{
+			block:    block,
+			tx:       tx,
+			entryID:  inputID,
+			runProgs: true,
+			gas: &gasState{
+				gasLeft: consensus.ActiveNetParams.MaxBlockGas,
+			},
+			cache: make(map[bc.Hash]error),
+		}
+
+		var runErr error
+		switch e := entry.(type) {
+		case *bc.Spend:
+			inputDiag.Type = "spend"
+			spentOutput, err := tx.Output(*e.SpentOutputId)
+			if err != nil {
+				runErr = err
+				break
+			}
+			runErr = ivs.verifyProgram(e, spentOutput.ControlProgram, e.WitnessArguments)
+
+		case *bc.Issuance:
+			inputDiag.Type = "issuance"
+			runErr = ivs.verifyProgram(e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments)
+
+		case *bc.Coinbase:
+			inputDiag.Type = "coinbase"
+
+		default:
+			continue
+		}
+
+		inputDiag.GasUsed = ivs.gas.gasUsed
+		if runErr != nil {
+			inputDiag.Error = runErr.Error()
+		}
+		diagnosis.Inputs = append(diagnosis.Inputs, inputDiag)
+	}
+	return diagnosis
+}
@@ -2,9 +2,12 @@ package validation
 
 import (
 	"fmt"
+	"runtime"
+	"sync"
 
 	"github.com/bytom/consensus"
 	"github.com/bytom/consensus/algorithm"
+	"github.com/bytom/crypto/ed25519"
 	"github.com/bytom/errors"
 	"github.com/bytom/math/checked"
 	"github.com/bytom/protocol/bc"
@@ -13,8 +16,7 @@ import (
 )
 
 const (
-	defaultGasLimit = int64(80000)
-	muxGasCost      = int64(10)
+	muxGasCost = int64(10)
 	// GasRate indicates the current gas rate
 	GasRate = int64(1000)
 )
@@ -34,7 +36,7 @@ func (g *gasState) setGas(BTMValue int64) error {
 	if gasAmount, ok := checked.DivInt64(BTMValue, GasRate); ok {
 		if gasAmount == 0 {
 			g.gasLeft = muxGasCost
-		} else if gasAmount < defaultGasLimit {
+		} else if gasAmount < consensus.ActiveNetParams.MaxBlockGas {
 			g.gasLeft = gasAmount
 		}
 	} else {
@@ -77,9 +79,66 @@ type validationState struct {
 	// Memoized per-entry validation results
 	cache map[bc.Hash]error
 
+	// Whether witness programs are executed. Disabled for blocks
+	// buried under a trusted checkpoint during initial sync.
+	runProgs bool
+
+	// Deferred signature checks collected across the whole
+	// transaction for batch verification; nil verifies them inline.
+	sigs *sigBatch
+
 	gas *gasState
 }
 
+// sigBatch collects the ed25519 checks the VM defers during a
+// transaction run, so they can be verified as one batch afterwards.
+type sigBatch struct {
+	pubkeys  []ed25519.PublicKey
+	messages [][]byte
+	sigs     [][]byte
+}
+
+func (b *sigBatch) add(pubkey, msg, sig []byte) {
+	b.pubkeys = append(b.pubkeys, ed25519.PublicKey(pubkey))
+	b.messages = append(b.messages, msg)
+	b.sigs = append(b.sigs, sig)
+}
+
+func (b *sigBatch) verify() bool {
+	return ed25519.VerifyBatch(b.pubkeys, b.messages, b.sigs)
+}
+
+// verifyProgram runs the given program in the VM and charges its gas.
+// It is a no-op when program checks are disabled for this validation.
+func (vs *validationState) verifyProgram(e bc.Entry, prog *bc.Program, args [][]byte) error {
+	if !vs.runProgs {
+		return nil
+	}
+
+	// A program that already verified - typically at mempool acceptance -
+	// only needs its gas charged again.
+	key := programCacheKey(&vs.tx.ID, &vs.entryID, prog, args)
+	if gasUsed, ok := lookupVerifiedProgram(key); ok && gasUsed <= vs.gas.gasLeft {
+		return vs.gas.updateUsage(vs.gas.gasLeft - gasUsed)
+	}
+
+	gasLimit := vs.gas.gasLeft
+	deferred := 0
+	if vs.sigs != nil {
+		deferred = len(vs.sigs.sigs)
+	}
+	gasLeft, err := vm.Verify(NewTxVMContext(vs, e, prog, args), gasLimit)
+	if err != nil {
+		return err
+	}
+	// A run that deferred signature checks has only provisionally
+	// passed; it must not be cached until the batch confirms it.
+	if vs.sigs == nil || len(vs.sigs.sigs) == deferred {
+		cacheVerifiedProgram(key, gasLimit-gasLeft)
+	}
+	return vs.gas.updateUsage(gasLeft)
+}
+
 var (
 	errGasCalculate             = errors.New("gas usage calculate got a math error")
 	errEmptyResults             = errors.New("transaction has no results")
@@ -196,13 +255,9 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 			}
 		}
 
-		gasLeft, err := vm.Verify(NewTxVMContext(vs, e, e.Program, e.WitnessArguments), vs.gas.gasLeft)
-		if err != nil {
+		if err := vs.verifyProgram(e, e.Program, e.WitnessArguments); err != nil {
 			return errors.Wrap(err, "checking mux program")
 		}
-		if err = vs.gas.updateUsage(gasLeft); err != nil {
-			return err
-		}
 
 		for i, src := range e.Sources {
 			vs2 := *vs
@@ -227,13 +282,9 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 
 	case *bc.Nonce:
 		//TODO: add block heigh range check on the control program
-		gasLeft, err := vm.Verify(NewTxVMContext(vs, e, e.Program, e.WitnessArguments), vs.gas.gasLeft)
-		if err != nil {
+		if err := vs.verifyProgram(e, e.Program, e.WitnessArguments); err != nil {
 			return errors.Wrap(err, "checking nonce program")
 		}
-		if err = vs.gas.updateUsage(gasLeft); err != nil {
-			return err
-		}
 
 		if vs.tx.Version == 1 && e.ExtHash != nil && !e.ExtHash.IsZero() {
 			return errNonemptyExtHash
@@ -274,13 +325,9 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 			return errors.Wrapf(bc.ErrMissingEntry, "entry for issuance anchor %x not found", e.AnchorId.Bytes())
 		}
 
-		gasLeft, err := vm.Verify(NewTxVMContext(vs, e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments), vs.gas.gasLeft)
-		if err != nil {
+		if err := vs.verifyProgram(e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments); err != nil {
 			return errors.Wrap(err, "checking issuance program")
 		}
-		if err = vs.gas.updateUsage(gasLeft); err != nil {
-			return err
-		}
 
 		var anchored *bc.Hash
 		switch a := anchor.(type) {
@@ -327,13 +374,9 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 		if err != nil {
 			return errors.Wrap(err, "getting spend prevout")
 		}
-		gasLeft, err := vm.Verify(NewTxVMContext(vs, e, spentOutput.ControlProgram, e.WitnessArguments), vs.gas.gasLeft)
-		if err != nil {
+		if err := vs.verifyProgram(e, spentOutput.ControlProgram, e.WitnessArguments); err != nil {
 			return errors.Wrap(err, "checking control program")
 		}
-		if err = vs.gas.updateUsage(gasLeft); err != nil {
-			return err
-		}
 
 		eq, err := spentOutput.Source.Value.Equal(e.WitnessDestination.Value)
 		if err != nil {
@@ -496,9 +539,11 @@ func checkValidDest(vs *validationState, vd *bc.ValueDestination) error {
 	return nil
 }
 
-// ValidateBlock validates a block and the transactions within.
-// It does not run the consensus program; for that, see ValidateBlockSig.
-func ValidateBlock(b, prev *bc.Block, seedCaches *seed.SeedCaches) error {
+// ValidateBlockHeader validates the consensus fields a header carries
+// on its own: linkage to the previous block, size and proof of work.
+// It is everything a light node can check without the block body;
+// transaction, coinbase and merkle root validation need the body.
+func ValidateBlockHeader(b, prev *bc.Block, seedCaches *seed.SeedCaches) error {
 	if b.Height > 1 {
 		if prev == nil {
 			return errors.WithDetailf(errNoPrevBlock, "height %d", b.Height)
@@ -524,18 +569,25 @@ func ValidateBlock(b, prev *bc.Block, seedCaches *seed.SeedCaches) error {
 	if !consensus.CheckProofOfWork(proofHash, b.BlockHeader.Bits) {
 		return errWorkProof
 	}
+	return nil
+}
 
-	coinbaseValue := consensus.BlockSubsidy(b.BlockHeader.Height)
-	for i, tx := range b.Transactions {
-		if b.Version == 1 && tx.Version != 1 {
-			return errors.WithDetailf(errTxVersion, "block version %d, transaction version %d", b.Version, tx.Version)
-		}
+// ValidateBlock validates a block and the transactions within.
+// It does not run the consensus program; for that, see ValidateBlockSig.
+// Witness programs are skipped when runProgs is false, which is only
+// safe for blocks buried under a trusted checkpoint.
+func ValidateBlock(b, prev *bc.Block, seedCaches *seed.SeedCaches, runProgs bool) error {
+	if err := ValidateBlockHeader(b, prev, seedCaches); err != nil {
+		return err
+	}
 
-		txBTMValue, err := ValidateTx(tx, b)
-		if err != nil {
-			return errors.Wrapf(err, "validity of transaction %d of %d", i, len(b.Transactions))
-		}
-		coinbaseValue += txBTMValue
+	coinbaseValue := consensus.BlockSubsidy(b.BlockHeader.Height)
+	fees, err := validateTxs(b, runProgs)
+	if err != nil {
+		return err
+	}
+	for _, fee := range fees {
+		coinbaseValue += fee
 	}
 
 	// check the coinbase output entry value
@@ -555,6 +607,57 @@ func ValidateBlock(b, prev *bc.Block, seedCaches *seed.SeedCaches) error {
 	return nil
 }
 
+// validateTxs checks every transaction of the block, fanning the work
+// out over one worker per CPU. Each transaction only reads shared state
+// at this stage - in-block spend dependencies are resolved later against
+// the UTXO view - so they can be verified concurrently. The returned
+// fees are indexed like b.Transactions.
+func validateTxs(b *bc.Block, runProgs bool) ([]uint64, error) {
+	txCount := len(b.Transactions)
+	fees := make([]uint64, txCount)
+	errs := make([]error, txCount)
+
+	workers := runtime.NumCPU()
+	if workers > txCount {
+		workers = txCount
+	}
+
+	jobs := make(chan int, txCount)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				tx := b.Transactions[i]
+				if b.Version == 1 && tx.Version != 1 {
+					errs[i] = errors.WithDetailf(errTxVersion, "block version %d, transaction version %d", b.Version, tx.Version)
+					continue
+				}
+				fee, err := validateTx(tx, b, runProgs)
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "validity of transaction %d of %d", i, txCount)
+					continue
+				}
+				fees[i] = fee
+			}
+		}()
+	}
+	for i := 0; i < txCount; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Keep the error the serial loop would have reported first.
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fees, nil
+}
+
 func validateCoinbase(tx *bc.Tx, value uint64) error {
 	resultEntry := tx.Entries[*tx.TxHeader.ResultIds[0]]
 	output, ok := resultEntry.(*bc.Output)
@@ -592,17 +695,38 @@ func validateBlockAgainstPrev(b, prev *bc.Block) error {
 
 // ValidateTx validates a transaction.
 func ValidateTx(tx *bc.Tx, block *bc.Block) (uint64, error) {
+	return validateTx(tx, block, true)
+}
+
+func validateTx(tx *bc.Tx, block *bc.Block, runProgs bool) (uint64, error) {
 	if tx.TxHeader.SerializedSize > consensus.MaxTxSize {
 		return 0, errWrongTransactionSize
 	}
 
+	// Fast path: run the transaction with signature checks deferred and
+	// verify them as one batch. Assuming a check true can only let a
+	// program pass that would have failed, so both a batch failure and
+	// an error force the authoritative inline run below, which reports
+	// the real result.
+	if runProgs {
+		sigs := &sigBatch{}
+		if fee, err := checkTx(tx, block, true, sigs); err == nil && sigs.verify() {
+			return fee, nil
+		}
+	}
+	return checkTx(tx, block, runProgs, nil)
+}
+
+func checkTx(tx *bc.Tx, block *bc.Block, runProgs bool, sigs *sigBatch) (uint64, error) {
 	//TODO: handle the gas limit
 	vs := &validationState{
-		block:   block,
-		tx:      tx,
-		entryID: tx.ID,
+		block:    block,
+		tx:       tx,
+		entryID:  tx.ID,
+		runProgs: runProgs,
+		sigs:     sigs,
 		gas: &gasState{
-			gasLeft: defaultGasLimit,
+			gasLeft: consensus.ActiveNetParams.MaxBlockGas,
 		},
 		cache: make(map[bc.Hash]error),
 	}
@@ -63,12 +63,12 @@ func TestGasStatus(t *testing.T) {
 		},
 		{
 			input: &gasState{
-				gasLeft:  defaultGasLimit,
+				gasLeft:  consensus.ActiveNetParams.MaxBlockGas,
 				gasUsed:  0,
 				BTMValue: 0,
 			},
 			output: &gasState{
-				gasLeft:  defaultGasLimit,
+				gasLeft:  consensus.ActiveNetParams.MaxBlockGas,
 				gasUsed:  0,
 				BTMValue: 80000000000,
 			},
@@ -380,9 +380,10 @@ func TestTxValidation(t *testing.T) {
 			fixture = sample(t, nil)
 			tx = legacy.NewTx(*fixture.tx).Tx
 			vs = &validationState{
-				block:   mockBlock(),
-				tx:      tx,
-				entryID: tx.ID,
+				block:    mockBlock(),
+				tx:       tx,
+				entryID:  tx.ID,
+				runProgs: true,
 				gas: &gasState{
 					gasLeft: int64(80000),
 					gasUsed: 0,
@@ -449,7 +450,7 @@ func TestValidateBlock(t *testing.T) {
 		}
 		c.block.TransactionsRoot = &txRoot
 
-		if err = ValidateBlock(c.block, nil, seedCaches); rootErr(err) != c.err {
+		if err = ValidateBlock(c.block, nil, seedCaches, true); rootErr(err) != c.err {
 			t.Errorf("got error %s, want %s", err, c.err)
 		}
 	}
@@ -566,9 +567,10 @@ type txFixture struct {
 // subsequent call to make iterative refinements to a test object.
 //
 // The default transaction produced is valid and has three inputs:
-//  - an issuance of 10 units
-//  - a spend of 20 units
-//  - a spend of 40 units
+//   - an issuance of 10 units
+//   - a spend of 20 units
+//   - a spend of 40 units
+//
 // and two outputs, one of 25 units and one of 45 units.
 // All amounts are denominated in the same asset.
 //
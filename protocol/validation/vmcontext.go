@@ -108,6 +108,9 @@ func NewTxVMContext(vs *validationState, entry bc.Entry, prog *bc.Program, args
 		SpentOutputID: spentOutputID,
 		CheckOutput:   ec.checkOutput,
 	}
+	if vs.sigs != nil {
+		result.DeferSigVerify = vs.sigs.add
+	}
 
 	return result
 }
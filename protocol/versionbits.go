@@ -0,0 +1,189 @@
+package protocol
+
+import (
+	"github.com/bytom/consensus"
+	"github.com/bytom/errors"
+)
+
+// ErrUnknownDeployment is returned when querying a deployment that was
+// never registered with the chain.
+var ErrUnknownDeployment = errors.New("unknown deployment")
+
+// ThresholdState is the activation state of one soft fork deployment.
+type ThresholdState int
+
+// The state machine a deployment moves through. Transitions happen only
+// on signaling window boundaries, so every node derives the same state
+// from the chain alone.
+const (
+	// ThresholdDefined is the initial state before the start height.
+	ThresholdDefined ThresholdState = iota
+	// ThresholdStarted means miners are signaling readiness.
+	ThresholdStarted
+	// ThresholdLockedIn means enough miners signaled in a full window;
+	// the rules activate at the next window boundary.
+	ThresholdLockedIn
+	// ThresholdActive means the new rules are in force.
+	ThresholdActive
+	// ThresholdFailed means the deployment timed out without locking in.
+	ThresholdFailed
+)
+
+func (s ThresholdState) String() string {
+	switch s {
+	case ThresholdDefined:
+		return "defined"
+	case ThresholdStarted:
+		return "started"
+	case ThresholdLockedIn:
+		return "locked_in"
+	case ThresholdActive:
+		return "active"
+	case ThresholdFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// Deployment defines one version-bits soft fork: miners signal
+// readiness by setting Bit in the block version between StartHeight
+// and TimeoutHeight.
+type Deployment struct {
+	Name          string
+	Bit           uint8
+	StartHeight   uint64
+	TimeoutHeight uint64
+}
+
+// Deployments shipped with the release, keyed by the chain ID carried
+// in the genesis doc, following the same pattern as the built-in
+// checkpoints.
+var defaultDeployments = map[string][]Deployment{
+	"mainnet": {},
+	"testnet": {},
+}
+
+// DefaultDeployments returns the soft fork deployments shipped with
+// the release for the given chain ID.
+func DefaultDeployments(chainID string) []Deployment {
+	return defaultDeployments[chainID]
+}
+
+// AddDeployments registers soft fork deployments with the chain. It
+// must be called before the chain starts processing blocks.
+func (c *Chain) AddDeployments(ds []Deployment) {
+	c.deployments = append(c.deployments, ds...)
+}
+
+// Deployments returns the registered soft fork deployments.
+func (c *Chain) Deployments() []Deployment {
+	return c.deployments
+}
+
+// ThresholdState returns the activation state of the named deployment
+// at the current tip.
+func (c *Chain) ThresholdState(name string) (ThresholdState, error) {
+	for i := range c.deployments {
+		if c.deployments[i].Name == name {
+			return c.deploymentState(&c.deployments[i], c.Height()), nil
+		}
+	}
+	return ThresholdFailed, ErrUnknownDeployment
+}
+
+// IsDeploymentActive reports whether the named deployment's rules are
+// in force at the current tip. Validation code gates new rules on it.
+func (c *Chain) IsDeploymentActive(name string) bool {
+	state, err := c.ThresholdState(name)
+	return err == nil && state == ThresholdActive
+}
+
+type thresholdKey struct {
+	name     string
+	boundary uint64
+}
+
+// deploymentState replays the deployment state machine over every
+// completed signaling window up to height. Window results are cached,
+// so steady-state queries only evaluate the newest window.
+func (c *Chain) deploymentState(d *Deployment, height uint64) ThresholdState {
+	window := consensus.ActiveNetParams.MinerConfirmationWindow
+	threshold := consensus.ActiveNetParams.RuleChangeActivationThreshold
+
+	c.vbMtx.Lock()
+	defer c.vbMtx.Unlock()
+
+	state := ThresholdDefined
+	for boundary := window; boundary <= height; boundary += window {
+		key := thresholdKey{name: d.Name, boundary: boundary}
+		if cached, ok := c.vbCache[key]; ok {
+			state = cached
+			continue
+		}
+
+		switch state {
+		case ThresholdDefined:
+			if boundary >= d.TimeoutHeight {
+				state = ThresholdFailed
+			} else if boundary >= d.StartHeight {
+				state = ThresholdStarted
+			}
+		case ThresholdStarted:
+			if boundary >= d.TimeoutHeight {
+				state = ThresholdFailed
+			} else if c.countSignals(d, boundary-window, boundary) >= threshold {
+				state = ThresholdLockedIn
+			}
+		case ThresholdLockedIn:
+			state = ThresholdActive
+		}
+		c.vbCache[key] = state
+	}
+	return state
+}
+
+// invalidateThresholdCache drops the cached results of signaling
+// windows whose boundary lies above forkHeight. A reorganization past
+// a window boundary changes which blocks the window counted, so the
+// states derived from the old branch must not outlive it.
+func (c *Chain) invalidateThresholdCache(forkHeight uint64) {
+	c.vbMtx.Lock()
+	defer c.vbMtx.Unlock()
+	for key := range c.vbCache {
+		if key.boundary > forkHeight {
+			delete(c.vbCache, key)
+		}
+	}
+}
+
+// countSignals counts the blocks in [from, to) whose version sets the
+// deployment's signal bit.
+func (c *Chain) countSignals(d *Deployment, from, to uint64) uint64 {
+	count := uint64(0)
+	for h := from; h < to; h++ {
+		block, err := c.GetBlockByHeight(h)
+		if err != nil {
+			continue
+		}
+		if block.Version&(uint64(1)<<d.Bit) != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// ComputeBlockVersion returns the version a newly mined block should
+// carry: the base version with the signal bit set for every deployment
+// currently in its signaling period.
+func (c *Chain) ComputeBlockVersion() uint64 {
+	version := uint64(1)
+	height := c.Height()
+	for i := range c.deployments {
+		d := &c.deployments[i]
+		switch c.deploymentState(d, height) {
+		case ThresholdStarted, ThresholdLockedIn:
+			version |= uint64(1) << d.Bit
+		}
+	}
+	return version
+}
@@ -35,4 +35,11 @@ type Context struct {
 
 	TxSigHash   func() []byte
 	CheckOutput func(index uint64, data []byte, amount uint64, assetID []byte, vmVersion uint64, code []byte, expansion bool) (bool, error)
+
+	// DeferSigVerify, when set, queues an ed25519 (pubkey, msg, sig)
+	// check for batch verification instead of running it inline; the
+	// opcode then assumes the check passes. Only checks whose results
+	// must all be true anyway are deferred, and the caller must run
+	// the batch afterwards and re-verify without deferral if it fails.
+	DeferSigVerify func(pubkey, msg, sig []byte)
 }
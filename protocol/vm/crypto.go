@@ -62,6 +62,10 @@ func opCheckSig(vm *virtualMachine) error {
 	if len(pubkeyBytes) != ed25519.PublicKeySize {
 		return vm.pushBool(false, true)
 	}
+	if vm.context != nil && vm.context.DeferSigVerify != nil {
+		vm.context.DeferSigVerify(pubkeyBytes, msg, sig)
+		return vm.pushBool(true, true)
+	}
 	return vm.pushBool(ed25519.Verify(ed25519.PublicKey(pubkeyBytes), msg, sig), true)
 }
 
@@ -117,6 +121,16 @@ func opCheckMultiSig(vm *virtualMachine) error {
 		pubkeys = append(pubkeys, ed25519.PublicKey(p))
 	}
 
+	// When there are as many signatures as pubkeys there is no trial
+	// matching to do - every signature must verify against its pubkey -
+	// so the checks can be deferred to the caller's batch.
+	if vm.context != nil && vm.context.DeferSigVerify != nil && len(sigs) == len(pubkeys) {
+		for i := range sigs {
+			vm.context.DeferSigVerify(pubkeys[i], msg, sigs[i])
+		}
+		return vm.pushBool(true, true)
+	}
+
 	for len(sigs) > 0 && len(pubkeys) > 0 {
 		if ed25519.Verify(pubkeys[0], msg, sigs[0]) {
 			sigs = sigs[1:]
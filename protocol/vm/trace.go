@@ -0,0 +1,70 @@
+package vm
+
+import (
+	"github.com/bytom/errors"
+)
+
+// TraceStep records the machine state after one executed instruction.
+type TraceStep struct {
+	PC      uint32
+	Op      Op
+	Data    []byte
+	GasCost int64
+	GasLeft int64
+	Stack   [][]byte
+}
+
+// Trace executes the program in the context just like Verify, but
+// records every executed instruction together with the stack and gas
+// state after it. The recorded steps are returned even when execution
+// fails, so callers can see exactly where a program went wrong.
+func Trace(context *Context, gasLimit int64) (steps []*TraceStep, gasLeft int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rErr, ok := r.(error); ok {
+				err = errors.Sub(ErrUnexpected, rErr)
+			} else {
+				err = errors.Wrap(ErrUnexpected, r)
+			}
+		}
+	}()
+
+	if context.VMVersion != 1 {
+		return nil, gasLimit, ErrUnsupportedVM
+	}
+
+	vm := &virtualMachine{
+		expansionReserved: context.TxVersion != nil && *context.TxVersion == 1,
+		program:           context.Code,
+		runLimit:          gasLimit,
+		context:           context,
+	}
+	vm.onStep = func(pc uint32, inst Instruction, cost int64) {
+		stack := make([][]byte, len(vm.dataStack))
+		for i, data := range vm.dataStack {
+			stack[i] = append([]byte{}, data...)
+		}
+		steps = append(steps, &TraceStep{
+			PC:      pc,
+			Op:      inst.Op,
+			Data:    inst.Data,
+			GasCost: cost,
+			GasLeft: vm.runLimit,
+			Stack:   stack,
+		})
+	}
+
+	args := context.Arguments
+	for i, arg := range args {
+		if err = vm.push(arg, false); err != nil {
+			return steps, vm.runLimit, errors.Wrapf(err, "pushing initial argument %d", i)
+		}
+	}
+
+	err = vm.run()
+	if err == nil && vm.falseResult() {
+		err = ErrFalseVMResult
+	}
+
+	return steps, vm.runLimit, wrapErr(err, vm, args)
+}
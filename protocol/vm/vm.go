@@ -29,6 +29,10 @@ type virtualMachine struct {
 	// In each of these stacks, stack[len(stack)-1] is the top element.
 	dataStack [][]byte
 	altStack  [][]byte
+
+	// onStep - if non-nil - receives a structured callback after each
+	// executed instruction. Used by Trace.
+	onStep func(pc uint32, inst Instruction, cost int64)
 }
 
 // TraceOut - if non-nil - will receive trace output during
@@ -114,6 +118,7 @@ func (vm *virtualMachine) step() error {
 		return vm.applyCost(1)
 	}
 
+	startLimit := vm.runLimit
 	vm.deferredCost = 0
 	vm.data = inst.Data
 	err = ops[inst.Op].fn(vm)
@@ -124,6 +129,9 @@ func (vm *virtualMachine) step() error {
 	if err != nil {
 		return err
 	}
+	if vm.onStep != nil {
+		vm.onStep(vm.pc, inst, startLimit-vm.runLimit)
+	}
 	vm.pc = vm.nextPC
 
 	if TraceOut != nil {
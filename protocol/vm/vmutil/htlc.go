@@ -0,0 +1,125 @@
+package vmutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/bytom/crypto/ed25519"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/vm"
+)
+
+// ErrBadHTLC means the program does not have the HTLC layout.
+var ErrBadHTLC = errors.New("bad htlc program format")
+
+// HTLCParams are the terms of a hashed-timelock contract: the recipient
+// can spend with the SHA-256 preimage of Hash before the sender becomes
+// able to refund at TimeoutHeight.
+type HTLCParams struct {
+	SenderPubkey    ed25519.PublicKey
+	RecipientPubkey ed25519.PublicKey
+	Hash            [32]byte
+	TimeoutHeight   uint64
+}
+
+// P2HTLCProgram generates the control program for a hashed-timelock
+// contract. It has two clauses selected by the last witness argument:
+//
+//	redeem, witness [sig preimage 1]: requires SHA256(preimage) == hash
+//	  and a recipient signature over the transaction sighash
+//	refund, witness [sig 0]: requires the block height to have reached
+//	  the timeout and a sender signature over the transaction sighash
+func P2HTLCProgram(params HTLCParams) ([]byte, error) {
+	if len(params.SenderPubkey) != ed25519.PublicKeySize || len(params.RecipientPubkey) != ed25519.PublicKeySize {
+		return nil, errors.WithDetail(ErrBadValue, "htlc pubkeys must be 32-byte ed25519 keys")
+	}
+	if params.TimeoutHeight == 0 {
+		return nil, errors.WithDetail(ErrBadValue, "htlc timeout height must be positive")
+	}
+
+	builder := NewBuilder()
+	redeem := builder.NewJumpTarget()
+	end := builder.NewJumpTarget()
+
+	builder.AddJumpIf(redeem)
+	// refund clause
+	builder.AddOp(vm.OP_BLOCKHEIGHT)
+	builder.AddInt64(int64(params.TimeoutHeight))
+	builder.AddOp(vm.OP_GREATERTHANOREQUAL).AddOp(vm.OP_VERIFY)
+	builder.AddOp(vm.OP_TXSIGHASH)
+	builder.AddData(params.SenderPubkey)
+	builder.AddOp(vm.OP_CHECKSIG)
+	builder.AddJump(end)
+	// redeem clause
+	builder.SetJumpTarget(redeem)
+	builder.AddOp(vm.OP_SHA256)
+	builder.AddData(params.Hash[:])
+	builder.AddOp(vm.OP_EQUALVERIFY)
+	builder.AddOp(vm.OP_TXSIGHASH)
+	builder.AddData(params.RecipientPubkey)
+	builder.AddOp(vm.OP_CHECKSIG)
+	builder.SetJumpTarget(end)
+
+	return builder.Build()
+}
+
+// the opcode sequence P2HTLCProgram emits; parsing pattern-matches it
+var htlcShape = []vm.Op{
+	vm.OP_JUMPIF,
+	vm.OP_BLOCKHEIGHT, 0, vm.OP_GREATERTHANOREQUAL, vm.OP_VERIFY,
+	vm.OP_TXSIGHASH, 0, vm.OP_CHECKSIG,
+	vm.OP_JUMP,
+	vm.OP_SHA256, 0, vm.OP_EQUALVERIFY,
+	vm.OP_TXSIGHASH, 0, vm.OP_CHECKSIG,
+}
+
+// ParseP2HTLCProgram recovers the contract terms from a program built
+// by P2HTLCProgram, or ErrBadHTLC when the program has another shape.
+func ParseP2HTLCProgram(program []byte) (*HTLCParams, error) {
+	pops, err := vm.ParseProgram(program)
+	if err != nil {
+		return nil, err
+	}
+	if len(pops) != len(htlcShape) {
+		return nil, ErrBadHTLC
+	}
+	for i, op := range htlcShape {
+		// the zero entries are data pushes, checked below
+		if op != 0 && pops[i].Op != op {
+			return nil, ErrBadHTLC
+		}
+	}
+
+	timeout, err := vm.AsInt64(pops[2].Data)
+	if err != nil || timeout <= 0 {
+		return nil, ErrBadHTLC
+	}
+	senderPubkey := pops[6].Data
+	hash := pops[10].Data
+	recipientPubkey := pops[13].Data
+	if len(senderPubkey) != ed25519.PublicKeySize || len(recipientPubkey) != ed25519.PublicKeySize || len(hash) != sha256.Size {
+		return nil, ErrBadHTLC
+	}
+
+	params := &HTLCParams{
+		SenderPubkey:    ed25519.PublicKey(senderPubkey),
+		RecipientPubkey: ed25519.PublicKey(recipientPubkey),
+		TimeoutHeight:   uint64(timeout),
+	}
+	copy(params.Hash[:], hash)
+
+	// rebuild to reject programs that decode to the same instructions
+	// but pad the jump targets or push encodings
+	rebuilt, err := P2HTLCProgram(*params)
+	if err != nil || !bytes.Equal(rebuilt, program) {
+		return nil, ErrBadHTLC
+	}
+	return params, nil
+}
+
+// IsHTLCProgram reports whether the program is a hashed-timelock
+// contract built by P2HTLCProgram.
+func IsHTLCProgram(program []byte) bool {
+	_, err := ParseP2HTLCProgram(program)
+	return err == nil
+}
@@ -0,0 +1,62 @@
+package vmutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/bytom/crypto/ed25519"
+)
+
+func TestP2HTLCProgramRoundTrip(t *testing.T) {
+	senderPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := HTLCParams{
+		SenderPubkey:    senderPub,
+		RecipientPubkey: recipientPub,
+		Hash:            sha256.Sum256([]byte("preimage")),
+		TimeoutHeight:   12345,
+	}
+	prog, err := P2HTLCProgram(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHTLCProgram(prog) {
+		t.Error("IsHTLCProgram should recognize a built htlc program")
+	}
+
+	got, err := ParseP2HTLCProgram(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.SenderPubkey, want.SenderPubkey) ||
+		!bytes.Equal(got.RecipientPubkey, want.RecipientPubkey) ||
+		got.Hash != want.Hash ||
+		got.TimeoutHeight != want.TimeoutHeight {
+		t.Errorf("round trip mismatch: got %+v want %+v", got, want)
+	}
+}
+
+func TestParseP2HTLCProgramRejectsOthers(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2sp, err := P2SPMultiSigProgram([]ed25519.PublicKey{pub}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prog := range [][]byte{nil, {0x51}, p2sp} {
+		if IsHTLCProgram(prog) {
+			t.Errorf("IsHTLCProgram(%x) should be false", prog)
+		}
+	}
+}
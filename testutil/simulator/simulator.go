@@ -0,0 +1,261 @@
+// Package simulator generates deterministic chains for integration
+// tests. A Simulator runs a real Chain against an in-memory store on
+// the regtest parameters, so blocks are minable instantly and every
+// run produces byte-identical blocks: timestamps advance on a scripted
+// clock, nonces are solved from zero, and transactions are whatever
+// the test passes in. The regtest genesis output is controlled by
+// OP_TRUE, so tests can script spends without keys or signatures.
+package simulator
+
+import (
+	"time"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/bytom/blockchain/txdb"
+	"github.com/bytom/config"
+	"github.com/bytom/consensus"
+	"github.com/bytom/consensus/algorithm"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol"
+	"github.com/bytom/protocol/bc"
+	"github.com/bytom/protocol/bc/legacy"
+	"github.com/bytom/protocol/validation"
+	"github.com/bytom/protocol/vm/vmutil"
+)
+
+// Simulator drives a deterministic chain. The embedded Chain, Store and
+// TxPool are real; only the clock and the proof of work are scripted.
+type Simulator struct {
+	Chain   *protocol.Chain
+	Store   *txdb.Store
+	TxPool  *protocol.TxPool
+	Genesis *legacy.Block
+
+	now  uint64 // timestamp of the next block, in ms
+	step uint64 // simulated time between blocks, in ms
+}
+
+// New creates a simulator on a fresh in-memory chain. It selects the
+// regtest consensus parameters process-wide, like a regtest node does
+// at startup.
+func New() (*Simulator, error) {
+	consensus.SelectNetParams("regtest")
+	if err := config.ApplyRegtestGenesis(nil); err != nil {
+		return nil, err
+	}
+
+	store := txdb.NewStore(dbm.NewMemDB())
+	txPool := protocol.NewTxPool()
+	genesis := config.GenerateGenesisBlock()
+
+	chain, err := protocol.NewChain(genesis.Hash(), store, txPool)
+	if err != nil {
+		return nil, err
+	}
+	if err := chain.SaveBlock(genesis); err != nil {
+		return nil, err
+	}
+	if err := chain.ConnectBlock(genesis); err != nil {
+		return nil, err
+	}
+
+	step := consensus.ActiveNetParams.TargetSecondsPerBlock * 1000
+	return &Simulator{
+		Chain:   chain,
+		Store:   store,
+		TxPool:  txPool,
+		Genesis: genesis,
+		now:     genesis.TimestampMS + step,
+		step:    step,
+	}, nil
+}
+
+// AdvanceTime moves the simulated clock forward, shifting the timestamp
+// of every block generated afterwards.
+func (s *Simulator) AdvanceTime(d time.Duration) {
+	s.now += uint64(d / time.Millisecond)
+}
+
+// GenerateBlock builds, solves and processes the next block on the best
+// chain tip, containing exactly the given transactions after the
+// coinbase.
+func (s *Simulator) GenerateBlock(txs ...*legacy.Tx) (*legacy.Block, error) {
+	return s.GenerateBlockOn(s.Chain.BestBlock(), txs...)
+}
+
+// GenerateBlocks extends the best chain by n empty blocks.
+func (s *Simulator) GenerateBlocks(n int) ([]*legacy.Block, error) {
+	blocks := make([]*legacy.Block, 0, n)
+	for i := 0; i < n; i++ {
+		block, err := s.GenerateBlock()
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// GenerateBlockOn builds, solves and processes a block on the given
+// parent, which need not be the best tip — building on an older block
+// scripts a fork.
+func (s *Simulator) GenerateBlockOn(parent *legacy.Block, txs ...*legacy.Tx) (*legacy.Block, error) {
+	block, err := generateOn(s.Chain, parent, s.now, txs)
+	if err != nil {
+		return nil, err
+	}
+	s.now += s.step
+	if _, err := s.Chain.ProcessBlock(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Reorg scripts a reorganization: it builds a side branch of the given
+// length on the main-chain block at fromHeight. Once the branch
+// outworks the main chain the Chain switches to it, exercising the same
+// code paths a real reorg does.
+func (s *Simulator) Reorg(fromHeight uint64, length int) ([]*legacy.Block, error) {
+	parent, err := s.Chain.GetBlockByHeight(fromHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*legacy.Block, 0, length)
+	for i := 0; i < length; i++ {
+		block, err := s.GenerateBlockOn(parent)
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, block)
+		parent = block
+	}
+	return blocks, nil
+}
+
+// SpendTx returns a transaction spending the index-th output of prev in
+// full, paying amount minus fee to the given control program. It
+// carries no witness arguments, which suffices for the simulator's
+// OP_TRUE outputs.
+func SpendTx(prev *legacy.Tx, index int, program []byte, fee uint64) (*legacy.Tx, error) {
+	if index < 0 || index >= len(prev.ResultIds) {
+		return nil, errors.New("output index out of range")
+	}
+	entry, ok := prev.Tx.Entries[*prev.ResultIds[index]].(*bc.Output)
+	if !ok {
+		return nil, errors.New("not an output (retired?)")
+	}
+
+	amount := entry.Source.Value.Amount
+	if fee > amount {
+		return nil, errors.New("fee exceeds output amount")
+	}
+
+	txData := legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput([][]byte{}, *entry.Source.Ref, *entry.Source.Value.AssetId,
+				amount, entry.Source.Position, prev.Outputs[index].ControlProgram, *entry.Data, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*entry.Source.Value.AssetId, amount-fee, program, nil),
+		},
+	}
+	return legacy.NewTx(txData), nil
+}
+
+// Populate extends an existing chain to toHeight with deterministic
+// empty blocks; the node's --simulate startup path uses it.
+func Populate(c *protocol.Chain, toHeight uint64) error {
+	parent := c.BestBlock()
+	step := consensus.ActiveNetParams.TargetSecondsPerBlock * 1000
+	now := parent.TimestampMS
+
+	for c.Height() < toHeight {
+		now += step
+		block, err := generateOn(c, parent, now, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := c.ProcessBlock(block); err != nil {
+			return err
+		}
+		parent = block
+	}
+	return nil
+}
+
+// generateOn assembles and solves one block, mirroring the mining
+// template but with a scripted timestamp and an explicit transaction
+// list instead of pool selection.
+func generateOn(c *protocol.Chain, parent *legacy.Block, nowMS uint64, txs []*legacy.Tx) (*legacy.Block, error) {
+	preBcBlock := legacy.MapBlock(parent)
+	height := parent.Height + 1
+	nextBlockSeed := algorithm.CreateSeed(height, preBcBlock.Seed, []*bc.Hash{&preBcBlock.ID})
+
+	var compareDiffBH *legacy.BlockHeader
+	if compareDiffBlock, err := c.GetBlockByHeight(height - consensus.ActiveNetParams.BlocksPerRetarget); err == nil {
+		compareDiffBH = &compareDiffBlock.BlockHeader
+	}
+
+	txFee := uint64(0)
+	for _, tx := range txs {
+		fee, err := validation.ValidateTx(tx.Tx, preBcBlock)
+		if err != nil {
+			return nil, errors.Wrapf(err, "scripted transaction %s", tx.ID.String())
+		}
+		txFee += fee
+	}
+
+	cbTx, err := coinbaseTx(txFee, height)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := append([]*legacy.Tx{cbTx}, txs...)
+	txEntries := make([]*bc.Tx, 0, len(transactions))
+	for _, tx := range transactions {
+		txEntries = append(txEntries, tx.Tx)
+	}
+
+	b := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			Version:           c.ComputeBlockVersion(),
+			Height:            height,
+			PreviousBlockHash: parent.Hash(),
+			Seed:              *nextBlockSeed,
+			TimestampMS:       nowMS,
+			Bits:              consensus.CalcNextRequiredDifficulty(&parent.BlockHeader, compareDiffBH),
+		},
+		Transactions: transactions,
+	}
+	if b.BlockHeader.BlockCommitment.TransactionsMerkleRoot, err = bc.MerkleRoot(txEntries); err != nil {
+		return nil, errors.Wrap(err, "calculating tx merkle root")
+	}
+
+	// solve from zero so the same chain yields the same nonces
+	for nonce := uint64(0); ; nonce++ {
+		b.Nonce = nonce
+		hash := b.Hash()
+		if consensus.CheckProofOfWork(&hash, b.Bits) {
+			return b, nil
+		}
+	}
+}
+
+// coinbaseTx mirrors the mining coinbase: subsidy plus fees to an
+// anyone-can-redeem height-locked program.
+func coinbaseTx(fee, height uint64) (*legacy.Tx, error) {
+	script, err := vmutil.CoinbaseProgram(nil, 0, height+consensus.CoinbasePendingBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	txData := legacy.TxData{
+		Version: 1,
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*consensus.BTMAssetID, fee+consensus.BlockSubsidy(height), script, nil),
+		},
+	}
+	return legacy.NewTx(txData), nil
+}
@@ -0,0 +1,79 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bytom/protocol/vm"
+)
+
+func TestDeterministicBlocks(t *testing.T) {
+	first, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.GenerateBlocks(3); err != nil {
+		t.Fatal(err)
+	}
+	if first.Chain.Height() != 4 {
+		t.Fatalf("height = %d, want 4", first.Chain.Height())
+	}
+
+	second, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.GenerateBlocks(3); err != nil {
+		t.Fatal(err)
+	}
+	if *first.Chain.BestBlockHash() != *second.Chain.BestBlockHash() {
+		t.Fatal("two runs produced different chains")
+	}
+}
+
+func TestScriptedTransaction(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spend, err := SpendTx(s.Genesis.Transactions[0], 0, []byte{byte(vm.OP_TRUE)}, 5000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := s.GenerateBlock(spend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Transactions) != 2 {
+		t.Fatalf("block has %d transactions, want 2", len(block.Transactions))
+	}
+	if block.Transactions[1].ID != spend.ID {
+		t.Fatal("scripted transaction missing from block")
+	}
+}
+
+func TestScriptedReorg(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GenerateBlocks(4); err != nil {
+		t.Fatal(err)
+	}
+	oldTip := *s.Chain.BestBlockHash()
+
+	s.AdvanceTime(time.Minute)
+	branch, err := s.Reorg(2, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newTip := branch[len(branch)-1]
+	if *s.Chain.BestBlockHash() != newTip.Hash() {
+		t.Fatal("chain did not switch to the longer branch")
+	}
+	if s.Chain.InMainChain(5, oldTip) {
+		t.Fatal("old tip still in main chain after reorg")
+	}
+}
@@ -20,6 +20,9 @@ func EventStringFork() string    { return "Fork" }
 //func EventStringTx(tx Tx) string { return cmn.Fmt("Tx:%X", tx.Hash()) }
 
 func EventStringNewBlock() string         { return "NewBlock" }
+func EventStringNewWork() string          { return "NewWork" }
+func EventStringReorg() string            { return "Reorg" }
+func EventStringDoubleSpend() string      { return "DoubleSpendDetected" }
 func EventStringNewBlockHeader() string   { return "NewBlockHeader" }
 func EventStringNewRound() string         { return "NewRound" }
 func EventStringNewRoundStep() string     { return "NewRoundStep" }
@@ -36,6 +39,9 @@ func EventStringVote() string             { return "Vote" }
 
 var (
 	EventDataNameNewBlock       = "new_block"
+	EventDataNameNewWork        = "new_work"
+	EventDataNameReorg          = "reorg"
+	EventDataNameDoubleSpend    = "double_spend"
 	EventDataNameNewBlockHeader = "new_block_header"
 	EventDataNameTx             = "tx"
 	EventDataNameRoundState     = "round_state"
@@ -82,6 +88,9 @@ const (
 	EventDataTypeFork           = byte(0x02)
 	EventDataTypeTx             = byte(0x03)
 	EventDataTypeNewBlockHeader = byte(0x04)
+	EventDataTypeNewWork        = byte(0x05)
+	EventDataTypeReorg          = byte(0x06)
+	EventDataTypeDoubleSpend    = byte(0x07)
 
 	EventDataTypeRoundState = byte(0x11)
 	EventDataTypeVote       = byte(0x12)
@@ -89,6 +98,9 @@ const (
 
 var tmEventDataMapper = data.NewMapper(TMEventData{}).
 	RegisterImplementation(EventDataNewBlock{}, EventDataNameNewBlock, EventDataTypeNewBlock).
+	RegisterImplementation(EventDataNewWork{}, EventDataNameNewWork, EventDataTypeNewWork).
+	RegisterImplementation(EventDataReorg{}, EventDataNameReorg, EventDataTypeReorg).
+	RegisterImplementation(EventDataDoubleSpend{}, EventDataNameDoubleSpend, EventDataTypeDoubleSpend).
 	RegisterImplementation(EventDataNewBlockHeader{}, EventDataNameNewBlockHeader, EventDataTypeNewBlockHeader).
 	RegisterImplementation(EventDataTx{}, EventDataNameTx, EventDataTypeTx).
 	RegisterImplementation(EventDataRoundState{}, EventDataNameRoundState, EventDataTypeRoundState).
@@ -101,6 +113,31 @@ type EventDataNewBlock struct {
 	//Block *Block `json:"block"`
 }
 
+// EventDataNewWork tells subscribed miners the block template changed:
+// either the chain advanced or new fees arrived in the mempool.
+type EventDataNewWork struct {
+	Height            uint64 `json:"height"`
+	PreviousBlockHash string `json:"previous_block_hash"`
+	Reason            string `json:"reason"`
+}
+
+// EventDataReorg reports a chain reorganization: how deep the rewind
+// was and which transactions fell off the old main chain.
+type EventDataReorg struct {
+	OldTip      string   `json:"old_tip"`
+	NewTip      string   `json:"new_tip"`
+	Depth       uint64   `json:"depth"`
+	DetachedTxs []string `json:"detached_txs"`
+}
+
+// EventDataDoubleSpend reports a transaction that tried to spend an
+// output an unconfirmed pool transaction already spends.
+type EventDataDoubleSpend struct {
+	TxID         string `json:"tx_id"`
+	ConflictTxID string `json:"conflict_tx_id"`
+	OutputID     string `json:"output_id"`
+}
+
 // light weight event for benchmarking
 type EventDataNewBlockHeader struct {
 	//Header *Header `json:"header"`
@@ -131,6 +168,9 @@ type EventDataVote struct {
 }
 
 func (_ EventDataNewBlock) AssertIsTMEventData()       {}
+func (_ EventDataNewWork) AssertIsTMEventData()        {}
+func (_ EventDataReorg) AssertIsTMEventData()          {}
+func (_ EventDataDoubleSpend) AssertIsTMEventData()    {}
 func (_ EventDataNewBlockHeader) AssertIsTMEventData() {}
 func (_ EventDataTx) AssertIsTMEventData()             {}
 func (_ EventDataRoundState) AssertIsTMEventData()     {}
@@ -188,6 +228,18 @@ func FireEventNewBlockHeader(fireable events.Fireable, header EventDataNewBlockH
 	fireEvent(fireable, EventStringNewBlockHeader(), TMEventData{header})
 }
 
+func FireEventNewWork(fireable events.Fireable, work EventDataNewWork) {
+	fireEvent(fireable, EventStringNewWork(), TMEventData{work})
+}
+
+func FireEventReorg(fireable events.Fireable, reorg EventDataReorg) {
+	fireEvent(fireable, EventStringReorg(), TMEventData{reorg})
+}
+
+func FireEventDoubleSpend(fireable events.Fireable, doubleSpend EventDataDoubleSpend) {
+	fireEvent(fireable, EventStringDoubleSpend(), TMEventData{doubleSpend})
+}
+
 func FireEventVote(fireable events.Fireable, vote EventDataVote) {
 	fireEvent(fireable, EventStringVote(), TMEventData{vote})
 }